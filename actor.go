@@ -42,9 +42,155 @@ func (e *Ent) Animate(move, frame int) bool {
 	if frame < actor.nFrames {
 		actor.frame = float64(frame)
 	}
+	actor.rooted = false      // don't move the part for the animation change.
 	return move == actor.move // true if the requested movement is available.
 }
 
+// Crossfade changes to the requested animation by blending from the
+// current pose to the new animation over the given number of seconds.
+// Returns true if the requested animation was available.
+//
+// Depends on initialization with Ent.MakeActor and loaded animation data.
+func (e *Ent) Crossfade(move int, seconds float64) bool {
+	actor := e.app.models.getActor(e.eid)
+	if actor == nil || actor.anm == nil {
+		log.Printf("Crossfade needs MakeActor %d and loaded data", e.eid)
+		return false
+	}
+	actor.rooted = actor.rooted && seconds > 0 // only a blended change is continuous.
+	if seconds > 0 {
+		actor.fadeMove, actor.fadeFrame = actor.move, actor.frame
+		actor.fadeTime, actor.faded = seconds, 0
+	}
+	actor.nFrames = actor.anm.maxFrames(move)
+	actor.move = actor.anm.isMovement(move)
+	actor.frame = 0
+	return move == actor.move
+}
+
+// AnimateLayer plays an animation on top of the base animation set using
+// Animate or Crossfade. Layers are applied in increasing layer order.
+//    layer   : layer number. Using an existing layer replaces it.
+//    move    : the animation played by the layer.
+//    weight  : 0 to 1 amount of the layer animation that is applied.
+//    additive: false blends towards the layer animation. True adds the
+//              layer animation change from its first frame, ie: to wave
+//              while walking.
+// Returns true if the requested animation was available.
+//
+// Depends on initialization with Ent.MakeActor and loaded animation data.
+func (e *Ent) AnimateLayer(layer, move int, weight float64, additive bool) bool {
+	actor := e.app.models.getActor(e.eid)
+	if actor == nil || actor.anm == nil {
+		log.Printf("AnimateLayer needs MakeActor %d and loaded data", e.eid)
+		return false
+	}
+	l := actor.getLayer(layer)
+	l.move = actor.anm.isMovement(move)
+	l.nFrames = actor.anm.maxFrames(l.move)
+	l.frame, l.weight, l.additive = 0, weight, additive
+	return move == l.move
+}
+
+// SetLayerWeight changes the amount, from 0 to 1, that an animation
+// layer affects the base animation.
+//
+// Depends on Ent.AnimateLayer.
+func (e *Ent) SetLayerWeight(layer int, weight float64) *Ent {
+	if actor := e.app.models.getActor(e.eid); actor != nil {
+		if l := actor.findLayer(layer); l != nil {
+			l.weight = weight
+			return e
+		}
+	}
+	log.Printf("SetLayerWeight needs AnimateLayer %d", e.eid)
+	return e
+}
+
+// SetLayerMask restricts an animation layer to some of the joints.
+// The mask has a 0 to 1 weight for each joint. Use JointMask to
+// create a mask for a part of the model. A nil mask affects all joints.
+//
+// Depends on Ent.AnimateLayer.
+func (e *Ent) SetLayerMask(layer int, mask []float64) *Ent {
+	if actor := e.app.models.getActor(e.eid); actor != nil && actor.anm != nil {
+		if l := actor.findLayer(layer); l != nil {
+			if mask != nil && len(mask) != actor.anm.jointCnt {
+				log.Printf("SetLayerMask needs %d joints", actor.anm.jointCnt)
+				return e
+			}
+			l.mask = mask
+			return e
+		}
+	}
+	log.Printf("SetLayerMask needs AnimateLayer %d", e.eid)
+	return e
+}
+
+// StopLayer removes an animation layer.
+//
+// Depends on Ent.AnimateLayer.
+func (e *Ent) StopLayer(layer int) *Ent {
+	if actor := e.app.models.getActor(e.eid); actor != nil {
+		for cnt, l := range actor.layers {
+			if l.layer == layer {
+				actor.layers = append(actor.layers[:cnt], actor.layers[cnt+1:]...)
+				return e
+			}
+		}
+	}
+	log.Printf("StopLayer needs AnimateLayer %d", e.eid)
+	return e
+}
+
+// JointMask returns a layer mask that includes the given joints
+// and all of their child joints, ie: the upper body joint.
+// Returns nil if the animation data has not yet been loaded.
+//
+// Depends on initialization with Ent.MakeActor and loaded animation data.
+func (e *Ent) JointMask(joints ...int) []float64 {
+	if actor := e.app.models.getActor(e.eid); actor != nil && actor.anm != nil {
+		return actor.anm.mask(joints...)
+	}
+	log.Printf("JointMask needs MakeActor %d and loaded data", e.eid)
+	return nil
+}
+
+// OnAnimEvent sets the function that is called when the current
+// animation passes a named event frame, ie: a footstep. Events are
+// declared in an optional sidecar file beside the animation file,
+// eg: "actor" finds models/actor.evt. The handler is called during
+// the engine update with the movement and the event name.
+// A nil handler stops event delivery.
+//
+// Depends on initialization with Ent.MakeActor.
+func (e *Ent) OnAnimEvent(handler func(move int, event string)) *Ent {
+	if actor := e.app.models.getActor(e.eid); actor != nil {
+		actor.onEvent = handler
+		return e
+	}
+	log.Printf("OnAnimEvent needs MakeActor %d", e.eid)
+	return e
+}
+
+// SetRootMotion uses the animated root joint translation to move
+// the part instead of moving the model away from the part location.
+// The axes are model axes. For example, a Y-up walk animation would
+// use (true, false, true) so that the part moves along the ground
+// while any vertical bounce stays in the animation.
+// Root motion is off by default. Turn it off using all false axes.
+//
+// Depends on initialization with Ent.MakeActor.
+func (e *Ent) SetRootMotion(x, y, z bool) *Ent {
+	if actor := e.app.models.getActor(e.eid); actor != nil {
+		actor.axes = [3]bool{x, y, z}
+		actor.rooted = false
+		return e
+	}
+	log.Printf("SetRootMotion needs MakeActor %d", e.eid)
+	return e
+}
+
 // Action returns the current animation information. Animations consist
 // of a number of different movements, each with a number of frames.
 //    move    the currently selected animation.
@@ -98,4 +244,204 @@ type actor struct {
 	move    int        // Current animation defaults to 0.
 	nFrames int        // Number of frames in the current movement.
 	pose    []lin.M4   // Pose refreshed each update.
+
+	// Optional crossfade from a previous movement.
+	fadeMove  int     // Previous movement.
+	fadeFrame float64 // Previous movement frame counter.
+	fadeTime  float64 // Crossfade seconds. Zero if not fading.
+	faded     float64 // Elapsed crossfade seconds.
+
+	// Optional layers applied over the base movement.
+	layers []*layer // Ordered by layer number.
+	other  []lin.M4 // Scratch joint transforms for blending.
+	base   []lin.M4 // Scratch first frame for additive layers.
+
+	// Optional animation events and root motion.
+	onEvent func(move int, event string) // Application event handler.
+	axes    [3]bool                      // Root translation axes that move the part.
+	rooted  bool                         // True once the root translation is tracked.
+	root    lin.V3                       // Root translation from the last update.
+	drift   lin.V3                       // Root translation change since the last update.
+}
+
+// layer is an animation applied over the base actor animation.
+type layer struct {
+	layer    int       // Layer number.
+	move     int       // Layer animation.
+	frame    float64   // Layer frame counter.
+	nFrames  int       // Number of frames in the layer movement.
+	weight   float64   // Amount applied, 0 to 1.
+	additive bool      // Add instead of blend.
+	mask     []float64 // Optional per joint weights.
+}
+
+// getLayer returns the given layer, creating it if necessary.
+func (a *actor) getLayer(layerNum int) *layer {
+	if l := a.findLayer(layerNum); l != nil {
+		return l
+	}
+	l := &layer{layer: layerNum}
+	index := len(a.layers)
+	for cnt, al := range a.layers {
+		if al.layer > layerNum {
+			index = cnt
+			break
+		}
+	}
+	a.layers = append(a.layers, nil)
+	copy(a.layers[index+1:], a.layers[index:])
+	a.layers[index] = l
+	return l
+}
+
+// findLayer returns the given layer or nil if it does not exist.
+func (a *actor) findLayer(layerNum int) *layer {
+	for _, l := range a.layers {
+		if l.layer == layerNum {
+			return l
+		}
+	}
+	return nil
+}
+
+// animate updates the actor pose, blending in any crossfade and layers,
+// extracting any root motion, and firing any animation events.
+//    dt: time since last update. Generally 0.02sec.
+func (a *actor) animate(dt float64) {
+	anm := a.anm
+	if len(anm.moves) <= 0 {
+		return
+	}
+	prev, ratio, frame := a.frame, 1.0, 0.0
+	if a.fadeTime <= 0 && len(a.layers) == 0 && !a.rootMotion() {
+		frame = anm.animate(dt, a.frame, a.move, a.pose)
+	} else {
+		ratio = a.blend(dt)
+		if a.rootMotion() {
+			a.extractRoot()
+		}
+		anm.concat(a.pose)
+		frame = anm.advance(dt, a.frame, a.move)
+	}
+	a.frame = wrapFrame(frame, a.nFrames)
+	wrapped := a.frame != frame
+	if wrapped {
+		a.shiftRoot(a.move, ratio)
+	}
+	a.fire(prev, wrapped)
+}
+
+// blend sets the actor pose to the local joint transforms combining
+// the current movement with any crossfade and layers. Returns the
+// amount of the current movement in the crossfade.
+func (a *actor) blend(dt float64) (ratio float64) {
+	anm := a.anm
+	if len(a.other) != anm.jointCnt {
+		a.other = make([]lin.M4, anm.jointCnt)
+		a.base = make([]lin.M4, anm.jointCnt)
+	}
+	anm.local(a.frame, a.move, a.pose)
+
+	// blend from the previous movement to the current movement.
+	ratio = 1.0
+	if a.fadeTime > 0 {
+		anm.local(a.fadeFrame, a.fadeMove, a.other)
+		ratio = a.faded / a.fadeTime
+		anm.blend(a.other, a.pose, ratio, nil)
+		a.pose, a.other = a.other, a.pose
+		frame := anm.advance(dt, a.fadeFrame, a.fadeMove)
+		a.fadeFrame = wrapFrame(frame, anm.maxFrames(a.fadeMove))
+		if a.fadeFrame != frame {
+			a.shiftRoot(a.fadeMove, 1-ratio)
+		}
+		if a.faded += dt; a.faded >= a.fadeTime {
+			a.fadeTime, a.faded = 0, 0
+		}
+	}
+
+	// apply the layers in order.
+	for _, l := range a.layers {
+		anm.local(l.frame, l.move, a.other)
+		if l.additive {
+			anm.local(0, l.move, a.base)
+			anm.add(a.pose, a.other, a.base, l.weight, l.mask)
+		} else {
+			anm.blend(a.pose, a.other, l.weight, l.mask)
+		}
+		l.frame = wrapFrame(anm.advance(dt, l.frame, l.move), l.nFrames)
+	}
+	return ratio
+}
+
+// rootMotion returns true if the root joint translation
+// moves the part along any of the axes.
+func (a *actor) rootMotion() bool { return a.axes[0] || a.axes[1] || a.axes[2] }
+
+// rootAxes zeros any translation that is not on a root motion axis.
+func (a *actor) rootAxes(x, y, z float64) (float64, float64, float64) {
+	if !a.axes[0] {
+		x = 0
+	}
+	if !a.axes[1] {
+		y = 0
+	}
+	if !a.axes[2] {
+		z = 0
+	}
+	return x, y, z
+}
+
+// extractRoot removes the root joint translation from the local pose
+// and tracks how far the root joint moved since the last update.
+func (a *actor) extractRoot() {
+	x, y, z := a.rootAxes(a.pose[0].Wx, a.pose[0].Wy, a.pose[0].Wz)
+	if a.rooted {
+		a.drift.SetS(x-a.root.X, y-a.root.Y, z-a.root.Z)
+	}
+	a.root.SetS(x, y, z)
+	a.rooted = true
+	for cnt, parent := range a.anm.joints {
+		if parent < 0 { // all root joints move together.
+			m := &a.pose[cnt]
+			m.Wx, m.Wy, m.Wz = m.Wx-x, m.Wy-y, m.Wz-z
+		}
+	}
+}
+
+// shiftRoot adjusts the tracked root translation when a movement loops
+// so that the part keeps moving forward instead of jumping back.
+//    move  : the movement that looped.
+//    amount: amount of the movement in the pose.
+func (a *actor) shiftRoot(move int, amount float64) {
+	if !a.rooted || amount == 0 {
+		return
+	}
+	x0, y0, z0 := a.anm.root(0, move)
+	x1, y1, z1 := a.anm.root(a.anm.maxFrames(move)-1, move)
+	x, y, z := a.rootAxes((x1-x0)*amount, (y1-y0)*amount, (z1-z0)*amount)
+	a.root.SetS(a.root.X-x, a.root.Y-y, a.root.Z-z)
+}
+
+// fire calls the event handler for the current movement events
+// from the previous frame up to, but not including, the current frame.
+func (a *actor) fire(prev float64, wrapped bool) {
+	if a.onEvent == nil {
+		return
+	}
+	move := a.move
+	for _, ev := range a.anm.moves[move].events {
+		after, before := ev.frame >= prev, ev.frame < a.frame
+		if (wrapped && (after || before)) || (after && before) {
+			a.onEvent(move, ev.name)
+		}
+	}
+}
+
+// wrapFrame keeps the frame counter within the movement frames.
+func wrapFrame(frame float64, nFrames int) float64 {
+	nextFrame := int(math.Floor(frame + 1))
+	if nextFrame >= nFrames {
+		frame -= float64(nFrames - 1)
+	}
+	return frame
 }
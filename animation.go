@@ -115,6 +115,18 @@ func (a *animation) animate(dt, frame float64, movement int, pose []lin.M4) floa
 	if len(a.moves) <= 0 {
 		return 0
 	}
+	a.local(frame, movement, pose)
+	a.concat(pose)
+	return a.advance(dt, frame, movement)
+}
+
+// local sets the joint transforms for the given movement frame without
+// concatenating the parent joints. Local transforms from different
+// movements can be blended before being concatenated into a pose.
+//    frame   : the current frame position.
+//    movement: the affected animation movement, indexed from 0 up.
+//    local   : interpolated data at the fractional frame position.
+func (a *animation) local(frame float64, movement int, local []lin.M4) {
 	mv := a.moves[movement]
 
 	// The frame timer, fcnt, controls the speed of the animation.
@@ -124,22 +136,87 @@ func (a *animation) animate(dt, frame float64, movement int, pose []lin.M4) floa
 	frame1 = (frame1 % (mv.fn)) + mv.f0
 	frame2 = (frame2 % (mv.fn)) + mv.f0
 
-	// Interpolate matrixes between the two closest frames and concatenate with
-	// parent matrix if necessary. Concatenate the result with the inverse of the
-	// base pose. FUTURE: blending and inter-frame blending could be done here.
+	// Interpolate matrixes between the two closest frames.
 	for cnt := 0; cnt < a.jointCnt; cnt++ {
-
-		// interpolate between the two closest frames.
 		m1, m2 := &a.frames[frame1*a.jointCnt+cnt], &a.frames[frame2*a.jointCnt+cnt]
-		a.jnt0.Set(m1).Scale(1-frameoffset).Add(a.jnt0, a.jnt1.Set(m2).Scale(frameoffset))
+		(&local[cnt]).Set(m1).Scale(1-frameoffset).Add(&local[cnt], a.jnt1.Set(m2).Scale(frameoffset))
+	}
+}
+
+// concat combines local joint transforms with their parent transforms.
+// Parent joints always appear before their children.
+func (a *animation) concat(pose []lin.M4) {
+	for cnt := 0; cnt < a.jointCnt; cnt++ {
 		if a.joints[cnt] >= 0 {
 
 			// parentPose * childPose * childInverseBasePose
-			a.jnt0.Mult(a.jnt0, &pose[a.joints[cnt]])
+			a.jnt0.Mult(&pose[cnt], &pose[a.joints[cnt]])
+			(&pose[cnt]).Set(a.jnt0)
 		}
-		(&pose[cnt]).Set(a.jnt0)
 	}
-	return frame + dt*mv.rate // return incremented frame position.
+}
+
+// advance returns the frame position after dt seconds.
+func (a *animation) advance(dt, frame float64, movement int) float64 {
+	return frame + dt*a.moves[movement].rate
+}
+
+// blend sets each local joint transform part way towards the
+// other joint transforms. Mask, if not nil, scales the amount
+// for each joint.
+func (a *animation) blend(local, other []lin.M4, amount float64, mask []float64) {
+	for cnt := 0; cnt < a.jointCnt; cnt++ {
+		w := amount
+		if mask != nil {
+			w *= mask[cnt]
+		}
+		if w != 0 {
+			m := &local[cnt]
+			m.Scale(1-w).Add(m, a.jnt1.Set(&other[cnt]).Scale(w))
+		}
+	}
+}
+
+// add adds the change between the other and base joint transforms to
+// each local joint transform. Mask, if not nil, scales the amount
+// for each joint.
+func (a *animation) add(local, other, base []lin.M4, amount float64, mask []float64) {
+	for cnt := 0; cnt < a.jointCnt; cnt++ {
+		w := amount
+		if mask != nil {
+			w *= mask[cnt]
+		}
+		if w != 0 {
+			a.jnt1.Set(&base[cnt]).Scale(-1).Add(a.jnt1, &other[cnt]).Scale(w)
+			(&local[cnt]).Add(&local[cnt], a.jnt1)
+		}
+	}
+}
+
+// root returns the root joint translation for the given movement frame.
+// The root joint is the first joint. Its translation is relative to
+// the base pose.
+func (a *animation) root(frame, movement int) (x, y, z float64) {
+	mv := a.moves[movement]
+	m := &a.frames[(frame%mv.fn+mv.f0)*a.jointCnt]
+	return m.Wx, m.Wy, m.Wz
+}
+
+// mask returns joint weights of 1 for the given joints and
+// all of their child joints. Other joints have 0 weight.
+func (a *animation) mask(joints ...int) []float64 {
+	mask := make([]float64, a.jointCnt)
+	for _, j := range joints {
+		if j >= 0 && j < a.jointCnt {
+			mask[j] = 1
+		}
+	}
+	for cnt := 0; cnt < a.jointCnt; cnt++ {
+		if p := a.joints[cnt]; p >= 0 && mask[p] == 1 {
+			mask[cnt] = 1 // parents appear before children.
+		}
+	}
+	return mask
 }
 
 // anim
@@ -153,4 +230,11 @@ type movement struct {
 	name   string  // Name of the movement.
 	f0, fn int     // First animation frame, number of animation frames.
 	rate   float64 // Animation frames per second. Often 24.
+	events []event // Optional named frames.
+}
+
+// event names a movement frame, ie: a footstep.
+type event struct {
+	name  string  // Name delivered to the application.
+	frame float64 // Frame relative to the movement start.
 }
@@ -0,0 +1,119 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// testAnimation creates a two joint animation with three movements.
+// Joint 1 is a child of joint 0. Movement 0 has identity joints,
+// movement 1 has joints scaled by 3, and movement 2 moves the root
+// joint along X with a step event at frame 1.
+func testAnimation() *animation {
+	anm := newAnimation("test")
+	frames := []*lin.M4{
+		lin.NewM4I(), lin.NewM4I(), lin.NewM4I(), lin.NewM4I(),
+		lin.NewM4I().ScaleSM(3, 3, 3), lin.NewM4I().ScaleSM(3, 3, 3),
+		lin.NewM4I().ScaleSM(3, 3, 3), lin.NewM4I().ScaleSM(3, 3, 3),
+		lin.NewM4I(), lin.NewM4I(),
+		&lin.M4{Xx: 1, Yy: 1, Zz: 1, Wx: 1, Ww: 1}, lin.NewM4I(),
+		&lin.M4{Xx: 1, Yy: 1, Zz: 1, Wx: 2, Ww: 1}, lin.NewM4I(),
+	}
+	moves := []movement{
+		{name: "rest", f0: 0, fn: 2, rate: 1},
+		{name: "grow", f0: 2, fn: 2, rate: 1},
+		{name: "walk", f0: 4, fn: 3, rate: 1, events: []event{{name: "step", frame: 1}}},
+	}
+	anm.setData(frames, []int32{-1, 0}, moves)
+	return anm
+}
+
+// testActor creates an actor playing the rest movement.
+func testActor() *actor {
+	a := &actor{anm: testAnimation(), nFrames: 2}
+	a.pose = make([]lin.M4, 2)
+	return a
+}
+
+func TestMask(t *testing.T) {
+	anm := testAnimation()
+	if mask := anm.mask(0); mask[0] != 1 || mask[1] != 1 {
+		t.Errorf("Expected child joint in mask %v", mask)
+	}
+	if mask := anm.mask(1); mask[0] != 0 || mask[1] != 1 {
+		t.Errorf("Expected parent joint not in mask %v", mask)
+	}
+}
+
+func TestCrossfade(t *testing.T) {
+	a := testActor()
+	a.fadeMove, a.fadeTime = 0, 1
+	a.move, a.nFrames = 1, 2
+	a.animate(0.5) // start of the crossfade is all rest.
+	if x := a.pose[0].Xx; !lin.Aeq(x, 1) {
+		t.Errorf("Expected rest pose got %f", x)
+	}
+	a.animate(0.5) // halfway through the crossfade.
+	if x := a.pose[0].Xx; !lin.Aeq(x, 2) {
+		t.Errorf("Expected blended pose got %f", x)
+	}
+	a.animate(0.5) // crossfade is done.
+	if x := a.pose[0].Xx; !lin.Aeq(x, 3) || a.fadeTime != 0 {
+		t.Errorf("Expected grow pose got %f", x)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	a := testActor()
+	l := a.getLayer(1)
+	l.move, l.nFrames, l.weight = 1, 2, 0.5
+	l.mask = a.anm.mask(1)
+	a.animate(0.5)
+	if x0, x1 := a.pose[0].Xx, a.pose[1].Xx; !lin.Aeq(x0, 1) || !lin.Aeq(x1, 2) {
+		t.Errorf("Expected masked blend got %f %f", x0, x1)
+	}
+
+	// additive layers with no change from the first frame do nothing.
+	l.additive, l.mask = true, nil
+	a.animate(0.5)
+	if x0, x1 := a.pose[0].Xx, a.pose[1].Xx; !lin.Aeq(x0, 1) || !lin.Aeq(x1, 1) {
+		t.Errorf("Expected unchanged pose got %f %f", x0, x1)
+	}
+	if a.getLayer(0); a.layers[0].layer != 0 || len(a.layers) != 2 {
+		t.Errorf("Expected ordered layers")
+	}
+}
+
+func TestRootMotion(t *testing.T) {
+	a := testActor()
+	a.move, a.nFrames, a.axes = 2, 3, [3]bool{true, false, false}
+	drift := []float64{}
+	for cnt := 0; cnt < 4; cnt++ {
+		a.drift.SetS(0, 0, 0)
+		a.animate(1)
+		drift = append(drift, a.drift.X)
+		if a.pose[0].Wx != 0 {
+			t.Errorf("Expected root translation to be removed got %f", a.pose[0].Wx)
+		}
+	}
+	if drift[0] != 0 || drift[1] != 1 || drift[2] != 1 || drift[3] != 1 {
+		t.Errorf("Expected steady root motion got %v", drift)
+	}
+}
+
+func TestAnimEvents(t *testing.T) {
+	a := testActor()
+	a.move, a.nFrames = 2, 3
+	events := []string{}
+	a.onEvent = func(move int, event string) { events = append(events, event) }
+	for cnt := 0; cnt < 8; cnt++ {
+		a.animate(0.5) // two loops from frame 0 to 2.
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected a step event each loop got %v", events)
+	}
+}
@@ -10,6 +10,7 @@ package vu
 import (
 	"time"
 
+	"github.com/gazed/vu/load"
 	"github.com/gazed/vu/physics"
 )
 
@@ -62,10 +63,14 @@ type application struct {
 	bodies *bodies // Physic components.
 	lights *lights // Light components.
 	sounds *sounds // Audio components.
+	tags   *tags   // Application entity names.
+	tweens *tweens // Entity value interpolation.
 }
 
 // newApplication is called once on startup by engine.
-func newApplication(callback App) *application {
+// Assets are found using the given Locator, or the default
+// Locator when loc is nil.
+func newApplication(callback App, loc load.Locator) *application {
 	app := &application{app: callback}
 	app.attrs = []EngAttr{}
 	app.input = &Input{Down: map[int]int{}, Dt: timeStepSecs}
@@ -77,11 +82,13 @@ func newApplication(callback App) *application {
 	app.eids.create()    // mark eid 0 as special.
 	app.scenes = newScenes()
 	app.povs = newPovs()
-	app.ld = newLoader()
+	app.ld = newLoader(loc)
 	app.lights = newLights()
 	app.sounds = newSounds()
 	app.bodies = newBodies()
 	app.models = newModels()
+	app.tags = newTags()
+	app.tweens = newTweens()
 	app.frame = frame{}
 	return app
 }
@@ -105,6 +112,7 @@ func (app *application) update(ut uint64,
 		app.scenes.resize(app.state.W, app.state.H)
 	}
 	app.ld.processImports()
+	app.models.reload(app.ld.reloads())
 
 	// Update physics and particles using a fixed timestep so that
 	// each update advances by the same amount.
@@ -114,7 +122,10 @@ func (app *application) update(ut uint64,
 
 	// Advance model animations by elapsed time, not at fixed rate like physics.
 	// Animation data expects to be played back at a particular frame rate.
-	app.models.animate(elapsed.Seconds())
+	app.models.animate(elapsed.Seconds(), app.povs)
+
+	// Tweens are stepped like physics, one tick per update.
+	app.tweens.step(app)
 
 	// The application updates its own state as well as creating and
 	// deleting game objects, or even shutting down the engine.
@@ -175,27 +186,44 @@ func (app *application) shutdown() {
 // dispose asks each of the component managers to completely remove any
 // knowledge of the given entity. The entity id is recycled.
 //
-// FUTURE: cleaning up resources is not complete. Dispose currently means
-// removing entities from the app entity manager, yet keeps assets in the cache
-// and bound on the GPU/Snd devices. Applications often "dispose" entities only
-// to (re)use the underlying data again. Likely need another API for the
-// application to indicate when assets are to be completely unloaded.
+// Model assets are unloaded once they are no longer used by any model.
+// FUTURE: sounds are kept in the cache and bound on the audio device.
 func (app *application) dispose(id eid) {
 	dead := []eid{} // collect other identies that need disposing.
 	if s := app.scenes.get(id); s != nil {
 		dead = app.scenes.dispose(id, dead)
 	}
 	dead = app.povs.dispose(id, dead)
-	app.models.dispose(id)
+	app.models.dispose(id, app.ld)
 	app.bodies.dispose(id)
 	app.lights.dispose(id)
 	app.sounds.dispose(id)
+	app.tags.dispose(id)
 	app.eids.dispose(id)
 	for _, id := range dead {
 		app.dispose(id)
 	}
 }
 
+// Preload imports a named group of model assets.
+func (app *application) Preload(group string, loaded func(group string), assets ...string) {
+	app.ld.preload(group, loaded, assets...)
+}
+
+// Progress reports the import status of a preload group.
+func (app *application) Progress(group string) (loaded, failed, total int) {
+	if p, ok := app.ld.groups[group]; ok {
+		return p.done, p.failed, p.total
+	}
+	return 0, 0, 0
+}
+
+// Unload releases the assets of a preload group.
+func (app *application) Unload(group string) { app.ld.unload(group) }
+
+// Resident returns the assets currently held by the engine.
+func (app *application) Resident() []Resource { return app.ld.resident() }
+
 // Times returns numbers collected each main update loop.
 // This allows the application to get a sense of time and resource usage.
 func (app *application) Times() *Profile { return app.prof }
@@ -8,6 +8,7 @@ package vu
 
 import (
 	"hash/crc64"
+	"image"
 	"math"
 	"math/rand"
 )
@@ -39,9 +40,13 @@ const (
 	tex               // texture
 	snd               // sound
 	anm               // animation
+	cub               // cube map texture
 	assetTypes        // end of asset types.
 )
 
+// assetKinds are the asset type prefixes used in MakeModel.
+var assetKinds = []string{"fnt", "shd", "mat", "msh", "tex", "snd", "anm", "cub"}
+
 // =============================================================================
 // asset utility methods.
 
@@ -50,9 +55,51 @@ const (
 // to avoid collisions.
 func assetID(t int, name string) aid { return aid(t) + aid(stringHash(name))<<8 }
 
-var crcTable = crc64.MakeTable(rand.Uint64())
+// crcTable uses a fixed seed so that asset hashes are the same each run.
+// The global rand source is randomly seeded since go1.20.
+var crcTable = crc64.MakeTable(rand.New(rand.NewSource(1)).Uint64())
 
 // stringHash turns a string into a number.
 func stringHash(s string) (hash uint64) {
 	return crc64.Checksum([]byte(s), crcTable)
 }
+
+// assetSize returns the approximate number of bytes used by the asset
+// data in CPU memory. GPU copies of the data are about the same size.
+func assetSize(a asset) (bytes int) {
+	switch d := a.(type) {
+	case *Mesh:
+		if d.faces != nil {
+			bytes += int(d.faces.Size())
+		}
+		for _, vd := range d.vdata {
+			bytes += int(vd.Size())
+		}
+	case *Texture:
+		images := append([]image.Image{d.img}, d.mips...)
+		for _, img := range append(images, d.faces...) {
+			if img != nil {
+				size := img.Bounds().Size()
+				bytes += size.X * size.Y * 4 // RGBA
+			}
+		}
+	case *shader:
+		for _, line := range d.vsh {
+			bytes += len(line)
+		}
+		for _, line := range d.fsh {
+			bytes += len(line)
+		}
+	case *material:
+		bytes = 11 * 4 // float32 colors and values.
+	case *font:
+		for _, ch := range d.chars {
+			bytes += 7*8 + len(ch.uvcs)*4 // int positions and float32 uvs.
+		}
+	case *animation:
+		bytes = len(d.frames)*16*8 + len(d.joints)*4 // float64 matricies.
+	case *sound:
+		bytes = len(d.data.AudioData)
+	}
+	return bytes
+}
@@ -16,10 +16,11 @@ package al
 // Check information available at openal.org.
 
 // #cgo darwin  LDFLAGS: -framework OpenAL
-// #cgo linux   LDFLAGS: -lopenal -ldl
+// #cgo linux   LDFLAGS: -ldl
 // #cgo windows LDFLAGS: -lOpenAL32
 //
 // #include <stdlib.h>
+// #include <stdint.h>
 // #if defined(__APPLE__)
 // #include <dlfcn.h>
 // #elif defined(_WIN32)
@@ -290,7 +291,8 @@ package al
 // ALC_API void         ALC_APIENTRY wrap_alcCaptureStop( uintptr_t device ) { (*pfn_alcCaptureStop)( (ALCdevice *)device ); }
 // ALC_API void         ALC_APIENTRY wrap_alcCaptureSamples( uintptr_t device, ALCvoid *buffer, int samples ) { (*pfn_alcCaptureSamples)( (ALCdevice *)device, buffer, samples ); }
 //
-// void al_init() {
+// // Returns 0 if the OpenAL library could not be bound.
+// int al_init() {
 //    // AL/al.h
 //    pfn_alEnable                  = bindMethod("alEnable");
 //    pfn_alDisable                 = bindMethod("alDisable");
@@ -387,10 +389,12 @@ package al
 //    pfn_alcCaptureStart           = bindMethod("alcCaptureStart");
 //    pfn_alcCaptureStop            = bindMethod("alcCaptureStop");
 //    pfn_alcCaptureSamples         = bindMethod("alcCaptureSamples");
+//    return pfn_alGetError != NULL && pfn_alcOpenDevice != NULL;
 // }
 //
 import "C"
 import "unsafe"
+import "errors"
 import "fmt"
 
 // AL/al.h constants (with AL_ removed). Refer to the original header for constant documentation.
@@ -489,9 +493,16 @@ const (
 	C_CAPTURE_SAMPLES                  = 0x312
 )
 
-// bind the methods to the function pointers
-func Init() {
-	C.al_init()
+// ErrMissing is returned by Init when the OpenAL library is not installed.
+var ErrMissing = errors.New("OpenAL library not found")
+
+// Init binds the methods to the function pointers. ErrMissing is
+// returned if the OpenAL library could not be found.
+func Init() error {
+	if C.al_init() == 0 {
+		return ErrMissing
+	}
+	return nil
 }
 
 // convert a uint boolean to a go bool
@@ -7,6 +7,7 @@ import (
 	"testing"
 	// "time"
 
+	"github.com/gazed/vu/audio/al"
 	"github.com/gazed/vu/load"
 )
 
@@ -14,7 +15,11 @@ import (
 // by the engine. Depends on sound resources from the examples directory.
 func TestAudio(t *testing.T) {
 	a := audioWrapper()
-	a.Init()
+	if err := a.Init(); err == al.ErrMissing {
+		t.Skipf("Skipping audio test: %s", err)
+	} else if err != nil {
+		t.Fatalf("Failed to initialize audio %s", err)
+	}
 	s := &load.SndData{}
 	soundData := &Data{}
 	if err := s.Load("bloop", load.NewLocator().Dir("WAV", "../eg/audio")); err == nil {
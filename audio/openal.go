@@ -34,7 +34,9 @@ func audioWrapper() Audio { return &openal{} }
 // Init runs the one time openal library initialization. It is expected to
 // be called once by the engine on startup.
 func (a *openal) Init() (err error) {
-	al.Init()
+	if err = al.Init(); err != nil {
+		return err
+	}
 	if err = a.validate(); err != nil {
 		return fmt.Errorf("%s", err)
 	}
@@ -58,6 +58,16 @@ func (e *Ent) Cast(ray Body) (hit bool, x, y, z float64) {
 	return false, 0, 0, 0
 }
 
+// Cast implements Eng. It checks the ray against every physics body.
+func (app *application) Cast(ray Body) (ents []*Ent, hits []*physics.Hit) {
+	ids, hits := app.bodies.cast(ray)
+	ents = make([]*Ent, len(ids))
+	for cnt, id := range ids {
+		ents[cnt] = &Ent{app: app, eid: id}
+	}
+	return ents, hits
+}
+
 // Push adds to the body's linear velocity.
 // It is a wrapper for physics.Body.Push
 //
@@ -70,6 +80,98 @@ func (e *Ent) Push(x, y, z float64) {
 	log.Printf("Push needs MakeBody %d", e.eid)
 }
 
+// JoinBall pins this entity's body to the other entity's body at the
+// world pivot x, y, z. The bodies turn freely around the pivot, ie: chain
+// links or ragdoll shoulders. A nil other pins the body to the world.
+// Nil is returned if either entity does not have a body.
+//
+// Depends on Ent.MakeBody.
+func (e *Ent) JoinBall(other *Ent, x, y, z float64) physics.Joint {
+	return e.join(other, "JoinBall", func(a, b physics.Body) physics.Joint {
+		return physics.NewBallJoint(a, b, x, y, z)
+	})
+}
+
+// JoinHinge joins this entity's body to the other entity's body at the
+// world pivot x, y, z so that it only turns around the world axis
+// ax, ay, az, ie: a door. A nil other hinges the body to the world.
+// Use the returned Hinge to set limits or a motor.
+//
+// Depends on Ent.MakeBody.
+func (e *Ent) JoinHinge(other *Ent, x, y, z, ax, ay, az float64) physics.Hinge {
+	if j := e.join(other, "JoinHinge", func(a, b physics.Body) physics.Joint {
+		return physics.NewHingeJoint(a, b, x, y, z, ax, ay, az)
+	}); j != nil {
+		return j.(physics.Hinge)
+	}
+	return nil
+}
+
+// JoinSlider joins this entity's body to the other entity's body so
+// that it only moves along the world axis ax, ay, az. A nil other slides
+// the body along the world. Use the returned Slider to set limits.
+//
+// Depends on Ent.MakeBody.
+func (e *Ent) JoinSlider(other *Ent, ax, ay, az float64) physics.Slider {
+	if j := e.join(other, "JoinSlider", func(a, b physics.Body) physics.Joint {
+		return physics.NewSliderJoint(a, b, ax, ay, az)
+	}); j != nil {
+		return j.(physics.Slider)
+	}
+	return nil
+}
+
+// JoinFixed glues this entity's body to the other entity's body.
+// A nil other fixes the body to the world.
+//
+// Depends on Ent.MakeBody.
+func (e *Ent) JoinFixed(other *Ent) physics.Joint {
+	return e.join(other, "JoinFixed", func(a, b physics.Body) physics.Joint {
+		return physics.NewFixedJoint(a, b)
+	})
+}
+
+// JoinDistance keeps the world anchor ax, ay, az on this entity's body
+// at its current distance from the world anchor bx, by, bz on the other
+// entity's body. A nil other ties the body to the world. Use the returned
+// Distance to change the length or add a spring.
+//
+// Depends on Ent.MakeBody.
+func (e *Ent) JoinDistance(other *Ent, ax, ay, az, bx, by, bz float64) physics.Distance {
+	if j := e.join(other, "JoinDistance", func(a, b physics.Body) physics.Joint {
+		return physics.NewDistanceJoint(a, b, ax, ay, az, bx, by, bz)
+	}); j != nil {
+		return j.(physics.Distance)
+	}
+	return nil
+}
+
+// Unjoin removes all joints from this entity's body.
+//
+// Depends on Ent.MakeBody.
+func (e *Ent) Unjoin() { e.app.bodies.unjoin(e.eid) }
+
+// join creates a joint between this entity's body and the other entity's
+// body, or the world if other is nil. The joint is added to physics.
+func (e *Ent) join(other *Ent, method string, create func(a, b physics.Body) physics.Joint) physics.Joint {
+	a, b := e.app.bodies.get(e.eid), physics.Body(nil)
+	if a == nil {
+		log.Printf("%s needs MakeBody %d", method, e.eid)
+		return nil
+	}
+	ids := []eid{e.eid}
+	if other != nil {
+		if b = e.app.bodies.get(other.eid); b == nil {
+			log.Printf("%s needs MakeBody %d", method, other.eid)
+			return nil
+		}
+		ids = append(ids, other.eid)
+	}
+	j := create(a, b)
+	e.app.bodies.join(j, ids...)
+	return j
+}
+
 // body entity methods
 // =============================================================================
 // bodies is the body component manager
@@ -78,11 +180,13 @@ func (e *Ent) Push(x, y, z float64) {
 // Physics data is kept internally in order to facilitate optimizing
 // the per-tick physics update.
 type bodies struct {
-	physics physics.Physics      // Physics system. Handles forces, collisions.
-	shapes  map[eid]physics.Body // Non-colliding physic components.
-	solids  map[eid]uint32       // Sparse map of colliding physic components.
-	bods    []physics.Body       // Dense array of colliding physics bodies.
-	eids    []eid                // Track last entity id to help with deletes.
+	physics physics.Physics         // Physics system. Handles forces, collisions.
+	shapes  map[eid]physics.Body    // Non-colliding physic components.
+	solids  map[eid]uint32          // Sparse map of colliding physic components.
+	bods    []physics.Body          // Dense array of colliding physics bodies.
+	eids    []eid                   // Track last entity id to help with deletes.
+	joints  map[eid][]physics.Joint // Joints for each joined body.
+	joined  map[physics.Joint][]eid // Joined bodies for each joint.
 }
 
 // newBodies creates a manager for a group of physics data. Expectation
@@ -94,6 +198,8 @@ func newBodies() *bodies {
 	bs.solids = map[eid]uint32{}       // Sparse map of colliding bodies.
 	bs.bods = []physics.Body{}         // Dense array of colliding bodies...
 	bs.eids = []eid{}                  // ...and associated entity identifiers.
+	bs.joints = map[eid][]physics.Joint{}
+	bs.joined = map[physics.Joint][]eid{}
 	return bs
 }
 
@@ -137,8 +243,9 @@ func (bs *bodies) get(id eid) physics.Body {
 	return nil
 }
 
-// dispose deletes the indicated physics body.
+// dispose deletes the indicated physics body and its joints.
 func (bs *bodies) dispose(id eid) {
+	bs.unjoin(id)
 	if _, ok := bs.shapes[id]; ok {
 		delete(bs.shapes, id)
 		return
@@ -162,6 +269,58 @@ func (bs *bodies) dispose(id eid) {
 	}
 }
 
+// join adds the joint to physics and tracks it for each of
+// the joined entities.
+func (bs *bodies) join(j physics.Joint, ids ...eid) {
+	bs.physics.AddJoint(j)
+	for _, id := range ids {
+		bs.joints[id] = append(bs.joints[id], j)
+	}
+	bs.joined[j] = ids
+}
+
+// unjoin removes all joints for the given entity from physics and
+// from the other joined entities.
+func (bs *bodies) unjoin(id eid) {
+	for _, j := range bs.joints[id] {
+		bs.physics.RemoveJoint(j)
+		for _, other := range bs.joined[j] {
+			if other == id {
+				continue
+			}
+			joints := bs.joints[other]
+			for cnt := len(joints) - 1; cnt >= 0; cnt-- {
+				if joints[cnt] == j {
+					joints = append(joints[:cnt], joints[cnt+1:]...)
+				}
+			}
+			if bs.joints[other] = joints; len(joints) == 0 {
+				delete(bs.joints, other)
+			}
+		}
+		delete(bs.joined, j)
+	}
+	delete(bs.joints, id)
+}
+
+// cast returns the entity ids and hits for all bodies hit by the ray,
+// closest first.
+func (bs *bodies) cast(ray physics.Body) (ids []eid, hits []*physics.Hit) {
+	all, owners := []physics.Body{}, map[physics.Body]eid{}
+	for id, b := range bs.shapes {
+		all, owners[b] = append(all, b), id
+	}
+	for cnt, b := range bs.bods {
+		all, owners[b] = append(all, b), bs.eids[cnt]
+	}
+	hits = bs.physics.RayCastAll(ray, all)
+	ids = make([]eid, len(hits))
+	for cnt, hit := range hits {
+		ids[cnt] = owners[hit.Body]
+	}
+	return ids, hits
+}
+
 // stepVelocities runs physics on all the bodies; adjusting location and
 // orientation. Physics has references to update the pov transform vectors.
 func (bs *bodies) stepVelocities(dts float64) {
@@ -0,0 +1,41 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// Check that unjoining a body removes its joints from
+// the joined bodies and leaves their other joints.
+func TestUnjoin(t *testing.T) {
+	bs := newBodies()
+	b := []physics.Body{}
+	for id := eid(1); id <= 3; id++ {
+		b = append(b, bs.create(id, physics.NewBody(physics.NewSphere(1)), lin.NewT()))
+	}
+	j12 := physics.NewFixedJoint(b[0], b[1])
+	j23 := physics.NewFixedJoint(b[1], b[2])
+	j1 := physics.NewFixedJoint(b[0], nil)
+	bs.join(j12, 1, 2)
+	bs.join(j23, 2, 3)
+	bs.join(j1, 1)
+	bs.unjoin(1)
+	if _, ok := bs.joints[1]; ok {
+		t.Errorf("Expected body 1 joints to be removed")
+	}
+	if joints := bs.joints[2]; len(joints) != 1 || joints[0] != j23 {
+		t.Errorf("Expected body 2 to keep joint 2-3, got %v", joints)
+	}
+	if len(bs.joined) != 1 || len(bs.joined[j23]) != 2 {
+		t.Errorf("Expected only joint 2-3 to be tracked, got %v", bs.joined)
+	}
+	bs.dispose(3)
+	if len(bs.joints) != 0 || len(bs.joined) != 0 {
+		t.Errorf("Expected no joints, got %v %v", bs.joints, bs.joined)
+	}
+}
@@ -5,8 +5,7 @@
 // Big thanks to GLFW (http://www.glfw.org) from which the minimalist API
 // philosophy was borrowed along with which OS specific API's mattered.
 // Also thank you to https://github.com/golang/mobile.
-// FUTURE: Linux support  : ignore X support and wait for Wayland.
-//                          Need to pick one distro for main testing.
+// FUTURE: Linux support  : X11 and GLX for now. Wayland later.
 // FUTURE: Android support: need access to android hardware.
 
 // Package device provides minimal platform/os access to a 3D rendering context
@@ -0,0 +1,59 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package device
+
+import (
+	"testing"
+)
+
+// The input tests run on all platforms, including headless builds,
+// since they do not need a native layer.
+
+// Held keys count up each update and released keys are reported once.
+func TestPressRelease(t *testing.T) {
+	in := newInput()
+	in.recordPress(KA)
+	in.getPressed(0, 0)
+	if down := in.getPressed(10, 20); down.Down[KA] != 2 || down.Mx != 10 || down.My != 20 {
+		t.Errorf("Expected key held for 2 ticks at 10,20, got %d at %d,%d", down.Down[KA], down.Mx, down.My)
+	}
+	in.recordRelease(KA)
+	if down := in.getPressed(0, 0); down.Down[KA] != 2+KeyReleased {
+		t.Errorf("Expected key released after 2 ticks, got %d", down.Down[KA])
+	}
+	if down := in.getPressed(0, 0); len(down.Down) != 0 {
+		t.Errorf("Expected released key to be cleared, got %v", down.Down)
+	}
+}
+
+// A press and release in the same update is reported as a press
+// and then a release.
+func TestQuickPressRelease(t *testing.T) {
+	in := newInput()
+	in.recordPress(KB)
+	in.recordRelease(KB)
+	if down := in.getPressed(0, 0); down.Down[KB] != 1 {
+		t.Errorf("Expected quick press to be held, got %d", down.Down[KB])
+	}
+	if down := in.getPressed(0, 0); down.Down[KB] != 1+KeyReleased {
+		t.Errorf("Expected quick press to be released, got %d", down.Down[KB])
+	}
+}
+
+// Presses are ignored without focus and losing focus releases all keys.
+func TestFocus(t *testing.T) {
+	in := newInput()
+	in.recordPress(KC)
+	in.getPressed(0, 0)
+	in.curr.Focus = false
+	in.releaseAll()
+	in.recordPress(KD)
+	down := in.getPressed(0, 0)
+	if _, ok := down.Down[KD]; ok || down.Focus {
+		t.Errorf("Expected press to be ignored without focus")
+	}
+	if down.Down[KC] != 1+KeyReleased {
+		t.Errorf("Expected held key to be released, got %d", down.Down[KC])
+	}
+}
@@ -0,0 +1,557 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// +build linux,cgo,!headless
+
+// The linux native layer implementation.
+// This wraps the X11 and GLX API's (where the real work is done).
+
+#include <stdio.h>
+#include <string.h>
+#include <sys/time.h>
+#include <unistd.h>
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+#include <X11/Xutil.h>
+#include <X11/XKBlib.h>
+#include <X11/keysym.h>
+#include <GL/glx.h>
+#include "os_linux.h"
+
+// The golang callbacks that would normally be defined in _cgo_export.h
+// are manually reproduced here and also implemented in the native test file
+// os_linux_test.c.
+extern void prepRender();
+extern void renderFrame();
+extern void handleInput(long event, long data);
+
+// Globals to track the X11 window and context handles.
+long        dev_win_alive = -1; // Global used to track window closure.
+Display    *display;            // X server connection.
+Window      window;             // Application window.
+GLXContext  context;            // Rendering context handle.
+Cursor      blank;              // Invisible cursor used to hide the cursor.
+
+// Globals to help toggle full screen and track window size.
+static unsigned char dev_full = 0;
+static long dev_width = 0, dev_height = 0;
+
+// Atoms used to talk to the window manager and other clients.
+static Atom wm_protocols, wm_delete, wm_state, wm_full, wm_name, utf8, clipboard, targets, selection;
+
+// The clipboard string owned by this application.
+static char* dev_clip = NULL;
+
+// Bind the opengl extension used to create a modern context.
+//     https://www.khronos.org/registry/OpenGL/extensions/ARB/GLX_ARB_create_context.txt
+typedef GLXContext (*glXCreateContextAttribsARBProc)(Display*, GLXFBConfig, GLXContext, Bool, const int*);
+
+// gs_key maps a key event to the keysym used for the key codes.
+// Keypad keys use their numlock value so they don't change with numlock.
+long gs_key(XKeyEvent *event)
+{
+    KeySym sym = XLookupKeysym(event, 0);
+    if (IsKeypadKey(sym))
+    {
+        KeySym numlock = XLookupKeysym(event, 1);
+        if (numlock != NoSymbol && IsKeypadKey(numlock))
+        {
+            sym = numlock;
+        }
+    }
+
+    // Merge left and right modifier keys.
+    switch (sym)
+    {
+        case XK_Control_R: return XK_Control_L;
+        case XK_Shift_R:   return XK_Shift_L;
+        case XK_Alt_R:     return XK_Alt_L;
+        case XK_Super_R:   return XK_Super_L;
+    }
+    return sym;
+}
+
+// gs_mouse maps X11 mouse buttons to the mouse key codes.
+long gs_mouse(unsigned int button)
+{
+    switch (button)
+    {
+        case Button1: return devMouseL;
+        case Button2: return devMouseM;
+        case Button3: return devMouseR;
+    }
+    return -1;
+}
+
+// gs_selection answers another application asking for the clipboard.
+void gs_selection(XSelectionRequestEvent *request)
+{
+    XSelectionEvent reply;
+    memset(&reply, 0, sizeof(reply));
+    reply.type = SelectionNotify;
+    reply.requestor = request->requestor;
+    reply.selection = request->selection;
+    reply.target = request->target;
+    reply.time = request->time;
+    reply.property = None;
+    if (dev_clip != NULL && request->target == targets)
+    {
+        Atom supported[] = {targets, utf8, XA_STRING};
+        XChangeProperty(display, request->requestor, request->property, XA_ATOM, 32,
+            PropModeReplace, (unsigned char *)supported, 3);
+        reply.property = request->property;
+    }
+    else if (dev_clip != NULL && (request->target == utf8 || request->target == XA_STRING))
+    {
+        XChangeProperty(display, request->requestor, request->property, request->target, 8,
+            PropModeReplace, (unsigned char *)dev_clip, strlen(dev_clip));
+        reply.property = request->property;
+    }
+    XSendEvent(display, request->requestor, False, 0, (XEvent *)&reply);
+}
+
+// gs_is_fullscreen checks the window manager state for the full screen
+// atom. Return 1 if the window is full screen, 0 otherwise.
+unsigned char gs_is_fullscreen()
+{
+    Atom type;
+    int format;
+    unsigned long count, remaining;
+    unsigned char *data = NULL;
+    unsigned char full = 0;
+    if (XGetWindowProperty(display, window, wm_state, 0, 1024, False, XA_ATOM,
+        &type, &format, &count, &remaining, &data) == Success && data != NULL)
+    {
+        Atom *states = (Atom *)data;
+        for (unsigned long cnt = 0; cnt < count; cnt++)
+        {
+            if (states[cnt] == wm_full)
+            {
+                full = 1;
+            }
+        }
+        XFree(data);
+    }
+    return full;
+}
+
+// gs_handle_event forwards X11 events to the application.
+// Called as frequently as possible to process user input and window changes.
+void gs_handle_event(XEvent *event)
+{
+    switch (event->type)
+    {
+        case KeyPress:
+        case KeyRelease:
+        {
+            // Ignore keys that have no keysym mapping.
+            long key = gs_key(&event->xkey);
+            if (key != NoSymbol)
+            {
+                handleInput(event->type == KeyPress ? devDown : devUp, key);
+            }
+            break;
+        }
+        case ButtonPress:
+        {
+            // Buttons 4 and 5 are the scroll wheel.
+            // Flip scroll direction to match OSX.
+            unsigned int button = event->xbutton.button;
+            if (button == Button4 || button == Button5)
+            {
+                handleInput(devScroll, button == Button4 ? -1 : 1);
+            }
+            else if (gs_mouse(button) >= 0)
+            {
+                handleInput(devDown, gs_mouse(button));
+            }
+            break;
+        }
+        case ButtonRelease:
+            if (gs_mouse(event->xbutton.button) >= 0)
+            {
+                handleInput(devUp, gs_mouse(event->xbutton.button));
+            }
+            break;
+        case FocusIn:
+            handleInput(devFocusIn, 0);
+            break;
+        case FocusOut:
+            handleInput(devFocusOut, 0);
+            break;
+        case ConfigureNotify:
+            if (event->xconfigure.width != dev_width || event->xconfigure.height != dev_height)
+            {
+                dev_width = event->xconfigure.width;
+                dev_height = event->xconfigure.height;
+                handleInput(devResize, 0);
+            }
+            break;
+        case ClientMessage:
+            if (event->xclient.message_type == wm_protocols &&
+                (Atom)event->xclient.data.l[0] == wm_delete)
+            {
+                dev_win_alive = -2;
+            }
+            break;
+        case PropertyNotify:
+            if (event->xproperty.atom == wm_state)
+            {
+                dev_full = gs_is_fullscreen();
+            }
+            break;
+        case SelectionClear:
+            free(dev_clip);
+            dev_clip = NULL;
+            break;
+        case SelectionRequest:
+            gs_selection(&event->xselectionrequest);
+            break;
+    }
+}
+
+// Create the window and an OpenGL 3.2 context. Return 0 on failure.
+int gs_context()
+{
+    display = XOpenDisplay(NULL);
+    if (display == NULL)
+    {
+        printf("Failed to open X display\n");
+        return 0;
+    }
+    int attribs[] = {
+        GLX_X_RENDERABLE,  True,
+        GLX_DRAWABLE_TYPE, GLX_WINDOW_BIT,
+        GLX_RENDER_TYPE,   GLX_RGBA_BIT,
+        GLX_RED_SIZE,      8,
+        GLX_GREEN_SIZE,    8,
+        GLX_BLUE_SIZE,     8,
+        GLX_ALPHA_SIZE,    8,
+        GLX_DEPTH_SIZE,    24,
+        GLX_DOUBLEBUFFER,  True,
+        None
+    };
+    int count = 0;
+    GLXFBConfig *configs = glXChooseFBConfig(display, DefaultScreen(display), attribs, &count);
+    if (configs == NULL || count == 0)
+    {
+        printf("Failed to find a frame buffer configuration\n");
+        return 0;
+    }
+    GLXFBConfig config = configs[0];
+    XFree(configs);
+    XVisualInfo *visual = glXGetVisualFromFBConfig(display, config);
+    if (visual == NULL)
+    {
+        printf("Failed to find a visual\n");
+        return 0;
+    }
+
+    // create the window.
+    Window root = RootWindow(display, visual->screen);
+    XSetWindowAttributes swa;
+    memset(&swa, 0, sizeof(swa));
+    swa.colormap = XCreateColormap(display, root, visual->visual, AllocNone);
+    swa.event_mask = KeyPressMask | KeyReleaseMask | ButtonPressMask | ButtonReleaseMask |
+        FocusChangeMask | StructureNotifyMask | PropertyChangeMask;
+    dev_width = 600;
+    dev_height = 400;
+    window = XCreateWindow(display, root, 600, 200, dev_width, dev_height, 0,
+        visual->depth, InputOutput, visual->visual, CWColormap | CWEventMask, &swa);
+    XFree(visual);
+    if (!window)
+    {
+        printf("Failed to create window\n");
+        return 0;
+    }
+
+    // get the atoms needed to work with the window manager and clipboard.
+    wm_protocols = XInternAtom(display, "WM_PROTOCOLS", False);
+    wm_delete = XInternAtom(display, "WM_DELETE_WINDOW", False);
+    wm_state = XInternAtom(display, "_NET_WM_STATE", False);
+    wm_full = XInternAtom(display, "_NET_WM_STATE_FULLSCREEN", False);
+    wm_name = XInternAtom(display, "_NET_WM_NAME", False);
+    utf8 = XInternAtom(display, "UTF8_STRING", False);
+    clipboard = XInternAtom(display, "CLIPBOARD", False);
+    targets = XInternAtom(display, "TARGETS", False);
+    selection = XInternAtom(display, "VU_SELECTION", False);
+    XSetWMProtocols(display, window, &wm_delete, 1);
+
+    // Report key releases only when keys are actually released.
+    XkbSetDetectableAutoRepeat(display, True, NULL);
+
+    // an empty cursor used to hide the cursor.
+    char none[8] = {0};
+    Pixmap pixmap = XCreateBitmapFromData(display, window, none, 8, 8);
+    XColor black;
+    memset(&black, 0, sizeof(black));
+    blank = XCreatePixmapCursor(display, pixmap, pixmap, &black, &black, 0, 0);
+    XFreePixmap(display, pixmap);
+
+    // Use the expected baseline opengl 3.2, falling back to
+    // a legacy context if the extension is not available.
+    glXCreateContextAttribsARBProc createContext = (glXCreateContextAttribsARBProc)
+        glXGetProcAddressARB((const GLubyte *)"glXCreateContextAttribsARB");
+    if (createContext != NULL)
+    {
+        int ctxattribs[] = {
+            GLX_CONTEXT_MAJOR_VERSION_ARB, 3,
+            GLX_CONTEXT_MINOR_VERSION_ARB, 2,
+            GLX_CONTEXT_FLAGS_ARB,         GLX_CONTEXT_FORWARD_COMPATIBLE_BIT_ARB,
+            GLX_CONTEXT_PROFILE_MASK_ARB,  GLX_CONTEXT_CORE_PROFILE_BIT_ARB,
+            None
+        };
+        context = createContext(display, config, NULL, True, ctxattribs);
+    }
+    if (context == NULL)
+    {
+        context = glXCreateNewContext(display, config, GLX_RGBA_TYPE, NULL, True);
+    }
+    if (context == NULL)
+    {
+        printf("Failed to create context\n");
+        return 0;
+    }
+    XMapRaised(display, window);
+    XSync(display, False);
+    glXMakeCurrent(display, window, context);
+    return 1;
+}
+
+// gs_display_dispose releases the window and context.
+void gs_display_dispose()
+{
+    if (display == NULL)
+    {
+        return;
+    }
+    glXMakeCurrent(display, None, NULL);
+    if (context != NULL)
+    {
+        glXDestroyContext(display, context);
+        context = NULL;
+    }
+    if (window)
+    {
+        XDestroyWindow(display, window);
+        window = 0;
+    }
+    XCloseDisplay(display);
+    display = NULL;
+    free(dev_clip);
+    dev_clip = NULL;
+}
+
+// Native layer wrappers.
+// =============================================================================
+
+// Process input and render frames. This is a simple game loop that expects
+// more complex stuff like fixed time-step to be handled by renderFrame.
+// Ensures user input is processed by routing events through gs_handle_event.
+//
+// It mimics macOS and iOS where the OS keeps the loop and calls the application
+// to render based on the display refresh rate. Here rendering is called as fast
+// as possible - which can be inefficient since frames are still only displayed
+// as fast as the monitor refresh rate.
+void dev_run()
+{
+    if (!gs_context())
+    {
+        gs_display_dispose();
+        return;
+    }
+    dev_win_alive = 1;
+    prepRender();
+    while (dev_win_alive == 1)
+    {
+        while (dev_win_alive == 1 && XPending(display) > 0)
+        {
+            XEvent event;
+            XNextEvent(display, &event);
+            gs_handle_event(&event);
+        }
+        if (dev_win_alive == 1)
+        {
+            renderFrame();
+        }
+    }
+    gs_display_dispose();
+}
+
+// Swaps rendering buffer. Called after rendering a frame.
+void dev_swap()
+{
+    glXSwapBuffers(display, window);
+}
+
+// Stops the event loop which then releases all resources
+// including the OpenGL context.
+void dev_dispose()
+{
+    dev_win_alive = -2;
+}
+
+// Used to check if the application is full screen mode.
+// Return 1 if the application is full screen, 0 otherwise.
+unsigned char dev_fullscreen()
+{
+    return dev_full;
+}
+
+// Flip full screen mode by asking the window manager. The full screen
+// state is updated when the window manager changes _NET_WM_STATE. See:
+//     https://specifications.freedesktop.org/wm-spec/latest/ar01s05.html
+void dev_toggle_fullscreen()
+{
+    XEvent event;
+    memset(&event, 0, sizeof(event));
+    event.type = ClientMessage;
+    event.xclient.window = window;
+    event.xclient.message_type = wm_state;
+    event.xclient.format = 32;
+    event.xclient.data.l[0] = dev_full ? 0 : 1; // _NET_WM_STATE_REMOVE or ADD
+    event.xclient.data.l[1] = wm_full;
+    event.xclient.data.l[3] = 1; // normal application.
+    XSendEvent(display, DefaultRootWindow(display), False,
+        SubstructureRedirectMask | SubstructureNotifyMask, &event);
+    XFlush(display);
+}
+
+// Show or hide cursor. Lock it to the window if it is hidden.
+void dev_show_cursor(unsigned char show)
+{
+    if (show)
+    {
+        XUngrabPointer(display, CurrentTime);
+        XUndefineCursor(display, window);
+    }
+    else
+    {
+        XDefineCursor(display, window, blank);
+        XGrabPointer(display, window, True, ButtonPressMask | ButtonReleaseMask,
+            GrabModeAsync, GrabModeAsync, window, blank, CurrentTime);
+    }
+    XFlush(display);
+}
+
+// Get the current mouse position relative to the bottom left corner
+// of the application window.
+void dev_cursor(long *x, long *y)
+{
+    Window root, child;
+    int rx, ry, wx, wy;
+    unsigned int mask;
+    if (XQueryPointer(display, window, &root, &child, &rx, &ry, &wx, &wy, &mask))
+    {
+        *x = wx;
+        *y = dev_height - wy;
+    }
+}
+
+// Position the cursor at the given window location. The incoming coordinates
+// are relative to the bottom left corner - switch that to be relative to the
+// top left corner expected by X11.
+void dev_set_cursor_location(long x, long y)
+{
+    XWarpPointer(display, None, window, 0, 0, 0, 0, x, dev_height - y);
+    XFlush(display);
+}
+
+// Sets the windows size and location.
+// The y value is reversed because the incoming coordinates are relative
+// to the bottom left corner. X11 expects it to be the top left.
+void dev_set_size(long x, long y, long w, long h)
+{
+    long desk = DisplayHeight(display, DefaultScreen(display));
+    XMoveResizeWindow(display, window, x, desk - y - h, w, h);
+    XFlush(display);
+}
+
+// Get the current main window drawing area size.
+// Reverse y so origin is bottom left.
+void dev_size(long *x, long *y, long *w, long *h)
+{
+    XWindowAttributes attrs;
+    Window child;
+    int wx = 0, wy = 0;
+    XGetWindowAttributes(display, window, &attrs);
+    XTranslateCoordinates(display, window, DefaultRootWindow(display), 0, 0, &wx, &wy, &child);
+    *w = attrs.width;
+    *h = attrs.height;
+    *x = wx;
+    *y = DisplayHeight(display, DefaultScreen(display)) - wy - attrs.height;
+}
+
+// Sets the windows title.
+void dev_set_title(char * label)
+{
+    XStoreName(display, window, label);
+    XChangeProperty(display, window, wm_name, utf8, 8, PropModeReplace,
+        (unsigned char *)label, strlen(label));
+    XFlush(display);
+}
+
+// gs_millis returns the current time in milliseconds.
+long gs_millis()
+{
+    struct timeval now;
+    gettimeofday(&now, NULL);
+    return now.tv_sec*1000 + now.tv_usec/1000;
+}
+
+// Return the current clipboard contents if the clipboard contains text.
+// Otherwise return nil. Any returned strings must be freed by the caller.
+// The clipboard owner is given a short time to reply.
+char* dev_clip_copy()
+{
+    Window owner = XGetSelectionOwner(display, clipboard);
+    if (owner == None)
+    {
+        return NULL;
+    }
+    if (owner == window)
+    {
+        return dev_clip == NULL ? NULL : strdup(dev_clip);
+    }
+    XConvertSelection(display, clipboard, utf8, selection, window, CurrentTime);
+    XEvent event;
+    long start = gs_millis();
+    while (!XCheckTypedWindowEvent(display, window, SelectionNotify, &event))
+    {
+        if (gs_millis()-start > 200)
+        {
+            return NULL;
+        }
+        XFlush(display);
+        usleep(1000);
+    }
+    if (event.xselection.property == None)
+    {
+        return NULL;
+    }
+    Atom type;
+    int format;
+    unsigned long count, remaining;
+    unsigned char *data = NULL;
+    char *clipboardString = NULL;
+    if (XGetWindowProperty(display, window, selection, 0, 1<<24, True, AnyPropertyType,
+        &type, &format, &count, &remaining, &data) == Success && data != NULL)
+    {
+        if (type == utf8 || type == XA_STRING)
+        {
+            clipboardString = strndup((char *)data, count);
+        }
+        XFree(data);
+    }
+    return clipboardString;
+}
+
+// Paste the given string into the general clipboard. The string is
+// kept and given to other applications when they ask for it.
+void dev_clip_paste(const char* string)
+{
+    free(dev_clip);
+    dev_clip = strdup(string);
+    XSetSelectionOwner(display, clipboard, window, CurrentTime);
+    XFlush(display);
+}
@@ -0,0 +1,300 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// +build linux,cgo,!headless
+// Use X11 and GLX by default. See os_other.go for headless builds.
+
+package device
+
+// The linux native layer. This wraps the c functions that wrap the
+// X11 and GLX API's (where the real work is done). It can be run on
+// a machine without a display using a virtual X server, ie:
+//     xvfb-run -s "-screen 0 1024x768x24" go run myapp.go
+// where Mesa's llvmpipe software renderer provides OpenGL.
+
+// // C code and cgo directvies.
+//
+// #cgo linux LDFLAGS: -lX11 -lGL
+//
+// #include "os_linux.h" // native function signatures and constants.
+import "C" // must be located here.
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// OpenGL related, see: https://code.google.com/p/go-wiki/wiki/LockOSThread
+func init() { runtime.LockOSThread() }
+
+// Device instance needed to handle callbacks on exported methods.
+var dev = &x11{}
+
+// runApp is the per-device entry method. Compiling will find the one
+// that matches the requested or current platform.
+func runApp(app App) {
+	dev.app = app // The app receiving device callbacks.
+	C.dev_run()   // returns when the window is closed.
+}
+
+// prepRender is called from os_linux.c after the underlying window
+// has been created and before the update render callbacks start.
+//
+//export prepRender
+func prepRender() {
+	dev.input = newInput()
+	dev.app.Init(dev)
+}
+
+// renderFrame is the called from os_linux.c each pass through the
+// event loop. Actual frame rate is still limited by the monitor.
+//
+//export renderFrame
+func renderFrame() {
+	if dev.input != nil {
+		dev.app.Refresh(dev)
+	}
+}
+
+// handleInput is called from os_linux.c to consolidate user input
+// events into a consumable summary of which keys are current pressed.
+// The summary is fowarded each update to the controlling application.
+//
+//export handleInput
+func handleInput(event, data int64) {
+	if dev.input == nil {
+		return // Ignore input before window is up.
+	}
+	in := dev.input
+	switch event {
+	case C.devUp:
+		in.recordRelease(int(data))
+	case C.devDown:
+		in.recordPress(int(data))
+	case C.devScroll:
+		in.curr.Scroll = int(data)
+	case C.devResize:
+		in.curr.Resized = true
+
+		// release all down keys on resize
+		// to avoid missing key release events.
+		in.releaseAll()
+	case C.devFocusIn, C.devFocusOut:
+		in.curr.Focus = event == C.devFocusIn
+
+		// keys released while the window does
+		// not have focus are never reported.
+		in.releaseAll()
+	}
+}
+
+// native layer callback functions. native->app calls.
+// =============================================================================
+// x11 Device implementation. app->native calls.
+
+// x11 is the linux implementation of the Device interface.
+// See the Device interface for method descriptions.
+type x11 struct {
+	app      App       // update/render callback.
+	input    *input    // tracks current keys pressed.
+	lastSwap time.Time // helps throttle very fast apps.
+}
+
+// Implement the Device interface. See docs in device.go
+// Mostly call the underlying native layer.
+func (os *x11) Down() *Pressed     { return os.input.getPressed(os.Cursor()) }
+func (os *x11) Dispose()           { C.dev_dispose() }
+func (os *x11) ToggleFullScreen()  { C.dev_toggle_fullscreen() }
+func (os *x11) IsFullScreen() bool { return uint(C.dev_fullscreen()) == 1 }
+func (os *x11) SwapBuffers() {
+	elapsed := time.Since(os.lastSwap)
+	os.lastSwap = time.Now()
+	C.dev_swap()
+
+	// Throttle unreasonable refresh rates since most monitors only
+	// refresh at 60 or 120 times a second. Generally an update is
+	// every 20ms, so start throttling if the app is twice that.
+	// Use a smaller sleep time since sleep is not exact.
+	if elapsed/time.Millisecond < 10 {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+func (os *x11) SetCursorAt(x, y int) {
+	C.dev_set_cursor_location(C.long(x), C.long(y))
+}
+func (os *x11) Cursor() (x, y int) {
+	var mx, my C.long
+	C.dev_cursor(&mx, &my)
+	return int(mx), int(my)
+}
+func (os *x11) Size() (x, y, w, h int) {
+	var winx, winy, width, height C.long
+	C.dev_size(&winx, &winy, &width, &height)
+	return int(winx), int(winy), int(width), int(height)
+}
+func (os *x11) SetSize(x, y, w, h int) {
+	C.dev_set_size(C.long(x), C.long(y), C.long(w), C.long(h))
+
+	// resising may not trigger an X11 configure event
+	// so inform the application directly.
+	os.input.curr.Resized = true
+}
+func (os *x11) SetTitle(title string) {
+	cstr := C.CString(title)
+	defer C.free(unsafe.Pointer(cstr))
+	C.dev_set_title(cstr)
+}
+func (os *x11) ShowCursor(show bool) {
+	trueFalse := 0 // trueFalse needs to be 0 or 1.
+	if show {
+		trueFalse = 1
+	}
+	C.dev_show_cursor(C.uchar(trueFalse))
+}
+func (os *x11) Copy() string {
+	if cstr := C.dev_clip_copy(); cstr != nil {
+		str := C.GoString(cstr)      // make a Go copy.
+		C.free(unsafe.Pointer(cstr)) // free the C copy.
+		return str
+	}
+	return ""
+}
+func (os *x11) Paste(s string) {
+	cstr := C.CString(s)
+	defer C.free(unsafe.Pointer(cstr))
+	C.dev_clip_paste(cstr)
+}
+
+// x11 Device implementation.
+// =============================================================================
+
+// Expose the underlying X11 keysyms to the Vu device key codes
+// supported by each of the native layers. Letter keys are the
+// unshifted, lower case, keysyms.
+//
+// X11 keysyms, see X11/keysymdef.h or:
+// https://www.x.org/releases/current/doc/xproto/x11protocol.html#keysym_encoding
+const (
+	// keyboard numbers.
+	K0 = 0x30 // XK_0 0 key
+	K1 = 0x31 // XK_1 1 key
+	K2 = 0x32 // XK_2 2 key
+	K3 = 0x33 // XK_3 3 key
+	K4 = 0x34 // XK_4 4 key
+	K5 = 0x35 // XK_5 5 key
+	K6 = 0x36 // XK_6 6 key
+	K7 = 0x37 // XK_7 7 key
+	K8 = 0x38 // XK_8 8 key
+	K9 = 0x39 // XK_9 9 key
+
+	// keyboard letters.
+	KA = 0x61 // XK_a A key
+	KB = 0x62 // XK_b B key
+	KC = 0x63 // XK_c C key
+	KD = 0x64 // XK_d D key
+	KE = 0x65 // XK_e E key
+	KF = 0x66 // XK_f F key
+	KG = 0x67 // XK_g G key
+	KH = 0x68 // XK_h H key
+	KI = 0x69 // XK_i I key
+	KJ = 0x6A // XK_j J key
+	KK = 0x6B // XK_k K key
+	KL = 0x6C // XK_l L key
+	KM = 0x6D // XK_m M key
+	KN = 0x6E // XK_n N key
+	KO = 0x6F // XK_o O key
+	KP = 0x70 // XK_p P key
+	KQ = 0x71 // XK_q Q key
+	KR = 0x72 // XK_r R key
+	KS = 0x73 // XK_s S key
+	KT = 0x74 // XK_t T key
+	KU = 0x75 // XK_u U key
+	KV = 0x76 // XK_v V key
+	KW = 0x77 // XK_w W key
+	KX = 0x78 // XK_x X key
+	KY = 0x79 // XK_y Y key
+	KZ = 0x7A // XK_z Z key
+
+	// Function Keys
+	KF1  = 0xFFBE // XK_F1  F1 key
+	KF2  = 0xFFBF // XK_F2  F2 key
+	KF3  = 0xFFC0 // XK_F3  F3 key
+	KF4  = 0xFFC1 // XK_F4  F4 key
+	KF5  = 0xFFC2 // XK_F5  F5 key
+	KF6  = 0xFFC3 // XK_F6  F6 key
+	KF7  = 0xFFC4 // XK_F7  F7 key
+	KF8  = 0xFFC5 // XK_F8  F8 key
+	KF9  = 0xFFC6 // XK_F9  F9 key
+	KF10 = 0xFFC7 // XK_F10 F10 key
+	KF11 = 0xFFC8 // XK_F11 F11 key
+	KF12 = 0xFFC9 // XK_F12 F12 key
+	KF13 = 0xFFCA // XK_F13 F13 key
+	KF14 = 0xFFCB // XK_F14 F14 key
+	KF15 = 0xFFCC // XK_F15 F15 key
+	KF16 = 0xFFCD // XK_F16 F16 key
+	KF17 = 0xFFCE // XK_F17 F17 key
+	KF18 = 0xFFCF // XK_F18 F18 key
+	KF19 = 0xFFD0 // XK_F19 F19 key
+	KF20 = 0xFFD1 // XK_F20 F20 key
+
+	// Keypad keys
+	KKpDot = 0xFFAE // XK_KP_Decimal  Decimal key
+	KKpMlt = 0xFFAA // XK_KP_Multiply Multiply key
+	KKpAdd = 0xFFAB // XK_KP_Add      Add key
+	KKpClr = 0xFF7F // XK_Num_Lock    Clear key position on PC keyboards.
+	KKpDiv = 0xFFAF // XK_KP_Divide   Divide key
+	KKpEnt = 0xFF8D // XK_KP_Enter    Enter key
+	KKpSub = 0xFFAD // XK_KP_Subtract Subtract key
+	KKpEql = 0xFFBD // XK_KP_Equal    Equal key
+	KKp0   = 0xFFB0 // XK_KP_0        keypad 0 key
+	KKp1   = 0xFFB1 // XK_KP_1        keypad 1 key
+	KKp2   = 0xFFB2 // XK_KP_2        keypad 2 key
+	KKp3   = 0xFFB3 // XK_KP_3        keypad 3 key
+	KKp4   = 0xFFB4 // XK_KP_4        keypad 4 key
+	KKp5   = 0xFFB5 // XK_KP_5        keypad 5 key
+	KKp6   = 0xFFB6 // XK_KP_6        keypad 6 key
+	KKp7   = 0xFFB7 // XK_KP_7        keypad 7 key
+	KKp8   = 0xFFB8 // XK_KP_8        keypad 8 key
+	KKp9   = 0xFFB9 // XK_KP_9        keypad 9 key
+
+	// Misc and Punctuation keys.
+	KEqual = 0x3D   // XK_equal      '=' key
+	KMinus = 0x2D   // XK_minus      '-' key
+	KLBkt  = 0x5B   // XK_bracketleft  '[{' key
+	KRBkt  = 0x5D   // XK_bracketright ']}' key
+	KQt    = 0x27   // XK_apostrophe 'single/double-quote' key
+	KSemi  = 0x3B   // XK_semicolon  ';:' key
+	KBSl   = 0x5C   // XK_backslash  '\|' key
+	KComma = 0x2C   // XK_comma      ',' key
+	KSlash = 0x2F   // XK_slash      '/?' key
+	KDot   = 0x2E   // XK_period     '.' key
+	KGrave = 0x60   // XK_grave      '`~' key
+	KRet   = 0xFF0D // XK_Return     ENTER key
+	KTab   = 0xFF09 // XK_Tab        TAB key
+	KSpace = 0x20   // XK_space      SPACEBAR
+	KDel   = 0xFF08 // XK_BackSpace  BACKSPACE key
+	KEsc   = 0xFF1B // XK_Escape     ESC key
+
+	// Control keys.
+	KHome  = 0xFF50 // XK_Home      HOME key
+	KPgUp  = 0xFF55 // XK_Page_Up   PAGE UP key
+	KFDel  = 0xFFFF // XK_Delete    DEL key
+	KEnd   = 0xFF57 // XK_End       END key
+	KPgDn  = 0xFF56 // XK_Page_Down PAGE DOWN key
+	KLa    = 0xFF51 // XK_Left      LEFT ARROW key
+	KRa    = 0xFF53 // XK_Right     RIGHT ARROW key
+	KDa    = 0xFF54 // XK_Down      DOWN ARROW key
+	KUa    = 0xFF52 // XK_Up        UP ARROW key
+	KCtl   = 0xFFE3 // XK_Control_L modifier keys. Right keys are
+	KShift = 0xFFE1 // XK_Shift_L   reported as left keys.
+	KFn    = 0x0004 // devKeyFn     Not reported by X11.
+	KCmd   = 0xFFEB // XK_Super_L
+	KAlt   = 0xFFE9 // XK_Alt_L
+
+	// Mouse buttons are treated like keys.
+	// Values don't conflict with other key codes.
+	KLm = C.devMouseL // Mouse buttons
+	KMm = C.devMouseM //   "
+	KRm = C.devMouseR //   "
+)
@@ -0,0 +1,93 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// +build ignore
+//
+// Ignored because cgo attempts to compile it during normal builds.
+// To build a native test application use:
+//     gcc -o linuxApp os_linux.c os_linux_test.c -lX11 -lGL -Wall
+// The test application can be run without a display using Xvfb and
+// Mesa's llvmpipe, ie: the following closes after 100 frames.
+//     xvfb-run -s "-screen 0 1024x768x24" ./linuxApp 100
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <GL/gl.h>
+#include "os_linux.h"
+
+// Number of frames to run before closing. Zero runs until
+// the window is closed.
+static long frames = 0;
+static long frame = 0;
+
+// Tests linux native library.
+// Example C program that ensures the graphic shell works.
+// This tests the native layer implmentation without golang.
+int main(int argc, char *argv[])
+{
+    if (argc > 1)
+    {
+        frames = atol(argv[1]);
+    }
+    dev_run(); // Returns when the window is closed.
+    printf("rendered %ld frames\n", frame);
+    return 0;
+}
+
+// prepRender is called one time after the application opens and
+// the drawing context has been initialized.
+void prepRender()
+{
+    dev_set_title("Test Window");
+    dev_set_size(600, 200, 600, 400);
+    long x, y, w, h;
+    dev_size(&x, &y, &w, &h);
+    printf("windows size %ld %ld %ld %ld\n", x, y, w, h);
+    printf("renderer %s %s\n", glGetString(GL_RENDERER), glGetString(GL_VERSION));
+}
+
+// renderFrame is called for the application to update its state
+// and render a frame.
+void renderFrame()
+{
+    glClearColor(0.2, 0.4, 0.6, 1.0);
+    glClear(GL_COLOR_BUFFER_BIT);
+    dev_swap();
+    frame++;
+    if (frames > 0 && frame >= frames)
+    {
+        dev_dispose();
+    }
+}
+
+// handleInput is called as user events occur.
+void handleInput(long event, long data)
+{
+    if (event == devDown) {
+        if (data == 0x63) { // c key
+            char *s = dev_clip_copy();
+            printf(" \"%s\"\n", s);
+            free(s);
+        } else if (data == 0x70) { // p key
+            dev_clip_paste("test paste string");
+        } else if (data == 0x74) { // t key
+            dev_toggle_fullscreen();
+        } else if (data == 0x68) { // h key
+            dev_show_cursor(0);
+        } else if (data == 0x73) { // s key
+            dev_show_cursor(1);
+        } else if (data == devMouseL) { // left click
+            long x, y;
+            dev_cursor(&x, &y);
+            printf("left mouse click %ld %ld\n", x, y);
+        } else {
+            printf("press %lx\n", data);
+        }
+    } else if (event == devUp) {
+        printf("release %lx\n", data);
+    } else if (event == devScroll) {
+        printf("scroll %ld\n", data);
+    } else {
+        printf("event %ld\n", event);
+    }
+}
@@ -0,0 +1,133 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// +build !darwin,!windows,!linux linux,!cgo linux,headless
+
+package device
+
+// Placeholder for platforms without a native layer. This allows the
+// engine to be built, and run headless, on any platform. Linux uses
+// the placeholder when cgo is disabled or when built with the headless
+// tag, ie: on machines without the X11 and GL development libraries.
+//     go test -tags headless ./...
+
+import (
+	"log"
+)
+
+// runApp is the per-device entry method. Compiling will find the one
+// that matches the requested or current platform.
+func runApp(app App) {
+	log.Printf("device: no native layer for this platform")
+}
+
+// Key codes for platforms without a native layer. There is no keyboard
+// so the codes only need to be unique. They match the input symbols.
+const (
+	K0     = 0x0030 // 0 48
+	K1     = 0x0031 // 1 49
+	K2     = 0x0032 // 2 50
+	K3     = 0x0033 // 3 51
+	K4     = 0x0034 // 4 52
+	K5     = 0x0035 // 5 53
+	K6     = 0x0036 // 6 54
+	K7     = 0x0037 // 7 55
+	K8     = 0x0038 // 8 56
+	K9     = 0x0039 // 9 57
+	KA     = 0x0041 // A 65
+	KB     = 0x0042 // B 66
+	KC     = 0x0043 // C 67
+	KD     = 0x0044 // D 68
+	KE     = 0x0045 // E 69
+	KF     = 0x0046 // F 70
+	KG     = 0x0047 // G 71
+	KH     = 0x0048 // H 72
+	KI     = 0x0049 // I 73
+	KJ     = 0x004A // J 74
+	KK     = 0x004B // K 75
+	KL     = 0x004C // L 76
+	KM     = 0x004D // M 77
+	KN     = 0x004E // N 78
+	KO     = 0x004F // O 79
+	KP     = 0x0050 // P 80
+	KQ     = 0x0051 // Q 81
+	KR     = 0x0052 // R 82
+	KS     = 0x0053 // S 83
+	KT     = 0x0054 // T 84
+	KU     = 0x0055 // U 85
+	KV     = 0x0056 // V 86
+	KW     = 0x0057 // W 87
+	KX     = 0x0058 // X 88
+	KY     = 0x0059 // Y 89
+	KZ     = 0x005A // Z 90
+	KEqual = 0x003D // = 61
+	KMinus = 0x002D // - 45
+	KRBkt  = 0x005D // ] 93
+	KLBkt  = 0x005B // [ 91
+	KQt    = 0x0022 // " 34
+	KSemi  = 0x003B // ; 59
+	KBSl   = 0x005C // \ 92
+	KComma = 0x002C // , 44
+	KSlash = 0x002F // / 47
+	KDot   = 0x002E // . 46
+	KGrave = 0x007E // ~ 126
+	KRet   = 0x21E6 // ⇦ 8678
+	KTab   = 0x21E8 // ⇨ 8680
+	KSpace = 0x25AD // ▭ 9645
+	KDel   = 0x21CD // ⇍ 8653
+	KEsc   = 0x25D7 // ◗ 9687
+	KF1    = 0x03B1 // α 945
+	KF2    = 0x03B2 // β 946
+	KF3    = 0x03B3 // γ 947
+	KF4    = 0x03B4 // δ 948
+	KF5    = 0x03B5 // ε 949
+	KF6    = 0x03B6 // ζ 950
+	KF7    = 0x03B7 // η 951
+	KF8    = 0x03B8 // θ 952
+	KF9    = 0x03B9 // ι 953
+	KF10   = 0x03BA // κ 954
+	KF11   = 0x03BB // λ 955
+	KF12   = 0x03BC // μ 956
+	KF13   = 0x03BD // ν 957
+	KF14   = 0x03BE // ξ 958
+	KF15   = 0x03BF // ο 959
+	KF16   = 0x03C0 // π 960
+	KF17   = 0x03C1 // ρ 961
+	KF18   = 0x03C2 // ς 962
+	KF19   = 0x03C3 // σ 963
+	KHome  = 0x25C8 // ◈ 9672
+	KPgUp  = 0x21D1 // ⇑ 8657
+	KFDel  = 0x21CF // ⇏ 8655
+	KEnd   = 0x25A3 // ▣ 9635
+	KPgDn  = 0x21D3 // ⇓ 8659
+	KLa    = 0x25C0 // ◀ 9664
+	KRa    = 0x25B6 // ▶ 9654
+	KDa    = 0x25BC // ▼ 9660
+	KUa    = 0x25B2 // ▲ 9650
+	KKpDot = 0x2299 // ⊙ 8857
+	KKpMlt = 0x2297 // ⊗ 8855
+	KKpAdd = 0x2295 // ⊕ 8853
+	KKpClr = 0x22A0 // ⊠ 8864
+	KKpDiv = 0x2298 // ⊘ 8856
+	KKpEnt = 0x21D0 // ⇐ 8656
+	KKpSub = 0x2296 // ⊖ 8854
+	KKpEql = 0x229C // ⊜ 8860
+	KKp0   = 0x2080 // ₀ 8320
+	KKp1   = 0x2081 // ₁ 8321
+	KKp2   = 0x2082 // ₂ 8322
+	KKp3   = 0x2083 // ₃ 8323
+	KKp4   = 0x2084 // ₄ 8324
+	KKp5   = 0x2085 // ₅ 8325
+	KKp6   = 0x2086 // ₆ 8326
+	KKp7   = 0x2087 // ₇ 8327
+	KKp8   = 0x2088 // ₈ 8328
+	KKp9   = 0x2089 // ₉ 8329
+	KLm    = 0x25D0 // ◐ 9680
+	KMm    = 0x25D3 // ◓ 9683
+	KRm    = 0x25D1 // ◑ 9681
+	KCtl   = 0x25CF // ● 9679
+	KFn    = 0x25CD // ◍ 9677
+	KShift = 0x21E7 // ⇧ 8679
+	KCmd   = 0x25C6 // ◆ 9670
+	KAlt   = 0x25C7 // ◇ 9671
+)
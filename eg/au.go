@@ -20,7 +20,10 @@ import (
 // CONTROLS: NA
 func au() {
 	// map the bindings to the OpenAL dynamic library.
-	al.Init()
+	if err := al.Init(); err != nil {
+		log.Printf("au: %s", err)
+		return
+	}
 	if report := al.BindingReport(); len(report) > 0 {
 		for _, line := range report {
 			if strings.Contains(line, "[ ]") {
@@ -95,7 +95,7 @@ func (ld *ldtag) initScene() {
 	var fbuff uint32
 	gl.GenBuffers(1, &fbuff)
 	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, fbuff)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int64(len(mesh.F)*2), gl.Pointer(&(mesh.F[0])), gl.STATIC_DRAW)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int64(len(mesh.F)*4), gl.Pointer(&(mesh.F[0])), gl.STATIC_DRAW)
 
 	// final state setup before launch.
 	ld.initShader()
@@ -136,7 +136,7 @@ func (ld *ldtag) render() {
 	ld.mvp64.Set(lin.M4I).ScaleSM(0.5, 0.5, 0.5).TranslateMT(0, 0, -2)
 	ld.mvp = render.M4ToData(ld.mvp64, ld.mvp) // Model transform.
 	gl.UniformMatrix4fv(ld.mm, 1, false, &(ld.mvp[0]))
-	gl.DrawElements(gl.TRIANGLES, ld.faceCount, gl.UNSIGNED_SHORT, 0)
+	gl.DrawElements(gl.TRIANGLES, ld.faceCount, gl.UNSIGNED_INT, 0)
 
 	// cleanup
 	gl.UseProgram(0)
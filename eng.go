@@ -7,11 +7,13 @@ package vu
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"time"
 
 	"github.com/gazed/vu/audio"
 	"github.com/gazed/vu/device"
+	"github.com/gazed/vu/load"
 	"github.com/gazed/vu/physics"
 	"github.com/gazed/vu/render"
 )
@@ -37,6 +39,21 @@ type Eng interface {
 	// played sound to the sound listener.
 	AddSound(name string) uint32
 
+	// SaveScene writes a scene entity and all of its parts to w.
+	// LoadScene creates a new scene from data written by SaveScene.
+	// Scene assets are saved by name and reloaded as needed.
+	SaveScene(scene *Ent, w io.Writer) error
+	LoadScene(r io.Reader) *Ent // Returns nil if r can't be read.
+
+	// Query returns the live entities that have the given tag, see
+	// Ent.Tag, and all of the requested components, ie: HasModel|HasBody.
+	Query(tag string, mask uint32) []*Ent
+
+	// Cast returns the entities with physics bodies that are hit by
+	// the ray, closest first, along with where each body was hit.
+	// See Ent.Cast to check a single entity.
+	Cast(ray Body) ([]*Ent, []*physics.Hit)
+
 	// Set changes engine wide attributes. It accepts one or more
 	// functions that take an EngAttr parameter, ie: vu.Color(0,0,0).
 	Set(...EngAttr) // Change one or more engine attributes.
@@ -44,6 +61,21 @@ type Eng interface {
 	// Times for the previous update loop. The application
 	// can average times over multiple updates.
 	Times() *Profile // Per update loop performance metrics.
+
+	// Preload imports a named group of assets, like "msh:name",
+	// "tex:name", "shd:name", before they are used by models. Progress
+	// reports the loaded, failed, and total assets in the group. The
+	// optional loaded callback is run on the update goroutine once
+	// every group asset has been loaded or has failed. Unload releases
+	// the group hold on its assets.
+	Preload(group string, loaded func(group string), assets ...string)
+	Progress(group string) (loaded, failed, total int)
+	Unload(group string)
+
+	// Resident returns the loaded assets and their approximate sizes.
+	// Assets are unloaded when the last model or preload group
+	// using them is disposed or unloaded.
+	Resident() []Resource
 }
 
 // engine controls the run loop and access to the device layer
@@ -54,6 +86,9 @@ type engine struct {
 	ac  audio.Audio    // Audio card interface.
 	app *application   // Application controller implementing Eng.
 
+	// Optionally record or play back user input.
+	replay replay // Only used on the main thread.
+
 	// Track time each refresh cycle to ensure fixed timestamp updates.
 	startTime  time.Time     // Track start of a game loop display refresh.
 	updateTime time.Duration // Grows until big enough to trigger update.
@@ -65,9 +100,9 @@ type engine struct {
 
 // newEngine initializes the device layers, returning an error
 // if any problems are encountered. Called once by Run on startup.
-func newEngine(app App) (eng *engine, err error) {
+func newEngine(app App, loc load.Locator) (eng *engine, err error) {
 	eng = &engine{}
-	eng.app = newApplication(app)
+	eng.app = newApplication(app, loc)
 	return eng, nil
 }
 
@@ -79,8 +114,11 @@ func (eng *engine) Init(d device.Device) {
 	eng.dev = d
 
 	// initialize audio. This is done here because iOS needs to
-	// configure shared audio before intializing OpenAL
-	eng.ac = audio.New()
+	// configure shared audio before intializing OpenAL.
+	// Audio may have already been set by RunHeadless.
+	if eng.ac == nil {
+		eng.ac = audio.New()
+	}
 	if err := eng.ac.Init(); err != nil {
 		log.Printf("No audio. %s.", err)
 		eng.ac = &audio.NoAudio{} // Disable audio.
@@ -88,8 +126,11 @@ func (eng *engine) Init(d device.Device) {
 
 	// initialize graphics now that context is available.
 	// Graphics can be initialized before device on OSX, but Windows
-	// needs a proper context to find the OpenGL functions
-	eng.gc = render.New()
+	// needs a proper context to find the OpenGL functions.
+	// Graphics may have already been set by RunHeadless.
+	if eng.gc == nil {
+		eng.gc = render.New()
+	}
 	if err := eng.gc.Init(); err != nil {
 		log.Printf("Failed starting graphics %s.", err)
 		eng.shutdown() // Can't continue without graphics.
@@ -123,7 +164,13 @@ func (eng *engine) Init(d device.Device) {
 func (eng *engine) Refresh(dev device.Device) {
 	eng.elapsed += time.Since(eng.startTime) // Add time since last Refresh
 	eng.startTime = time.Now()               // Reset loop start time tracker.
+	eng.refresh()
+}
 
+// refresh renders a frame and runs an update when enough time has
+// been added to eng.elapsed. It is separate from Refresh so that
+// RunHeadless can supply fixed elapsed times.
+func (eng *engine) refresh() {
 	// Need the *application instance back from update,
 	// blocking if update is not done.
 	if eng.app == nil {
@@ -138,6 +185,8 @@ func (eng *engine) Refresh(dev device.Device) {
 	// application goroutine can't access device layer resources.
 	eng.app.setAttributes(eng)  // set device attributes from update.
 	eng.app.scenes.release(eng) // remove disposed device data.
+	eng.app.ld.release(eng)     // remove evicted asset data.
+	eng.app.ld.rebind(eng)      // compile reloaded shaders.
 	eng.app.scenes.rebind(eng)  // refresh changes to GPU assets.
 	eng.app.models.rebind(eng)  // refresh changes to GPU assets.
 	eng.app.sounds.rebind(eng)  // refresh changes to Audio assets.
@@ -200,10 +249,12 @@ func (eng *engine) update() {
 		app.ut++                   // Track the total update ticks.
 
 		// Input polling clears the accumulated user input.
-		app.input.poll(eng.dev.Down(), app.ut)
+		// The input can be recorded or replaced by played back input.
+		pressed, win := eng.replay.input(eng.dev, app.ut)
+		app.input.poll(pressed, app.ut)
 		if app.input.Resized {
-			app.state.Full = eng.dev.IsFullScreen()
-			app.state.setScreen(eng.dev.Size())
+			app.state.Full = win.Full
+			app.state.setScreen(win.X, win.Y, win.W, win.H)
 			eng.gc.Viewport(app.state.W, app.state.H)
 		}
 
@@ -236,6 +287,7 @@ func (eng *engine) update() {
 
 // shutdown releases the engine resources allocated on startup.
 func (eng *engine) shutdown() {
+	eng.replay.record("") // finish any recording.
 	if eng.ac != nil {
 		eng.ac.Dispose()
 		eng.ac = nil
@@ -267,16 +319,30 @@ func (eng *engine) bind(a asset) error {
 		return eng.gc.BindMesh(&d.vao, d.vdata, d.faces)
 	case *shader:
 		var err error
+		if ns, ok := eng.gc.(render.NamedShaders); ok {
+			d.program, err = ns.BindNamedShader(d.name, d.vsh, d.fsh, d.uniforms, d.layouts)
+			return err
+		}
 		d.program, err = eng.gc.BindShader(d.vsh, d.fsh, d.uniforms, d.layouts)
 		return err
 	case *Texture:
 		d.rebind = false // for bind() from loader instead of Texture.bind().
-		return eng.gc.BindTexture(&d.tid, d.img)
+		if d.isCube() {
+			return eng.gc.BindCubemap(&d.tid, d.faces)
+		}
+		if err := eng.gc.BindTexture(&d.tid, d.img, d.mips...); err != nil {
+			return err
+		}
+		d.mode = d.clamp || d.filter != render.Trilinear // reapply non-defaults.
+		return nil
 	case *sound:
 		return eng.ac.BindSound(&d.sid, &d.did, d.data)
 	case *shadows:
 		return eng.gc.BindMap(&d.bid, &d.tex.tid)
 	case *target:
+		if d.w > 0 && d.h > 0 {
+			return eng.gc.BindPostTarget(&d.bid, &d.tex.tid, &d.db, d.w, d.h)
+		}
 		err := eng.gc.BindTarget(&d.bid, &d.tex.tid, &d.db)
 		return err
 	}
@@ -307,10 +373,10 @@ func (eng *engine) release(asset interface{}) {
 	}
 }
 
-// clampTex requests a texture to be non-repeating.
+// texMode requests a texture be clamped or filtered.
 // Expected to be called once when setting up a texture.
-func (eng *engine) clampTex(tid uint32) {
-	eng.gc.SetTextureMode(tid, true)
+func (eng *engine) texMode(t *Texture) {
+	eng.gc.SetTextureMode(t.tid, t.clamp, t.filter)
 }
 
 // engine
@@ -40,11 +40,14 @@ import (
 //         SetUniform, Alpha, SetAlpha, SetColor, SetDraw, Clamp,
 //         MakeInstancedModel, DrawInstances.
 // Actor : MakeActor attaches an animated model with a part entity.
-//         MakeActor, Animate, Action, Actions, Pose.
+//         MakeActor, Animate, Action, Actions, Pose, Crossfade,
+//         AnimateLayer, SetLayerWeight, SetLayerMask, StopLayer, JointMask,
+//         OnAnimEvent, SetRootMotion.
 // Label : MakeLabel attaches a string model with a part entity.
 //         MakeLabel, Typeset, SetWrap, Size.
 // Body  : MakeBody attaches a physics body with a part entity.
-//         MakeBody, Body, DisposeBody, SetSolid, Cast, Push.
+//         MakeBody, Body, DisposeBody, SetSolid, Cast, Push,
+//         JoinBall, JoinHinge, JoinSlider, JoinFixed, JoinDistance, Unjoin.
 // Light : MakeLight creates and attaches light data to a scene entity.
 //         MakeLight, AffectAmbient, AffectDiffuse, AffectSpecular.
 //         SetAttenuation - for PointLights and SpotLights
@@ -56,6 +59,11 @@ import (
 // Target: AsTex controls rendering a scene to a texture for an
 // existing scene entity.
 //         AsTex.
+// Tag   : Tag names any entity so it can be found using Eng.Query.
+//         Tag, Untag, Tags, Tagged.
+// Tween : Tween methods interpolate part and model values over time.
+//         TweenAt, TweenView, TweenScale, TweenAlpha, TweenColor,
+//         TweenUniform.
 type Ent struct {
 	eid eid          // Unique entity identifier.
 	app *application // Manager of all component managers.
@@ -63,7 +71,8 @@ type Ent struct {
 
 // Dispose all components for this entity.
 // If the entity is a scene or a part with child entities,
-// then all child entities are also disposed.
+// then all child entities are also disposed. Model assets are
+// unloaded once they are no longer used by any model.
 func (e *Ent) Dispose() {
 	e.app.dispose(e.eid)
 }
@@ -151,7 +160,7 @@ func (ids *eids) create() eid {
 // valid entities are those that have been created and not yet disposed.
 func (ids *eids) valid(e eid) bool {
 	id := e.id()
-	if id >= uint32(len(ids.editions)) {
+	if id == 0 || id > uint32(len(ids.editions)) {
 		return false
 	}
 	return ids.editions[e.id()-1] == e.edition()
@@ -21,6 +21,14 @@ func TestFirstIsOne(t *testing.T) {
 	}
 }
 
+func TestLastIsValid(t *testing.T) {
+	ids := &eids{}
+	ids.create()
+	if eid := ids.create(); !ids.valid(eid) || ids.valid(eid+1) {
+		t.Errorf("Expecting only created entities to be valid")
+	}
+}
+
 func TestMaxCreate(t *testing.T) {
 	ids := &eids{}
 	for cnt := 1; cnt < maxEntID; cnt++ {
@@ -0,0 +1,109 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// headless.go runs the engine without a device layer. It is intended
+// for testing application logic on machines without a display.
+
+import (
+	"fmt"
+
+	"github.com/gazed/vu/audio"
+	"github.com/gazed/vu/device"
+	"github.com/gazed/vu/load"
+	"github.com/gazed/vu/render"
+)
+
+// Headless configures RunHeadless. Zero values are replaced
+// with reasonable defaults.
+type Headless struct {
+	Ticks int            // Number of fixed timestep updates to run.
+	W, H  int            // Simulated window size. Default 800x600.
+	Gc    render.Context // Graphics context. Default render.NoRender.
+	Loc   load.Locator   // Asset locator. Default load.NewLocator.
+}
+
+// RunHeadless creates the engine and runs the given number of fixed
+// timestep updates without a device layer or audio. Each tick renders
+// a frame and runs one App.Update. Asset imports are completed before
+// each update so that every run of an application behaves the same.
+// RunHeadless returns once the ticks are done or the application
+// calls Eng.Shutdown.
+//    app  : used by engine to communicate with App.
+//    opts : number of ticks and optional window size, render context,
+//           and asset locator.
+func RunHeadless(app App, opts Headless) (err error) {
+	if app == nil {
+		return fmt.Errorf("No application. Shutting down.")
+	}
+	eng, err := newEngine(app, opts.Loc)
+	if err != nil {
+		return err
+	}
+	eng.app.ld.wait = true // deterministic asset loading.
+	eng.ac = &audio.NoAudio{}
+	eng.gc = opts.Gc
+	if eng.gc == nil {
+		eng.gc = &render.NoRender{}
+	}
+	if opts.W < minWindowSize || opts.H < minWindowSize {
+		opts.W, opts.H = 800, 600
+	}
+	eng.Init(newHeadlessDevice(opts.W, opts.H))
+	for tick := 0; tick < opts.Ticks && eng.dev != nil; tick++ {
+		eng.elapsed += timeStep // exactly one update each refresh.
+		eng.refresh()
+	}
+	if eng.dev != nil {
+		if eng.app == nil {
+			eng.app = <-eng.doneUpdate // wait for the last update.
+		}
+		eng.shutdown()
+	}
+	return nil
+}
+
+// RunHeadless
+// =============================================================================
+// headless device
+
+// headless implements device.Device for RunHeadless. It has a window
+// that always has focus and never has user input.
+type headless struct {
+	w, h    int             // Window size in pixels.
+	resized bool            // True until the window size is polled.
+	pressed *device.Pressed // Always empty user input.
+}
+
+// newHeadlessDevice returns a device with the given window size.
+// The size is reported as a resize on the first poll, like a window
+// being opened.
+func newHeadlessDevice(w, h int) *headless {
+	hd := &headless{w: w, h: h, resized: true}
+	hd.pressed = &device.Pressed{Focus: true, Down: map[int]int{}}
+	return hd
+}
+
+// Down implements device.Device by reporting any resize.
+func (hd *headless) Down() *device.Pressed {
+	hd.pressed.Resized, hd.resized = hd.resized, false
+	return hd.pressed
+}
+
+// SetSize implements device.Device by resizing the window.
+func (hd *headless) SetSize(x, y, w, h int) {
+	hd.w, hd.h, hd.resized = w, h, true
+}
+
+// Implement device.Device.
+func (hd *headless) Dispose()               {}
+func (hd *headless) Size() (x, y, w, h int) { return 0, 0, hd.w, hd.h }
+func (hd *headless) SwapBuffers()           {}
+func (hd *headless) Copy() string           { return "" }
+func (hd *headless) Paste(s string)         {}
+func (hd *headless) SetTitle(t string)      {}
+func (hd *headless) IsFullScreen() bool     { return false }
+func (hd *headless) ToggleFullScreen()      {}
+func (hd *headless) ShowCursor(show bool)   {}
+func (hd *headless) SetCursorAt(x, y int)   {}
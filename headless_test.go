@@ -0,0 +1,308 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gazed/vu/load"
+	"github.com/gazed/vu/render"
+)
+
+// Check that the update loop, asset loading, scene drawing and
+// physics all run without a device.
+func TestRunHeadless(t *testing.T) {
+	ha := &headlessApp{}
+	if err := RunHeadless(ha, Headless{Ticks: 10}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if ha.creates != 1 || ha.updates != 10 {
+		t.Errorf("Expected 1 create and 10 updates, got %d %d", ha.creates, ha.updates)
+	}
+	if ha.lastUt != 10 {
+		t.Errorf("Expected update tick 10, got %d", ha.lastUt)
+	}
+	if ha.draws == 0 {
+		t.Errorf("Expected the generated model to be drawn")
+	}
+	if ha.fallY >= 10 {
+		t.Errorf("Expected solid body to fall from 10, got %f", ha.fallY)
+	}
+}
+
+// Check that the application can stop the engine early.
+func TestRunHeadlessShutdown(t *testing.T) {
+	ha := &headlessApp{stopAt: 3}
+	if err := RunHeadless(ha, Headless{Ticks: 10}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if ha.updates != 3 {
+		t.Errorf("Expected 3 updates, got %d", ha.updates)
+	}
+}
+
+// headlessApp implements App for the headless tests.
+type headlessApp struct {
+	creates, updates int
+	lastUt           uint64
+	stopAt           int     // Shutdown after this many updates.
+	draws            int     // Draw calls from the last render.
+	fallY            float64 // Location of the falling body.
+	ball             *Ent
+}
+
+// Create a triangle model and a solid falling body.
+func (ha *headlessApp) Create(eng Eng, s *State) {
+	ha.creates++
+	scene := eng.AddScene()
+	genTriangle(scene.AddPart().SetAt(0, 0, -5).MakeModel("colored"), "triangle")
+	ha.ball = scene.AddPart().SetAt(0, 10, 0).MakeBody(Sphere(1))
+	ha.ball.SetSolid(1, 0)
+}
+
+// Update tracks the engine state.
+func (ha *headlessApp) Update(eng Eng, in *Input, s *State) {
+	ha.updates++
+	ha.lastUt = in.Ut
+	ha.draws = len(eng.(*application).frame)
+	_, ha.fallY, _ = ha.ball.At()
+	if ha.updates == ha.stopAt {
+		eng.Shutdown()
+	}
+}
+
+// Check that a scene can be rendered and inspected without a GPU.
+func TestRunHeadlessSoftware(t *testing.T) {
+	sw := render.NewSoftware()
+	if err := RunHeadless(&softApp{}, Headless{Ticks: 3, W: 200, H: 100, Gc: sw}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	img := sw.Image()
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		t.Fatalf("Expected 200x100 image, got %v", img.Bounds())
+	}
+	if c := img.RGBAAt(100, 50); c.R != 255 || c.G != 0 || c.A != 255 {
+		t.Errorf("Expected red triangle in front of green, got %v", c)
+	}
+	if c := img.RGBAAt(2, 2); c.R != 0 || c.G != 0 || c.B != 255 {
+		t.Errorf("Expected blue background, got %v", c)
+	}
+}
+
+// softApp draws a red triangle in front of a larger green triangle.
+type softApp struct{}
+
+// Create the two triangles in a 3D scene.
+func (sa *softApp) Create(eng Eng, s *State) {
+	eng.Set(Color(0, 0, 1, 1))
+	scene := eng.AddScene()
+	scene.Cam().SetClip(0.1, 50).SetFov(60)
+	for cnt, z := range []float64{-5, -10} {
+		tri := scene.AddPart().SetAt(0, 0, z).MakeModel("colored")
+		tri.SetColor(float64(1-cnt), float64(cnt), 0)
+		genTriangle(tri, "tri"+string('0'+rune(cnt)))
+	}
+}
+
+// Update does nothing.
+func (sa *softApp) Update(eng Eng, in *Input, s *State) {}
+
+// Check that releasing a mesh releases its vertex and face buffers.
+func TestRunHeadlessRelease(t *testing.T) {
+	fsys := fstest.MapFS{"models/tri.obj": {Data: []byte("o tri\nv -1 -1 0\nv 1 -1 0\nv 0 1 0\nvn 0 0 1\nf 1//1 2//1 3//1\n")}}
+	sw := render.NewSoftware()
+	ra := &releaseApp{sw: sw}
+	if err := RunHeadless(ra, Headless{Ticks: 4, Gc: sw, Loc: load.NewFSLocator("test", fsys)}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if ra.bound == 0 {
+		t.Errorf("Expected bound mesh buffers")
+	}
+	if sw.Buffers() != 0 {
+		t.Errorf("Expected released mesh buffers, got %d", sw.Buffers())
+	}
+}
+
+// releaseApp disposes a model with a loaded mesh.
+type releaseApp struct {
+	sw    *render.Software
+	model *Ent
+	bound int
+}
+
+// Create a model using a loaded mesh.
+func (ra *releaseApp) Create(eng Eng, s *State) {
+	ra.model = eng.AddScene().AddPart().MakeModel("colored", "msh:tri")
+}
+
+// Update disposes the model once its mesh is bound.
+func (ra *releaseApp) Update(eng Eng, in *Input, s *State) {
+	if in.Ut == 2 {
+		ra.bound = ra.sw.Buffers()
+		ra.model.Dispose()
+	}
+}
+
+// Check that the draw order can be verified using a recorder.
+func TestRunHeadlessRecorder(t *testing.T) {
+	rec := render.NewRecorder(&render.NoRender{})
+	sa := &overlayApp{}
+	if err := RunHeadless(sa, Headless{Ticks: 3, Gc: rec}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	draws := rec.Draws(rec.Frame)
+	if len(draws) != 2 {
+		t.Fatalf("Expected 2 draws, got %d", len(draws))
+	}
+	if draws[0].Tag != uint32(sa.model.eid) || draws[1].Tag != uint32(sa.label.eid) {
+		t.Errorf("Expected 3D model %d before UI %d, got %d %d",
+			sa.model.eid, sa.label.eid, draws[0].Tag, draws[1].Tag)
+	}
+	if kd := draws[0].Uniforms["kd"]; len(kd) != 3 || kd[0] != 1 {
+		t.Errorf("Expected red kd uniform, got %v", kd)
+	}
+	if js, err := rec.JSON(); err != nil || len(js) == 0 {
+		t.Errorf("Expected JSON log %s", err)
+	}
+}
+
+// Check that the recorder keeps the clear color values.
+func TestRunHeadlessRecorderColor(t *testing.T) {
+	rec := render.NewRecorder(&render.NoRender{})
+	if err := RunHeadless(&softApp{}, Headless{Ticks: 2, Gc: rec}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	var color []float32
+	for _, c := range rec.Calls {
+		if c.Op == "Color" {
+			color = c.Values
+		}
+	}
+	if !reflect.DeepEqual(color, []float32{0, 0, 1, 1}) {
+		t.Errorf("Expected blue clear color, got %v", color)
+	}
+}
+
+// overlayApp draws a UI model over a 3D model.
+type overlayApp struct{ model, label *Ent }
+
+// Create the UI scene before the 3D scene.
+func (oa *overlayApp) Create(eng Eng, s *State) {
+	ui := eng.AddScene().SetUI()
+	ui.Cam().SetClip(0, 10)
+	oa.label = ui.AddPart().SetScale(100, 100, 1).SetAt(200, 200, 0).MakeModel("colored")
+	genTriangle(oa.label, "label")
+	scene := eng.AddScene()
+	scene.Cam().SetClip(0.1, 50).SetFov(60)
+	oa.model = scene.AddPart().SetAt(0, 0, -5).MakeModel("colored")
+	genTriangle(oa.model.SetColor(1, 0, 0), "model")
+}
+
+// Update does nothing.
+func (oa *overlayApp) Update(eng Eng, in *Input, s *State) {}
+
+// genTriangle gives a model a counter-clockwise triangle mesh
+// centered on the origin.
+func genTriangle(model *Ent, name string) {
+	m := model.GenMesh(name)
+	m.InitData(0, 3, StaticDraw, false).SetData(0, []float32{-1, -1, 0, 1, -1, 0, 0, 1, 0})
+	m.InitFaces(StaticDraw).SetFaces([]uint16{0, 1, 2})
+}
+
+// Check that post processing passes are drawn after the scene in order,
+// each reading the previous target and the last drawing to the display.
+func TestRunHeadlessPost(t *testing.T) {
+	rec := render.NewRecorder(&render.NoRender{})
+	pa := &postApp{}
+	if err := RunHeadless(pa, Headless{Ticks: 5, Gc: rec}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	draws := rec.Draws(rec.Frame)
+	if len(draws) != 4 {
+		t.Fatalf("Expected model and 3 pass draws, got %d", len(draws))
+	}
+	p := pa.app.scenes.posts[pa.scene.eid]
+	t0, t1 := p.targets[0], p.targets[1]
+	expect := []struct {
+		tag     eid
+		in, fbo uint32
+	}{
+		{pa.model.eid, 0, t0.bid},
+		{pa.passes[0].eid, t0.tex.tid, t1.bid},
+		{pa.passes[1].eid, t1.tex.tid, t0.bid},
+		{pa.passes[2].eid, t0.tex.tid, 0},
+	}
+	for cnt, ex := range expect {
+		d := draws[cnt]
+		if d.Tag != uint32(ex.tag) || d.Fbo != ex.fbo {
+			t.Errorf("Draw %d expected tag %d fbo %d, got %d %d", cnt, ex.tag, ex.fbo, d.Tag, d.Fbo)
+		}
+		if cnt > 0 && (d.Textures[0] != ex.in || d.Depth) {
+			t.Errorf("Pass %d expected input %d, got %d", cnt, ex.in, d.Textures[0])
+		}
+	}
+	if v := draws[1].Uniforms["threshold"]; len(v) != 1 || v[0] != 0.5 {
+		t.Errorf("Expected threshold uniform 0.5, got %v", v)
+	}
+	if v := draws[2].Uniforms["exposure"]; len(v) != 1 || v[0] != 1 {
+		t.Errorf("Expected default exposure uniform 1, got %v", v)
+	}
+}
+
+// Check that post processing targets match the window size
+// and are recreated when the window is resized.
+func TestRunHeadlessPostResize(t *testing.T) {
+	rec := render.NewRecorder(&render.NoRender{})
+	pa := &postApp{resize: true}
+	if err := RunHeadless(pa, Headless{Ticks: 5, W: 320, H: 200, Gc: rec}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	sizes, released := [][]int{}, 0
+	for _, c := range rec.Calls {
+		switch c.Op {
+		case "BindTarget":
+			t.Errorf("Expected post targets, got %v", c)
+		case "BindPostTarget":
+			sizes = append(sizes, c.Attrs)
+		case "ReleaseTarget":
+			released++
+		}
+	}
+	expect := [][]int{{320, 200}, {320, 200}, {640, 400}, {640, 400}}
+	if !reflect.DeepEqual(sizes, expect) || released != 2 {
+		t.Errorf("Expected targets %v and 2 released, got %v %d", expect, sizes, released)
+	}
+	p := pa.app.scenes.posts[pa.scene.eid]
+	if draws := rec.Draws(rec.Frame); len(draws) != 4 || draws[0].Fbo != p.targets[0].bid {
+		t.Errorf("Expected scene drawn to the recreated target")
+	}
+}
+
+// postApp draws a model through a chain of post processing passes.
+type postApp struct {
+	app          *application
+	scene, model *Ent
+	passes       []*Ent
+	resize       bool // Resize the window after the first update.
+}
+
+// Create a scene with three post processing passes.
+func (pa *postApp) Create(eng Eng, s *State) {
+	pa.scene = eng.AddScene()
+	pa.app = pa.scene.app
+	pa.model = pa.scene.AddPart().SetAt(0, 0, -5).MakeModel("colored")
+	genTriangle(pa.model, "model")
+	pa.passes = append(pa.passes, pa.scene.AddPost("bloom").SetUniform("threshold", 0.5))
+	pa.passes = append(pa.passes, pa.scene.AddPost("toneMap"))
+	pa.passes = append(pa.passes, pa.scene.AddPost("vignette"))
+}
+
+// Update optionally resizes the window.
+func (pa *postApp) Update(eng Eng, in *Input, s *State) {
+	if pa.resize && in.Ut == 1 {
+		eng.Set(Size(0, 0, 640, 400))
+	}
+}
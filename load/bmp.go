@@ -0,0 +1,124 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+// BMP: Windows bitmap format.
+// Supports the uncompressed bitmaps commonly exported by paint programs:
+//    8  : palette indexed pixels.
+//    24 : BGR pixels.
+//    32 : BGRA pixels. Alpha is only used when the header has
+//         an alpha mask, otherwise the pixels are opaque.
+// Rows are padded to 4 bytes and are bottom up unless the
+// height is negative.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+)
+
+// Bmp populates image data using the given reader.
+// The Reader r is expected to be opened and closed by the caller.
+// A successful import replaces the image in ImgData with a new NRGBA image.
+func Bmp(r io.Reader, d *ImgData) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("Invalid .bmp file: %s", err)
+	}
+	if len(data) < 14+40 || data[0] != 'B' || data[1] != 'M' {
+		return fmt.Errorf("Invalid .bmp file header")
+	}
+	le := binary.LittleEndian
+	offset, hdrSize := int(le.Uint32(data[10:])), int(le.Uint32(data[14:]))
+	width, height := int(int32(le.Uint32(data[18:]))), int(int32(le.Uint32(data[22:])))
+	bpp, compression := int(le.Uint16(data[28:])), le.Uint32(data[30:])
+	colors := int(le.Uint32(data[46:]))
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	switch {
+	case hdrSize < 40 || width <= 0 || height == 0:
+		return fmt.Errorf("Invalid .bmp info header")
+	case bpp != 8 && bpp != 24 && bpp != 32:
+		return fmt.Errorf("Unsupported .bmp depth %d", bpp)
+	case compression != bmpRGB && !(compression == bmpBitFields && bpp == 32):
+		return fmt.Errorf("Unsupported .bmp compression %d", compression)
+	}
+
+	// 32 bit pixels use alpha when the header has a standard alpha mask.
+	// The color masks follow a version 3 header and are the next fields
+	// in later headers. Only later headers have an alpha mask.
+	alpha := false
+	if compression == bmpBitFields {
+		masks := 14 + 40
+		if len(data) < masks+16 {
+			return fmt.Errorf("Invalid .bmp color masks")
+		}
+		red, green, blue := le.Uint32(data[masks:]), le.Uint32(data[masks+4:]), le.Uint32(data[masks+8:])
+		if red != 0xff0000 || green != 0xff00 || blue != 0xff {
+			return fmt.Errorf("Unsupported .bmp color masks")
+		}
+		alpha = hdrSize > 40 && le.Uint32(data[masks+12:]) == 0xff000000
+	}
+
+	// palette entries are BGR plus one unused byte.
+	var palette []byte
+	if bpp == 8 {
+		if colors == 0 || colors > 256 {
+			colors = 256
+		}
+		start := 14 + hdrSize
+		if len(data) < start+colors*4 {
+			return fmt.Errorf("Corrupt .bmp palette")
+		}
+		palette = data[start : start+colors*4]
+	}
+
+	// convert to top down NRGBA.
+	stride := (width*bpp/8 + 3) &^ 3
+	if offset < 0 || offset+stride*height > len(data) {
+		return fmt.Errorf("Corrupt .bmp file: missing pixels")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := y
+		if !topDown {
+			row = height - 1 - y
+		}
+		src := data[offset+row*stride:]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			q := dst[x*4:]
+			switch bpp {
+			case 8:
+				index := int(src[x]) * 4
+				if index >= len(palette) {
+					return fmt.Errorf("Corrupt .bmp palette index %d", src[x])
+				}
+				p := palette[index:]
+				q[0], q[1], q[2], q[3] = p[2], p[1], p[0], 255
+			case 24:
+				p := src[x*3:]
+				q[0], q[1], q[2], q[3] = p[2], p[1], p[0], 255
+			case 32:
+				p := src[x*4:]
+				q[0], q[1], q[2], q[3] = p[2], p[1], p[0], 255
+				if alpha {
+					q[3] = p[3]
+				}
+			}
+		}
+	}
+	d.Img, d.Mips = img, nil
+	return nil
+}
+
+// BMP compression types.
+const (
+	bmpRGB       = 0 // Uncompressed.
+	bmpBitFields = 3 // Uncompressed with color masks.
+)
@@ -0,0 +1,55 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestBmp(t *testing.T) {
+	// 1x2 bottom up 24 bit image. Each row is padded to 4 bytes.
+	pix := []byte{1, 2, 3, 0, 4, 5, 6, 0}
+	img := &ImgData{}
+	if err := Bmp(bytes.NewReader(testBmp(1, 2, 24, nil, pix)), img); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	want := []byte{6, 5, 4, 255, 3, 2, 1, 255}
+	if got := img.Img.(*image.NRGBA).Pix; !bytes.Equal(got, want) {
+		t.Errorf("Expected %v got %v", want, got)
+	}
+}
+
+func TestBmpPalette(t *testing.T) {
+	// 2x1 top down palette image.
+	palette := []byte{10, 20, 30, 0, 40, 50, 60, 0}
+	pix := []byte{1, 0, 0, 0}
+	img := &ImgData{}
+	if err := Bmp(bytes.NewReader(testBmp(2, -1, 8, palette, pix)), img); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	want := []byte{60, 50, 40, 255, 30, 20, 10, 255}
+	if got := img.Img.(*image.NRGBA).Pix; !bytes.Equal(got, want) {
+		t.Errorf("Expected %v got %v", want, got)
+	}
+	if err := Bmp(bytes.NewReader([]byte("BM")), img); err == nil {
+		t.Errorf("Expected error for short file")
+	}
+}
+
+// testBmp returns a bitmap file with a version 3 header.
+func testBmp(width, height int32, bpp uint16, palette, pix []byte) []byte {
+	buf := &bytes.Buffer{}
+	offset := uint32(14 + 40 + len(palette))
+	buf.WriteString("BM")
+	binary.Write(buf, binary.LittleEndian, []uint32{offset + uint32(len(pix)), 0, offset, 40})
+	binary.Write(buf, binary.LittleEndian, []int32{width, height})
+	binary.Write(buf, binary.LittleEndian, []uint16{1, bpp})
+	binary.Write(buf, binary.LittleEndian, []uint32{0, uint32(len(pix)), 0, 0, uint32(len(palette) / 4), 0})
+	buf.Write(palette)
+	buf.Write(pix)
+	return buf.Bytes()
+}
@@ -0,0 +1,73 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+// Cube maps stored as one image have the faces arranged in a cross.
+// The horizontal cross is 4 faces wide and 3 faces high:
+//         +Y
+//    -X   +Z   +X   -Z
+//         -Y
+// The vertical cross is 3 faces wide and 4 faces high with the -Z
+// face upside down below the -Y face:
+//         +Y
+//    -X   +Z   +X
+//         -Y
+//         -Z
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// Cross splits an image with cube map faces arranged in a horizontal
+// or vertical cross into six face images. The faces are returned
+// in CubeData order: +X, -X, +Y, -Y, +Z, -Z.
+func Cross(img image.Image) (faces [6]image.Image, err error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var cells [6]image.Point // face column and row.
+	size, vertical := 0, false
+	switch {
+	case w*3 == h*4 && w%4 == 0:
+		size = w / 4
+		cells = [6]image.Point{{2, 1}, {0, 1}, {1, 0}, {1, 2}, {1, 1}, {3, 1}}
+	case w*4 == h*3 && w%3 == 0:
+		size, vertical = w/3, true
+		cells = [6]image.Point{{2, 1}, {0, 1}, {1, 0}, {1, 2}, {1, 1}, {1, 3}}
+	default:
+		return faces, fmt.Errorf("Expected 4x3 or 3x4 cube map cross, got %dx%d", w, h)
+	}
+	for cnt, cell := range cells {
+		face := image.NewNRGBA(image.Rect(0, 0, size, size))
+		at := b.Min.Add(cell.Mul(size))
+		draw.Draw(face, face.Bounds(), img, at, draw.Src)
+		if vertical && cnt == 5 {
+			rotate180(face)
+		}
+		faces[cnt] = face
+	}
+	return faces, nil
+}
+
+// rotate180 turns an image upside down in place.
+func rotate180(img *image.NRGBA) {
+	pix := img.Pix
+	for i, j := 0, len(pix)-4; i < j; i, j = i+4, j-4 {
+		for c := 0; c < 4; c++ {
+			pix[i+c], pix[j+c] = pix[j+c], pix[i+c]
+		}
+	}
+}
+
+// cubeCheck ensures the cube map faces are square and the same size.
+func cubeCheck(faces [6]image.Image) error {
+	size := faces[0].Bounds().Size()
+	for _, face := range faces {
+		if fs := face.Bounds().Size(); fs.X != fs.Y || fs != size {
+			return fmt.Errorf("Expected square cube faces of size %dx%d got %dx%d", size.X, size.Y, fs.X, fs.Y)
+		}
+	}
+	return nil
+}
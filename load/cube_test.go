@@ -0,0 +1,75 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCross(t *testing.T) {
+	// horizontal cross with each face colored by its face index.
+	cells := []image.Point{{2, 1}, {0, 1}, {1, 0}, {1, 2}, {1, 1}, {3, 1}}
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 6))
+	for face, cell := range cells {
+		img.Set(cell.X*2, cell.Y*2, color.NRGBA{uint8(face), 0, 0, 255})
+	}
+	faces, err := Cross(img)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	for face, f := range faces {
+		if r, _, _, _ := f.At(0, 0).RGBA(); r>>8 != uint32(face) || f.Bounds().Dx() != 2 {
+			t.Errorf("Expected face %d got %d", face, r>>8)
+		}
+	}
+
+	// vertical cross has the -Z face upside down.
+	img = image.NewNRGBA(image.Rect(0, 0, 6, 8))
+	img.Set(2, 6, color.NRGBA{9, 0, 0, 255})
+	if faces, err = Cross(img); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if r, _, _, _ := faces[5].At(1, 1).RGBA(); r>>8 != 9 {
+		t.Errorf("Expected rotated -Z face")
+	}
+	if _, err = Cross(image.NewNRGBA(image.Rect(0, 0, 8, 8))); err == nil {
+		t.Errorf("Expected error for square image")
+	}
+}
+
+// Check that cube maps load from six images or from one cross image.
+func TestLoadCube(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cube")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, w, h int) {
+		file, _ := os.Create(filepath.Join(dir, name))
+		png.Encode(file, image.NewNRGBA(image.Rect(0, 0, w, h)))
+		file.Close()
+	}
+	for _, suffix := range CubeFaces {
+		write("sky"+suffix+".png", 4, 4)
+	}
+	write("cross.png", 16, 12)
+	write("bad_posx.png", 4, 4)
+	loc := NewLocator().Dir("PNG", dir)
+	cube := &CubeData{}
+	if err := cube.Load("sky", loc); err != nil || cube.Faces[5].Bounds().Dx() != 4 {
+		t.Errorf("Expected six face cube map %s", err)
+	}
+	if err := cube.Load("cross", loc); err != nil || cube.Faces[0].Bounds().Dx() != 4 {
+		t.Errorf("Expected cross cube map %s", err)
+	}
+	if err := cube.Load("bad", loc); err == nil {
+		t.Errorf("Expected error for missing faces")
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Evt loads animation events from a text file that sits beside
+// an animated model file. Each non-blank line names a movement,
+// a frame within that movement, and the event name. Lines
+// starting with # are comments. For example:
+//    # movement  frame event
+//    Runner_Run  10    footstep
+//    Runner_Run  37    footstep
+// The Reader r is expected to be opened and closed by the caller.
+// Events are appended to the matching AnmData movements.
+func Evt(r io.Reader, d *AnmData) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var move, name string
+		var frame uint32
+		if _, err := fmt.Sscanf(line, "%s %d %s", &move, &frame, &name); err != nil {
+			return fmt.Errorf("could not parse event line %d: %s", lineNum, err)
+		}
+		found := false
+		for cnt := range d.Movements {
+			if mv := &d.Movements[cnt]; mv.Name == move {
+				if frame >= mv.Fn {
+					return fmt.Errorf("event line %d: frame %d past movement end", lineNum, frame)
+				}
+				mv.Events = append(mv.Events, Event{Name: name, Frame: frame})
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("event line %d: unknown movement %s", lineNum, move)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,42 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvt(t *testing.T) {
+	d := &AnmData{Movements: []Movement{{Name: "walk", Fn: 20}, {Name: "jump", Fn: 10}}}
+	events := "# comment\n\nwalk 5 left\nwalk 15 right\njump 0 takeoff\n"
+	if err := Evt(strings.NewReader(events), d); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if walk := d.Movements[0].Events; len(walk) != 2 || walk[1].Name != "right" || walk[1].Frame != 15 {
+		t.Errorf("Expected two walk events got %v", walk)
+	}
+	if jump := d.Movements[1].Events; len(jump) != 1 || jump[0].Name != "takeoff" {
+		t.Errorf("Expected one jump event got %v", jump)
+	}
+	for _, bad := range []string{"run 1 step", "walk 20 late", "walk step"} {
+		if err := Evt(strings.NewReader(bad), d); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
+
+// Uses vu/eg resource directories.
+func TestLoadEvt(t *testing.T) {
+	m := &ModData{}
+	if err := m.Load("runner", NewLocator().Dir("IQM", "../eg/models").Dir("EVT", "../eg/models")); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	for _, mv := range m.Movements {
+		if mv.Name == "Runner_Run" && len(mv.Events) > 0 {
+			return
+		}
+	}
+	t.Errorf("Expected Runner_Run events")
+}
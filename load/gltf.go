@@ -0,0 +1,978 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+// glTF: GL Transmission Format from the Khronos Group.
+// A JSON, or binary JSON, format for 3D models and skeletal animation:
+//    https://github.com/KhronosGroup/glTF/tree/master/specification/2.0
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// GltfRate is the number of frames per second used to sample
+// glTF animations into Movement frames.
+const GltfRate = 30
+
+// Gltf loads a glTF 2.0 model, either a JSON .gltf file or a binary .glb
+// file, into ModData. The triangles from all mesh primitives are combined
+// into one mesh where each primitive has a TexMap and a MtlData.
+// The first skin provides the animation joints and each glTF animation
+// becomes a Movement sampled at GltfRate frames per second.
+// This loader has been tested against a subset of the full specification:
+//    Triangle primitives using the first texture coordinates, joints
+//    and weights. Skinned models are limited to 256 joints.
+//    Buffers embedded in a .glb or as base64 data URIs. Use
+//    ModData.Load or MshData.Load for buffers kept in separate files.
+//    Cubic spline animations are sampled without their tangents.
+// The Reader r is expected to be opened and closed by the caller.
+func Gltf(r io.Reader, d *ModData) error { return gltfModel(r, d, nil) }
+
+// public inteface
+// =============================================================================
+// internal implementation for loading glTF files.
+
+// loadGltf looks for a .gltf or .glb model with the given name.
+// Buffers kept in separate files are found using the Locator.
+// Returns false if there is no glTF model with the given name.
+func loadGltf(name string, l Locator, d *ModData) (found bool, err error) {
+	for _, ext := range []string{".gltf", ".glb"} {
+		fname := name + ext
+		reader, rerr := l.GetResource(fname)
+		if rerr != nil {
+			continue
+		}
+		defer reader.Close()
+		files := func(uri string) ([]byte, error) {
+			if unescaped, err := url.PathUnescape(uri); err == nil {
+				uri = unescaped
+			}
+			file, err := l.GetResource(path.Join(path.Dir(name), uri))
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			return ioutil.ReadAll(file)
+		}
+		if err = gltfModel(reader, d, files); err != nil {
+			return true, fmt.Errorf("Could not load model from %s: %s\n", fname, err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// gltfModel loads glTF data into ModData. The files function, if not nil,
+// fetches buffers that are kept in separate files.
+func gltfModel(r io.Reader, d *ModData, files func(uri string) ([]byte, error)) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("Invalid glTF file: %s", err)
+	}
+	g := &gltf{files: files}
+	if err = g.parse(data); err != nil {
+		return err
+	}
+	*d = ModData{}
+	if err = g.loadMeshes(d); err != nil {
+		return err
+	}
+	return g.loadAnims(d)
+}
+
+// gltf holds the parsed glTF document and its buffer data
+// while a single model is loaded.
+type gltf struct {
+	doc     gltfDoc                          // Parsed JSON.
+	bin     []byte                           // .glb binary chunk.
+	buffers [][]byte                         // Loaded buffer data.
+	files   func(uri string) ([]byte, error) // Optional file lookup.
+
+	// Scratch data shared between meshes and animations.
+	parents []int     // Parent node for each node, -1 for root nodes.
+	world   []*lin.M4 // Rest pose world transform for each node.
+	skinned bool      // True if a mesh uses the skin.
+	order   []int     // Skin joint for each model joint.
+}
+
+// glb chunk identifiers.
+const (
+	glbMagic = 0x46546C67 // "glTF"
+	glbJSON  = 0x4E4F534A // "JSON"
+	glbBIN   = 0x004E4942 // "BIN\0"
+)
+
+// parse splits out the JSON and binary chunks from a .glb file,
+// parses the JSON, and loads the buffer data.
+func (g *gltf) parse(data []byte) (err error) {
+	js := data
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == glbMagic {
+		if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+			return fmt.Errorf("Expecting .glb version 2, got : %d", version)
+		}
+		js = nil
+		for at := 12; at+8 <= len(data); {
+			size := int(binary.LittleEndian.Uint32(data[at:]))
+			kind := binary.LittleEndian.Uint32(data[at+4:])
+			if at+8+size > len(data) {
+				return fmt.Errorf("Invalid .glb chunk size %d", size)
+			}
+			switch chunk := data[at+8 : at+8+size]; kind {
+			case glbJSON:
+				js = chunk
+			case glbBIN:
+				g.bin = chunk
+			}
+			at += 8 + size
+		}
+		if js == nil {
+			return fmt.Errorf("Invalid .glb file: missing JSON chunk")
+		}
+	}
+	if err = json.Unmarshal(js, &g.doc); err != nil {
+		return fmt.Errorf("Invalid glTF file: %s", err)
+	}
+	if !strings.HasPrefix(g.doc.Asset.Version, "2.") {
+		return fmt.Errorf("Expecting glTF version 2, got : %s", g.doc.Asset.Version)
+	}
+
+	// get the buffer data.
+	g.buffers = make([][]byte, len(g.doc.Buffers))
+	for cnt, b := range g.doc.Buffers {
+		switch {
+		case b.URI == "" && g.bin != nil:
+			g.buffers[cnt] = g.bin
+		case strings.HasPrefix(b.URI, "data:"):
+			sep := strings.Index(b.URI, ";base64,")
+			if sep < 0 {
+				return fmt.Errorf("Invalid glTF buffer %d: expected base64 data", cnt)
+			}
+			if g.buffers[cnt], err = base64.StdEncoding.DecodeString(b.URI[sep+8:]); err != nil {
+				return fmt.Errorf("Invalid glTF buffer %d: %s", cnt, err)
+			}
+		case g.files != nil && b.URI != "":
+			if g.buffers[cnt], err = g.files(b.URI); err != nil {
+				return fmt.Errorf("Could not load glTF buffer %s: %s", b.URI, err)
+			}
+		default:
+			return fmt.Errorf("Could not load glTF buffer %d %s", cnt, b.URI)
+		}
+		if len(g.buffers[cnt]) < b.ByteLength {
+			return fmt.Errorf("Invalid glTF buffer %d: expected %d bytes", cnt, b.ByteLength)
+		}
+	}
+	g.restPose()
+	return nil
+}
+
+// restPose calculates the parent and rest pose world transform
+// of each node. Parent nodes do not have to appear before children.
+func (g *gltf) restPose() {
+	nodes := g.doc.Nodes
+	g.parents = make([]int, len(nodes))
+	g.world = make([]*lin.M4, len(nodes))
+	for cnt := range g.parents {
+		g.parents[cnt] = -1
+	}
+	for cnt, n := range nodes {
+		for _, kid := range n.Children {
+			if kid >= 0 && kid < len(nodes) {
+				g.parents[kid] = cnt
+			}
+		}
+	}
+	var worldAt func(node int) *lin.M4
+	worldAt = func(node int) *lin.M4 {
+		if g.world[node] == nil {
+			m := g.doc.Nodes[node].local()
+			g.world[node] = m // set before the parents to stop any cycles.
+			if parent := g.parents[node]; parent >= 0 {
+				m.Mult(m, worldAt(parent))
+			}
+		}
+		return g.world[node]
+	}
+	for cnt := range nodes {
+		worldAt(cnt)
+	}
+}
+
+// loadMeshes combines the mesh primitives from all nodes in the
+// default scene into the model vertex data.
+func (g *gltf) loadMeshes(d *ModData) (err error) {
+	prims := []*gltfPrim{}
+	for _, node := range g.sceneNodes() {
+		n := g.doc.Nodes[node]
+		if n.Mesh == nil || *n.Mesh < 0 || *n.Mesh >= len(g.doc.Meshes) {
+			continue
+		}
+		mesh := g.doc.Meshes[*n.Mesh]
+		if d.Name == "" {
+			d.Name = mesh.Name
+		}
+		for _, p := range mesh.Primitives {
+			if p.Mode != nil && *p.Mode != 4 {
+				continue // only triangles are supported.
+			}
+			prim, err := g.readPrim(p)
+			if err != nil {
+				return fmt.Errorf("Invalid glTF mesh %s: %s", mesh.Name, err)
+			}
+			if n.Skin != nil {
+				g.skinned = true
+			} else {
+				prim.transform(g.world[node])
+			}
+			prims = append(prims, prim)
+		}
+	}
+	if len(prims) == 0 {
+		return fmt.Errorf("Invalid glTF file: no triangle meshes")
+	}
+	return g.combine(prims, d)
+}
+
+// sceneNodes returns the nodes in the default scene, parents first.
+// All nodes are returned if there is no scene.
+func (g *gltf) sceneNodes() []int {
+	nodes := []int{}
+	if len(g.doc.Scenes) == 0 {
+		for cnt := range g.doc.Nodes {
+			nodes = append(nodes, cnt)
+		}
+		return nodes
+	}
+	scene := g.doc.Scenes[0]
+	if g.doc.Scene != nil && *g.doc.Scene >= 0 && *g.doc.Scene < len(g.doc.Scenes) {
+		scene = g.doc.Scenes[*g.doc.Scene]
+	}
+	visited := map[int]bool{}
+	var visit func(node int)
+	visit = func(node int) {
+		if node >= 0 && node < len(g.doc.Nodes) && !visited[node] {
+			visited[node] = true
+			nodes = append(nodes, node)
+			for _, kid := range g.doc.Nodes[node].Children {
+				visit(kid)
+			}
+		}
+	}
+	for _, node := range scene.Nodes {
+		visit(node)
+	}
+	return nodes
+}
+
+// gltfPrim is the vertex data for a single primitive.
+type gltfPrim struct {
+	v, n, t, x []float64 // Positions, normals, uvs, tangents.
+	j, w       []float64 // Joints and weights.
+	f          []int     // Triangle indexes.
+	material   int       // Material index or -1.
+}
+
+// readPrim gets the vertex data for one mesh primitive.
+func (g *gltf) readPrim(p gltfPrimitive) (prim *gltfPrim, err error) {
+	prim = &gltfPrim{material: -1}
+	if p.Material != nil {
+		prim.material = *p.Material
+	}
+	attrs := []struct {
+		name  string
+		size  int
+		store *[]float64
+	}{
+		{"POSITION", 3, &prim.v}, {"NORMAL", 3, &prim.n},
+		{"TEXCOORD_0", 2, &prim.t}, {"TANGENT", 4, &prim.x},
+		{"JOINTS_0", 4, &prim.j}, {"WEIGHTS_0", 4, &prim.w},
+	}
+	for _, attr := range attrs {
+		if index, ok := p.Attributes[attr.name]; ok {
+			if *attr.store, err = g.read(index, attr.size); err != nil {
+				return nil, fmt.Errorf("%s %s", attr.name, err)
+			}
+		}
+	}
+	if len(prim.v) == 0 {
+		return nil, fmt.Errorf("missing POSITION")
+	}
+	count := len(prim.v) / 3
+	if p.Indices == nil {
+		for cnt := 0; cnt < count; cnt++ {
+			prim.f = append(prim.f, cnt)
+		}
+	} else {
+		faces, err := g.read(*p.Indices, 1)
+		if err != nil {
+			return nil, fmt.Errorf("indices %s", err)
+		}
+		for _, index := range faces {
+			if int(index) >= count {
+				return nil, fmt.Errorf("index %d past vertex count %d", int(index), count)
+			}
+			prim.f = append(prim.f, int(index))
+		}
+	}
+	return prim, nil
+}
+
+// transform moves the vertex positions, normals, and tangents of
+// a primitive that is not skinned by its node world transform.
+// Normals use the inverse-transpose so that they stay perpendicular
+// to scaled surfaces. Mirrored nodes flip the triangle winding and
+// the tangent handedness so that the faces still point outwards.
+func (prim *gltfPrim) transform(m *lin.M4) {
+	for cnt := 0; cnt+2 < len(prim.v); cnt += 3 {
+		x, y, z := prim.v[cnt], prim.v[cnt+1], prim.v[cnt+2]
+		prim.v[cnt] = x*m.Xx + y*m.Yx + z*m.Zx + m.Wx
+		prim.v[cnt+1] = x*m.Xy + y*m.Yy + z*m.Zy + m.Wy
+		prim.v[cnt+2] = x*m.Xz + y*m.Yz + z*m.Zz + m.Wz
+	}
+	normalize := func(vals []float64, cnt int, dx, dy, dz float64) {
+		if length := math.Sqrt(dx*dx + dy*dy + dz*dz); length > 0 {
+			vals[cnt], vals[cnt+1], vals[cnt+2] = dx/length, dy/length, dz/length
+		}
+	}
+	inv := invAffine(m)
+	for cnt := 0; cnt+2 < len(prim.n); cnt += 3 {
+		x, y, z := prim.n[cnt], prim.n[cnt+1], prim.n[cnt+2]
+		normalize(prim.n, cnt,
+			x*inv.Xx+y*inv.Xy+z*inv.Xz,
+			x*inv.Yx+y*inv.Yy+z*inv.Yz,
+			x*inv.Zx+y*inv.Zy+z*inv.Zz)
+	}
+	mirrored := lin.NewM3().SetM4(m).Det() < 0
+	for cnt := 0; cnt+3 < len(prim.x); cnt += 4 {
+		x, y, z := prim.x[cnt], prim.x[cnt+1], prim.x[cnt+2]
+		normalize(prim.x, cnt,
+			x*m.Xx+y*m.Yx+z*m.Zx,
+			x*m.Xy+y*m.Yy+z*m.Zy,
+			x*m.Xz+y*m.Yz+z*m.Zz)
+		if mirrored {
+			prim.x[cnt+3] = -prim.x[cnt+3]
+		}
+	}
+	if mirrored {
+		for cnt := 0; cnt+2 < len(prim.f); cnt += 3 {
+			prim.f[cnt+1], prim.f[cnt+2] = prim.f[cnt+2], prim.f[cnt+1]
+		}
+	}
+}
+
+// combine merges the primitives into a single mesh.
+// Vertex data missing from some primitives is zero filled.
+func (g *gltf) combine(prims []*gltfPrim, d *ModData) error {
+	has := map[string]bool{}
+	vcount, fcount := 0, 0
+	for _, p := range prims {
+		has["n"] = has["n"] || len(p.n) > 0
+		has["t"] = has["t"] || len(p.t) > 0
+		has["x"] = has["x"] || len(p.x) > 0
+		has["j"] = has["j"] || (len(p.j) > 0 && len(p.w) > 0)
+		vcount += len(p.v) / 3
+	}
+	appendf := func(out []float32, in []float64, size, count int) []float32 {
+		if len(in) < size*count {
+			return append(out, make([]float32, size*count)...)
+		}
+		for _, val := range in[:size*count] {
+			out = append(out, float32(val))
+		}
+		return out
+	}
+	var remap []int
+	if has["j"] {
+		var err error
+		if remap, err = g.loadSkin(d); err != nil {
+			return err
+		}
+	}
+	for _, p := range prims {
+		count, base := len(p.v)/3, len(d.V)/3
+		d.V = appendf(d.V, p.v, 3, count)
+		if has["n"] {
+			d.N = appendf(d.N, p.n, 3, count)
+		}
+		if has["t"] {
+			d.T = appendf(d.T, p.t, 2, count)
+		}
+		if has["x"] {
+			d.X = appendf(d.X, p.x, 4, count)
+		}
+		if has["j"] {
+			if err := p.blends(d, remap, count); err != nil {
+				return err
+			}
+		}
+		for _, index := range p.f[:len(p.f)/3*3] {
+			d.F = append(d.F, uint32(base+index))
+		}
+		tmap := TexMap{Name: g.texture(p.material)}
+		tmap.F0, tmap.Fn = uint32(fcount), uint32(len(p.f)/3)
+		fcount += len(p.f) / 3
+		d.TMap = append(d.TMap, tmap)
+		d.Mtls = append(d.Mtls, g.material(p.material))
+	}
+	return nil
+}
+
+// blends appends the primitive joint indexes and weights as bytes.
+func (prim *gltfPrim) blends(d *ModData, remap []int, count int) error {
+	if len(prim.j) < 4*count || len(prim.w) < 4*count {
+		d.Blends = append(d.Blends, make([]byte, 4*count)...)
+		d.Weights = append(d.Weights, make([]byte, 4*count)...)
+		return nil
+	}
+	for cnt := 0; cnt < 4*count; cnt++ {
+		joint := int(prim.j[cnt])
+		if joint < 0 || joint >= len(remap) {
+			return fmt.Errorf("Invalid glTF joint %d", joint)
+		}
+		d.Blends = append(d.Blends, byte(remap[joint]))
+		d.Weights = append(d.Weights, byte(lin.Clamp(prim.w[cnt], 0, 1)*255+0.5))
+	}
+	return nil
+}
+
+// texture returns the base color texture image name, without
+// a file extension, for the given material. The material name is
+// used if there is no texture.
+func (g *gltf) texture(material int) string {
+	if material < 0 || material >= len(g.doc.Materials) {
+		return ""
+	}
+	mat := g.doc.Materials[material]
+	if tex := mat.Pbr.BaseColorTexture; tex != nil && tex.Index >= 0 && tex.Index < len(g.doc.Textures) {
+		if src := g.doc.Textures[tex.Index].Source; src != nil && *src >= 0 && *src < len(g.doc.Images) {
+			img := g.doc.Images[*src]
+			if img.URI != "" && !strings.HasPrefix(img.URI, "data:") {
+				name := path.Base(img.URI)
+				return strings.TrimSuffix(name, path.Ext(name))
+			}
+			if img.Name != "" {
+				return img.Name
+			}
+		}
+	}
+	return mat.Name
+}
+
+// material approximates the metallic roughness material as colors.
+func (g *gltf) material(material int) MtlData {
+	m := MtlData{KdR: 1, KdG: 1, KdB: 1, Alpha: 1, Ns: 1}
+	if material < 0 || material >= len(g.doc.Materials) {
+		return m
+	}
+	pbr := g.doc.Materials[material].Pbr
+	if c := pbr.BaseColorFactor; len(c) == 4 {
+		m.KdR, m.KdG, m.KdB, m.Alpha = c[0], c[1], c[2], c[3]
+	}
+	metal, rough := float32(1), float32(1) // glTF defaults.
+	if pbr.MetallicFactor != nil {
+		metal = *pbr.MetallicFactor
+	}
+	if pbr.RoughnessFactor != nil {
+		rough = *pbr.RoughnessFactor
+	}
+
+	// Specular color from metal tinted by the base color and
+	// specular exponent from roughness using: 2/roughness^4 - 2.
+	spec := 0.04*(1-metal) + metal
+	m.KsR = spec * (1 + metal*(m.KdR-1))
+	m.KsG = spec * (1 + metal*(m.KdG-1))
+	m.KsB = spec * (1 + metal*(m.KdB-1))
+	m.Ns = float32(lin.Clamp(2/math.Max(math.Pow(float64(rough), 4), 1e-3)-2, 1, 1000))
+	return m
+}
+
+// loadSkin creates the joints from the first skin. Joints are reordered
+// so that parent joints appear before child joints. The returned remap
+// maps glTF joint indexes to the reordered joints.
+func (g *gltf) loadSkin(d *ModData) (remap []int, err error) {
+	if len(g.doc.Skins) == 0 {
+		return nil, fmt.Errorf("Invalid glTF file: joints without a skin")
+	}
+	skin := g.doc.Skins[0]
+	if len(skin.Joints) > 256 {
+		return nil, fmt.Errorf("Not loading glTF skins with more than 256 joints")
+	}
+	for _, node := range skin.Joints {
+		if node < 0 || node >= len(g.doc.Nodes) {
+			return nil, fmt.Errorf("Invalid glTF joint node %d", node)
+		}
+	}
+
+	// order joints by their joint depth.
+	parents := g.jointParents(skin.Joints)
+	depth := make([]int, len(skin.Joints))
+	for cnt := range skin.Joints {
+		for p := parents[cnt]; p >= 0; p = parents[p] {
+			if depth[cnt]++; depth[cnt] > len(skin.Joints) {
+				return nil, fmt.Errorf("Invalid glTF joint hierarchy")
+			}
+		}
+	}
+	order := make([]int, len(skin.Joints))
+	for cnt := range order {
+		order[cnt] = cnt
+	}
+	sort.SliceStable(order, func(i, j int) bool { return depth[order[i]] < depth[order[j]] })
+	g.order = order
+	remap = make([]int, len(skin.Joints))
+	for index, joint := range order {
+		remap[joint] = index
+	}
+	d.Joints = make([]int32, len(order))
+	for index, joint := range order {
+		d.Joints[index] = -1
+		if p := parents[joint]; p >= 0 {
+			d.Joints[index] = int32(remap[p])
+		}
+	}
+	return remap, nil
+}
+
+// jointParents returns the parent joint index, or -1, for each
+// of the given joint nodes.
+func (g *gltf) jointParents(joints []int) []int {
+	index := map[int]int{}
+	for cnt, node := range joints {
+		index[node] = cnt
+	}
+	parents := make([]int, len(joints))
+	for cnt, node := range joints {
+		parents[cnt] = -1
+		for p, depth := g.parents[node], 0; p >= 0 && depth < len(g.parents); p, depth = g.parents[p], depth+1 {
+			if joint, ok := index[p]; ok {
+				parents[cnt] = joint
+				break
+			}
+		}
+	}
+	return parents
+}
+
+// loadAnims samples each glTF animation of the skin joints into frames.
+// Frames combine the joint pose with the inverse bind matrices so that
+// they can be used directly by the animation system. See iqm genFrame.
+func (g *gltf) loadAnims(d *ModData) error {
+	if !g.skinned || len(g.order) == 0 || len(g.doc.Animations) == 0 {
+		return nil
+	}
+	skin := g.doc.Skins[0]
+	parents := g.jointParents(skin.Joints)
+	bind, inv, err := g.bindPose(skin)
+	if err != nil {
+		return err
+	}
+	for cnt, anim := range g.doc.Animations {
+		channels, duration, err := g.readChannels(anim)
+		if err != nil {
+			return fmt.Errorf("Invalid glTF animation %d: %s", cnt, err)
+		}
+		mv := Movement{Name: anim.Name, Rate: GltfRate}
+		if mv.Name == "" {
+			mv.Name = fmt.Sprintf("animation%d", cnt)
+		}
+		mv.F0 = uint32(len(d.Frames) / len(g.order))
+		mv.Fn = uint32(math.Floor(duration*GltfRate+0.5)) + 1
+		for frame := 0; frame < int(mv.Fn); frame++ {
+			at := float64(frame) / GltfRate
+			for _, joint := range g.order {
+				node := skin.Joints[joint]
+				t, q, s := g.doc.Nodes[node].trs()
+				for _, c := range channels[node] {
+					c.sample(at, t, q, s)
+				}
+				pose := trsMatrix(t, q, s)
+				if p := parents[joint]; p >= 0 {
+					// parentBasePose * childPose * childInverseBasePose
+					pose.Mult(inv[joint], pose).Mult(pose, bind[p])
+				} else {
+					// childPose * childInverseBasePose where the
+					// pose includes any parent nodes that are not joints.
+					if parent := g.parents[node]; parent >= 0 {
+						pose.Mult(pose, g.world[parent])
+					}
+					pose.Mult(inv[joint], pose)
+				}
+				d.Frames = append(d.Frames, pose)
+			}
+		}
+		d.Movements = append(d.Movements, mv)
+	}
+	return nil
+}
+
+// bindPose returns the bind pose and inverse bind pose for each joint.
+// Joints without inverse bind matrices use the rest pose.
+func (g *gltf) bindPose(skin gltfSkin) (bind, inv []*lin.M4, err error) {
+	var ibm []float64
+	if skin.InverseBindMatrices != nil {
+		if ibm, err = g.read(*skin.InverseBindMatrices, 16); err != nil {
+			return nil, nil, fmt.Errorf("Invalid glTF inverse bind matrices %s", err)
+		}
+	}
+	bind = make([]*lin.M4, len(skin.Joints))
+	inv = make([]*lin.M4, len(skin.Joints))
+	for cnt, node := range skin.Joints {
+		if len(ibm) >= (cnt+1)*16 {
+			inv[cnt] = gltfMatrix(ibm[cnt*16 : cnt*16+16])
+			bind[cnt] = invAffine(inv[cnt])
+		} else {
+			bind[cnt] = lin.NewM4().Set(g.world[node])
+			inv[cnt] = invAffine(bind[cnt])
+		}
+	}
+	return bind, inv, nil
+}
+
+// gltfSampler is an animation channel ready for sampling.
+type gltfSampler struct {
+	path   string    // translation, rotation, or scale.
+	lerp   string    // LINEAR, STEP, or CUBICSPLINE.
+	times  []float64 // Key frame times in seconds.
+	values []float64 // Key frame values.
+}
+
+// readChannels gets the animation samplers for each animated node.
+// Returns the samplers and the animation duration in seconds.
+func (g *gltf) readChannels(anim gltfAnimation) (channels map[int][]*gltfSampler, duration float64, err error) {
+	channels = map[int][]*gltfSampler{}
+	for _, c := range anim.Channels {
+		size := map[string]int{"translation": 3, "rotation": 4, "scale": 3}[c.Target.Path]
+		if c.Target.Node == nil || size == 0 {
+			continue // morph target weights are not supported.
+		}
+		if c.Sampler < 0 || c.Sampler >= len(anim.Samplers) {
+			return nil, 0, fmt.Errorf("invalid sampler %d", c.Sampler)
+		}
+		as := anim.Samplers[c.Sampler]
+		smp := &gltfSampler{path: c.Target.Path, lerp: as.Interpolation}
+		if smp.times, err = g.read(as.Input, 1); err != nil {
+			return nil, 0, err
+		}
+		if smp.values, err = g.read(as.Output, size); err != nil {
+			return nil, 0, err
+		}
+		keys := len(smp.times)
+		if smp.lerp == "CUBICSPLINE" {
+			keys *= 3 // in-tangent, value, out-tangent.
+		}
+		if keys == 0 || len(smp.values) < keys*size {
+			return nil, 0, fmt.Errorf("%s needs %d values", smp.path, keys*size)
+		}
+		if last := smp.times[len(smp.times)-1]; last > duration {
+			duration = last
+		}
+		channels[*c.Target.Node] = append(channels[*c.Target.Node], smp)
+	}
+	return channels, duration, nil
+}
+
+// sample updates the translation, rotation, or scale
+// with the channel value at the given time.
+func (smp *gltfSampler) sample(at float64, t *lin.V3, q *lin.Q, s *lin.V3) {
+	size, stride, offset := 3, 3, 0
+	if smp.path == "rotation" {
+		size, stride = 4, 4
+	}
+	if smp.lerp == "CUBICSPLINE" {
+		stride, offset = size*3, size // skip the tangents.
+	}
+	k0, k1, ratio := 0, 0, 0.0
+	times := smp.times
+	switch last := len(times) - 1; {
+	case at <= times[0]:
+	case at >= times[last]:
+		k0, k1 = last, last
+	default:
+		k1 = sort.SearchFloat64s(times, at)
+		if times[k1] == at {
+			k0 = k1
+		} else {
+			k0 = k1 - 1
+			ratio = (at - times[k0]) / (times[k1] - times[k0])
+		}
+		if smp.lerp == "STEP" {
+			k1, ratio = k0, 0
+		}
+	}
+	v0 := smp.values[k0*stride+offset : k0*stride+offset+size]
+	v1 := smp.values[k1*stride+offset : k1*stride+offset+size]
+	switch smp.path {
+	case "translation":
+		t.SetS(v0[0]+(v1[0]-v0[0])*ratio, v0[1]+(v1[1]-v0[1])*ratio, v0[2]+(v1[2]-v0[2])*ratio)
+	case "scale":
+		s.SetS(v0[0]+(v1[0]-v0[0])*ratio, v0[1]+(v1[1]-v0[1])*ratio, v0[2]+(v1[2]-v0[2])*ratio)
+	case "rotation":
+		q0 := &lin.Q{X: v0[0], Y: v0[1], Z: v0[2], W: v0[3]}
+		q1 := &lin.Q{X: v1[0], Y: v1[1], Z: v1[2], W: v1[3]}
+		if q0.Dot(q1) < 0 {
+			q1.Neg() // interpolate the shortest way around.
+		}
+		q.Nlerp(q0, q1, ratio)
+	}
+}
+
+// read returns the accessor values as floats. Normalized integer values
+// are scaled to floats. Size is the number of values in each element.
+func (g *gltf) read(accessor, size int) ([]float64, error) {
+	if accessor < 0 || accessor >= len(g.doc.Accessors) {
+		return nil, fmt.Errorf("invalid accessor %d", accessor)
+	}
+	a := g.doc.Accessors[accessor]
+	if want := gltfSizes[a.Type]; want != size {
+		return nil, fmt.Errorf("accessor %d type %s expected %d values", accessor, a.Type, size)
+	}
+	if a.Sparse != nil {
+		return nil, fmt.Errorf("accessor %d sparse data is not supported", accessor)
+	}
+	values := make([]float64, a.Count*size)
+	if a.BufferView == nil {
+		return values, nil // all zeros.
+	}
+	if *a.BufferView < 0 || *a.BufferView >= len(g.doc.BufferViews) {
+		return nil, fmt.Errorf("invalid buffer view %d", *a.BufferView)
+	}
+	bv := g.doc.BufferViews[*a.BufferView]
+	if bv.Buffer < 0 || bv.Buffer >= len(g.buffers) {
+		return nil, fmt.Errorf("invalid buffer %d", bv.Buffer)
+	}
+	width := gltfBytes[a.ComponentType]
+	if width == 0 {
+		return nil, fmt.Errorf("accessor %d invalid component type %d", accessor, a.ComponentType)
+	}
+	stride := bv.ByteStride
+	if stride == 0 {
+		stride = width * size
+	}
+	start := bv.ByteOffset + a.ByteOffset
+	if a.Count > 0 {
+		end := start + stride*(a.Count-1) + width*size
+		if end > bv.ByteOffset+bv.ByteLength || end > len(g.buffers[bv.Buffer]) {
+			return nil, fmt.Errorf("accessor %d past end of buffer", accessor)
+		}
+	}
+	data := g.buffers[bv.Buffer]
+	le := binary.LittleEndian
+	for cnt := 0; cnt < a.Count; cnt++ {
+		for c := 0; c < size; c++ {
+			at := start + cnt*stride + c*width
+			var val float64
+			switch a.ComponentType {
+			case 5120: // byte
+				val = float64(int8(data[at]))
+				if a.Normalized {
+					val = math.Max(val/127, -1)
+				}
+			case 5121: // unsigned byte
+				val = float64(data[at])
+				if a.Normalized {
+					val /= 255
+				}
+			case 5122: // short
+				val = float64(int16(le.Uint16(data[at:])))
+				if a.Normalized {
+					val = math.Max(val/32767, -1)
+				}
+			case 5123: // unsigned short
+				val = float64(le.Uint16(data[at:]))
+				if a.Normalized {
+					val /= 65535
+				}
+			case 5125: // unsigned int
+				val = float64(le.Uint32(data[at:]))
+			case 5126: // float
+				val = float64(math.Float32frombits(le.Uint32(data[at:])))
+			}
+			values[cnt*size+c] = val
+		}
+	}
+	return values, nil
+}
+
+// gltfSizes is the number of values for each accessor type.
+var gltfSizes = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT4": 16}
+
+// gltfBytes is the number of bytes for each accessor component type.
+var gltfBytes = map[int]int{5120: 1, 5121: 1, 5122: 2, 5123: 2, 5125: 4, 5126: 4}
+
+// trsMatrix combines translation, rotation, and scale into a transform
+// matrix using the same row vector conventions as the IQM loader.
+func trsMatrix(t *lin.V3, q *lin.Q, s *lin.V3) *lin.M4 {
+	q.Unit() // ensure unit quaternion.
+	m4 := lin.NewM4().SetQ(q)
+	m4.Transpose(m4).ScaleSM(s.X, s.Y, s.Z)       // apply scale before rotation.
+	m4.Wx, m4.Wy, m4.Wz, m4.Ww = t.X, t.Y, t.Z, 1 // translation added in, not multiplied.
+	return m4
+}
+
+// gltfMatrix converts a glTF column major matrix to a transform matrix.
+// Column major column vector matricies have the same layout as
+// row major row vector matricies.
+func gltfMatrix(m []float64) *lin.M4 {
+	return &lin.M4{
+		Xx: m[0], Xy: m[1], Xz: m[2], Xw: m[3],
+		Yx: m[4], Yy: m[5], Yz: m[6], Yw: m[7],
+		Zx: m[8], Zy: m[9], Zz: m[10], Zw: m[11],
+		Wx: m[12], Wy: m[13], Wz: m[14], Ww: m[15],
+	}
+}
+
+// invAffine returns the inverse of a transform matrix
+// that has no projection. See iqm createBaseFrames.
+func invAffine(m *lin.M4) *lin.M4 {
+	i3 := lin.NewM3()
+	i3.Inv(i3.SetM4(m))
+	t := &lin.V3{X: m.Wx, Y: m.Wy, Z: m.Wz}
+	i4 := lin.NewM4()
+	i4.Xx, i4.Xy, i4.Xz, i4.Xw = i3.Xx, i3.Xy, i3.Xz, 0
+	i4.Yx, i4.Yy, i4.Yz, i4.Yw = i3.Yx, i3.Yy, i3.Yz, 0
+	i4.Zx, i4.Zy, i4.Zz, i4.Zw = i3.Zx, i3.Zy, i3.Zz, 0
+	i4.Wx = -(i3.Xx*t.X + i3.Yx*t.Y + i3.Zx*t.Z)
+	i4.Wy = -(i3.Xy*t.X + i3.Yy*t.Y + i3.Zy*t.Z)
+	i4.Wz = -(i3.Xz*t.X + i3.Yz*t.Y + i3.Zz*t.Z)
+	i4.Ww = 1
+	return i4
+}
+
+// =============================================================================
+// The JSON structures for the subset of glTF that is loaded.
+
+type gltfDoc struct {
+	Asset       struct{ Version string } `json:"asset"`
+	Scene       *int                     `json:"scene"`
+	Scenes      []struct{ Nodes []int }  `json:"scenes"`
+	Nodes       []gltfNode               `json:"nodes"`
+	Meshes      []gltfMesh               `json:"meshes"`
+	Skins       []gltfSkin               `json:"skins"`
+	Animations  []gltfAnimation          `json:"animations"`
+	Materials   []gltfMaterial           `json:"materials"`
+	Textures    []struct{ Source *int }  `json:"textures"`
+	Images      []gltfImage              `json:"images"`
+	Accessors   []gltfAccessor           `json:"accessors"`
+	BufferViews []gltfBufferView         `json:"bufferViews"`
+	Buffers     []gltfBuffer             `json:"buffers"`
+}
+
+// gltfNode is a scene graph node. The transform is either
+// a matrix or a translation, rotation, and scale.
+type gltfNode struct {
+	Name        string    `json:"name"`
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Skin        *int      `json:"skin"`
+	Matrix      []float64 `json:"matrix"`      // 16 column major values.
+	Translation []float64 `json:"translation"` // x, y, z
+	Rotation    []float64 `json:"rotation"`    // x, y, z, w
+	Scale       []float64 `json:"scale"`       // x, y, z
+}
+
+// trs returns the node translation, rotation, and scale.
+func (n gltfNode) trs() (t *lin.V3, q *lin.Q, s *lin.V3) {
+	t, q, s = &lin.V3{}, lin.NewQ().SetS(0, 0, 0, 1), &lin.V3{X: 1, Y: 1, Z: 1}
+	if len(n.Translation) == 3 {
+		t.SetS(n.Translation[0], n.Translation[1], n.Translation[2])
+	}
+	if len(n.Rotation) == 4 {
+		q.SetS(n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3])
+	}
+	if len(n.Scale) == 3 {
+		s.SetS(n.Scale[0], n.Scale[1], n.Scale[2])
+	}
+	return t, q, s
+}
+
+// local returns the node transform relative to its parent.
+func (n gltfNode) local() *lin.M4 {
+	if len(n.Matrix) == 16 {
+		return gltfMatrix(n.Matrix)
+	}
+	return trsMatrix(n.trs())
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"` // 4 is triangles.
+}
+
+type gltfSkin struct {
+	Joints              []int `json:"joints"`
+	InverseBindMatrices *int  `json:"inverseBindMatrices"`
+}
+
+type gltfAnimation struct {
+	Name     string `json:"name"`
+	Channels []struct {
+		Sampler int `json:"sampler"`
+		Target  struct {
+			Node *int   `json:"node"`
+			Path string `json:"path"`
+		} `json:"target"`
+	} `json:"channels"`
+	Samplers []struct {
+		Input         int    `json:"input"`
+		Output        int    `json:"output"`
+		Interpolation string `json:"interpolation"`
+	} `json:"samplers"`
+}
+
+type gltfMaterial struct {
+	Name string `json:"name"`
+	Pbr  struct {
+		BaseColorFactor  []float32 `json:"baseColorFactor"`
+		BaseColorTexture *struct {
+			Index int `json:"index"`
+		} `json:"baseColorTexture"`
+		MetallicFactor  *float32 `json:"metallicFactor"`
+		RoughnessFactor *float32 `json:"roughnessFactor"`
+	} `json:"pbrMetallicRoughness"`
+}
+
+type gltfImage struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
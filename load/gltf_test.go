@@ -0,0 +1,180 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+func TestGltf(t *testing.T) {
+	js, bin := testGltf()
+	uri := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)
+	m := &ModData{}
+	if err := Gltf(strings.NewReader(strings.Replace(js, `"URI"`, `"`+uri+`"`, 1)), m); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	checkGltf(t, m)
+}
+
+func TestGlb(t *testing.T) {
+	js, bin := testGltf()
+	js = strings.Replace(js, `"uri": "URI", `, "", 1)
+	for len(js)%4 != 0 {
+		js += " "
+	}
+	glb := &bytes.Buffer{}
+	size := 12 + 8 + len(js) + 8 + len(bin)
+	binary.Write(glb, binary.LittleEndian, []uint32{glbMagic, 2, uint32(size), uint32(len(js)), glbJSON})
+	glb.WriteString(js)
+	binary.Write(glb, binary.LittleEndian, []uint32{uint32(len(bin)), glbBIN})
+	glb.Write(bin)
+	m := &ModData{}
+	if err := Gltf(glb, m); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	checkGltf(t, m)
+}
+
+// Check that the Load methods find glTF files and their buffer files.
+func TestLoadGltf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gltf")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	js, bin := testGltf()
+	ioutil.WriteFile(filepath.Join(dir, "skin.gltf"), []byte(strings.Replace(js, `"URI"`, `"skin%20data.bin"`, 1)), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "skin data.bin"), bin, 0644)
+	loc := NewLocator().Dir("GLTF", dir).Dir("BIN", dir)
+	m := &ModData{}
+	if err := m.Load("skin", loc); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	checkGltf(t, m)
+	msh := &MshData{}
+	if err := msh.Load("skin", loc); err != nil || len(msh.F) != 3 {
+		t.Errorf("Expected mesh data %s", err)
+	}
+	mtl := &MtlData{}
+	if err := mtl.Load("skin", loc); err != nil || mtl.KdR != 1 || mtl.KdG != 0.5 {
+		t.Errorf("Expected material data %s", err)
+	}
+	if err := m.Load("missing", loc); err == nil {
+		t.Errorf("Expected error for missing model")
+	}
+}
+
+// Check that node transforms keep normals perpendicular to scaled
+// surfaces and that mirrored nodes keep their faces pointing outwards.
+func TestGltfTransform(t *testing.T) {
+	n := 1 / math.Sqrt(2)
+	prim := &gltfPrim{n: []float64{n, n, 0}, x: []float64{1, 0, 0, 1}, f: []int{0, 1, 2}}
+	prim.transform(&lin.M4{Xx: 2, Yy: 1, Zz: 1, Ww: 1})
+	want := 1 / math.Sqrt(5)
+	if !lin.Aeq(prim.n[0], want) || !lin.Aeq(prim.n[1], 2*want) || prim.n[2] != 0 {
+		t.Errorf("Expected inverse-transpose normal got %v", prim.n)
+	}
+	prim = &gltfPrim{n: []float64{0, 0, 1}, x: []float64{1, 0, 0, 1}, f: []int{0, 1, 2}}
+	prim.transform(&lin.M4{Xx: -1, Yy: 1, Zz: 1, Ww: 1})
+	if prim.f[0] != 0 || prim.f[1] != 2 || prim.f[2] != 1 {
+		t.Errorf("Expected mirrored winding got %v", prim.f)
+	}
+	if prim.x[0] != -1 || prim.x[3] != -1 {
+		t.Errorf("Expected mirrored tangent got %v", prim.x)
+	}
+}
+
+// checkGltf verifies the model loaded from testGltf.
+func checkGltf(t *testing.T, m *ModData) {
+	if len(m.V) != 9 || len(m.N) != 9 || len(m.T) != 6 || len(m.F) != 3 {
+		t.Fatalf("Expected one triangle got %d %d %d %d", len(m.V), len(m.N), len(m.T), len(m.F))
+	}
+	if len(m.TMap) != 1 || m.TMap[0].Name != "skin" || m.TMap[0].Fn != 1 || len(m.Mtls) != 1 {
+		t.Errorf("Expected skin texture got %v", m.TMap)
+	}
+
+	// joints are reordered so that the parent joint is first.
+	if len(m.Joints) != 2 || m.Joints[0] != -1 || m.Joints[1] != 0 {
+		t.Errorf("Expected parent joint first got %v", m.Joints)
+	}
+	if len(m.Blends) != 12 || m.Blends[0] != 1 || m.Blends[4] != 0 || m.Weights[0] != 255 {
+		t.Errorf("Expected remapped joints got %v %v", m.Blends, m.Weights)
+	}
+	if len(m.Movements) != 1 || m.Movements[0].Name != "stretch" || m.Movements[0].Fn != GltfRate+1 {
+		t.Fatalf("Expected one second movement got %v", m.Movements)
+	}
+	if len(m.Frames) != 2*(GltfRate+1) {
+		t.Fatalf("Expected frames for both joints got %d", len(m.Frames))
+	}
+	if first := m.Frames[1]; !first.Aeq(lin.M4I) {
+		t.Errorf("Expected bind pose on first frame got %v", first)
+	}
+	if last := m.Frames[2*GltfRate+1]; !lin.Aeq(last.Wy, 1) {
+		t.Errorf("Expected child joint stretch got %f", last.Wy)
+	}
+}
+
+// testGltf returns a skinned triangle with two joints and a one
+// second animation that moves the child joint. The skin lists the
+// child joint first. The buffer uri is the string "URI".
+func testGltf() (js string, bin []byte) {
+	buf := &bytes.Buffer{}
+	views, accessors := []string{}, []string{}
+	add := func(data interface{}, component, count int, kind string) {
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		start := buf.Len()
+		binary.Write(buf, binary.LittleEndian, data)
+		views = append(views, fmt.Sprintf(`{"buffer": 0, "byteOffset": %d, "byteLength": %d}`, start, buf.Len()-start))
+		accessors = append(accessors, fmt.Sprintf(`{"bufferView": %d, "componentType": %d, "count": %d, "type": "%s"}`,
+			len(views)-1, component, count, kind))
+	}
+	ibm := []float32{ // inverse bind matrices with the child joint first.
+		1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, -1, 0, 1,
+		1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	add([]float32{0, 0, 0, 1, 0, 0, 0, 1, 0}, 5126, 3, "VEC3")          // 0 positions
+	add([]float32{0, 0, 1, 0, 0, 1, 0, 0, 1}, 5126, 3, "VEC3")          // 1 normals
+	add([]float32{0, 0, 1, 0, 0, 1}, 5126, 3, "VEC2")                   // 2 uvs
+	add([]uint8{0, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0}, 5121, 3, "VEC4")   // 3 joints
+	add([]float32{1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0}, 5126, 3, "VEC4") // 4 weights
+	add([]uint16{0, 1, 2}, 5123, 3, "SCALAR")                           // 5 indices
+	add(ibm, 5126, 2, "MAT4")                                           // 6 inverse bind
+	add([]float32{0, 1}, 5126, 2, "SCALAR")                             // 7 times
+	add([]float32{0, 1, 0, 0, 2, 0}, 5126, 2, "VEC3")                   // 8 translations
+	js = `{
+  "asset": {"version": "2.0"},
+  "scene": 0,
+  "scenes": [{"nodes": [0, 1]}],
+  "nodes": [
+    {"name": "body", "mesh": 0, "skin": 0},
+    {"name": "hip", "children": [2]},
+    {"name": "spine", "translation": [0, 1, 0]}
+  ],
+  "meshes": [{"name": "skin", "primitives": [{"attributes": {"POSITION": 0, "NORMAL": 1,
+    "TEXCOORD_0": 2, "JOINTS_0": 3, "WEIGHTS_0": 4}, "indices": 5, "material": 0}]}],
+  "materials": [{"name": "coat", "pbrMetallicRoughness": {"baseColorFactor": [1, 0.5, 0, 1],
+    "baseColorTexture": {"index": 0}}}],
+  "textures": [{"source": 0}],
+  "images": [{"uri": "images/skin.png"}],
+  "skins": [{"joints": [2, 1], "inverseBindMatrices": 6}],
+  "animations": [{"name": "stretch", "channels": [{"sampler": 0, "target": {"node": 2, "path": "translation"}}],
+    "samplers": [{"input": 7, "output": 8}]}],
+  "accessors": [` + strings.Join(accessors, ",\n    ") + `],
+  "bufferViews": [` + strings.Join(views, ",\n    ") + `],
+  "buffers": [{"uri": "URI", "byteLength": ` + fmt.Sprint(buf.Len()) + `}]
+}`
+	return js, buf.Bytes()
+}
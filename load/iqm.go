@@ -146,14 +146,10 @@ func loadIqmMeshes(hdr *iqmheader, data []byte, mod *ModData, scr *scratch) (err
 
 	// Get the triangle face data.
 	buff.Seek(int64(hdr.OfsTriangles-iqmheaderSize), 0)
-	faces := make([]uint32, 3*hdr.NumTriangles)
-	if err = binary.Read(buff, binary.LittleEndian, faces); err != nil {
+	mod.F = make([]uint32, 3*hdr.NumTriangles)
+	if err = binary.Read(buff, binary.LittleEndian, mod.F); err != nil {
 		return fmt.Errorf("Invalid .iqm triangles %s", err)
 	}
-	mod.F = make([]uint16, 3*hdr.NumTriangles)
-	for cnt := 0; cnt < len(faces); cnt++ {
-		mod.F[cnt] = uint16(faces[cnt])
-	}
 
 	// Multiple meshes mean means that multiple textures are used for this model.
 	msh := &iqmmesh{}
@@ -0,0 +1,19 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"image/jpeg"
+	"io"
+)
+
+// Jpg populates image data using the given reader.
+// The Reader r is expected to be opened and closed by the caller.
+// A successful import replaces the image in ImgData with a new image.
+// JPEG images are returned as YCbCr and converted to RGBA when bound.
+func Jpg(r io.Reader, d *ImgData) (err error) {
+	d.Img, err = jpeg.Decode(r)
+	d.Mips = nil
+	return err
+}
@@ -0,0 +1,33 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestJpg(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for cnt := range src.Pix {
+		src.Pix[cnt] = 200
+	}
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, src, nil); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	img := &ImgData{}
+	if err := Jpg(buf, img); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if size := img.Img.Bounds().Size(); size.X != 16 || size.Y != 8 {
+		t.Errorf("Expected 16x8 image got %v", size)
+	}
+	if r, _, _, _ := color.NRGBAModel.Convert(img.Img.At(3, 3)).RGBA(); r>>8 < 195 || r>>8 > 205 {
+		t.Errorf("Expected gray pixel got %d", r>>8)
+	}
+}
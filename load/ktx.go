@@ -0,0 +1,134 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+// KTX: Khronos texture container, version 1.
+//    https://www.khronos.org/opengles/sdk/tools/KTX/file_format_spec/
+// A KTX file holds texture data ready for the GPU along with
+// precomputed mipmap levels. Only uncompressed 8 bit RGB and RGBA
+// 2D textures are supported. Each mipmap level is:
+//    imageSize : uint32 number of bytes in the level.
+//    pixels    : rows padded to 4 bytes, then the level is padded to 4 bytes.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+)
+
+// Ktx populates image data using the given reader. The first mipmap
+// level is the image and any remaining levels are returned as Mips.
+// The Reader r is expected to be opened and closed by the caller.
+// A successful import replaces the images in ImgData with new NRGBA images.
+func Ktx(r io.Reader, d *ImgData) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("Invalid .ktx file: %s", err)
+	}
+	if len(data) < len(ktxMagic)+13*4 || !bytes.Equal(ktxMagic, data[:len(ktxMagic)]) {
+		return fmt.Errorf("Invalid .ktx file header")
+	}
+	hdr := &ktxHeader{}
+	var order binary.ByteOrder = binary.LittleEndian
+	if binary.BigEndian.Uint32(data[len(ktxMagic):]) == ktxEndian {
+		order = binary.BigEndian
+	}
+	binary.Read(bytes.NewReader(data[len(ktxMagic):]), order, hdr)
+	switch {
+	case hdr.Endianness != ktxEndian:
+		return fmt.Errorf("Invalid .ktx endianness %X", hdr.Endianness)
+	case hdr.Type != ktxUnsignedByte || (hdr.Format != ktxRGBA && hdr.Format != ktxRGB):
+		return fmt.Errorf("Unsupported .ktx format %X type %X", hdr.Format, hdr.Type)
+	case hdr.Depth > 0 || hdr.ArrayElements > 0 || hdr.Faces != 1:
+		return fmt.Errorf("Only 2D .ktx textures are supported")
+	case hdr.Width == 0 || hdr.Height == 0 || hdr.Width > ktxMaxSize || hdr.Height > ktxMaxSize:
+		return fmt.Errorf("Invalid .ktx size %dx%d", hdr.Width, hdr.Height)
+	}
+	levels := int(hdr.MipmapLevels)
+	if levels == 0 {
+		levels = 1 // zero means mipmaps are generated when bound.
+	}
+
+	// read each mipmap level. Each level halves the size of the previous.
+	bpp := 4
+	if hdr.Format == ktxRGB {
+		bpp = 3
+	}
+	at := len(ktxMagic) + 13*4 + int(hdr.KeyValueBytes)
+	width, height := int(hdr.Width), int(hdr.Height)
+	images := []image.Image{}
+	for level := 0; level < levels; level++ {
+		if at < 0 || at+4 > len(data) {
+			return fmt.Errorf("Corrupt .ktx file: missing level %d", level)
+		}
+		size := int(order.Uint32(data[at:]))
+		at += 4
+		stride := (width*bpp + 3) &^ 3
+		if size < stride*height || at+size > len(data) {
+			return fmt.Errorf("Corrupt .ktx file: short level %d", level)
+		}
+		img := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			src, dst := data[at+y*stride:], img.Pix[y*img.Stride:]
+			for x := 0; x < width; x++ {
+				p, q := src[x*bpp:], dst[x*4:]
+				q[0], q[1], q[2], q[3] = p[0], p[1], p[2], 255
+				if bpp == 4 {
+					q[3] = p[3]
+				}
+			}
+		}
+		images = append(images, img)
+		at += (size + 3) &^ 3
+		width, height = ktxHalf(width), ktxHalf(height)
+	}
+	d.Img, d.Mips = images[0], images[1:]
+	if len(d.Mips) == 0 {
+		d.Mips = nil
+	}
+	return nil
+}
+
+// public inteface
+// =============================================================================
+// internal implementation for reading KTX files.
+
+// ktxMagic identifies the file type.
+var ktxMagic = []byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// OpenGL values used in KTX headers.
+const (
+	ktxEndian       = 0x04030201 // Reads correctly in the file byte order.
+	ktxUnsignedByte = 0x1401     // GL_UNSIGNED_BYTE
+	ktxRGB          = 0x1907     // GL_RGB
+	ktxRGBA         = 0x1908     // GL_RGBA
+	ktxMaxSize      = 1 << 14    // Guard against corrupt image sizes.
+)
+
+// ktxHeader follows the magic identifier.
+type ktxHeader struct {
+	Endianness     uint32 // Expecting ktxEndian.
+	Type           uint32 // Pixel component type.
+	TypeSize       uint32 // Bytes in the pixel component type.
+	Format         uint32 // Pixel format, ie: RGBA.
+	InternalFormat uint32 // GPU format.
+	BaseFormat     uint32 // GPU base format.
+	Width, Height  uint32 // Pixel size of the first level.
+	Depth          uint32 // Expecting 0 for 2D textures.
+	ArrayElements  uint32 // Expecting 0 for non-array textures.
+	Faces          uint32 // Expecting 1 for non-cubemap textures.
+	MipmapLevels   uint32 // Number of levels. 0 to generate mipmaps.
+	KeyValueBytes  uint32 // Metadata size.
+}
+
+// ktxHalf returns the size of the next mipmap level.
+func ktxHalf(size int) int {
+	if size > 1 {
+		return size / 2
+	}
+	return 1
+}
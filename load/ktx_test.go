@@ -0,0 +1,74 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKtx(t *testing.T) {
+	img := &ImgData{}
+	if err := Ktx(bytes.NewReader(testKtx()), img); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if size := img.Img.Bounds().Size(); size.X != 4 || size.Y != 2 || len(img.Mips) != 2 {
+		t.Fatalf("Expected 4x2 image and 2 mipmaps got %v %d", size, len(img.Mips))
+	}
+	if size := img.Mips[1].Bounds().Size(); size.X != 1 || size.Y != 1 {
+		t.Errorf("Expected 1x1 last level got %v", size)
+	}
+	if got := img.Mips[0].(*image.NRGBA).Pix[:4]; !bytes.Equal(got, []byte{1, 1, 1, 255}) {
+		t.Errorf("Expected level 1 pixel got %v", got)
+	}
+	if err := Ktx(bytes.NewReader(testKtx()[:80]), img); err == nil {
+		t.Errorf("Expected error for truncated file")
+	}
+}
+
+// Check that image Load prefers .ktx files and finds the other formats.
+func TestLoadImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "img")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "ground.ktx"), testKtx(), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "ground.bmp"), testBmp(1, 1, 24, nil, []byte{0, 0, 0, 0}), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "sky.bmp"), testBmp(1, 1, 24, nil, []byte{0, 0, 0, 0}), 0644)
+	loc := NewLocator().Dir("KTX", dir).Dir("BMP", dir)
+	img := &ImgData{}
+	if err := img.Load("ground", loc); err != nil || len(img.Mips) != 2 {
+		t.Errorf("Expected ktx mipmaps %s", err)
+	}
+	if err := img.Load("sky", loc); err != nil || img.Mips != nil || img.Img.Bounds().Dx() != 1 {
+		t.Errorf("Expected bmp image %s", err)
+	}
+	if err := img.Load("missing", loc); err == nil {
+		t.Errorf("Expected error for missing image")
+	}
+}
+
+// testKtx returns a 4x2 RGB texture with all mipmap levels.
+// Each pixel value is the mipmap level.
+func testKtx() []byte {
+	buf := &bytes.Buffer{}
+	buf.Write(ktxMagic)
+	binary.Write(buf, binary.LittleEndian, []uint32{ktxEndian, ktxUnsignedByte, 1,
+		ktxRGB, 0x8051, ktxRGB, 4, 2, 0, 0, 1, 3, 4})
+	buf.Write([]byte{0, 0, 0, 0}) // key value data.
+	width, height := 4, 2
+	for level := 0; level < 3; level++ {
+		stride := (width*3 + 3) &^ 3
+		binary.Write(buf, binary.LittleEndian, uint32(stride*height))
+		buf.Write(bytes.Repeat([]byte{byte(level)}, stride*height))
+		width, height = ktxHalf(width), ktxHalf(height)
+	}
+	return buf.Bytes()
+}
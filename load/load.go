@@ -4,19 +4,22 @@
 // Package load fetches disk based 3D assets. Assets are loaded into
 // one of the following intermediate data structures:
 //    FntData.Load uses Fnt to load bitmapped characters.
-//    ImgData.Load uses Png to load model textures.
-//    ModData.Load uses Iqm to load animated models.
-//    MshData.Load uses Obj to load static models.
-//    MtlData.Load uses Mtl to load model lighting data.
+//    ImgData.Load uses Ktx, Png, Jpg, Tga or Bmp to load model textures.
+//    CubeData.Load uses ImgData and Cross to load cube map textures.
+//    ModData.Load uses Vmsh, Iqm or Gltf, and Evt to load animated models.
+//    MshData.Load uses Vmsh, Obj or Gltf to load static models.
+//    MtlData.Load uses Mtl or Gltf to load model lighting data.
 //    ShdData.Load uses Src to load GPU shader programs.
 //    SndData.Load uses Wav to load 3D audio.
-// Each intermediate data format is currently associated with one file
-// format. Asset loading is currently intended for smaller 3D applications
+// Each intermediate data format is associated with one or two file
+// formats. Asset loading is currently intended for smaller 3D applications
 // where data is loaded directly from disk to memory, i.e. no database.
 //
 // A default file Locator is provided. It can be replaced with
 // a different Locator that follows a different string based naming
-// convention for finding disk based assets. Overall package load
+// convention for finding disk based assets. NewFSLocator finds assets
+// in any fs.FS and NewOverlay layers Locators so that mod or patch
+// directories can override individual assets. Overall package load
 // attempts to shield users from knowledge about:
 //    File Formats  : how asset contents are stored on disk.
 //    File Types    : how file types map to asset data structs.
@@ -92,22 +95,85 @@ func (d *FntData) Load(name string, l Locator) (err error) {
 // This is an intermediate data format that needs further processing by
 // something like vu.Ent.MakeModel to bind the data to a GPU based texture.
 type ImgData struct {
-	Img image.Image
+	Img  image.Image   // Full size image.
+	Mips []image.Image // Optional precomputed mipmaps, largest first.
 }
 
 // Load image data. Existing ImgData is discarded and
 // replaced with information found by the Locator.
+// A .ktx file is preferred when it exists since it can include
+// precomputed mipmaps, then .png, .jpg, .jpeg, .tga, and .bmp files.
 func (d *ImgData) Load(name string, l Locator) (err error) {
-	fname := name + ".png" // FUTURE: other image file formats.
-	var reader io.ReadCloser
-	if reader, err = l.GetResource(fname); err != nil {
-		return fmt.Errorf("Could not load image from %s: %s\n", fname, err)
+	for _, format := range imgFormats {
+		var reader io.ReadCloser
+		if reader, err = l.GetResource(name + format.ext); err != nil {
+			continue
+		}
+		defer reader.Close()
+		if err = format.decode(reader, d); err != nil {
+			return fmt.Errorf("Could not load image from %s: %s\n", name+format.ext, err)
+		}
+		return nil
 	}
-	defer reader.Close()
-	return Png(reader, d)
+	return fmt.Errorf("Could not load image %s: %s\n", name, err)
 }
 
-// FntData
+// imgFormats are the image file types in the order they are tried.
+var imgFormats = []struct {
+	ext    string                          // File extension.
+	decode func(io.Reader, *ImgData) error // File decoder.
+}{
+	{".ktx", Ktx}, {".png", Png}, {".jpg", Jpg}, {".jpeg", Jpg}, {".tga", Tga}, {".bmp", Bmp},
+}
+
+// ImgData
+// =============================================================================
+// CubeData
+
+// CubeData holds the six square images of a cube map texture.
+// The faces are ordered +X, -X, +Y, -Y, +Z, -Z which matches
+// both the OpenGL cube map faces and the synth cube face order.
+//
+// This is an intermediate data format that needs further processing by
+// something like vu.Ent.MakeModel to bind the data to a GPU based texture.
+type CubeData struct {
+	Faces [6]image.Image
+}
+
+// CubeFaces are the name suffixes for cube maps stored as
+// six separate images, ie: "sky_posx.png" for the +X face.
+var CubeFaces = [6]string{"_posx", "_negx", "_posy", "_negy", "_posz", "_negz"}
+
+// Load cube map data. Existing CubeData is discarded and
+// replaced with information found by the Locator. Six images named
+// using CubeFaces are preferred, otherwise one image with the faces
+// arranged in a cross is split using Cross.
+func (d *CubeData) Load(name string, l Locator) (err error) {
+	faces := [6]image.Image{}
+	for cnt, suffix := range CubeFaces {
+		img := &ImgData{}
+		if err = img.Load(name+suffix, l); err != nil {
+			break
+		}
+		faces[cnt] = img.Img
+	}
+	if err != nil {
+		img := &ImgData{}
+		if err = img.Load(name, l); err != nil {
+			return fmt.Errorf("Could not load cube map %s: %s\n", name, err)
+		}
+		if faces, err = Cross(img.Img); err != nil {
+			return fmt.Errorf("Could not load cube map %s: %s\n", name, err)
+		}
+	}
+	if err = cubeCheck(faces); err != nil {
+		return fmt.Errorf("Could not load cube map %s: %s\n", name, err)
+	}
+	d.Faces = faces
+	return nil
+}
+
+// CubeData
 // =============================================================================
 // ModData
 
@@ -116,9 +182,10 @@ func (d *ImgData) Load(name string, l Locator) (err error) {
 // format that needs further processing by something like vu.Ent.MakeModel
 // to bind the data to a GPU.
 type ModData struct {
-	MshData          // Vertex based data.
-	AnmData          // Animation data.
-	TMap    []TexMap // Texture name and vertex mapping data.
+	MshData           // Vertex based data.
+	AnmData           // Animation data.
+	TMap    []TexMap  // Texture name and vertex mapping data.
+	Mtls    []MtlData // Optional material for each TexMap.
 }
 
 // AnmData holds the data necessary to a. It is an intermediate data
@@ -138,6 +205,15 @@ type Movement struct {
 	Name   string  // Name of the animation
 	F0, Fn uint32  // First frame, number of frames.
 	Rate   float32 // Frames per second.
+	Events []Event // Optional named frames.
+}
+
+// Event names a frame within a Movement, ie: a footstep. Events are
+// loaded from an optional sidecar file. Expected to be used as part
+// of Movement.
+type Event struct {
+	Name  string // Name of the event.
+	Frame uint32 // Frame relative to the start of the movement.
 }
 
 // TexMap allows a model to have multiple textures. The named texture
@@ -150,14 +226,38 @@ type TexMap struct {
 
 // Load model vertex and animation data. Existing ModData is
 // overwritten with information found by the Locator.
+// A binary .vmsh file is preferred when it exists and is not older
+// than the model source files, then an .iqm file, and finally a .gltf
+// or .glb file. Animation events are loaded from an optional .evt file
+// with the same name.
 func (d *ModData) Load(name string, l Locator) (err error) {
-	fname := name + ".iqm" // FUTURE: other animated model file formats.
+	fname := name + ".iqm"
 	var reader io.ReadCloser
-	if reader, err = l.GetResource(fname); err != nil {
+	if reader, err = openVmsh(name, l, ".iqm", ".gltf", ".glb"); err == nil {
+		defer reader.Close()
+		if err = Vmsh(reader, d); err != nil {
+			return err
+		}
+	} else if reader, err = l.GetResource(fname); err == nil {
+		defer reader.Close()
+		if err = Iqm(reader, d); err != nil {
+			return err
+		}
+	} else if found, gerr := loadGltf(name, l, d); gerr != nil {
+		return gerr
+	} else if !found {
 		return fmt.Errorf("Could not load animated model from %s: %s\n", fname, err)
 	}
-	defer reader.Close()
-	return Iqm(reader, d)
+	ename := name + ".evt"
+	var events io.ReadCloser
+	if events, err = l.GetResource(ename); err != nil {
+		return nil // animation events are optional.
+	}
+	defer events.Close()
+	if err = Evt(events, &d.AnmData); err != nil {
+		return fmt.Errorf("Could not load animation events from %s: %s\n", ename, err)
+	}
+	return nil
 }
 
 // ModData
@@ -176,16 +276,35 @@ type MshData struct {
 	N    []float32 // Vertex normals.      Arranged as [][3]float32
 	T    []float32 // Texture coordinates. Arranged as [][2]float32
 	X    []float32 // Vertex tangents.     Arranged as [][2]float32
-	F    []uint16  // Triangle faces.      Arranged as [][3]uint16
+	F    []uint32  // Triangle faces.      Arranged as [][3]uint32
 }
 
 // Load model mesh vertex data. Existing MshData is
 // overwritten with information found by the Locator.
+// A binary .vmsh file is preferred when it exists and is not older
+// than the model source files, then an .obj file, and finally a .gltf
+// or .glb file.
 func (d *MshData) Load(name string, l Locator) (err error) {
-	fname := name + ".obj" // FUTURE: other model mesh file formats.
+	fname := name + ".obj"
 	var reader io.ReadCloser
+	if reader, err = openVmsh(name, l, ".obj", ".gltf", ".glb"); err == nil {
+		defer reader.Close()
+		mod := &ModData{}
+		if err = Vmsh(reader, mod); err != nil {
+			return err
+		}
+		*d = mod.MshData
+		return nil
+	}
 	if reader, err = l.GetResource(fname); err != nil {
-		return fmt.Errorf("Could not load mesh data from %s: %s\n", fname, err)
+		mod := &ModData{}
+		if found, gerr := loadGltf(name, l, mod); gerr != nil {
+			return gerr
+		} else if !found {
+			return fmt.Errorf("Could not load mesh data from %s: %s\n", fname, err)
+		}
+		*d = mod.MshData
+		return nil
 	}
 	defer reader.Close()
 	return Obj(reader, d)
@@ -211,11 +330,20 @@ type MtlData struct {
 
 // Load model lighting material data. Existing MtlData is
 // overwritten with information found by the Locator.
+// An .mtl file is used if it exists, otherwise the first
+// material from a .gltf or .glb file.
 func (d *MtlData) Load(name string, l Locator) (err error) {
-	fname := name + ".mtl" // FUTURE: other model material file formats.
+	fname := name + ".mtl"
 	var reader io.ReadCloser
 	if reader, err = l.GetResource(fname); err != nil {
-		return fmt.Errorf("could not open %s %s", fname, err)
+		mod := &ModData{}
+		if found, gerr := loadGltf(name, l, mod); gerr != nil {
+			return gerr
+		} else if !found || len(mod.Mtls) == 0 {
+			return fmt.Errorf("could not open %s %s", fname, err)
+		}
+		*d = mod.Mtls[0]
+		return nil
 	}
 	defer reader.Close()
 	return Mtl(reader, d)
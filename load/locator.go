@@ -5,12 +5,16 @@ package load
 
 import (
 	"archive/zip"
+	"fmt"
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Locator knows how to search disk based locations for files.
@@ -27,40 +31,91 @@ type Locator interface {
 	GetResource(name string) (file io.ReadCloser, err error)
 }
 
+// Watcher is an optional Locator interface for Locators that can
+// report resources that have changed since the last time they were
+// checked. Watching is expected to be used in development builds
+// to reload assets without restarting the application.
+type Watcher interface {
+	Changed() []string // Names of changed resources.
+}
+
 // NewLocator returns the default asset locator. The default Locator
 // looks directly to disk for development builds and for a zip file for
 // production builds. The default asset locator expects all locations
 // are directories relative to the application location.
 // The default Locator maps the following file types to the given directories.
-//    PNG               : "images"
+//    PNG, JPG, JPEG    : "images"
+//    TGA, BMP, KTX     : "images"
 //    WAV               : "audio"
-//    OBJ, IQM, MTL     : "models"
+//    OBJ, IQM, MTL, EVT: "models"
+//    GLTF, GLB, BIN    : "models"
+//    VMSH              : "models"
 //    FNT, VSH, FSH, TXT: "source"
+// The default Locator is a Watcher when it reads directly from disk.
+// A zip file, when found, overlays the disk files.
 func NewLocator() Locator { return newLocator() }
 
+// NewFSLocator returns a Locator that reads resources from the given
+// file system, ie: an embed.FS, a zip.Reader, or an in memory test
+// file system. The name identifies the file system in logs.
+// The file types are mapped to the same directories as NewLocator.
+func NewFSLocator(name string, fsys fs.FS) Locator {
+	return &locator{name: name, fsys: fsys, dirs: defaultDirs()}
+}
+
+// NewDirLocator returns a Locator that reads resources from disk below
+// the given directory, ie: a mod or patch directory. The file types
+// are mapped to the same sub-directories as NewLocator.
+// A NewDirLocator is a Watcher.
+func NewDirLocator(dir string) Locator {
+	return &locator{name: dir, root: dir, watch: true, dirs: defaultDirs()}
+}
+
+// NewOverlay returns a Locator that searches the given Locators in order
+// and returns the first resource found. Earlier Locators override the
+// resources of later Locators, for example:
+//    base := load.NewFSLocator("assets.zip", zipReader)
+//    loc := load.NewOverlay(load.NewDirLocator("mods/patch"), base)
+// The search order is logged. Dir is applied to each of the Locators.
+func NewOverlay(layers ...Locator) Locator {
+	names := []string{}
+	for _, layer := range layers {
+		names = append(names, layerName(layer))
+	}
+	log.Printf("Asset search order: %s", strings.Join(names, ", "))
+	return &overlay{layers: layers}
+}
+
 // ===========================================================================
 // locator implements Locator.
 
-// locator knows where to find asset data on disk.
+// locator finds resources in a file system, or on disk when there is
+// no file system, using a mapping of file types to directories.
 type locator struct {
-	reader *zip.ReadCloser   // Used as the resource file if set.
-	dirs   map[string]string //
+	name   string               // Identifies the resources in logs.
+	fsys   fs.FS                // Used as the resource files if set.
+	closer io.Closer            // Closes the file system, if necessary.
+	root   string               // Disk directory when there is no fsys.
+	watch  bool                 // True to report changed disk files.
+	dirs   map[string]string    //
+	times  map[string]time.Time // File modification times for Changed.
 }
 
 // newLocator returns the default Locator implementation and asset
 // directory locations. These are conventions for locating zipped assets
 // in different situations.
-func newLocator() *locator {
+func newLocator() Locator {
 	var resources *zip.ReadCloser // packaged resources.
+	var zipName string            // packaged resources file.
 	programName := os.Args[0]     // qualified path to executable
 	assetZip0 := path.Join(path.Dir(programName), "assets.zip")
 	assetZip1 := path.Join(path.Dir(programName), "../Resources/assets.zip")
 	if reader, err := zip.OpenReader(assetZip0); err == nil {
-		resources = reader // iOS
+		resources, zipName = reader, assetZip0 // iOS
 	} else if reader, err := zip.OpenReader(assetZip1); err == nil {
-		resources = reader // OSX
+		resources, zipName = reader, assetZip1 // OSX
 	} else if reader, err := zip.OpenReader(programName); err == nil {
-		resources = reader // windows non-store exe: zip is in Exe.
+		resources, zipName = reader, programName // windows non-store exe: zip is in Exe.
 	} else {
 		// windows store app.
 		// use absolute path to executable since relative files
@@ -73,17 +128,31 @@ func newLocator() *locator {
 		absDir, err0 := filepath.Abs(programName)
 		assetZip2 := path.Join(absDir, "Assets/assets.zip")
 		if reader, err := zip.OpenReader(assetZip2); err0 == nil && err == nil {
-			resources = reader // Windows
+			resources, zipName = reader, assetZip2 // Windows
 		}
 	}
 
 	// if resources is still nil then this is likely a debug build
 	// and GetResources below will attempt to read directly from disk.
-	l := &locator{reader: resources}
-	l.dirs = map[string]string{ // default directories for file locations.
+	disk := &locator{name: "disk", watch: resources == nil, dirs: defaultDirs()}
+	if resources == nil {
+		return disk
+	}
+	packed := &locator{name: zipName, fsys: resources, closer: resources, dirs: defaultDirs()}
+	return NewOverlay(packed, disk)
+}
+
+// defaultDirs returns the default directories for file locations.
+func defaultDirs() map[string]string {
+	return map[string]string{
 		"OBJ":  "models",
 		"IQM":  "models",
 		"MTL":  "models",
+		"EVT":  "models",
+		"GLTF": "models",
+		"GLB":  "models",
+		"BIN":  "models",
+		"VMSH": "models",
 		"WAV":  "audio",
 		"TXT":  "source",
 		"VSH":  "source",
@@ -91,8 +160,12 @@ func newLocator() *locator {
 		"FNT":  "source",
 		"JSON": "source",
 		"PNG":  "images",
+		"JPG":  "images",
+		"JPEG": "images",
+		"TGA":  "images",
+		"BMP":  "images",
+		"KTX":  "images",
 	}
-	return l
 }
 
 // GetResource locates the named resource. This is expected to be used either
@@ -109,19 +182,10 @@ func (l *locator) GetResource(name string) (file io.ReadCloser, err error) {
 		prefix = val
 	}
 	filePath := strings.TrimSpace(path.Join(prefix, name))
-	if l.reader != nil {
-		for _, resource := range l.reader.File {
-			if filePath == resource.Name {
-				rc, zerr := resource.Open()
-				if zerr != nil {
-					log.Printf("Could not open resource %s: %s", resource.Name, zerr)
-					return nil, zerr
-				}
-				return rc, nil
-			}
-		}
+	if l.fsys != nil {
+		return l.fsys.Open(filePath)
 	}
-	return os.Open(filePath)
+	return os.Open(path.Join(l.root, filePath))
 }
 
 // Dir maps a file extention to a directory. Having a convention
@@ -136,7 +200,113 @@ func (l *locator) Dir(ext, dir string) Locator {
 // Dispose properly terminates the loader.
 // This is only needed when the loader has been reading resources from a file.
 func (l *locator) Dispose() {
-	if l.reader != nil {
-		l.reader.Close()
+	if l.closer != nil {
+		l.closer.Close()
+	}
+}
+
+// String identifies the locator resources in logs.
+func (l *locator) String() string { return l.name }
+
+// Changed implements Watcher. It returns the names of files that have
+// been added or modified since the previous call. The first call records
+// the current files and returns nothing. Nothing is returned when the
+// resources are not read directly from disk.
+func (l *locator) Changed() (names []string) {
+	if !l.watch {
+		return nil
+	}
+	first := l.times == nil
+	if first {
+		l.times = map[string]time.Time{}
+	}
+	scanned := map[string]bool{}
+	for _, dir := range l.dirs {
+		if scanned[dir] {
+			continue // many file types share a directory.
+		}
+		scanned[dir] = true
+		files, err := ioutil.ReadDir(path.Join(l.root, dir))
+		if err != nil {
+			continue // directories are optional.
+		}
+		for _, file := range files {
+			name := file.Name()
+			ext := strings.ToUpper(strings.TrimPrefix(path.Ext(name), "."))
+			if file.IsDir() || l.dirs[ext] != dir {
+				continue // only files that GetResource would find.
+			}
+			filePath := path.Join(l.root, dir, name)
+			if last, ok := l.times[filePath]; !ok || file.ModTime().After(last) {
+				l.times[filePath] = file.ModTime()
+				if !first {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// ===========================================================================
+// overlay implements Locator.
+
+// overlay searches a list of Locators in order.
+type overlay struct {
+	layers []Locator // Searched first to last.
+}
+
+// GetResource returns the resource from the first Locator that has it.
+// The error from the last Locator is returned if none have it.
+func (o *overlay) GetResource(name string) (file io.ReadCloser, err error) {
+	err = os.ErrNotExist
+	for _, layer := range o.layers {
+		if file, err = layer.GetResource(name); err == nil {
+			return file, nil
+		}
+	}
+	return nil, err
+}
+
+// Dir maps a file extension to a directory for each Locator.
+func (o *overlay) Dir(ext, dir string) Locator {
+	for _, layer := range o.layers {
+		layer.Dir(ext, dir)
+	}
+	return o
+}
+
+// Dispose each of the Locators.
+func (o *overlay) Dispose() {
+	for _, layer := range o.layers {
+		layer.Dispose()
+	}
+}
+
+// String identifies the overlay resources in logs.
+func (o *overlay) String() string {
+	names := []string{}
+	for _, layer := range o.layers {
+		names = append(names, layerName(layer))
+	}
+	return strings.Join(names, "+")
+}
+
+// Changed implements Watcher by combining the changes
+// from each Locator that is a Watcher.
+func (o *overlay) Changed() (names []string) {
+	for _, layer := range o.layers {
+		if w, ok := layer.(Watcher); ok {
+			names = append(names, w.Changed()...)
+		}
+	}
+	return names
+}
+
+// layerName identifies a Locator in logs.
+func layerName(l Locator) string {
+	if s, ok := l.(fmt.Stringer); ok {
+		return s.String()
 	}
+	return "locator"
 }
@@ -0,0 +1,83 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// Check that changed files are reported once.
+func TestChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	fsh := filepath.Join(dir, "fire.fsh")
+	ioutil.WriteFile(fsh, []byte("void main() {}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "notes.doc"), []byte("ignored"), 0644)
+	loc := NewLocator().Dir("FSH", dir).Dir("VSH", dir)
+	w, ok := loc.(Watcher)
+	if !ok {
+		t.Fatalf("Expected disk locator to be a Watcher")
+	}
+	if names := w.Changed(); len(names) != 0 {
+		t.Errorf("Expected no changes on first call got %v", names)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(fsh, later, later)
+	ioutil.WriteFile(filepath.Join(dir, "fire.vsh"), []byte("void main() {}"), 0644)
+	if names := w.Changed(); len(names) != 2 {
+		t.Errorf("Expected changed shader files got %v", names)
+	}
+	if names := w.Changed(); len(names) != 0 {
+		t.Errorf("Expected changes to be reported once got %v", names)
+	}
+}
+
+// Check that resources are found in a file system.
+func TestFSLocator(t *testing.T) {
+	fsys := fstest.MapFS{"models/tint.mtl": {Data: []byte("Kd 1 0.5 0\n")}}
+	mtl := &MtlData{}
+	if err := mtl.Load("tint", NewFSLocator("test", fsys)); err != nil || mtl.KdG != 0.5 {
+		t.Errorf("Expected material from file system %s", err)
+	}
+	if err := mtl.Load("tint", NewFSLocator("test", fsys).Dir("MTL", "../models")); err == nil {
+		t.Errorf("Expected error for path outside the file system")
+	}
+}
+
+// Check that earlier overlay Locators override later ones.
+func TestOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "models"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "models", "tint.mtl"), []byte("Kd 0 0 1\n"), 0644)
+	base := NewFSLocator("base", fstest.MapFS{
+		"models/tint.mtl": {Data: []byte("Kd 1 0 0\n")},
+		"models/gray.mtl": {Data: []byte("Kd 0.5 0.5 0.5\n")},
+	})
+	loc := NewOverlay(NewDirLocator(dir), base)
+	mtl := &MtlData{}
+	if err := mtl.Load("tint", loc); err != nil || mtl.KdB != 1 {
+		t.Errorf("Expected patched material %s %f", err, mtl.KdB)
+	}
+	if err := mtl.Load("gray", loc); err != nil || mtl.KdR != 0.5 {
+		t.Errorf("Expected base material %s", err)
+	}
+	if err := mtl.Load("missing", loc); err == nil {
+		t.Errorf("Expected error for missing material")
+	}
+	if _, ok := loc.(Watcher); !ok {
+		t.Errorf("Expected overlay to be a Watcher")
+	}
+}
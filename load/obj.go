@@ -140,7 +140,7 @@ func obj2Data(lines []string, odata *objData) (faces []face, err error) {
 //    mesh.V = append(mesh.V, ...3-float32) - indexed from 0
 //    mesh.N = append(mesh.N, ...3-float32) - indexed from 0
 //    mesh.T = append(mesh.T, ...2-float32)	- indexed from 0
-//    mesh.F = append(mesh.F, ...3-uint16)	- refers to above zero indexed values
+//    mesh.F = append(mesh.F, ...3-uint32)	- refers to above zero indexed values
 //
 // odata holds the global vertex, texture, and normal point information.
 // faces are the indexes for this mesh.
@@ -187,7 +187,7 @@ func obj2MshData(name string, odata *objData, faces []face, data *MshData) (err
 				n2.Add(n2, n1).Unit()
 				data.N[ni], data.N[ni+1], data.N[ni+2] = float32(n2.X), float32(n2.Y), float32(n2.Z)
 			}
-			data.F = append(data.F, uint16(vmap[vertexIndex]))
+			data.F = append(data.F, uint32(vmap[vertexIndex]))
 		}
 	}
 	if len(data.V) <= 0 || len(data.F) <= 0 {
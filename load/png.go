@@ -13,5 +13,6 @@ import (
 // A successful import replaces the image in ImgData with a new image.
 func Png(r io.Reader, d *ImgData) (err error) {
 	d.Img, err = png.Decode(r)
+	d.Mips = nil
 	return err
 }
@@ -0,0 +1,123 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+// TGA: Truevision image format.
+// Supports the image types commonly exported by paint programs:
+//    2  : uncompressed true color, 24 or 32 bits per pixel.
+//    3  : uncompressed grayscale, 8 bits per pixel.
+//    10 : run length encoded true color.
+//    11 : run length encoded grayscale.
+// Color mapped images are not supported. Pixels are stored BGR(A)
+// and rows are bottom up unless the header origin bit is set.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+)
+
+// Tga populates image data using the given reader.
+// The Reader r is expected to be opened and closed by the caller.
+// A successful import replaces the image in ImgData with a new NRGBA image.
+func Tga(r io.Reader, d *ImgData) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("Invalid .tga file: %s", err)
+	}
+	if len(data) < tgaHeaderSize {
+		return fmt.Errorf("Invalid .tga file: short header")
+	}
+	le := binary.LittleEndian
+	idLength, cmapType, imageType := int(data[0]), data[1], data[2]
+	cmapLength, cmapDepth := int(le.Uint16(data[5:])), int(data[7])
+	width, height := int(le.Uint16(data[12:])), int(le.Uint16(data[14:]))
+	depth, descriptor := int(data[16]), data[17]
+	gray := imageType == 3 || imageType == 11
+	switch {
+	case imageType != 2 && imageType != 3 && imageType != 10 && imageType != 11:
+		return fmt.Errorf("Unsupported .tga image type %d", imageType)
+	case gray && depth != 8:
+		return fmt.Errorf("Unsupported .tga grayscale depth %d", depth)
+	case !gray && depth != 24 && depth != 32:
+		return fmt.Errorf("Unsupported .tga color depth %d", depth)
+	}
+
+	// skip the image id and any unused color map.
+	start := tgaHeaderSize + idLength
+	if cmapType == 1 {
+		start += cmapLength * ((cmapDepth + 7) / 8)
+	}
+	if start > len(data) {
+		return fmt.Errorf("Corrupt .tga file")
+	}
+	pixels, bpp := data[start:], depth/8
+	if imageType >= 10 {
+		if pixels, err = tgaUnpack(pixels, width*height, bpp); err != nil {
+			return err
+		}
+	}
+	if len(pixels) < width*height*bpp {
+		return fmt.Errorf("Corrupt .tga file: missing pixels")
+	}
+
+	// convert to top down NRGBA.
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	topDown := descriptor&0x20 != 0
+	for y := 0; y < height; y++ {
+		row := y
+		if !topDown {
+			row = height - 1 - y
+		}
+		src := pixels[row*width*bpp:]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			p, q := src[x*bpp:], dst[x*4:]
+			switch bpp {
+			case 1:
+				q[0], q[1], q[2], q[3] = p[0], p[0], p[0], 255
+			case 3:
+				q[0], q[1], q[2], q[3] = p[2], p[1], p[0], 255
+			case 4:
+				q[0], q[1], q[2], q[3] = p[2], p[1], p[0], p[3]
+			}
+		}
+	}
+	d.Img, d.Mips = img, nil
+	return nil
+}
+
+// tgaHeaderSize is the fixed size header at the start of each TGA file.
+const tgaHeaderSize = 18
+
+// tgaUnpack expands run length encoded pixel data. Each packet
+// starts with a count. The high bit of the count indicates a single
+// pixel that is repeated, otherwise the count pixels follow.
+func tgaUnpack(data []byte, count, bpp int) ([]byte, error) {
+	pixels := make([]byte, 0, count*bpp)
+	for len(pixels) < count*bpp {
+		if len(data) < 1 {
+			return nil, fmt.Errorf("Corrupt .tga run length data")
+		}
+		run := int(data[0]&0x7f) + 1
+		if data[0]&0x80 != 0 {
+			if len(data) < 1+bpp {
+				return nil, fmt.Errorf("Corrupt .tga run length data")
+			}
+			for cnt := 0; cnt < run; cnt++ {
+				pixels = append(pixels, data[1:1+bpp]...)
+			}
+			data = data[1+bpp:]
+			continue
+		}
+		if len(data) < 1+run*bpp {
+			return nil, fmt.Errorf("Corrupt .tga raw data")
+		}
+		pixels = append(pixels, data[1:1+run*bpp]...)
+		data = data[1+run*bpp:]
+	}
+	return pixels[:count*bpp], nil
+}
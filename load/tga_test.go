@@ -0,0 +1,46 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestTga(t *testing.T) {
+	// 2x2 bottom up true color image with alpha.
+	hdr := []byte{0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 2, 0, 32, 0}
+	pix := []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, // bottom row BGRA
+		9, 10, 11, 12, 13, 14, 15, 16} // top row BGRA
+	img := &ImgData{}
+	if err := Tga(bytes.NewReader(append(hdr, pix...)), img); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	nrgba := img.Img.(*image.NRGBA)
+	if got := nrgba.Pix[:4]; !bytes.Equal(got, []byte{11, 10, 9, 12}) {
+		t.Errorf("Expected top row first got %v", got)
+	}
+	if got := nrgba.Pix[12:]; !bytes.Equal(got, []byte{7, 6, 5, 8}) {
+		t.Errorf("Expected bottom row last got %v", got)
+	}
+}
+
+func TestTgaRLE(t *testing.T) {
+	// 3x1 top down run length encoded grayscale image.
+	hdr := []byte{0, 0, 11, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 0, 1, 0, 8, 0x20}
+	pix := []byte{0x81, 50, 0x00, 90} // two repeated pixels, one raw pixel.
+	img := &ImgData{}
+	if err := Tga(bytes.NewReader(append(hdr, pix...)), img); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	want := []byte{50, 50, 50, 255, 50, 50, 50, 255, 90, 90, 90, 255}
+	if got := img.Img.(*image.NRGBA).Pix; !bytes.Equal(got, want) {
+		t.Errorf("Expected %v got %v", want, got)
+	}
+	if err := Tga(bytes.NewReader(append(hdr, 0x81)), img); err == nil {
+		t.Errorf("Expected error for truncated run")
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+// VMSH: vu mesh format.
+// A compact binary cache of ModData that is written by tools/vmsh
+// from .obj and .iqm files. The layout mirrors the ModData fields so
+// that loading is mostly a matter of reading sized arrays:
+//    header    : magic, version, and the length of each array.
+//    name      : length prefixed mesh name.
+//    V,N,T,X   : float32 vertex data.
+//    F         : uint16 or uint32 face indicies.
+//    Blends    : vertex blend indicies.
+//    Weights   : vertex blend weights.
+//    Joints    : int32 joint parents.
+//    Frames    : float32 joint transforms, 16 per frame.
+//    Movements : name, F0, Fn, Rate for each movement.
+//    TMap      : name, F0, Fn for each texture.
+//    Mtls      : float32 material values.
+// All values are little endian. Animation events are not included
+// since they continue to be read from the .evt sidecar file.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"time"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Vmsh loads a binary vu mesh file into ModData.
+// Existing ModData is overwritten.
+// The Reader r is expected to be opened and closed by the caller.
+func Vmsh(r io.Reader, d *ModData) error {
+	hdr := &vmshHeader{}
+	if err := binary.Read(r, binary.LittleEndian, hdr); err != nil {
+		return fmt.Errorf("Invalid .vmsh file: %s", err)
+	}
+	if !bytes.Equal(vmshMagic, hdr.Magic[:]) {
+		return fmt.Errorf("Invalid .vmsh header magic: %s", string(hdr.Magic[:]))
+	}
+	if hdr.Version != vmshVersion {
+		return fmt.Errorf("Expecting .vmsh version %d, got : %d", vmshVersion, hdr.Version)
+	}
+	if hdr.IndexBytes != 2 && hdr.IndexBytes != 4 {
+		return fmt.Errorf("Invalid .vmsh index size %d", hdr.IndexBytes)
+	}
+	for _, count := range hdr.counts() {
+		if count > vmshMaxCount {
+			return fmt.Errorf("Not loading .vmsh arrays larger than %d", vmshMaxCount)
+		}
+	}
+
+	// read the sized arrays directly into the model data.
+	vr := &vmshReader{r: r}
+	*d = ModData{}
+	d.Name = vr.str()
+	d.V = make([]float32, hdr.NumV)
+	d.N = make([]float32, hdr.NumN)
+	d.T = make([]float32, hdr.NumT)
+	d.X = make([]float32, hdr.NumX)
+	vr.read(d.V, d.N, d.T, d.X)
+	if hdr.IndexBytes == 2 {
+		faces := make([]uint16, hdr.NumF)
+		vr.read(faces)
+		d.F = make([]uint32, hdr.NumF)
+		for cnt, index := range faces {
+			d.F[cnt] = uint32(index)
+		}
+	} else {
+		d.F = make([]uint32, hdr.NumF)
+		vr.read(d.F)
+	}
+	d.Blends = make([]byte, hdr.NumBlends)
+	d.Weights = make([]byte, hdr.NumWeights)
+	d.Joints = make([]int32, hdr.NumJoints)
+	values := make([]float32, 16*hdr.NumFrames)
+	vr.read(d.Blends, d.Weights, d.Joints, values)
+	frames := make([]lin.M4, hdr.NumFrames) // one allocation for all frames.
+	d.Frames = make([]*lin.M4, hdr.NumFrames)
+	for cnt := range frames {
+		f, m := values[cnt*16:cnt*16+16], &frames[cnt]
+		m.Xx, m.Xy, m.Xz, m.Xw = float64(f[0]), float64(f[1]), float64(f[2]), float64(f[3])
+		m.Yx, m.Yy, m.Yz, m.Yw = float64(f[4]), float64(f[5]), float64(f[6]), float64(f[7])
+		m.Zx, m.Zy, m.Zz, m.Zw = float64(f[8]), float64(f[9]), float64(f[10]), float64(f[11])
+		m.Wx, m.Wy, m.Wz, m.Ww = float64(f[12]), float64(f[13]), float64(f[14]), float64(f[15])
+		d.Frames[cnt] = m
+	}
+	d.Movements = make([]Movement, hdr.NumMovements)
+	for cnt := range d.Movements {
+		mv := &d.Movements[cnt]
+		mv.Name = vr.str()
+		vr.read(&mv.F0, &mv.Fn, &mv.Rate)
+	}
+	d.TMap = make([]TexMap, hdr.NumTMaps)
+	for cnt := range d.TMap {
+		tm := &d.TMap[cnt]
+		tm.Name = vr.str()
+		vr.read(&tm.F0, &tm.Fn)
+	}
+	d.Mtls = make([]MtlData, hdr.NumMtls)
+	vr.read(d.Mtls)
+	if vr.err != nil {
+		return fmt.Errorf("Corrupt .vmsh file: %s", vr.err)
+	}
+
+	// empty optional data is nil, matching the other loaders.
+	if len(d.N) == 0 {
+		d.N = nil
+	}
+	if len(d.T) == 0 {
+		d.T = nil
+	}
+	if len(d.X) == 0 {
+		d.X = nil
+	}
+	if len(d.Mtls) == 0 {
+		d.Mtls = nil
+	}
+	return nil
+}
+
+// WriteVmsh saves ModData in the binary vu mesh format. Face
+// indicies are written as uint16 when there are few enough vertices.
+// Movement events are not written.
+// The Writer w is expected to be opened and closed by the caller.
+func WriteVmsh(w io.Writer, d *ModData) error {
+	hdr := &vmshHeader{Version: vmshVersion, IndexBytes: 2}
+	copy(hdr.Magic[:], vmshMagic)
+	hdr.NumV, hdr.NumN = uint32(len(d.V)), uint32(len(d.N))
+	hdr.NumT, hdr.NumX = uint32(len(d.T)), uint32(len(d.X))
+	hdr.NumF = uint32(len(d.F))
+	hdr.NumBlends, hdr.NumWeights = uint32(len(d.Blends)), uint32(len(d.Weights))
+	hdr.NumJoints, hdr.NumFrames = uint32(len(d.Joints)), uint32(len(d.Frames))
+	hdr.NumMovements, hdr.NumTMaps = uint32(len(d.Movements)), uint32(len(d.TMap))
+	hdr.NumMtls = uint32(len(d.Mtls))
+	for _, index := range d.F {
+		if index > math.MaxUint16 {
+			hdr.IndexBytes = 4
+			break
+		}
+	}
+
+	vw := &vmshWriter{w: w}
+	vw.write(hdr)
+	vw.str(d.Name)
+	vw.write(d.V, d.N, d.T, d.X)
+	if hdr.IndexBytes == 2 {
+		faces := make([]uint16, len(d.F))
+		for cnt, index := range d.F {
+			faces[cnt] = uint16(index)
+		}
+		vw.write(faces)
+	} else {
+		vw.write(d.F)
+	}
+	values := make([]float32, 0, 16*len(d.Frames))
+	for _, m := range d.Frames {
+		values = append(values,
+			float32(m.Xx), float32(m.Xy), float32(m.Xz), float32(m.Xw),
+			float32(m.Yx), float32(m.Yy), float32(m.Yz), float32(m.Yw),
+			float32(m.Zx), float32(m.Zy), float32(m.Zz), float32(m.Zw),
+			float32(m.Wx), float32(m.Wy), float32(m.Wz), float32(m.Ww))
+	}
+	vw.write(d.Blends, d.Weights, d.Joints, values)
+	for _, mv := range d.Movements {
+		vw.str(mv.Name)
+		vw.write(mv.F0, mv.Fn, mv.Rate)
+	}
+	for _, tm := range d.TMap {
+		vw.str(tm.Name)
+		vw.write(tm.F0, tm.Fn)
+	}
+	vw.write(d.Mtls)
+	if vw.err != nil {
+		return fmt.Errorf("Could not write .vmsh file: %s", vw.err)
+	}
+	return nil
+}
+
+// public inteface
+// =============================================================================
+// internal implementation for reading and writing VMSH files.
+
+// openVmsh returns the .vmsh cache for the named model unless one of
+// the model source files, given by extension, is newer than the cache.
+// Resources without modification times, ie: production zip files,
+// always use the cache. The caller closes the returned reader.
+func openVmsh(name string, l Locator, sources ...string) (io.ReadCloser, error) {
+	reader, err := l.GetResource(name + ".vmsh")
+	if err != nil {
+		return nil, err
+	}
+	cached, ok := modTime(reader)
+	for _, ext := range sources {
+		if !ok {
+			break
+		}
+		if src, err := l.GetResource(name + ext); err == nil {
+			changed, found := modTime(src)
+			src.Close()
+			if found && changed.After(cached) {
+				reader.Close()
+				return nil, fmt.Errorf("%s.vmsh is older than %s%s", name, name, ext)
+			}
+		}
+	}
+	return reader, nil
+}
+
+// modTime returns the modification time for resources that are files.
+func modTime(resource io.ReadCloser) (time.Time, bool) {
+	if file, ok := resource.(fs.File); ok {
+		if info, err := file.Stat(); err == nil {
+			return info.ModTime(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// vmshMagic identifies the file type. Note that the version
+// is bumped whenever the layout changes.
+var vmshMagic = []byte("VMSH")
+
+const (
+	vmshVersion  = 1
+	vmshMaxCount = 1 << 26 // Guard against corrupt array lengths.
+)
+
+// vmshHeader starts each file and gives the size of each array.
+type vmshHeader struct {
+	Magic        [4]byte // Expecting "VMSH".
+	Version      uint32  // Expecting vmshVersion.
+	IndexBytes   uint32  // 2 or 4 bytes for each face index.
+	NumV, NumN   uint32  // Number of float32 vertex values.
+	NumT, NumX   uint32  // Number of float32 vertex values.
+	NumF         uint32  // Number of face indicies.
+	NumBlends    uint32  // Number of blend bytes.
+	NumWeights   uint32  // Number of weight bytes.
+	NumJoints    uint32  // Number of joints.
+	NumFrames    uint32  // Number of joint transforms.
+	NumMovements uint32  // Number of movements.
+	NumTMaps     uint32  // Number of texture maps.
+	NumMtls      uint32  // Number of materials.
+}
+
+// counts returns the array lengths from the header.
+func (h *vmshHeader) counts() []uint32 {
+	return []uint32{h.NumV, h.NumN, h.NumT, h.NumX, h.NumF, h.NumBlends, h.NumWeights,
+		h.NumJoints, h.NumFrames, h.NumMovements, h.NumTMaps, h.NumMtls}
+}
+
+// vmshReader reads values until the first error.
+type vmshReader struct {
+	r   io.Reader
+	err error
+}
+
+// read fills in each of the given fixed size values.
+func (vr *vmshReader) read(data ...interface{}) {
+	for _, val := range data {
+		if vr.err == nil {
+			vr.err = binary.Read(vr.r, binary.LittleEndian, val)
+		}
+	}
+}
+
+// str reads a length prefixed string.
+func (vr *vmshReader) str() string {
+	size := uint16(0)
+	if vr.read(&size); vr.err != nil || size == 0 {
+		return ""
+	}
+	buff := make([]byte, size)
+	if _, err := io.ReadFull(vr.r, buff); err != nil && vr.err == nil {
+		vr.err = err
+	}
+	return string(buff)
+}
+
+// vmshWriter writes values until the first error.
+type vmshWriter struct {
+	w   io.Writer
+	err error
+}
+
+// write saves each of the given fixed size values.
+func (vw *vmshWriter) write(data ...interface{}) {
+	for _, val := range data {
+		if vw.err == nil {
+			vw.err = binary.Write(vw.w, binary.LittleEndian, val)
+		}
+	}
+}
+
+// str writes a length prefixed string.
+func (vw *vmshWriter) str(s string) {
+	if len(s) > math.MaxUint16 {
+		s = s[:math.MaxUint16]
+	}
+	vw.write(uint16(len(s)))
+	if vw.err == nil {
+		_, vw.err = io.WriteString(vw.w, s)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// Check that an animated model survives a round trip.
+func TestVmsh(t *testing.T) {
+	iqm := &ModData{}
+	if err := iqm.Load("runner", NewLocator().Dir("IQM", modDir)); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	for cnt := range iqm.Movements {
+		iqm.Movements[cnt].Events = nil // events are not saved.
+	}
+	buff := &bytes.Buffer{}
+	if err := WriteVmsh(buff, iqm); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	m := &ModData{}
+	if err := Vmsh(buff, m); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	for cnt, frame := range m.Frames {
+		if !frame.Aeq(iqm.Frames[cnt]) {
+			t.Fatalf("Expected same frame %d got %v", cnt, frame)
+		}
+	}
+	m.Frames, iqm.Frames = nil, nil // frames are saved as float32.
+	if !reflect.DeepEqual(iqm, m) {
+		t.Errorf("Expected same model after round trip")
+	}
+}
+
+// Check that 32-bit face indicies are kept.
+func TestVmshWide(t *testing.T) {
+	msh := &ModData{MshData: MshData{Name: "wide", V: []float32{1, 2, 3}, F: []uint32{0, 1, 70000}}}
+	buff := &bytes.Buffer{}
+	WriteVmsh(buff, msh)
+	m := &ModData{}
+	if err := Vmsh(buff, m); err != nil || m.Name != "wide" || m.F[2] != 70000 {
+		t.Errorf("Expected wide faces got %v %s", m.F, err)
+	}
+	if err := Vmsh(bytes.NewReader([]byte("VMSH")), m); err == nil {
+		t.Errorf("Expected error for truncated file")
+	}
+}
+
+// Check that the binary file is preferred when it exists.
+func TestLoadVmsh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vmsh")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	obj := &MshData{}
+	loc := NewLocator().Dir("OBJ", modDir).Dir("VMSH", dir)
+	if err := obj.Load("cube", loc); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	obj.Name = "binary"
+	file, _ := os.Create(filepath.Join(dir, "cube.vmsh"))
+	WriteVmsh(file, &ModData{MshData: *obj})
+	file.Close()
+	msh := &MshData{}
+	if err := msh.Load("cube", loc); err != nil || msh.Name != "binary" || len(msh.F) != 36 {
+		t.Errorf("Expected binary mesh %s %s", msh.Name, err)
+	}
+}
+
+// Check that the source file is used when the binary file is older.
+func TestLoadStaleVmsh(t *testing.T) {
+	obj := &MshData{}
+	if err := obj.Load("cube", NewLocator().Dir("OBJ", modDir)); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	source, _ := ioutil.ReadFile(filepath.Join(modDir, "cube.obj"))
+	obj.Name = "binary"
+	binary := &bytes.Buffer{}
+	WriteVmsh(binary, &ModData{MshData: *obj})
+	now := time.Now()
+	fsys := fstest.MapFS{
+		"models/cube.obj":  {Data: source, ModTime: now},
+		"models/cube.vmsh": {Data: binary.Bytes(), ModTime: now.Add(-time.Hour)},
+	}
+	msh := &MshData{}
+	if err := msh.Load("cube", NewFSLocator("test", fsys)); err != nil || msh.Name == "binary" || len(msh.F) != 36 {
+		t.Errorf("Expected source mesh %s %s", msh.Name, err)
+	}
+	fsys["models/cube.vmsh"].ModTime = now.Add(time.Hour)
+	if err := msh.Load("cube", NewFSLocator("test", fsys)); err != nil || msh.Name != "binary" {
+		t.Errorf("Expected binary mesh %s %s", msh.Name, err)
+	}
+}
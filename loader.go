@@ -4,6 +4,7 @@
 package vu
 
 // loader.go gets data from disk. Puts disk loads on worker goroutines.
+// Assets are reloaded when their files change during development.
 // FUTURE: handle releaseData requests. See eng.dispose design note.
 //
 // FUTURE lookat "gltf v2" godot importer.
@@ -13,6 +14,9 @@ package vu
 import (
 	"fmt"
 	"log"
+	"path"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gazed/vu/load"
@@ -27,20 +31,45 @@ type loader struct {
 	loc   load.Locator // Locates the asset data on disk.
 	cache cache        // asset cache.
 
+	// Model assets are reference counted and evicted from the cache
+	// when no longer used. Evicted GPU data is released on the main thread.
+	refs    map[aid]int // Number of models using each asset.
+	evicted []asset     // Assets needing GPU data released.
+
+	// Preload groups track the import progress of a set of assets.
+	groups  map[string]*preload // Preload groups by name.
+	waiting map[aid][]*preload  // Groups waiting for an asset import.
+
 	// pending tracks outstanding asset requests. Asset requests are
 	// loaded from disk by workers on a goroutine. Syncronization happens
 	// by passing ownership of asset data.
 	pending   map[aid][]func(asset)
+	queued    []*diskAsset    // assets waiting for room on needAsset.
 	needAsset chan *diskAsset // assets to be imported from disk.
 	haveAsset chan *diskAsset // assets finished importing.
+	wait      bool            // true to block until imports are done.
+
+	// Assets are reloaded when their files change on disk.
+	// Only done when the Locator is reading directly from disk.
+	watched   time.Time    // Last check for changed files.
+	reloading map[aid]bool // Outstanding reload requests.
+	reloaded  []asset      // Cached assets updated by reloads.
+	compile   []*shader    // Reloaded shaders needing a main thread compile.
 }
 
 // newLoader is called once on startup by the engine.
-func newLoader() *loader {
-	l := &loader{}
-	l.loc = load.NewLocator()
+// The default Locator is used when loc is nil.
+func newLoader(loc load.Locator) *loader {
+	l := &loader{loc: loc}
+	if l.loc == nil {
+		l.loc = load.NewLocator()
+	}
 	l.cache = newCache()
 	l.pending = map[aid][]func(asset){}
+	l.refs = map[aid]int{}
+	l.groups = map[string]*preload{}
+	l.waiting = map[aid][]*preload{}
+	l.reloading = map[aid]bool{}
 
 	// allocate enough that ideally avoids blocking and waiting
 	// for asset import workers in reasonable scenarios.
@@ -61,7 +90,7 @@ func newLoader() *loader {
 func (l *loader) fetch(a asset, loaded func(asset)) {
 	kind := a.aid().kind()
 	switch kind {
-	case msh, tex, shd, fnt, mat, snd:
+	case msh, tex, shd, fnt, mat, snd, cub:
 		l.fetchOrImport(a, loaded)
 	case anm:
 		// animations are special in that they need animation data and
@@ -102,7 +131,7 @@ func (l *loader) importAsset(callback func(asset), assets ...asset) {
 	if len(assets) > 0 {
 		a := assets[0]
 		if callbacks, ok := l.pending[a.aid()]; !ok {
-			l.needAsset <- &diskAsset{assets: assets, loc: l.loc}
+			l.queue(&diskAsset{assets: assets, loc: l.loc})
 			l.pending[a.aid()] = append([]func(asset){}, callback)
 		} else {
 			l.pending[a.aid()] = append(callbacks, callback)
@@ -110,6 +139,25 @@ func (l *loader) importAsset(callback func(asset), assets ...asset) {
 	}
 }
 
+// queue adds an import request to the work queue. Requests are passed
+// to the import workers by feed so that asking for many assets at once
+// can't block while the workers are waiting to return finished imports.
+func (l *loader) queue(da *diskAsset) { l.queued = append(l.queued, da) }
+
+// feed passes queued import requests to the import workers
+// until the workers are busy.
+func (l *loader) feed() {
+	for len(l.queued) > 0 {
+		select {
+		case l.needAsset <- l.queued[0]:
+			l.queued[0] = nil
+			l.queued = l.queued[1:]
+		default:
+			return // workers are busy.
+		}
+	}
+}
+
 // processImports is run on the main thread each update tick to retreive
 // the results of any asset import workers. It fetches finished imports
 // within a time window so as to not stall the main loop. This results
@@ -118,64 +166,396 @@ func (l *loader) importAsset(callback func(asset), assets ...asset) {
 // Asset binding is done on the main thread due to the single
 // threaded render context.
 func (l *loader) processImports() {
+	defer l.notify() // report any finished preload groups.
+	l.watch()
+	if l.wait {
+		l.waitImports()
+		return
+	}
 	var timeUsed time.Duration
 	start := time.Now()
 	timeLimit := 0.01 // 10 milliseconds, about half an update cycle.
 	for timeUsed.Seconds() < timeLimit {
+		l.feed()
 		select {
 		case done := <-l.haveAsset:
-			a := done.assets[0]
-			if done.err != nil {
-				log.Printf("Failed to load asset %s: %s", a.label(), done.err)
-				break // dev error - dev to debug why asset is missing.
-			}
-
-			// handle assets that need binding (copy data to GPU).
-			switch a.(type) {
-			case *Mesh, *shader, *Texture, *sound, *material, *font:
-				l.cache.store(a) // cache asset.
-			case *animation:
-				if len(done.assets) == 2 {
-					a2 := done.assets[1] // animation mesh always second.
-					l.cache.store(a2)    // cache mesh data.
-					l.cache.store(a)     // cache animation data.
-				}
-			}
-			for _, callback := range l.pending[a.aid()] {
-				for _, a := range done.assets {
-					callback(a)
-				}
-			}
-			delete(l.pending, a.aid())
+			l.imported(done)
 		default:
 			// Called each update so return immediately if there are no assets.
 			return
 		}
 		timeUsed = time.Since(start)
 	}
+	l.feed()
 }
 
-// release is called when the cached data is no
-// longer needed and can be discarded entirely.
-func (l *loader) release(data interface{}) {
-	switch d := data.(type) {
-	case *Mesh:
-		l.cache.remove(d)
-	case *font:
-		l.cache.remove(d)
+// waitImports blocks until all outstanding asset requests have been
+// imported. Used by RunHeadless so that each update sees the same
+// assets regardless of how long the import workers take.
+func (l *loader) waitImports() {
+	for len(l.pending) > 0 || len(l.reloading) > 0 {
+		if len(l.queued) == 0 {
+			l.imported(<-l.haveAsset)
+			continue
+		}
+		select {
+		case l.needAsset <- l.queued[0]:
+			l.queued[0] = nil
+			l.queued = l.queued[1:]
+		case done := <-l.haveAsset:
+			l.imported(done)
+		}
+	}
+}
+
+// imported caches a finished asset import and returns the
+// asset to everyone that requested it.
+func (l *loader) imported(done *diskAsset) {
+	if done.reload {
+		l.reload(done)
+		return
+	}
+	a := done.assets[0]
+	if done.err != nil {
+		log.Printf("Failed to load asset %s: %s", a.label(), done.err)
+		delete(l.pending, a.aid())
+		l.progress(a.aid(), true)
+		return // dev error - dev to debug why asset is missing.
+	}
+	defer l.progress(a.aid(), false)
+	if refs, ok := l.refs[a.aid()]; ok && refs <= 0 {
+		delete(l.refs, a.aid()) // everyone stopped waiting for the asset.
+		delete(l.pending, a.aid())
+		return
+	}
+
+	// handle assets that need binding (copy data to GPU).
+	switch a.(type) {
+	case *Mesh, *shader, *Texture, *sound, *material, *font:
+		l.cache.store(a) // cache asset.
+	case *animation:
+		if len(done.assets) == 2 {
+			a2 := done.assets[1] // animation mesh always second.
+			l.cache.store(a2)    // cache mesh data.
+			l.cache.store(a)     // cache animation data.
+		}
+	}
+	for _, callback := range l.pending[a.aid()] {
+		for _, a := range done.assets {
+			callback(a)
+		}
+	}
+	delete(l.pending, a.aid())
+}
+
+// preload starts importing a named group of assets. Assets are given
+// as model asset attributes, ie: "msh:name", and the group counts as
+// a user of each asset so that the assets stay cached until the group
+// is unloaded. The optional loaded callback is run once all the group
+// assets have been loaded or have failed. A group replaces any previous
+// group with the same name.
+func (l *loader) preload(group string, loaded func(string), assets ...string) {
+	previous := l.groups[group]
+	p := &preload{name: group, loaded: loaded}
+	l.groups[group] = p
+	for _, attribute := range assets {
+		p.total++
+		a := preloadAsset(attribute)
+		if a == nil {
+			log.Printf("Unknown preload asset %s for %s", attribute, group)
+			p.failed++
+			continue
+		}
+		p.assets = append(p.assets, attribute)
+		for _, id := range assetIDs(attribute) {
+			l.retain(id) // released by unload.
+		}
+		cached := false
+		l.fetch(a, func(asset) { cached = true }) // called now if cached.
+		if cached {
+			p.done++
+			continue
+		}
+		l.waiting[a.aid()] = append(l.waiting[a.aid()], p)
+	}
+	if previous != nil {
+		l.releaseGroup(previous) // after retaining any shared assets.
+	}
+}
+
+// unload releases the assets of the named preload group.
+// Assets are evicted once no model or group uses them.
+func (l *loader) unload(group string) {
+	if p, ok := l.groups[group]; ok {
+		delete(l.groups, group)
+		l.releaseGroup(p)
+	}
+}
+
+// releaseGroup drops the group use of its assets and stops
+// tracking the group import progress.
+func (l *loader) releaseGroup(p *preload) {
+	for _, attribute := range p.assets {
+		for _, id := range assetIDs(attribute) {
+			l.drop(id)
+		}
+	}
+	p.assets = nil
+	for id, groups := range l.waiting {
+		for cnt := len(groups) - 1; cnt >= 0; cnt-- {
+			if groups[cnt] == p {
+				groups = append(groups[:cnt], groups[cnt+1:]...)
+			}
+		}
+		if l.waiting[id] = groups; len(groups) == 0 {
+			delete(l.waiting, id)
+		}
+	}
+}
+
+// progress updates the preload groups that are waiting
+// for the identified asset import.
+func (l *loader) progress(id aid, failed bool) {
+	for _, p := range l.waiting[id] {
+		if failed {
+			p.failed++
+		} else {
+			p.done++
+		}
+	}
+	delete(l.waiting, id)
+}
+
+// notify runs the callback for finished preload groups.
+func (l *loader) notify() {
+	for _, p := range l.groups {
+		if !p.notified && p.done+p.failed >= p.total {
+			p.notified = true
+			if p.loaded != nil {
+				p.loaded(p.name)
+			}
+		}
+	}
+}
+
+// preloadAsset returns a new, unloaded, asset for
+// a model asset attribute like "msh:name".
+func preloadAsset(attribute string) asset {
+	attr := strings.Split(attribute, ":")
+	if len(attr) != 2 {
+		return nil
+	}
+	name := attr[1]
+	switch attr[0] {
+	case "msh":
+		return newMesh(name)
+	case "mat":
+		return newMaterial(name)
+	case "tex":
+		return newTexture(name)
+	case "cub":
+		return newCubemap(name)
+	case "shd":
+		return newShader(name)
+	case "fnt":
+		return newFont(name)
+	case "anm":
+		return newAnimation(name)
+	case "snd":
+		return newSound(name)
+	}
+	return nil
+}
+
+// preload tracks the import progress of a group of assets.
+type preload struct {
+	name     string       // Unique group name.
+	assets   []string     // Asset attributes used by the group.
+	total    int          // Number of assets in the group.
+	done     int          // Number of assets loaded.
+	failed   int          // Number of assets that could not be loaded.
+	loaded   func(string) // Called once when the group is finished.
+	notified bool         // True once loaded has been called.
+}
+
+// watch checks, about once a second, for asset files that have
+// changed on disk. Changed files that match cached assets are sent
+// to the import workers. Only Locators that read directly from disk,
+// ie: development builds, report changed files.
+func (l *loader) watch() {
+	w, ok := l.loc.(load.Watcher)
+	if !ok || time.Since(l.watched) < time.Second {
+		return
+	}
+	l.watched = time.Now()
+	for _, file := range w.Changed() {
+		for _, a := range reloadAssets(file) {
+			if _, cached := l.cache[a.aid()]; cached && !l.reloading[a.aid()] {
+				l.reloading[a.aid()] = true
+				l.queue(&diskAsset{assets: []asset{a}, loc: l.loc, reload: true})
+			}
+		}
+	}
+}
+
+// reload copies a re-imported asset into the cached asset so that
+// all models using the asset see the changes. Failed imports are
+// logged and the cached asset is left as is.
+func (l *loader) reload(done *diskAsset) {
+	fresh := done.assets[0]
+	delete(l.reloading, fresh.aid())
+	if done.err != nil {
+		log.Printf("Failed to reload asset %s: %s", fresh.label(), done.err)
+		return // dev error - keep using the previous asset.
+	}
+	cached, ok := l.cache[fresh.aid()].(asset)
+	if !ok {
+		return // released while reloading.
+	}
+	switch c := cached.(type) {
 	case *shader:
-		l.cache.remove(d)
-	case *material:
-		l.cache.remove(d)
+		if c.reload == nil {
+			l.compile = append(l.compile, c)
+		}
+		c.reload = fresh.(*shader) // compiled on the main thread.
 	case *Texture:
-		l.cache.remove(d)
-	case *animation:
-		l.cache.remove(d)
-	case *sound:
-		l.cache.remove(d)
-	default:
-		log.Printf("loader.dispose unknown %T", d)
+		// Copy everything since cube maps only have faces and
+		// KTX textures have precomputed mipmaps.
+		f := fresh.(*Texture)
+		c.img, c.mips, c.faces, c.rebind = f.img, f.mips, f.faces, true
+		c.mode = c.clamp || c.filter != render.Trilinear // rebinding resets the mode.
+	case *Mesh:
+		// Keep the vao and copy into the existing data so that models
+		// continue to reference the same mesh and GPU buffers are reused.
+		// Data missing from the reload, ie: actor skinning data, is kept.
+		f := fresh.(*Mesh)
+		for lloc, fd := range f.vdata {
+			if cd, ok := c.vdata[lloc]; ok {
+				cd.Set(fd)
+				continue
+			}
+			c.vdata[lloc] = fd
+		}
+		if c.faces != nil && f.faces != nil {
+			c.faces.Set(f.faces)
+		} else {
+			c.faces = f.faces
+		}
+		c.rebind = true
+	case *material:
+		f := fresh.(*material)
+		c.kd, c.ka, c.ks, c.ns, c.tr = f.kd, f.ka, f.ks, f.ns, f.tr
+	case *font:
+		f := fresh.(*font)
+		c.w, c.h, c.chars = f.w, f.h, f.chars
+	}
+	l.reloaded = append(l.reloaded, cached)
+}
+
+// rebind compiles reloaded shaders on the main thread. This is done
+// here, rather than by models, so that every use of a shader is updated.
+func (l *loader) rebind(eng *engine) {
+	for _, s := range l.compile {
+		if s.reload != nil {
+			s.recompile(eng)
+		}
+	}
+	l.compile = l.compile[:0] // reset keeping allocated memory.
+}
+
+// reloads returns the cached assets that have been
+// reloaded since the last call.
+func (l *loader) reloads() (assets []asset) {
+	assets, l.reloaded = l.reloaded, nil
+	return assets
+}
+
+// reloadAssets returns new, unloaded, assets for the given file name.
+// Nothing is returned for file types that are not reloaded.
+func reloadAssets(file string) []asset {
+	ext := path.Ext(file)
+	name := strings.TrimSuffix(file, ext)
+	switch strings.ToLower(ext) {
+	case ".vsh", ".fsh":
+		return []asset{newShader(name)}
+	case ".png", ".jpg", ".jpeg", ".tga", ".bmp", ".ktx":
+		assets := []asset{newTexture(name), newCubemap(name)}
+		for _, suffix := range load.CubeFaces {
+			if strings.HasSuffix(name, suffix) {
+				assets = append(assets, newCubemap(strings.TrimSuffix(name, suffix)))
+			}
+		}
+		return assets
+	case ".obj", ".vmsh":
+		return []asset{newMesh(name)}
+	case ".gltf", ".glb":
+		return []asset{newMesh(name), newMaterial(name)}
+	case ".mtl":
+		return []asset{newMaterial(name)}
+	case ".fnt":
+		return []asset{newFont(name)}
+	}
+	return nil
+}
+
+// retain counts another user of the identified asset.
+func (l *loader) retain(id aid) { l.refs[id]++ }
+
+// drop removes a user of the identified asset. The asset is evicted
+// from the cache once it has no more users. Assets that are still
+// being imported are discarded when the import finishes.
+func (l *loader) drop(id aid) {
+	refs, ok := l.refs[id]
+	if !ok {
+		return // asset was not counted.
+	}
+	if refs > 1 {
+		l.refs[id] = refs - 1
+		return
 	}
+	if _, pending := l.pending[id]; pending {
+		l.refs[id] = 0 // checked when the import finishes.
+		return
+	}
+	delete(l.refs, id)
+	if a, ok := l.cache[id].(asset); ok {
+		l.cache.remove(a)
+		switch a.(type) {
+		case *Mesh, *shader, *Texture:
+			l.evicted = append(l.evicted, a) // has GPU data.
+		}
+	}
+}
+
+// release is called on the main thread to free the GPU
+// data for assets that have been evicted from the cache.
+func (l *loader) release(eng *engine) {
+	for _, a := range l.evicted {
+		eng.release(a)
+	}
+	l.evicted = l.evicted[:0] // reset keeping allocated memory.
+}
+
+// resident returns the cached assets sorted by type and name.
+func (l *loader) resident() []Resource {
+	resources := []Resource{}
+	for id, data := range l.cache {
+		if a, ok := data.(asset); ok {
+			resources = append(resources, Resource{
+				Kind:  assetKinds[id.kind()],
+				Name:  a.label(),
+				Refs:  l.refs[id],
+				Bytes: assetSize(a),
+			})
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		return resources[i].Name < resources[j].Name
+	})
+	return resources
 }
 
 // dispose is called when the engine is shutting down.
@@ -203,6 +583,7 @@ type diskAsset struct {
 	assets []asset      // asset instances to be loaded.
 	err    error        // used to report asset import errors.
 	loc    load.Locator // helper to find assets on disk.
+	reload bool         // true if replacing a cached asset.
 }
 
 // importer imports assets from persistent store. Currently persistent
@@ -284,12 +665,21 @@ func importMesh(loc load.Locator, m *Mesh) error {
 
 // importTexture transfers data loaded from disk to the render object.
 func importTexture(loc load.Locator, t *Texture) error {
+	if t.isCube() {
+		cube := &load.CubeData{}
+		if err := cube.Load(t.name, loc); err != nil {
+			return fmt.Errorf("importTexture %s: %s", t.name, err)
+		}
+		t.SetFaces(cube.Faces[:]...)
+		return nil
+	}
 	img := &load.ImgData{}
 	err := img.Load(t.name, loc)
 	if err != nil {
 		return fmt.Errorf("importTexture %s: %s", t.name, err)
 	}
 	t.Set(img.Img)
+	t.mips = img.Mips
 	return nil
 }
 
@@ -383,6 +773,9 @@ func transferAnim(data *load.ModData, m *Mesh, a *animation) {
 				f0:   int(ia.F0),
 				fn:   int(ia.Fn),
 				rate: float64(ia.Rate)}
+			for _, ev := range ia.Events {
+				movement.events = append(movement.events, event{name: ev.Name, frame: float64(ev.Frame)})
+			}
 			moves = append(moves, movement)
 		}
 		a.setData(data.Frames, data.Joints, moves)
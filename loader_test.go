@@ -0,0 +1,386 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gazed/vu/load"
+	"github.com/gazed/vu/render"
+)
+
+// Check that changed files update the cached assets.
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "tint.mtl")
+	ioutil.WriteFile(file, []byte("Kd 1 0 0\n"), 0644)
+	l := newLoader(nil)
+	defer l.dispose()
+	l.wait = true
+	l.loc.Dir("MTL", dir).Dir("FSH", dir)
+	var mat *material
+	l.fetch(newMaterial("tint"), func(a asset) { mat = a.(*material) })
+	l.processImports()
+	if mat == nil || mat.kd.R != 1 {
+		t.Fatalf("Expected material to load")
+	}
+
+	// change the file and force a check for changes.
+	ioutil.WriteFile(file, []byte("Kd 0 1 0\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(file, later, later)
+	ioutil.WriteFile(filepath.Join(dir, "fire.fsh"), []byte("void main() {}"), 0644)
+	l.watched = time.Time{}
+	l.processImports()
+	if mat.kd.R != 0 || mat.kd.G != 1 {
+		t.Errorf("Expected reloaded material got %v", mat.kd)
+	}
+	if reloaded := l.reloads(); len(reloaded) != 1 || reloaded[0] != mat {
+		t.Errorf("Expected the cached material to be reloaded got %v", reloaded)
+	}
+	if len(l.reloads()) != 0 {
+		t.Errorf("Expected reloads to be cleared")
+	}
+}
+
+// Check that reloads update cached assets in place.
+func TestReloadInPlace(t *testing.T) {
+	l := newLoader(nil)
+	defer l.dispose()
+	msh := newMesh("box")
+	msh.InitData(0, 3, render.StaticDraw, false).SetData(0, []float32{0, 0, 0})
+	msh.InitData(4, 4, render.StaticDraw, false).SetData(4, []byte{1, 2, 3, 4})
+	msh.InitFaces(render.StaticDraw).SetFaces([]uint16{0, 0, 0})
+	verts, faces := msh.vdata[0], msh.faces
+	cube := newCubemap("sky")
+	cube.faces = []image.Image{image.NewRGBA(image.Rect(0, 0, 1, 1))}
+	shd := newShader("fire")
+	l.cache.store(msh)
+	l.cache.store(cube)
+	l.cache.store(shd)
+
+	// reload the mesh without skinning data.
+	fresh := newMesh("box")
+	fresh.InitData(0, 3, render.StaticDraw, false).SetData(0, []float32{0, 0, 0, 1, 1, 1})
+	fresh.InitFaces(render.StaticDraw).SetFaces([]uint16{0, 1, 0})
+	l.reload(&diskAsset{assets: []asset{fresh}, reload: true})
+	if msh.vdata[0] != verts || verts.Len() != 2 || msh.faces != faces || msh.vdata[4] == nil || !msh.rebind {
+		t.Errorf("Expected mesh data to be updated in place")
+	}
+
+	// reload the cube map faces.
+	freshCube := newCubemap("sky")
+	freshCube.faces = []image.Image{image.NewRGBA(image.Rect(0, 0, 2, 2))}
+	l.reload(&diskAsset{assets: []asset{freshCube}, reload: true})
+	if len(cube.faces) != 1 || cube.faces[0] != freshCube.faces[0] || !cube.rebind {
+		t.Errorf("Expected cube map faces to be reloaded")
+	}
+
+	// shaders are compiled on the main thread.
+	l.reload(&diskAsset{assets: []asset{newShader("fire")}, reload: true})
+	l.reload(&diskAsset{assets: []asset{newShader("fire")}, reload: true})
+	if len(l.compile) != 1 || l.compile[0] != shd || shd.reload == nil {
+		t.Errorf("Expected one shader waiting for compile")
+	}
+}
+
+// Check that many import requests don't block the update loop.
+func TestImportQueue(t *testing.T) {
+	l := newLoader(nil)
+	defer l.dispose()
+	l.wait = true
+	for cnt := 0; cnt < 500; cnt++ {
+		l.fetch(newMaterial(fmt.Sprintf("missing%d", cnt)), func(asset) {})
+	}
+	if len(l.queued) != 500 {
+		t.Errorf("Expected imports to be queued, got %d", len(l.queued))
+	}
+	l.processImports()
+	if len(l.queued) != 0 || len(l.pending) != 0 {
+		t.Errorf("Expected all imports to finish")
+	}
+}
+
+// Check that model assets are evicted once no model uses them.
+func TestResident(t *testing.T) {
+	ra := &residentApp{}
+	if err := RunHeadless(ra, Headless{Ticks: 3}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(ra.shared) != 1 || ra.shared[0].Refs != 2 || ra.shared[0].Bytes == 0 {
+		t.Errorf("Expected one shader used twice got %v", ra.shared)
+	}
+	if len(ra.single) != 1 || ra.single[0].Refs != 1 {
+		t.Errorf("Expected one shader used once got %v", ra.single)
+	}
+	if len(ra.none) != 0 {
+		t.Errorf("Expected shader to be evicted got %v", ra.none)
+	}
+}
+
+// residentApp disposes models one at a time.
+type residentApp struct {
+	models               []*Ent
+	shared, single, none []Resource
+}
+
+// Create two models that share a shader.
+func (ra *residentApp) Create(eng Eng, s *State) {
+	scene := eng.AddScene()
+	ra.models = append(ra.models, scene.AddPart().MakeModel("colored"))
+	ra.models = append(ra.models, scene.AddPart().MakeModel("colored"))
+}
+
+// Update disposes a model each update.
+func (ra *residentApp) Update(eng Eng, in *Input, s *State) {
+	switch in.Ut {
+	case 1:
+		ra.shared = eng.Resident()
+		ra.models[0].Dispose()
+		ra.single = eng.Resident()
+	case 2:
+		ra.models[1].Dispose()
+		ra.none = eng.Resident()
+	}
+}
+
+// Check that preload groups report progress and finish once.
+func TestPreload(t *testing.T) {
+	pa := &preloadApp{}
+	if err := RunHeadless(pa, Headless{Ticks: 3}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if pa.finished != 1 {
+		t.Errorf("Expected group to finish once got %d", pa.finished)
+	}
+	if pa.loaded != 2 || pa.failed != 2 || pa.total != 4 {
+		t.Errorf("Expected 2 loaded 2 failed got %d %d %d", pa.loaded, pa.failed, pa.total)
+	}
+	if !pa.started || pa.cached != 1 {
+		t.Errorf("Expected progress for started and cached groups")
+	}
+}
+
+// preloadApp preloads a group of assets.
+type preloadApp struct {
+	finished, cached      int
+	loaded, failed, total int
+	started               bool     // Progress available before loading.
+	assets                []string // Assets to preload.
+}
+
+// Create starts preloading assets where two are missing.
+func (pa *preloadApp) Create(eng Eng, s *State) {
+	if len(pa.assets) == 0 {
+		pa.assets = []string{"shd:colored", "shd:textured", "shd:missing", "bad"}
+	}
+	eng.Preload("level", func(group string) { pa.finished++ }, pa.assets...)
+	pa.loaded, pa.failed, pa.total = eng.Progress("level")
+	pa.started = pa.failed == 1 && pa.total == 4 // bad asset fails immediately.
+}
+
+// Update checks group progress.
+func (pa *preloadApp) Update(eng Eng, in *Input, s *State) {
+	pa.loaded, pa.failed, pa.total = eng.Progress("level")
+	if in.Ut == 1 {
+		eng.Preload("cached", func(group string) { pa.cached++ }, "shd:colored")
+	}
+}
+
+// Check that preloaded assets stay cached until their group is unloaded.
+func TestUnload(t *testing.T) {
+	ua := &unloadApp{}
+	if err := RunHeadless(ua, Headless{Ticks: 4}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(ua.loaded) != 1 || ua.loaded[0].Name != "colored" || ua.loaded[0].Refs != 1 {
+		t.Errorf("Expected preloaded shader with one reference got %v", ua.loaded)
+	}
+	if len(ua.unloaded) != 0 {
+		t.Errorf("Expected no assets after unload got %v", ua.unloaded)
+	}
+}
+
+// unloadApp preloads a shader and then unloads it.
+type unloadApp struct {
+	loaded, unloaded []Resource
+}
+
+// Create preloads a group with a single shader.
+func (ua *unloadApp) Create(eng Eng, s *State) {
+	eng.Preload("level", nil, "shd:colored")
+}
+
+// Update unloads the group once it has loaded.
+func (ua *unloadApp) Update(eng Eng, in *Input, s *State) {
+	switch in.Ut {
+	case 2:
+		ua.loaded = eng.Resident()
+		eng.Unload("level")
+	case 3:
+		ua.unloaded = eng.Resident()
+	}
+}
+
+// Check that the engine uses the given Locator.
+func TestRunWithLocator(t *testing.T) {
+	fsys := fstest.MapFS{"models/tint.mtl": {Data: []byte("Kd 1 0 0\n")}}
+	pa := &preloadApp{assets: []string{"mat:tint"}}
+	if err := RunHeadless(pa, Headless{Ticks: 2, Loc: load.NewFSLocator("test", fsys)}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if pa.loaded != 1 || pa.failed != 0 {
+		t.Errorf("Expected material from locator got %d %d", pa.loaded, pa.failed)
+	}
+}
+
+// Check that loaded mipmaps and texture filters reach the render context.
+func TestTextureMipmaps(t *testing.T) {
+	ktx := &bytes.Buffer{}
+	ktx.Write([]byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'})
+	binary.Write(ktx, binary.LittleEndian, []uint32{0x04030201, 0x1401, 1, 0x1908, 0x8058, 0x1908, 2, 2, 0, 0, 1, 2, 0})
+	binary.Write(ktx, binary.LittleEndian, uint32(16))
+	ktx.Write(make([]byte, 16)) // 2x2 RGBA
+	binary.Write(ktx, binary.LittleEndian, uint32(4))
+	ktx.Write(make([]byte, 4)) // 1x1 RGBA
+	fsys := fstest.MapFS{"images/ground.ktx": {Data: ktx.Bytes()}}
+	rec := render.NewRecorder(&render.NoRender{})
+	if err := RunHeadless(&textureApp{}, Headless{Ticks: 3, Gc: rec, Loc: load.NewFSLocator("test", fsys)}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	binds, modes := 0, 0
+	for _, c := range rec.Calls {
+		switch {
+		case c.Op == "BindTexture" && len(c.Attrs) == 3 && c.Attrs[2] == 1:
+			binds++
+		case c.Op == "SetTextureMode" && len(c.Attrs) == 2 && c.Attrs[0] == 1 && c.Attrs[1] == render.Nearest:
+			modes++
+		}
+	}
+	if binds != 1 || modes != 1 {
+		t.Errorf("Expected one mipmapped, clamped, nearest texture got %d %d", binds, modes)
+	}
+}
+
+// textureApp uses a clamped and filtered texture.
+type textureApp struct{}
+
+// Create a model with a texture.
+func (ta *textureApp) Create(eng Eng, s *State) {
+	m := eng.AddScene().AddPart().MakeModel("colored", "tex:ground")
+	m.Clamp("ground").Filter("ground", render.Nearest)
+	genTriangle(m, "ground")
+}
+
+// Update does nothing.
+func (ta *textureApp) Update(eng Eng, in *Input, s *State) {}
+
+// Check that a hot reloaded texture keeps its clamp and filter.
+func TestReloadTextureMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "images"), 0755)
+	ra := &reloadTextureApp{file: filepath.Join(dir, "images", "ground.png")}
+	ra.write(2)
+	rec := render.NewRecorder(&render.NoRender{})
+	if err := RunHeadless(ra, Headless{Ticks: 4, Gc: rec, Loc: load.NewDirLocator(dir)}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	sizes, modes := []int{}, 0
+	for _, c := range rec.Calls {
+		switch {
+		case c.Op == "BindTexture":
+			sizes = append(sizes, c.Attrs[0])
+		case c.Op == "SetTextureMode" && c.Attrs[0] == 1 && c.Attrs[1] == render.Nearest:
+			if len(sizes) != modes+1 {
+				t.Errorf("Expected the mode to follow each bind")
+			}
+			modes++
+		}
+	}
+	if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 4 || modes != 2 {
+		t.Errorf("Expected reloaded, clamped, nearest texture got %v %d", sizes, modes)
+	}
+}
+
+// reloadTextureApp changes a clamped and filtered texture on disk.
+type reloadTextureApp struct {
+	textureApp
+	file string // Texture image file.
+}
+
+// write saves a size by size texture image.
+func (ra *reloadTextureApp) write(size int) {
+	img := &bytes.Buffer{}
+	png.Encode(img, image.NewNRGBA(image.Rect(0, 0, size, size)))
+	ioutil.WriteFile(ra.file, img.Bytes(), 0644)
+	later := time.Now().Add(time.Duration(size) * time.Minute)
+	os.Chtimes(ra.file, later, later)
+}
+
+// Update changes the texture and forces a check for changes.
+func (ra *reloadTextureApp) Update(eng Eng, in *Input, s *State) {
+	if in.Ut == 2 {
+		ra.write(4)
+		eng.(*application).ld.watched = time.Time{}
+	}
+}
+
+// Check that cube maps are loaded from a cross image and generated
+// from faces, then bound as cube maps.
+func TestCubemap(t *testing.T) {
+	cross := &bytes.Buffer{}
+	png.Encode(cross, image.NewNRGBA(image.Rect(0, 0, 8, 6)))
+	fsys := fstest.MapFS{"images/sky.png": {Data: cross.Bytes()}}
+	rec := render.NewRecorder(&render.NoRender{})
+	if err := RunHeadless(&cubeApp{}, Headless{Ticks: 3, Gc: rec, Loc: load.NewFSLocator("test", fsys)}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	sizes := []int{}
+	for _, c := range rec.Calls {
+		if c.Op == "BindCubemap" && len(c.Attrs) == 2 && c.Attrs[1] == 6 {
+			sizes = append(sizes, c.Attrs[0])
+		}
+	}
+	if len(sizes) != 2 || sizes[0]+sizes[1] != 2+4 {
+		t.Errorf("Expected loaded and generated cube maps got %v", sizes)
+	}
+}
+
+// cubeApp uses a loaded cube map sky and a generated cube map planet.
+type cubeApp struct{}
+
+// Create a sky and a planet.
+func (ca *cubeApp) Create(eng Eng, s *State) {
+	scene := eng.AddScene()
+	sky := scene.AddSky().MakeModel("cubemapped", "cub:sky")
+	genTriangle(sky, "dome")
+	planet := scene.AddPart().SetAt(0, 0, -5).MakeModel("cubemapped")
+	genTriangle(planet, "planet")
+	faces := []image.Image{}
+	for cnt := 0; cnt < 6; cnt++ {
+		faces = append(faces, image.NewNRGBA(image.Rect(0, 0, 4, 4)))
+	}
+	planet.GenCube("planet").SetFaces(faces...)
+}
+
+// Update does nothing.
+func (ca *cubeApp) Update(eng Eng, in *Input, s *State) {}
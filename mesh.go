@@ -113,8 +113,10 @@ func (m *Mesh) InitFaces(usage uint32) *Mesh {
 
 // SetFaces stores data for a triangle face index buffer.
 // May be called one or more times after a one-time call to InitFaces.
+// Data is expected as []uint16 or []uint32. 32-bit indicies are used
+// only for meshes with more verticies than fit in 16-bits.
 // Marks the mesh as needing a rebind.
-func (m *Mesh) SetFaces(data []uint16) {
+func (m *Mesh) SetFaces(data interface{}) {
 	if m.faces != nil {
 		m.faces.Set(data)
 		m.rebind = true
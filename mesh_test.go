@@ -26,3 +26,19 @@ func TestClone(t *testing.T) {
 		t.Errorf("clone failed vdata %d %d", c.vdata[1].Len(), m.vdata[1].Len())
 	}
 }
+
+// Check that 32-bit face indicies are only kept when needed.
+func TestWideFaces(t *testing.T) {
+	m := newMesh("meshTest")
+	m.InitFaces(StaticDraw).SetFaces([]uint32{0, 1, 2})
+	if m.faces.Size() != 6 || m.faces.Len() != 3 {
+		t.Errorf("Expected 16-bit faces got %d bytes", m.faces.Size())
+	}
+	m.SetFaces([]uint32{0, 1, 70000})
+	if m.faces.Size() != 12 || m.faces.Len() != 3 {
+		t.Errorf("Expected 32-bit faces got %d bytes", m.faces.Size())
+	}
+	if c := m.clone(); c.faces.Size() != 12 {
+		t.Errorf("clone failed faces %d", c.faces.Size())
+	}
+}
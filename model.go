@@ -7,7 +7,6 @@ package vu
 
 import (
 	"log"
-	"math"
 	"strings"
 	"time"
 
@@ -23,6 +22,7 @@ import (
 //    shd: first parameter, "name" of shader - one per model.
 //    msh: "msh:name" - one per model.
 //    tex: "tex:name" - 0 to 14 per model.
+//    cub: "cub:name" - cube map texture. Counts as a tex.
 //    mat: "mat:name" - 0 or 1 per model.
 //
 // A model manages rendered 3D objects. It is the link between loaded
@@ -87,6 +87,7 @@ func (e *Ent) DrawInstances(instances int) *Ent {
 // Names must be unique within an asset type.
 //    msh: "msh:name" - one per model.
 //    tex: "txt:name" - 0 to 14 per model.
+//    cub: "cub:name" - cube map texture. Counts as a tex.
 //    mat: "mat:name" - 0 to 1 per model.
 //
 // Depends on Ent.MakeModel.
@@ -166,6 +167,25 @@ func (e *Ent) GenTex(name string) *Texture {
 	return nil
 }
 
+// GenCube is used to create a cube map texture where the six faces
+// are filled by the application instead of the loader, ie: from
+// synth.Land.Fill3D tiles. See Texture.SetFaces. Can be called on
+// an existing model entity.
+//
+// Depends on Ent.MakeModel. Returns nil if missing model component.
+func (e *Ent) GenCube(name string) *Texture {
+	if m := e.app.models.get(e.eid); m != nil {
+		t := newCubemap(name)
+		m.texs = append(m.texs, t)
+		m.tpos[name] = len(m.texs) - 1
+		m.track[tex] = m.track[tex] + 1
+		e.app.models.rebinds[e.eid] = m
+		return t
+	}
+	log.Printf("GenCube needs MakeModel %d", e.eid)
+	return nil
+}
+
 // SetTex assigns the model a texture that has been generated
 // from a scene and which already exists on the GPU.
 // Ignored if there is already a texture assigned to the model.
@@ -276,6 +296,22 @@ func (e *Ent) Clamp(name string) *Ent {
 	return e
 }
 
+// Filter sets how a texture is sampled when it is drawn larger or
+// smaller than the texture image. The filter is one of:
+//    render.Trilinear: default. Smooth with generated or loaded mipmaps.
+//    render.Bilinear : nearest mipmap, slightly sharper and faster.
+//    render.Linear   : no mipmaps. Distant textures may shimmer.
+//    render.Nearest  : no mipmaps or smoothing, ie: pixel art.
+// Like Clamp, expected to be called after a model with texture assets
+// has been defined, but before the model assets have been loaded.
+func (e *Ent) Filter(name string, filter int) *Ent {
+	if e.app.models.filters[e.eid] == nil {
+		e.app.models.filters[e.eid] = map[string]int{}
+	}
+	e.app.models.filters[e.eid][name] = filter
+	return e
+}
+
 // model entity methods.
 // =============================================================================
 // model data
@@ -314,6 +350,7 @@ type model struct {
 
 	time     time.Time            // Shader uniform.
 	uniforms map[string][]float32 // Shader uniform names and data.
+	assets   []string             // Requested assets, ie: "msh:name".
 }
 
 // newModel initializes the data structures and default uniforms.
@@ -511,8 +548,9 @@ type models struct {
 	// Texture clamps are textures that need clamping. Need to remember
 	// which ones because the request often happens when the texture
 	// asset is away for loading. They are processed once the texture
-	// is loaded.
-	clamps map[eid][]string // Texture clamps.
+	// is loaded. Texture filters are remembered for the same reason.
+	clamps  map[eid][]string       // Texture clamps.
+	filters map[eid]map[string]int // Texture filters by texture name.
 }
 
 // newModels creates the render model component manager.
@@ -528,6 +566,7 @@ func newModels() *models {
 	ms.effects = map[eid]*effect{} // optional particle data.
 	ms.labels = map[eid]*label{}   // optional label data.
 	ms.clamps = map[eid][]string{} // optional texture clamps
+	ms.filters = map[eid]map[string]int{}
 	return ms
 }
 
@@ -596,6 +635,10 @@ func (ms *models) loadAssets(e *Ent, m *model, assets ...string) {
 			continue
 		}
 		name := attr[1]
+		m.assets = append(m.assets, attribute)
+		for _, id := range assetIDs(attribute) {
+			ld.retain(id) // released in dispose.
+		}
 		switch attr[0] {
 		case "msh": // static model.
 			m.track[msh] = 1
@@ -607,6 +650,10 @@ func (ms *models) loadAssets(e *Ent, m *model, assets ...string) {
 			m.tpos[name] = len(m.tpos)
 			m.track[tex] = m.track[tex] + 1
 			defer ld.fetch(newTexture(name), callback)
+		case "cub": // cube map texture.
+			m.tpos[name] = len(m.tpos)
+			m.track[tex] = m.track[tex] + 1
+			defer ld.fetch(newCubemap(name), callback)
 		case "shd": // shader.
 			m.track[shd] = 1
 			defer ld.fetch(newShader(name), callback)
@@ -779,12 +826,19 @@ func (ms *models) getReady(eid eid) *model { return ms.ready[eid] }
 
 // animate updates the animations. Needs to be called each update tick.
 // Animations are always updated even if they are not rendered.
-func (ms *models) animate(dt float64) {
-	for _, a := range ms.acting {
-		a.frame = a.anm.animate(dt, a.frame, a.move, a.pose)
-		nextFrame := int(math.Floor(a.frame + 1))
-		if nextFrame >= a.nFrames {
-			a.frame -= float64(a.nFrames - 1)
+// Actors with root motion move their parts.
+func (ms *models) animate(dt float64, povs *povs) {
+	for eid, a := range ms.acting {
+		a.drift.SetS(0, 0, 0)
+		a.animate(dt)
+		if d := a.drift; d.X != 0 || d.Y != 0 || d.Z != 0 {
+			if p := povs.get(eid); p != nil {
+				dx, dy, dz := lin.MultSQ(d.X*p.sn.X, d.Y*p.sn.Y, d.Z*p.sn.Z, p.tn.Rot)
+				p.tn.Loc.X += dx
+				p.tn.Loc.Y += dy
+				p.tn.Loc.Z += dz
+				povs.updateWorld(p, eid)
+			}
 		}
 	}
 }
@@ -825,7 +879,39 @@ func (ms *models) processTexture(eid eid, m *model, t *Texture) {
 	if clamps, ok := ms.clamps[eid]; ok {
 		for _, name := range clamps {
 			if t.name == name {
-				t.clamp = true
+				t.clamp, t.mode = true, true
+			}
+		}
+	}
+	if filter, ok := ms.filters[eid][t.name]; ok {
+		t.filter, t.mode = filter, true
+	}
+}
+
+// reload marks the models that use reloaded assets for rebinding.
+// Labels are regenerated when their font has been reloaded.
+func (ms *models) reload(assets []asset) {
+	for _, a := range assets {
+		for eid, m := range ms.all {
+			switch ra := a.(type) {
+			case *shader:
+				if m.shd == ra {
+					ms.rebinds[eid] = m
+				}
+			case *Mesh:
+				if m.msh == ra {
+					ms.rebinds[eid] = m
+				}
+			case *Texture:
+				for _, t := range m.texs {
+					if t == ra {
+						ms.rebinds[eid] = m
+					}
+				}
+			case *font:
+				if l := ms.getLabel(eid); l != nil && l.fnt == ra {
+					ms.updateLabel(eid, l, m)
+				}
 			}
 		}
 	}
@@ -853,9 +939,9 @@ func (ms *models) rebind(eng *engine) {
 					log.Printf("Bind texture %s failed : %s", t.name, err)
 				}
 			}
-			if t.clamp {
-				eng.clampTex(t.tid)
-				t.clamp = false // HACK: only do once.
+			if t.mode {
+				eng.texMode(t)
+				t.mode = false // only do once.
 			}
 		}
 		// model removed once all outstanding rebinds are completed.
@@ -879,9 +965,15 @@ func (ms *models) draw(eid eid, m *model, d *render.Draw, p *pov, sc *scene) {
 }
 
 // dispose of the model, removing it from all of the maps.
-// There is no easy way of knowing when to delete the related assets.
-// Leave that to the application.
-func (ms *models) dispose(eid eid) {
+// The loader evicts model assets that are no longer used.
+func (ms *models) dispose(eid eid, ld *loader) {
+	if m := ms.all[eid]; m != nil {
+		for _, attribute := range m.assets {
+			for _, id := range assetIDs(attribute) {
+				ld.drop(id)
+			}
+		}
+	}
 	delete(ms.all, eid)
 	delete(ms.loading, eid)
 	delete(ms.ready, eid)
@@ -891,6 +983,34 @@ func (ms *models) dispose(eid eid) {
 	delete(ms.effects, eid)
 	delete(ms.labels, eid)
 	delete(ms.clamps, eid)
+	delete(ms.filters, eid)
+}
+
+// assetIDs returns the cached asset identifiers for a model asset
+// attribute like "msh:name". Animations also cache a mesh.
+func assetIDs(attribute string) []aid {
+	attr := strings.Split(attribute, ":")
+	if len(attr) != 2 {
+		return nil
+	}
+	name := attr[1]
+	switch attr[0] {
+	case "msh":
+		return []aid{assetID(msh, name)}
+	case "mat":
+		return []aid{assetID(mat, name)}
+	case "tex":
+		return []aid{assetID(tex, name)}
+	case "cub":
+		return []aid{assetID(cub, name)}
+	case "shd":
+		return []aid{assetID(shd, name)}
+	case "fnt":
+		return []aid{assetID(fnt, name)}
+	case "anm":
+		return []aid{assetID(anm, name), assetID(msh, name)}
+	}
+	return nil
 }
 
 // stats returns the number of all models. Used by profile.go.
@@ -43,6 +43,10 @@ type Body interface {
 	//                 colliding bodies. If one of the bodies has 0
 	//                 bounciness then there is no bounce effect.
 	SetProps(mass, bounciness float64) Body
+
+	// Props returns the mass and bounciness last set using SetProps.
+	// Static bodies have zero mass.
+	Props() (mass, bounciness float64)
 }
 
 // Body interface
@@ -159,6 +163,12 @@ func (b *body) Turn(x, y, z float64) {
 func (b *body) SetProps(mass, bounciness float64) Body {
 	return b.setProps(mass, bounciness)
 }
+func (b *body) Props() (mass, bounciness float64) {
+	if b.imass != 0 {
+		mass = 1.0 / b.imass
+	}
+	return mass, b.restitution
+}
 func (b *body) setProps(mass, bounciness float64) *body {
 	b.imass = 0 // static unless there is mass.
 	if !lin.AeqZ(mass) {
@@ -26,6 +26,12 @@ func TestSphereProperties(t *testing.T) {
 		t.Errorf("Expecting initial inverse inertia %s", dumpV3(b.iit))
 	}
 }
+func TestProps(t *testing.T) {
+	b := newBody(NewSphere(1)).SetProps(0.5, 0.8)
+	if mass, bounce := b.Props(); !lin.Aeq(mass, 0.5) || bounce != 0.8 {
+		t.Errorf("Expecting mass 0.5 bounce 0.8, got %f %f", mass, bounce)
+	}
+}
 func TestBoxProperties(t *testing.T) {
 	b := newBody(NewBox(100, 1, 100)).SetProps(0, 0.1).(*body)
 	if b.movable == true || b.imass != 0.0 {
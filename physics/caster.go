@@ -13,22 +13,52 @@ import (
 	"github.com/gazed/vu/math/lin"
 )
 
+// Hit is the result of a ray cast or sweep that touched a body.
+type Hit struct {
+	Body   Body    // Body that was hit.
+	Point  lin.V3  // World space point of contact on the body.
+	Normal lin.V3  // World space surface normal facing the ray or swept body.
+	Dist   float64 // Distance from the ray origin, or sweep start, to the hit.
+}
+
 // cast is the function prototype for ray casting algorithms. It takes two
-// Solids, expecting the first solid to be a ray. It returns the nearest point
-// of contact, if any.
+// Solids, expecting the first solid to be a ray. It returns true and fills
+// in the nearest point of contact, if any.
 //    r   : Ray.
 //    f   : Form (shape + location and orientation).
-//    hit : true if the ray hit the Geometry.
-//    xyz : Point of contact when hit is true.
-type cast func(r, f Body) (hit bool, x, y, z float64)
+//    hit : Updated with the point of contact when the ray hits.
+type cast func(r, f Body, hit *Hit) bool
 
 // rayCastAlgorithms holds the algorithms for the supported shapes that
-// a ray can be checked against.
-//
-// FUTURE: Look into adding support for boxes.
+// a ray can be checked against. Compound shapes are cast against each
+// of their children.
 var rayCastAlgorithms = map[int]cast{
-	PlaneShape:  castRayPlane,
-	SphereShape: castRaySphere,
+	PlaneShape:       castRayPlane,
+	SphereShape:      castRaySphere,
+	BoxShape:         castRayLocal,
+	CapsuleShape:     castRayLocal,
+	CylinderShape:    castRayLocal,
+	HullShape:        castRayLocal,
+	TriMeshShape:     castRayLocal,
+	HeightfieldShape: castRayLocal,
+}
+
+// castRay checks ray r against body b using the algorithm for the
+// shape of b. Hit is updated and true returned if the ray hits b.
+func castRay(r, b Body, hit *Hit) bool {
+	if r == nil || b == nil || b.Shape() == nil {
+		return false
+	}
+	if _, ok := r.Shape().(*ray); !ok {
+		return false
+	}
+	if b.Shape().Type() == CompoundShape {
+		return castRayCompound(r, b, hit)
+	}
+	if alg, ok := rayCastAlgorithms[b.Shape().Type()]; ok {
+		return alg(r, b, hit)
+	}
+	return false
 }
 
 // ============================================================================
@@ -36,29 +66,31 @@ var rayCastAlgorithms = map[int]cast{
 
 // castRayPlane calculates the point of collision between ray:a and
 // plane:b. The contact point is returned if there is an intersection.
-func castRayPlane(a, b Body) (hit bool, x, y, z float64) {
+func castRayPlane(a, b Body, hit *Hit) bool {
 	sa, sb := a.Shape().(*ray), b.Shape().(*plane)
 	la, lb := a.World().Loc, b.World().Loc
-	rdir := b.(*body).v0.SetS(sa.dx, sa.dy, sa.dz).Unit() // ray direction.
-	nrm := a.(*body).v0.SetS(sb.nx, sb.ny, sb.nz).Unit()  // plane normal.
-	nrm.MultQ(nrm, b.World().Rot)                         // apply world spin to plane normal.
+	rdir := lin.NewV3S(sa.dx, sa.dy, sa.dz).Unit() // ray direction.
+	nrm := lin.NewV3S(sb.nx, sb.ny, sb.nz).Unit()  // plane normal.
+	nrm.MultQ(nrm, b.World().Rot)                  // apply world spin to plane normal.
 	denom := rdir.Dot(nrm)
 	if lin.AeqZ(denom) || denom < 0 {
-		return false, 0, 0, 0 // plane is behind ray or ray is parallel to plane.
+		return false // plane is behind ray or ray is parallel to plane.
 	}
 
 	// calculate the difference from a point on the plane to the ray origin.
 	dx, dy, dz := rdir.X, rdir.Y, rdir.Z
-	diff := b.(*body).v0.SetS(lb.X-la.X, lb.Y-la.Y, lb.Z-la.Z)
+	diff := lin.NewV3S(lb.X-la.X, lb.Y-la.Y, lb.Z-la.Z)
 	dlen := diff.Dot(nrm) / denom
 	if dlen < 0 {
-		return false, 0, 0, 0
+		return false
 	}
 
 	// Get contact point by scaling the ray direction with the contact distance
 	// and adding the ray origin.
-	x, y, z = dx*dlen+la.X, dy*dlen+la.Y, dz*dlen+la.Z
-	return true, x, y, z
+	hit.Body, hit.Dist = b, dlen
+	hit.Point.SetS(dx*dlen+la.X, dy*dlen+la.Y, dz*dlen+la.Z)
+	hit.Normal.Neg(nrm) // the ray hits the back of the plane.
+	return true
 }
 
 // ============================================================================
@@ -67,30 +99,321 @@ func castRayPlane(a, b Body) (hit bool, x, y, z float64) {
 
 // castRaySphere calculates the point of collision between ray:a and
 // sphere:b. The closest contact point is returned if there is an intersection.
-func castRaySphere(a, b Body) (hit bool, x, y, z float64) {
+func castRaySphere(a, b Body, hit *Hit) bool {
 	sa, sb := a.Shape().(*ray), b.Shape().(*sphere)
 	la, lb := a.World().Loc, b.World().Loc
-	sc := a.(*body).v0.SetS(lb.X-la.X, lb.Y-la.Y, lb.Z-la.Z) // sphere center - ray origin
-	rdir := b.(*body).v0.SetS(sa.dx, sa.dy, sa.dz).Unit()    // ray direction.
+	sc := lin.NewV3S(lb.X-la.X, lb.Y-la.Y, lb.Z-la.Z) // sphere center - ray origin
+	rdir := lin.NewV3S(sa.dx, sa.dy, sa.dz).Unit()    // ray direction.
+	radius2 := sb.R * sb.R
+	if sc.Dot(sc) < radius2 {
+		return false // ray starts inside the sphere.
+	}
 	d0 := rdir.Dot(sc)
 	if d0 < 0 {
-		return false, 0, 0, 0 // no solutions
+		return false // no solutions
 	}
-	radius2 := sb.R * sb.R
 	d1 := sc.Dot(sc) - d0*d0
 	if d1 > radius2 {
-		return false, 0, 0, 0 // no solutions
+		return false // no solutions
 	}
 	dlen := d0 - math.Sqrt(radius2-d1)
 
 	// Get contact point by scaling the ray direction with the contact distance
 	// and adding the ray origin.
-	x, y, z = rdir.X*dlen+la.X, rdir.Y*dlen+la.Y, rdir.Z*dlen+la.Z
-	return true, x, y, z
+	hit.Body, hit.Dist = b, dlen
+	hit.Point.SetS(rdir.X*dlen+la.X, rdir.Y*dlen+la.Y, rdir.Z*dlen+la.Z)
+	hit.Normal.SetS(hit.Point.X-lb.X, hit.Point.Y-lb.Y, hit.Point.Z-lb.Z).Unit()
+	return true
 }
 
 // ============================================================================
-//
-// FUTURE:
-// https://truesculpt.googlecode.com/hg-history/Release%25200.8/Doc/ray_box_intersect.pdf
-// http://www.scratchapixel.com/lessons/3d-basic-lessons/lesson-7-intersecting-simple-shapes/ray-box-intersection/
+// ray casts in the local space of the shape.
+
+// castRayLocal moves the ray into the local space of body b so that
+// the shape can be checked without its world transform. Rays that start
+// inside a volume shape do not hit that shape.
+func castRayLocal(r, b Body, hit *Hit) bool {
+	o, d := localRay(r, b)
+	if d.AeqZ() {
+		return false
+	}
+	var t float64
+	var n lin.V3
+	var ok bool
+	switch sh := b.Shape().(type) {
+	case *box:
+		t, n, ok = rayBox(sh, &o, &d)
+	case *capsule:
+		t, n, ok = rayCapsule(sh, &o, &d)
+	case *cylinder:
+		t, n, ok = rayCylinder(sh, &o, &d)
+	case *hull:
+		t, n, ok = rayHull(sh, &o, &d)
+	case *trimesh:
+		t, n, ok = rayTriangles(sh, sh.ab, &o, &d)
+	case *heightfield:
+		t, n, ok = rayTriangles(sh, sh.ab, &o, &d)
+	}
+	if !ok {
+		return false
+	}
+	w := b.World()
+	hit.Body, hit.Dist = b, t
+	hit.Point.X, hit.Point.Y, hit.Point.Z = w.AppS(o.X+d.X*t, o.Y+d.Y*t, o.Z+d.Z*t)
+	hit.Normal.X, hit.Normal.Y, hit.Normal.Z = w.AppR(n.X, n.Y, n.Z)
+	return true
+}
+
+// localRay returns the origin and unit direction of ray r
+// in the local space of body b.
+func localRay(r, b Body) (o, d lin.V3) {
+	sr, w, lr := r.Shape().(*ray), b.World(), r.World().Loc
+	d.SetS(sr.dx, sr.dy, sr.dz).Unit()
+	o.X, o.Y, o.Z = w.InvS(lr.X, lr.Y, lr.Z)
+	dx, dy, dz := w.InvS(lr.X+d.X, lr.Y+d.Y, lr.Z+d.Z)
+	d.SetS(dx-o.X, dy-o.Y, dz-o.Z)
+	return o, d
+}
+
+// rayBox intersects the ray with the slabs between each pair of
+// opposite box faces. The ray enters the box where it has entered
+// all the slabs. See Real-Time Collision Detection 5.3.3.
+func rayBox(b *box, o, d *lin.V3) (t float64, n lin.V3, ok bool) {
+	return raySlabs([3]float64{-b.Hx, -b.Hy, -b.Hz}, [3]float64{b.Hx, b.Hy, b.Hz}, o, d)
+}
+
+// raySlabs returns the distance and normal where the ray enters the
+// axis aligned box between the small and large corners.
+func raySlabs(small, large [3]float64, o, d *lin.V3) (t float64, n lin.V3, ok bool) {
+	near, far, axis, sign := slabs(small, large, o, d)
+	if axis < 0 || near > far || near < 0 {
+		return 0, n, false // missed or inside the box.
+	}
+	switch axis {
+	case 0:
+		n.X = sign
+	case 1:
+		n.Y = sign
+	case 2:
+		n.Z = sign
+	}
+	return near, n, true
+}
+
+// slabs returns the distances along the ray where it enters and leaves
+// the axis aligned box between the small and large corners. The ray
+// misses the box if near is greater than far. The axis and sign give
+// the face where the ray enters. Axis is -1 if the ray missed.
+func slabs(small, large [3]float64, o, d *lin.V3) (near, far float64, axis int, sign float64) {
+	origin, dir := [3]float64{o.X, o.Y, o.Z}, [3]float64{d.X, d.Y, d.Z}
+	near, far, axis = -math.MaxFloat64, math.MaxFloat64, -1
+	for cnt := 0; cnt < 3; cnt++ {
+		if math.Abs(dir[cnt]) < lin.Epsilon {
+			if origin[cnt] < small[cnt] || origin[cnt] > large[cnt] {
+				return 1, 0, -1, 0 // parallel and outside the slab.
+			}
+			continue
+		}
+		t0, t1, s := (small[cnt]-origin[cnt])/dir[cnt], (large[cnt]-origin[cnt])/dir[cnt], -1.0
+		if t0 > t1 {
+			t0, t1, s = t1, t0, 1
+		}
+		if t0 > near {
+			near, axis, sign = t0, cnt, s
+		}
+		if far = math.Min(far, t1); near > far {
+			return 1, 0, -1, 0
+		}
+	}
+	return near, far, axis, sign
+}
+
+// rayCapsule checks the ray against the capsule side and end spheres.
+func rayCapsule(c *capsule, o, d *lin.V3) (t float64, n lin.V3, ok bool) {
+	y := lin.Clamp(o.Y, -c.H, c.H) // inside if close to the center line.
+	if o.X*o.X+(o.Y-y)*(o.Y-y)+o.Z*o.Z <= c.R*c.R {
+		return 0, n, false
+	}
+	t = math.MaxFloat64
+	if ts, hit := raySide(c.R, o, d); hit && math.Abs(o.Y+d.Y*ts) <= c.H {
+		t = ts
+		n.SetS((o.X+d.X*t)/c.R, 0, (o.Z+d.Z*t)/c.R)
+	}
+	for _, end := range []float64{-c.H, c.H} {
+		center := &lin.V3{X: 0, Y: end, Z: 0}
+		if ts, hit := raySphere(center, c.R, o, d); hit && ts < t {
+			t = ts
+			n.SetS(o.X+d.X*t, o.Y+d.Y*t-end, o.Z+d.Z*t).Unit()
+		}
+	}
+	return t, n, t < math.MaxFloat64
+}
+
+// rayCylinder checks the ray against the cylinder side and end caps.
+func rayCylinder(c *cylinder, o, d *lin.V3) (t float64, n lin.V3, ok bool) {
+	if o.X*o.X+o.Z*o.Z <= c.R*c.R && math.Abs(o.Y) <= c.H {
+		return 0, n, false // inside.
+	}
+	t = math.MaxFloat64
+	if ts, hit := raySide(c.R, o, d); hit && math.Abs(o.Y+d.Y*ts) <= c.H {
+		t = ts
+		n.SetS((o.X+d.X*t)/c.R, 0, (o.Z+d.Z*t)/c.R)
+	}
+	if math.Abs(d.Y) > lin.Epsilon {
+		for _, end := range []float64{-c.H, c.H} {
+			ts := (end - o.Y) / d.Y
+			x, z := o.X+d.X*ts, o.Z+d.Z*ts
+			if ts >= 0 && ts < t && x*x+z*z <= c.R*c.R {
+				t = ts
+				n.SetS(0, math.Copysign(1, end), 0)
+			}
+		}
+	}
+	return t, n, t < math.MaxFloat64
+}
+
+// raySide returns where the ray enters the infinite Y axis
+// cylinder of radius r.
+func raySide(r float64, o, d *lin.V3) (t float64, ok bool) {
+	a := d.X*d.X + d.Z*d.Z
+	if a < lin.Epsilon {
+		return 0, false // parallel to the side.
+	}
+	b, c := o.X*d.X+o.Z*d.Z, o.X*o.X+o.Z*o.Z-r*r
+	disc := b*b - a*c
+	if c < 0 || disc < 0 {
+		return 0, false // inside or missed.
+	}
+	t = (-b - math.Sqrt(disc)) / a
+	return t, t >= 0
+}
+
+// raySphere returns where the ray enters the sphere of radius r
+// at the given center. See Real-Time Collision Detection 5.3.2.
+func raySphere(center *lin.V3, r float64, o, d *lin.V3) (t float64, ok bool) {
+	oc := lin.NewV3().Sub(o, center)
+	b, c := oc.Dot(d), oc.Dot(oc)-r*r
+	if c > 0 && b > 0 {
+		return 0, false // outside and pointing away.
+	}
+	disc := b*b - c
+	if c < 0 || disc < 0 {
+		return 0, false // inside or missed.
+	}
+	t = -b - math.Sqrt(disc)
+	return t, t >= 0
+}
+
+// rayHull clips the ray by the plane of each hull face. The ray enters
+// the hull where it has passed into all the face planes.
+// Flat hulls have no faces and are not hit.
+func rayHull(h *hull, o, d *lin.V3) (t float64, n lin.V3, ok bool) {
+	near, far := -math.MaxFloat64, math.MaxFloat64
+	diff, fn := &lin.V3{}, &lin.V3{}
+	for cnt := 0; cnt+2 < len(h.faces); cnt += 3 {
+		a := &h.points[h.faces[cnt]]
+		faceNormal(fn, a, &h.points[h.faces[cnt+1]], &h.points[h.faces[cnt+2]])
+		denom, dist := fn.Dot(d), fn.Dot(diff.Sub(o, a))
+		switch {
+		case math.Abs(denom) < lin.Epsilon:
+			if dist > 0 {
+				return 0, n, false // parallel and outside the face.
+			}
+		case denom < 0: // entering.
+			if ts := -dist / denom; ts > near {
+				near = ts
+				n.Set(fn)
+			}
+		default: // leaving.
+			far = math.Min(far, -dist/denom)
+		}
+		if near > far {
+			return 0, n, false
+		}
+	}
+	return near, n, near >= 0 && near < math.MaxFloat64
+}
+
+// rayTriangles checks the ray against the triangles of a static shape.
+// The ray is clipped to the shape bounds and checked in short pieces,
+// nearest first, so that only the triangles near the ray are checked.
+// Triangles are only hit from the front.
+func rayTriangles(source triangleSource, bounds *Abox, o, d *lin.V3) (t float64, n lin.V3, ok bool) {
+	small, large := [3]float64{bounds.Sx, bounds.Sy, bounds.Sz}, [3]float64{bounds.Lx, bounds.Ly, bounds.Lz}
+	start, end, _, _ := slabs(small, large, o, d)
+	if start > end || end < 0 {
+		return 0, n, false // missed the bounds.
+	}
+	start = math.Max(start, 0)
+
+	// check the pieces of the ray in order.
+	size := math.Max(bounds.Lx-bounds.Sx, math.Max(bounds.Ly-bounds.Sy, bounds.Lz-bounds.Sz)) / 16
+	if size <= 0 {
+		size = end - start + 1
+	}
+	ab, p0, p1 := &Abox{}, &lin.V3{}, &lin.V3{}
+	for s := start; s <= end; s += size {
+		e := math.Min(s+size, end)
+		p0.SetS(o.X+d.X*s, o.Y+d.Y*s, o.Z+d.Z*s)
+		p1.SetS(o.X+d.X*e, o.Y+d.Y*e, o.Z+d.Z*e)
+		ab.Sx, ab.Sy, ab.Sz = math.Min(p0.X, p1.X), math.Min(p0.Y, p1.Y), math.Min(p0.Z, p1.Z)
+		ab.Lx, ab.Ly, ab.Lz = math.Max(p0.X, p1.X), math.Max(p0.Y, p1.Y), math.Max(p0.Z, p1.Z)
+		t = math.MaxFloat64
+		tris := source.triangles(ab)
+		for cnt := range tris {
+			tri := &tris[cnt]
+			if ts, hit := rayTriangle(tri, o, d); hit && ts < t && ts <= e+lin.Epsilon {
+				t, ok = ts, true
+				faceNormal(&n, &tri.points[0], &tri.points[1], &tri.points[2])
+			}
+		}
+		if ok {
+			return t, n, true
+		}
+	}
+	return 0, n, false
+}
+
+// rayTriangle returns where the ray hits the front of the triangle.
+// Based on "Fast, Minimum Storage Ray/Triangle Intersection"
+// by Tomas Möller and Ben Trumbore.
+func rayTriangle(tri *triangle, o, d *lin.V3) (t float64, ok bool) {
+	a, b, c := &tri.points[0], &tri.points[1], &tri.points[2]
+	e1, e2 := lin.NewV3().Sub(b, a), lin.NewV3().Sub(c, a)
+	p := lin.NewV3().Cross(d, e2)
+	det := e1.Dot(p)
+	if det < lin.Epsilon {
+		return 0, false // parallel or hitting the back.
+	}
+	s := lin.NewV3().Sub(o, a)
+	u := s.Dot(p) / det
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+	q := lin.NewV3().Cross(s, e1)
+	v := d.Dot(q) / det
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+	t = e2.Dot(q) / det
+	return t, t >= 0
+}
+
+// castRayCompound checks the ray against each child of the compound
+// body b, keeping the closest hit.
+func castRayCompound(r, b Body, hit *Hit) bool {
+	cs, ok := b.Shape().(*compound)
+	if !ok {
+		return false
+	}
+	found, closest := false, &Hit{}
+	for _, ch := range cs.children {
+		cb := &body{shape: ch.shape, world: ch.place(b.World(), lin.NewT())}
+		if castRay(r, cb, closest) && (!found || closest.Dist < hit.Dist) {
+			found = true
+			*hit = *closest
+			hit.Body = b
+		}
+	}
+	return found
+}
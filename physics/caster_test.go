@@ -4,6 +4,7 @@
 package physics
 
 import (
+	"math"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -13,7 +14,7 @@ func TestCastRayPlane(t *testing.T) {
 	r := newBody(NewRay(0, 0.70710678, 0.70710678)) // ray at origin pointing down +Y +Z
 	p := newBody(NewPlane(0, 0, 1))                 // normal +Z
 	p.World().Loc.SetS(0, 0, 20)                    // move plane 20 +Z
-	hit, x, y, z := castRayPlane(r, p)
+	hit, x, y, z := Cast(r, p)
 	cx, cy, cz := 0.0, 20.0, 20.0 // expected contact location.
 	if !hit || !lin.Aeq(x, cx) || !lin.Aeq(y, cy) || !lin.Aeq(z, cz) {
 		t.Errorf("%t Expected ray-plane hit at %f %f %f, got %f %f %f", hit, cx, cy, cz, x, y, z)
@@ -24,7 +25,7 @@ func TestCastRotatedRayPlane(t *testing.T) {
 	r := newBody(NewRay(0, 0.70710678, -0.70710678)) // ray at origin pointing down +Y -Z
 	r.World().Loc.SetS(0, 0, 20)                     // move ray origin +20 on Z axis.
 	p := newBody(NewPlane(0, 0, -1))                 // plane at origin with normal -Z
-	hit, x, y, z := castRayPlane(r, p)
+	hit, x, y, z := Cast(r, p)
 	cx, cy, cz := 0.0, 20.0, 0.0 // expected contact location.
 	if !hit || !lin.Aeq(x, cx) || !lin.Aeq(y, cy) || !lin.Aeq(z, cz) {
 		t.Errorf("%t Expected ray-plane hit at %f %f %f, got %f %f %f", hit, cx, cy, cz, x, y, z)
@@ -35,11 +36,15 @@ func TestCastRaySphere(t *testing.T) {
 	r := newBody(NewRay(0.70710678, 0.70710678, 0.70710678)) // 45 degrees from each axis.
 	s := newBody(NewSphere(1))                               // sphere of radius 1
 	s.World().Loc.SetS(20, 20, 20)
-	hit, x, y, z := castRaySphere(r, s)
+	hit, x, y, z := Cast(r, s)
 	cx, cy, cz := 19.4226497, 19.4226497, 19.4226497 // expected contact location.
 	if !hit || !lin.Aeq(x, cx) || !lin.Aeq(y, cy) || !lin.Aeq(z, cz) {
 		t.Errorf("%t Expected ray-plane hit at %2.7f %2.7f %2.7f, got %2.7f %2.7f %2.7f", hit, cx, cy, cz, x, y, z)
 	}
+	s.World().Loc.SetS(0.5, 0, 0)
+	if ok, _, _, _ := castHit(r, s); ok {
+		t.Error("Ray starting inside the sphere should not hit")
+	}
 }
 
 func TestCastRotatedRaySphere(t *testing.T) {
@@ -47,9 +52,116 @@ func TestCastRotatedRaySphere(t *testing.T) {
 	r.World().Loc.SetS(0, 0, 20)                     // move ray origin +20 on Z axis.
 	s := newBody(NewSphere(1))                       // sphere of radius 1.
 	s.World().Loc.SetS(0, 20, 0)                     // put sphere up the y-axis.
-	hit, x, y, z := castRaySphere(r, s)
+	hit, x, y, z := Cast(r, s)
 	cx, cy, cz := 0.0, 19.2928932, 0.7071068 // expected contact location.
 	if !hit || !lin.Aeq(x, cx) || !lin.Aeq(y, cy) || !lin.Aeq(z, cz) {
 		t.Errorf("%t Expected ray-plane hit at %2.7f %2.7f %2.7f, got %2.7f %2.7f %2.7f", hit, cx, cy, cz, x, y, z)
 	}
 }
+
+// castHit checks the ray against the body and returns the hit details.
+func castHit(r, b Body) (bool, string, string, float64) {
+	hit := &Hit{}
+	if !castRay(r, b, hit) {
+		return false, "", "", 0
+	}
+	round := func(v *lin.V3) string { // avoid printing -0.0
+		return dumpV3(&lin.V3{X: math.Round(v.X*10)/10 + 0, Y: math.Round(v.Y*10)/10 + 0, Z: math.Round(v.Z*10)/10 + 0})
+	}
+	return true, round(&hit.Point), round(&hit.Normal), hit.Dist
+}
+
+func TestCastRayBox(t *testing.T) {
+	r := newBody(NewRay(0, 0, -1)) // ray at origin pointing down -Z
+	b := newBody(NewBox(1, 2, 3))
+	b.World().Loc.SetS(0.5, 0, -10)
+	if ok, p, n, d := castHit(r, b); !ok || p != "{0.0 0.0 -7.0}" || n != "{0.0 0.0 1.0}" || !lin.Aeq(d, 7) {
+		t.Errorf("Expected ray-box hit %t %s %s %f", ok, p, n, d)
+	}
+	b.World().Rot.SetAa(0, 1, 0, lin.Rad(90)) // turn the long side to face the ray.
+	if ok, p, n, _ := castHit(r, b); !ok || p != "{0.0 0.0 -9.0}" || n != "{0.0 0.0 1.0}" {
+		t.Errorf("Expected rotated ray-box hit %t %s %s", ok, p, n)
+	}
+	b.World().Loc.SetS(4, 0, -10)
+	if ok, _, _, _ := castHit(r, b); ok {
+		t.Error("Ray should miss the box")
+	}
+	b.World().Loc.SetS(0, 0, 0)
+	if ok, _, _, _ := castHit(r, b); ok {
+		t.Error("Ray starting inside the box should not hit")
+	}
+}
+
+func TestCastRayCapsuleCylinder(t *testing.T) {
+	r := newBody(NewRay(0, -1, 0)) // ray pointing down -Y
+	r.World().Loc.SetS(0, 10, 0)
+	cp, cy := newBody(NewCapsule(0.5, 1)), newBody(NewCylinder(0.5, 1))
+	if ok, p, n, _ := castHit(r, cp); !ok || p != "{0.0 1.5 0.0}" || n != "{0.0 1.0 0.0}" {
+		t.Errorf("Expected ray-capsule end hit %t %s %s", ok, p, n)
+	}
+	if ok, p, n, _ := castHit(r, cy); !ok || p != "{0.0 1.0 0.0}" || n != "{0.0 1.0 0.0}" {
+		t.Errorf("Expected ray-cylinder cap hit %t %s %s", ok, p, n)
+	}
+	SetRay(r, -1, 0, 0) // ray pointing along -X at the sides.
+	r.World().Loc.SetS(10, 0.5, 0)
+	if ok, p, n, _ := castHit(r, cp); !ok || p != "{0.5 0.5 0.0}" || n != "{1.0 0.0 0.0}" {
+		t.Errorf("Expected ray-capsule side hit %t %s %s", ok, p, n)
+	}
+	if ok, p, n, _ := castHit(r, cy); !ok || p != "{0.5 0.5 0.0}" || n != "{1.0 0.0 0.0}" {
+		t.Errorf("Expected ray-cylinder side hit %t %s %s", ok, p, n)
+	}
+	r.World().Loc.SetS(10, 1.2, 0) // above the cylinder, through the capsule end.
+	if ok, _, _, _ := castHit(r, cy); ok {
+		t.Error("Ray should pass over the cylinder")
+	}
+	if ok, p, _, _ := castHit(r, cp); !ok || p != "{0.5 1.2 0.0}" {
+		t.Errorf("Expected ray-capsule end hit %t %s", ok, p)
+	}
+}
+
+func TestCastRayHull(t *testing.T) {
+	points := []float32{0, 1, 0, -1, -1, -1, 1, -1, -1, 0, -1, 1} // tetrahedron.
+	r, hl := newBody(NewRay(0, 1, 0)), newBody(NewHull(points))
+	r.World().Loc.SetS(0, -10, 0)
+	if ok, p, n, d := castHit(r, hl); !ok || p != "{0.0 -1.0 0.0}" || n != "{0.0 -1.0 0.0}" || !lin.Aeq(d, 9) {
+		t.Errorf("Expected ray-hull hit %t %s %s %f", ok, p, n, d)
+	}
+	r.World().Loc.SetS(2, -10, 0)
+	if ok, _, _, _ := castHit(r, hl); ok {
+		t.Error("Ray should miss the hull")
+	}
+}
+
+func TestCastRayTriangles(t *testing.T) {
+	r, tm := newBody(NewRay(0.1, -1, 0)), newBody(NewTriMesh(floorVerts, floorFaces))
+	r.World().Loc.SetS(0, 5, 0.5)
+	if ok, p, n, _ := castHit(r, tm); !ok || p != "{0.5 0.0 0.5}" || n != "{0.0 1.0 0.0}" {
+		t.Errorf("Expected ray-trimesh hit %t %s %s", ok, p, n)
+	}
+	r.World().Loc.SetS(0, -5, 0.5)
+	SetRay(r, 0, 1, 0)
+	if ok, _, _, _ := castHit(r, tm); ok {
+		t.Error("Ray should not hit the back of the trimesh")
+	}
+
+	topo := [][]float64{{0, 0, 0}, {0, 1, 0}, {0, 0, 0}}
+	hf := newBody(NewHeightfield(topo, 2, 1))
+	hf.World().Loc.SetS(0, 1, 0)
+	SetRay(r, 1, -1, 0) // ray from above and to the left.
+	r.World().Loc.SetS(-10, 10, 0)
+	if ok, p, _, _ := castHit(r, hf); !ok || p != "{-1.3 1.3 0.0}" {
+		t.Errorf("Expected ray-heightfield hit %t %s", ok, p)
+	}
+}
+
+func TestCastRayCompound(t *testing.T) {
+	r := newBody(NewRay(0, -1, 0)) // ray pointing down at the upright.
+	r.World().Loc.SetS(-0.75, 10, 0)
+	cm := newBody(newLShape())
+	if ok, p, n, _ := castHit(r, cm); !ok || p != "{-0.8 1.8 0.0}" || n != "{0.0 1.0 0.0}" {
+		t.Errorf("Expected ray-compound hit %t %s %s", ok, p, n)
+	}
+	if hit, _, y, _ := Cast(r, cm); !hit || !lin.Aeq(y, 1.75) {
+		t.Errorf("Expected ray-compound cast %t %f", hit, y)
+	}
+}
@@ -7,6 +7,8 @@ package physics
 // // It is used to include collision.c code.
 //
 // #cgo CFLAGS: -std=c99
+// // collision.c uses the C math library.
+// #cgo linux LDFLAGS: -lm
 //
 // #include "collision.h"
 import "C" // must be located here.
@@ -23,21 +25,51 @@ import (
 // calculate points of contact between shape primitives.
 type collider struct {
 	algorithms [][]collide
+	gjk        *gjk       // Reused by each convex collision.
+	tris       triScratch // Reused by each static shape collision.
 }
 
 // newCollider initializes the algorithms needed for narrowphase.
 //
 // FUTURE: Look into adding support for planes and rays.
 func newCollider() *collider {
-	c := &collider{}
-	c.algorithms = make([][]collide, VolumeShapes)
+	c := &collider{gjk: &gjk{}}
+	c.algorithms = make([][]collide, NumShapes)
 	for cnt := range c.algorithms {
-		c.algorithms[cnt] = make([]collide, VolumeShapes)
+		c.algorithms[cnt] = make([]collide, NumShapes)
 	}
 	c.algorithms[SphereShape][SphereShape] = collideSphereSphere
 	c.algorithms[SphereShape][BoxShape] = collideSphereBox
 	c.algorithms[BoxShape][SphereShape] = collideBoxSphere
 	c.algorithms[BoxShape][BoxShape] = collideBoxBox
+
+	// capsules, cylinders, and hulls use the general convex algorithm
+	// except for the cases with simpler direct calculations.
+	for _, sa := range volumes {
+		for _, sb := range []int{CapsuleShape, CylinderShape, HullShape} {
+			c.algorithms[sa][sb] = c.collideConvex
+			c.algorithms[sb][sa] = c.collideConvex
+		}
+	}
+	c.algorithms[CapsuleShape][SphereShape] = collideCapsuleSphere
+	c.algorithms[SphereShape][CapsuleShape] = collideSphereCapsule
+	c.algorithms[CapsuleShape][CapsuleShape] = collideCapsuleCapsule
+
+	// static triangle shapes collide with all volume shapes.
+	for _, sa := range volumes {
+		for _, sb := range []int{TriMeshShape, HeightfieldShape} {
+			c.algorithms[sa][sb] = c.collideTriangles
+			c.algorithms[sb][sa] = c.collideStaticVolume
+		}
+	}
+
+	// compound shapes collide each child with the other shape.
+	others := append([]int{TriMeshShape, HeightfieldShape}, volumes...)
+	for _, sa := range others {
+		c.algorithms[CompoundShape][sa] = c.collideCompound
+		c.algorithms[sa][CompoundShape] = c.collideShapeCompound
+	}
+	c.algorithms[CompoundShape][CompoundShape] = c.collideCompound
 	return c
 }
 
@@ -251,10 +283,113 @@ func collideBoxBox(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfConta
 
 // box-box collision
 // ============================================================================
-// FUTURE: Implementat collision detection and contact point generation for
-//         more complex types. Choose one generic algorithm for convex shapes
-//         (anything with faces/edges) and use it for all cases.
+// capsule collision
+
+// collideCapsuleSphere treats the capsule as a sphere centered on the
+// closest point of the capsule center line. It returns 0 or 1 contact
+// points. Like sphere-sphere, no collision margin is used.
+func collideCapsuleSphere(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	aa, bb := a.(*body), b.(*body)
+	ca, sb := aa.shape.(*capsule), bb.shape.(*sphere)
+	p0, p1 := capsuleSegment(aa.world, ca)
+	lb := bb.world.Loc
+	t := closestOnSegment(p0, p1, lb.X, lb.Y, lb.Z)
+	ax, ay, az := p0.X+(p1.X-p0.X)*t, p0.Y+(p1.Y-p0.Y)*t, p0.Z+(p1.Z-p0.Z)*t
+	return a, b, spheresContact(ax, ay, az, ca.R, lb.X, lb.Y, lb.Z, sb.R, c)
+}
+
+// collideSphereCapsule reverses the collision to be CapsuleSphere.
+func collideSphereCapsule(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	return collideCapsuleSphere(b, a, c)
+}
+
+// collideCapsuleCapsule treats the capsules as spheres centered on the
+// closest points of the two capsule center lines. It returns 0 or 1
+// contact points. Like sphere-sphere, no collision margin is used.
+func collideCapsuleCapsule(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	aa, bb := a.(*body), b.(*body)
+	ca, cb := aa.shape.(*capsule), bb.shape.(*capsule)
+	a0, a1 := capsuleSegment(aa.world, ca)
+	b0, b1 := capsuleSegment(bb.world, cb)
+	s, t := closestSegments(a0, a1, b0, b1)
+	ax, ay, az := a0.X+(a1.X-a0.X)*s, a0.Y+(a1.Y-a0.Y)*s, a0.Z+(a1.Z-a0.Z)*s
+	bx, by, bz := b0.X+(b1.X-b0.X)*t, b0.Y+(b1.Y-b0.Y)*t, b0.Z+(b1.Z-b0.Z)*t
+	return a, b, spheresContact(ax, ay, az, ca.R, bx, by, bz, cb.R, c)
+}
+
+// closestSegments returns the fractions, 0 to 1, along segments a0, a1
+// and b0, b1 of the closest points between the two segments.
 //
+// Based on Real-Time Collision Detection by Christer Ericson. Section 5.1.9
+func closestSegments(a0, a1, b0, b1 *lin.V3) (s, t float64) {
+	d1 := lin.NewV3().Sub(a1, a0) // segment A direction.
+	d2 := lin.NewV3().Sub(b1, b0) // segment B direction.
+	r := lin.NewV3().Sub(a0, b0)
+	la, lb, f := d1.Dot(d1), d2.Dot(d2), d2.Dot(r)
+	switch {
+	case la <= lin.Epsilon && lb <= lin.Epsilon: // both are points.
+	case la <= lin.Epsilon:
+		t = lin.Clamp(f/lb, 0, 1)
+	default:
+		cc := d1.Dot(r)
+		if lb <= lin.Epsilon {
+			s = lin.Clamp(-cc/la, 0, 1)
+			break
+		}
+		bd := d1.Dot(d2)
+		if denom := la*lb - bd*bd; denom > lin.Epsilon { // not parallel.
+			s = lin.Clamp((bd*f-cc*lb)/denom, 0, 1)
+		}
+		t = (bd*s + f) / lb
+		switch {
+		case t < 0:
+			t, s = 0, lin.Clamp(-cc/la, 0, 1)
+		case t > 1:
+			t, s = 1, lin.Clamp((bd-cc)/la, 0, 1)
+		}
+	}
+	return s, t
+}
+
+// capsuleSegment returns the world space end points of the capsule center line.
+func capsuleSegment(t *lin.T, c *capsule) (p0, p1 *lin.V3) {
+	p0, p1 = lin.NewV3(), lin.NewV3()
+	p0.SetS(t.AppS(0, -c.H, 0))
+	p1.SetS(t.AppS(0, c.H, 0))
+	return p0, p1
+}
+
+// closestOnSegment returns the fraction, 0 to 1, along the segment
+// p0, p1 that is closest to the point x, y, z.
+func closestOnSegment(p0, p1 *lin.V3, x, y, z float64) float64 {
+	dx, dy, dz := p1.X-p0.X, p1.Y-p0.Y, p1.Z-p0.Z
+	if lsqr := dx*dx + dy*dy + dz*dz; lsqr > lin.Epsilon {
+		return lin.Clamp(((x-p0.X)*dx+(y-p0.Y)*dy+(z-p0.Z)*dz)/lsqr, 0, 1)
+	}
+	return 0
+}
+
+// spheresContact sets the contact point for sphere A at ax, ay, az with
+// radius ra colliding with sphere B at bx, by, bz with radius rb.
+// Returns the single contact or no contacts if the spheres are apart.
+func spheresContact(ax, ay, az, ra, bx, by, bz, rb float64, c []*pointOfContact) []*pointOfContact {
+	dx, dy, dz := ax-bx, ay-by, az-bz
+	separation := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	if separation > ra+rb {
+		return c[0:0] // no contact.
+	}
+	c0 := c[0]
+	c0.depth = separation - (ra + rb)
+	c0.normal.SetS(1, 0, 0) // same center.
+	if separation > lin.Epsilon {
+		c0.normal.SetS(dx/separation, dy/separation, dz/separation)
+	}
+	c0.point.SetS(bx+c0.normal.X*rb, by+c0.normal.Y*rb, bz+c0.normal.Z*rb) // on B.
+	return c[0:1]
+}
+
+// capsule collision
+// ============================================================================
 // FUTURE look at "The Separating Axis Test between Convex Polyhedra" talk
 //        as given in the GDC3013 talk by Dirk Gregorius:
 //    https://code.google.com/p/box2d/downloads/detail?name=DGregorius_GDC2013.zip&can=2&q=
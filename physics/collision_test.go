@@ -4,6 +4,7 @@
 package physics
 
 import (
+	"math"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -173,3 +174,202 @@ func BenchmarkCollideBoxBox(b *testing.B) {
 		collideBoxBox(a, o, cs)
 	}
 }
+func BenchmarkCollideConvex(b *testing.B) {
+	a, o, c, cs := NewBody(NewCylinder(0.5, 1)), NewBody(NewBox(1, 1, 1)), newCollider(), newManifold()
+	a.World().Loc.SetS(0, 1.9, 0)
+	for cnt := 0; cnt < b.N; cnt++ {
+		c.collideConvex(a, o, cs)
+	}
+}
+
+// Check that GJK/EPA reuses the collider scratch space.
+func TestConvexScratch(t *testing.T) {
+	a, o, c := NewBody(NewCylinder(0.5, 1)), NewBody(NewBox(1, 1, 1)), newCollider()
+	a.World().Loc.SetS(0.2, 1.9, 0.1)
+	g := c.gjk
+	g.a, g.b = a.(*body), o.(*body)
+	if !g.overlaps() || !g.expand() {
+		t.Fatalf("Expected overlap")
+	}
+	if _, _, _, _, _, _, _, ok := g.penetration(); !ok {
+		t.Fatalf("Expected penetration")
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		g.overlaps()
+		g.expand()
+		g.penetration()
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %f", allocs)
+	}
+}
+
+func TestCollideCapsuleSphere(t *testing.T) {
+	a, b, cons := NewBody(NewCapsule(0.5, 1)), NewBody(NewSphere(1)), newManifold()
+	b.World().Loc.SetS(0, 2.25, 0) // above the top of the capsule.
+	if _, _, cs := collideCapsuleSphere(a, b, cons); len(cs) != 1 || !lin.Aeq(cs[0].depth, -0.25) ||
+		dumpV3(cs[0].point) != "{0.0 1.2 0.0}" || dumpV3(cs[0].normal) != "{0.0 -1.0 0.0}" {
+		t.Errorf("Sphere on capsule %f %s %s", cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+	b.World().Loc.SetS(1.25, 0.5, 0) // beside the capsule.
+	if _, _, cs := collideSphereCapsule(b, a, cons); len(cs) != 1 || !lin.Aeq(cs[0].depth, -0.25) ||
+		dumpV3(cs[0].point) != "{0.2 0.5 0.0}" || dumpV3(cs[0].normal) != "{-1.0 0.0 0.0}" {
+		t.Errorf("Capsule beside sphere %f %s %s", cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+	b.World().Loc.SetS(1.6, 0, 0)
+	if _, _, cs := collideCapsuleSphere(a, b, cons); len(cs) != 0 {
+		t.Error("Capsule and sphere should not collide")
+	}
+}
+
+func TestCollideCapsuleCapsule(t *testing.T) {
+	a, b, cons := NewBody(NewCapsule(0.5, 1)), NewBody(NewCapsule(0.5, 1)), newManifold()
+	a.World().Loc.SetS(0, 0, 0.75)
+	a.World().Rot.SetAa(0, 0, 1, lin.Rad(90)) // crossing b.
+	if _, _, cs := collideCapsuleCapsule(a, b, cons); len(cs) != 1 || !lin.Aeq(cs[0].depth, -0.25) ||
+		dumpV3(cs[0].point) != "{0.0 0.0 0.5}" || dumpV3(cs[0].normal) != "{0.0 0.0 1.0}" {
+		t.Errorf("Crossed capsules %f %s %s", cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+	a.World().Loc.SetS(0, 0, 1.1)
+	if _, _, cs := collideCapsuleCapsule(a, b, cons); len(cs) != 0 {
+		t.Error("Capsules should not collide")
+	}
+}
+
+// Checks that the general convex collision matches sphere-box.
+func TestCollideConvex(t *testing.T) {
+	a, b, c, cons := NewBody(NewSphere(1)), NewBody(NewBox(1, 1, 1)), newCollider(), newManifold()
+	a.World().Loc.SetS(0, 1.9, 0)
+	_, _, want := collideSphereBox(a, b, newManifold())
+	if _, _, cs := c.collideConvex(a, b, cons); len(cs) != 1 || !lin.Aeq(cs[0].depth, want[0].depth-margin) ||
+		!cs[0].point.Aeq(want[0].point) || !cs[0].normal.Aeq(want[0].normal) {
+		t.Errorf("Convex sphere on box %f %s %s", cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+	a.World().Loc.SetS(0, 2.1, 0)
+	if _, _, cs := c.collideConvex(a, b, cons); len(cs) != 0 {
+		t.Error("Convex sphere and box should not collide")
+	}
+}
+
+func TestCollideCylinderBox(t *testing.T) {
+	cy, bx, c, cons := newBody(NewCylinder(0.5, 1)), newBody(NewBox(2, 0.5, 2)), newCollider(), newManifold()
+	cy.World().Loc.SetS(0.2, 1.4, -0.3) // standing on the box.
+	algorithm := c.algorithms[cy.shape.Type()][bx.shape.Type()]
+	_, _, cs := algorithm(cy, bx, cons)
+	if len(cs) != 4 {
+		t.Fatalf("Expected 4 contacts for cylinder standing on box, got %d", len(cs))
+	}
+	for _, poc := range cs {
+		if !near(poc.depth, -0.1-2*margin) || !near(poc.point.Y, 0.5+margin) || !near(poc.normal.Y, 1) {
+			t.Errorf("Cylinder on box %f %s %s", poc.depth, dumpV3(poc.point), dumpV3(poc.normal))
+		}
+	}
+
+	// lying on its side touches along a line.
+	cy.World().Loc.SetS(0.2, 0.9, -0.3)
+	cy.World().Rot.SetAa(1, 0, 0, lin.Rad(90))
+	if _, _, cs = algorithm(cy, bx, cons); len(cs) != 2 || !near(cs[0].point.Z+cs[1].point.Z, -0.6) || !near(math.Abs(cs[0].point.Z-cs[1].point.Z), 2) {
+		t.Errorf("Expected 2 contacts at the cylinder ends, got %d", len(cs))
+	}
+}
+
+func TestCollideHullSphere(t *testing.T) {
+	points := []float32{0, 1, 0, -1, -1, -1, 1, -1, -1, 0, -1, 1} // tetrahedron.
+	hl, sp, c, cons := newBody(NewHull(points)), newBody(NewSphere(0.5)), newCollider(), newManifold()
+	sp.World().Loc.SetS(0, -1.4, 0) // below the base.
+	algorithm := c.algorithms[hl.shape.Type()][sp.shape.Type()]
+	if _, _, cs := algorithm(hl, sp, cons); len(cs) != 1 || !near(cs[0].depth, -0.1-2*margin) ||
+		!near(cs[0].normal.Y, 1) {
+		t.Errorf("Sphere below hull %f %s %s", cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+	sp.World().Loc.SetS(0, -1.7, 0)
+	if _, _, cs := algorithm(hl, sp, cons); len(cs) != 0 {
+		t.Error("Sphere and hull should not collide")
+	}
+}
+
+// near is a looser float comparison for the iterative collision algorithms.
+func near(a, b float64) bool { return math.Abs(a-b) < 0.001 }
+
+func TestCollideSphereTriMesh(t *testing.T) {
+	sp, tm, c, cons := newBody(NewSphere(0.5)), newBody(NewTriMesh(floorVerts, floorFaces)), newCollider(), newManifold()
+	sp.World().Loc.SetS(0.2, 0.45, 0.3)
+	algorithm := c.algorithms[tm.shape.Type()][sp.shape.Type()]
+	i, _, cs := algorithm(tm, sp, cons)
+	if i.(*body).shape.Type() != SphereShape {
+		t.Error("Should have flipped the objects into Sphere, TriMesh")
+	}
+	if len(cs) != 1 || !near(cs[0].depth, -0.05-2*margin) || !near(cs[0].normal.Y, 1) || !near(cs[0].point.X, 0.2) {
+		t.Errorf("Sphere on triangle mesh %d %f %s %s", len(cs), cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+
+	// triangles only collide from the front.
+	sp.World().Loc.SetS(0.2, -0.45, 0.3)
+	if _, _, cs := algorithm(tm, sp, cons); len(cs) != 0 {
+		t.Errorf("Sphere below triangle mesh should not collide %d", len(cs))
+	}
+}
+
+func TestCollideBoxHeightfield(t *testing.T) {
+	topo := [][]float64{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}
+	bx, hf, c, cons := newBody(NewBox(0.5, 0.5, 0.5)), newBody(NewHeightfield(topo, 2, 1)), newCollider(), newManifold()
+	bx.World().Loc.SetS(0, 0.5, 0) // on the center grid point.
+	algorithm := c.algorithms[bx.shape.Type()][hf.shape.Type()]
+	_, _, cs := algorithm(bx, hf, cons)
+	if len(cs) != 4 {
+		t.Fatalf("Expected 4 contacts for box on heightfield, got %d", len(cs))
+	}
+	for _, poc := range cs {
+		if !near(poc.depth, -2*margin) || !near(poc.normal.Y, 1) || !near(math.Abs(poc.point.X), 0.5) {
+			t.Errorf("Box on heightfield %f %s %s", poc.depth, dumpV3(poc.point), dumpV3(poc.normal))
+		}
+	}
+}
+
+func TestCollideCompoundSphere(t *testing.T) {
+	cm, sp, c, cons := newBody(newLShape()), newBody(NewSphere(0.5)), newCollider(), newManifold()
+	sp.World().Loc.SetS(-0.75, 2.2, 0) // on top of the upright box.
+	algorithm := c.algorithms[sp.shape.Type()][cm.shape.Type()]
+	i, _, cs := algorithm(sp, cm, cons)
+	if i.(*body).shape.Type() != CompoundShape {
+		t.Error("Should have flipped the objects into Compound, Sphere")
+	}
+	if len(cs) != 1 || !near(cs[0].depth, -0.05-margin) || !near(cs[0].normal.Y, -1) || !near(cs[0].point.Y, 1.7) {
+		t.Errorf("Sphere on compound %d %f %s %s", len(cs), cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+	sp.World().Loc.SetS(0.5, 1, 0) // inside the L, touching neither box.
+	if _, _, cs := algorithm(sp, cm, cons); len(cs) != 0 {
+		t.Errorf("Sphere and compound should not collide %d", len(cs))
+	}
+}
+
+func TestCollideCompoundCompound(t *testing.T) {
+	a, b, c, cons := newBody(newLShape()), newBody(newLShape()), newCollider(), newManifold()
+	a.World().Loc.SetS(0, 1.95, 0) // a resting on the upright of b.
+	algorithm := c.algorithms[a.shape.Type()][b.shape.Type()]
+	_, _, cs := algorithm(a, b, cons)
+	if len(cs) != 4 {
+		t.Fatalf("Expected 4 contacts for stacked compounds, got %d", len(cs))
+	}
+	for _, poc := range cs {
+		if !near(poc.depth, -0.05-2*margin) || !near(poc.normal.Y, 1) || !near(poc.point.Y, 1.75+margin) {
+			t.Errorf("Stacked compounds %f %s %s", poc.depth, dumpV3(poc.point), dumpV3(poc.normal))
+		}
+	}
+}
+
+// Check that bodies sharing a compound shape use separate child bodies.
+func TestCollideSharedCompound(t *testing.T) {
+	shape := newLShape()
+	a, b, c, cons := newBody(shape), newBody(shape), newCollider(), newManifold()
+	a.World().Loc.SetS(0, 1.95, 0) // a resting on the upright of b.
+	algorithm := c.algorithms[CompoundShape][CompoundShape]
+	if _, _, cs := algorithm(a, b, cons); len(cs) != 4 || !near(cs[0].depth, -0.05-2*margin) {
+		t.Errorf("Expected 4 contacts for stacked compounds, got %d", len(cs))
+	}
+	for _, ch := range shape.(*compound).children {
+		if ch.busy != 0 {
+			t.Errorf("Expected child bodies to be released")
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+// compound.go groups several shapes into a single rigid shape,
+// ie: a vehicle, a piece of furniture, or an L-shaped wall.
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Compound is a Shape made from child shapes that are each offset
+// from the compound origin. The children move together as one body.
+// The compound origin is used as the center of mass, so place the
+// children so that the origin is near the middle of the compound.
+type Compound interface {
+	Shape
+
+	// Add appends a child shape located at the given offset from the
+	// compound origin. Only volume shapes and other compounds can be added.
+	// The offset is copied. The updated Compound is returned.
+	//    s      : child shape.
+	//    offset : child location and direction. Nil means no offset.
+	Add(s Shape, offset *lin.T) Compound
+
+	// Child returns the shape and offset of the given child.
+	// Nil values are returned for an invalid index.
+	Child(index int) (s Shape, offset *lin.T)
+	Size() int // Size returns the number of children.
+}
+
+// compound is the Compound implementation.
+type compound struct {
+	children []*child
+}
+
+// child is one of the shapes in a compound.
+type child struct {
+	shape  Shape  // Child shape.
+	offset *lin.T // Child location and direction relative to the compound.
+	world  *lin.T // Scratch world transform.
+	ab     *Abox  // Scratch bounding box.
+
+	// Scratch collision bodies. There is one for each of the colliding
+	// bodies since both bodies can use the same compound shape.
+	bodies [2]*body
+	busy   int // Number of bodies in use.
+}
+
+// NewCompound creates a compound shape without any children.
+// Use Add to give the compound shape.
+func NewCompound() Compound { return &compound{} }
+
+// Implements Compound.Add
+func (c *compound) Add(s Shape, offset *lin.T) Compound {
+	if s == nil || (!IsVolume(s.Type()) && s.Type() != CompoundShape) {
+		return c
+	}
+	ch := &child{shape: s, offset: lin.NewT().SetI(), world: lin.NewT(), ab: &Abox{}}
+	ch.bodies = [2]*body{newBody(s), newBody(s)}
+	if offset != nil {
+		ch.offset.Set(offset)
+	}
+	c.children = append(c.children, ch)
+	return c
+}
+
+// Implements Compound.Child
+func (c *compound) Child(index int) (s Shape, offset *lin.T) {
+	if index < 0 || index >= len(c.children) {
+		return nil, nil
+	}
+	return c.children[index].shape, c.children[index].offset
+}
+
+// Implements Compound.Size
+func (c *compound) Size() int { return len(c.children) }
+
+// Implements Shape.Type
+func (c *compound) Type() int { return CompoundShape }
+
+// Implements Shape.Aabb
+// The bounding box surrounds the transformed child bounding boxes.
+func (c *compound) Aabb(t *lin.T, ab *Abox, margin float64) *Abox {
+	if len(c.children) == 0 {
+		ab.Sx, ab.Sy, ab.Sz = t.Loc.X-margin, t.Loc.Y-margin, t.Loc.Z-margin
+		ab.Lx, ab.Ly, ab.Lz = t.Loc.X+margin, t.Loc.Y+margin, t.Loc.Z+margin
+		return ab
+	}
+	ab.Sx, ab.Sy, ab.Sz = math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	ab.Lx, ab.Ly, ab.Lz = -math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+	for _, ch := range c.children {
+		cb := ch.shape.Aabb(ch.place(t, ch.world), ch.ab, margin)
+		ab.Sx, ab.Sy, ab.Sz = math.Min(ab.Sx, cb.Sx), math.Min(ab.Sy, cb.Sy), math.Min(ab.Sz, cb.Sz)
+		ab.Lx, ab.Ly, ab.Lz = math.Max(ab.Lx, cb.Lx), math.Max(ab.Ly, cb.Ly), math.Max(ab.Lz, cb.Lz)
+	}
+	return ab
+}
+
+// Implements Shape.Volume
+// The volume is the sum of the child volumes. Overlapping
+// children are counted more than once.
+func (c *compound) Volume() float64 {
+	volume := 0.0
+	for _, ch := range c.children {
+		volume += ch.shape.Volume()
+	}
+	return volume
+}
+
+// Implements Shape.Inertia
+// The mass is shared between the children by volume. Each child inertia
+// is rotated by the child offset and moved to the compound origin using
+// the parallel axis theorem. Only the diagonal of the combined inertia
+// tensor is kept.
+func (c *compound) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	inertia.SetS(0, 0, 0)
+	volume := c.Volume()
+	if volume <= 0 {
+		return inertia
+	}
+	ci := &lin.V3{}
+	for _, ch := range c.children {
+		m := mass * ch.shape.Volume() / volume
+		ch.shape.Inertia(m, ci)
+
+		// diagonal of R*I*Rt where the columns of R are the rotated axes.
+		xx, xy, xz := ch.offset.AppR(1, 0, 0)
+		yx, yy, yz := ch.offset.AppR(0, 1, 0)
+		zx, zy, zz := ch.offset.AppR(0, 0, 1)
+		inertia.X += ci.X*xx*xx + ci.Y*yx*yx + ci.Z*zx*zx
+		inertia.Y += ci.X*xy*xy + ci.Y*yy*yy + ci.Z*zy*zy
+		inertia.Z += ci.X*xz*xz + ci.Y*yz*yz + ci.Z*zz*zz
+
+		// parallel axis: shift from the child center to the compound origin.
+		d := ch.offset.Loc
+		inertia.X += m * (d.Y*d.Y + d.Z*d.Z)
+		inertia.Y += m * (d.X*d.X + d.Z*d.Z)
+		inertia.Z += m * (d.X*d.X + d.Y*d.Y)
+	}
+	return inertia
+}
+
+// place updates and returns the child world transform w given
+// the compound world transform t.
+func (ch *child) place(t, w *lin.T) *lin.T {
+	w.Loc.X, w.Loc.Y, w.Loc.Z = t.AppS(ch.offset.Loc.X, ch.offset.Loc.Y, ch.offset.Loc.Z)
+	w.Rot.Mult(ch.offset.Rot, t.Rot)
+	return w
+}
+
+// acquire returns a scratch body that is not being used by a collision
+// further up the call stack. The caller decrements busy when done.
+func (ch *child) acquire() *body {
+	ch.busy++
+	if ch.busy > len(ch.bodies) {
+		return newBody(ch.shape) // not expected.
+	}
+	return ch.bodies[ch.busy-1]
+}
+
+// compound
+// ============================================================================
+// compound collision
+
+// collideCompound returns up to 4 contact points between compound body a
+// and body b. Each child of a is collided with b using the algorithm for
+// the child and b shapes. The deepest and most spread out contacts are kept.
+// Each child is collided using one of its scratch bodies.
+func (c *collider) collideCompound(a, b Body, pocs []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	aa, bb := a.(*body), b.(*body)
+	cs, ok := aa.shape.(*compound)
+	if !ok {
+		return a, b, pocs[0:0]
+	}
+	points, normals, depths := []lin.V3{}, []lin.V3{}, []float64{}
+	scratch, offset := newManifold(), &lin.V3{}
+	for _, ch := range cs.children {
+		algorithm := c.algorithms[ch.shape.Type()][bb.shape.Type()]
+		if algorithm == nil {
+			continue
+		}
+		cb := ch.acquire()
+		ch.place(aa.world, cb.world)
+		ca, _, found := algorithm(cb, bb, scratch)
+		ch.busy--
+		swapped := ca != Body(cb)
+		for _, poc := range found {
+			point, normal := *poc.point, *poc.normal
+			if swapped {
+				// move the contact from the child to b and reverse the normal.
+				point.Add(&point, offset.Scale(&normal, poc.depth))
+				normal.Neg(&normal)
+			}
+			points = append(points, point)
+			normals = append(normals, normal)
+			depths = append(depths, poc.depth)
+		}
+	}
+	k = pocs[0:0]
+	for _, index := range bestContacts(points, depths) {
+		poc := pocs[len(k)]
+		poc.point.Set(&points[index])
+		poc.normal.Set(&normals[index])
+		poc.depth = depths[index]
+		k = pocs[0 : len(k)+1]
+	}
+	return a, b, k
+}
+
+// collideShapeCompound reverses the collision so the compound body is first.
+func (c *collider) collideShapeCompound(a, b Body, pocs []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	return c.collideCompound(b, a, pocs)
+}
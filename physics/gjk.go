@@ -0,0 +1,728 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+// gjk.go is a general purpose narrowphase for any pair of convex shapes.
+// GJK checks for overlap by searching the Minkowski difference of the
+// two shapes for the origin. When the shapes overlap EPA expands the
+// final GJK simplex to find the penetration depth and contact normal.
+// Each shape only needs to provide its furthest point in a direction.
+// For background see:
+//    Real-Time Collision Detection by Christer Ericson. Section 9.5
+//    http://allenchou.net/2013/12/game-physics-collision-detection-gjk/
+//    http://allenchou.net/2013/12/game-physics-contact-generation-epa/
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// collideConvex uses GJK and EPA to find the contact normal between two
+// convex shapes. The parts of each shape touching along the normal are
+// then clipped against each other to return up to 4 contact points.
+// Both shapes are enlarged by the collision margin so that close enough
+// objects are reported as colliding. The GJK/EPA working data is reused
+// from the collider.
+func (col *collider) collideConvex(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	aa, bb := a.(*body), b.(*body)
+	g := col.gjk
+	g.a, g.b = aa, bb
+	if !g.overlaps() || !g.expand() {
+		return a, b, c[0:0] // no contact.
+	}
+	nx, ny, nz, depth, px, py, pz, ok := g.penetration()
+	if !ok {
+		return a, b, c[0:0] // degenerate contact.
+	}
+	normal := &lin.V3{X: -nx, Y: -ny, Z: -nz} // from B towards A.
+	if k = clipContacts(aa, bb, normal, c); len(k) > 0 {
+		return a, b, k
+	}
+
+	// use the EPA point if clipping fails.
+	c0 := c[0]
+	c0.normal.SetS(-nx, -ny, -nz) // from B towards A.
+	c0.point.SetS(px, py, pz)     // on B.
+	c0.depth = -depth
+	return a, b, c[0:1]
+}
+
+// collideConvex
+// ============================================================================
+// support functions
+
+// support returns the point on shape s furthest in the local
+// direction dx, dy, dz. The direction does not need to be unit length.
+func support(s Shape, dx, dy, dz float64) (x, y, z float64) {
+	switch sh := s.(type) {
+	case *sphere:
+		if l := math.Sqrt(dx*dx + dy*dy + dz*dz); l > lin.Epsilon {
+			return dx / l * sh.R, dy / l * sh.R, dz / l * sh.R
+		}
+		return sh.R, 0, 0
+	case *box:
+		return math.Copysign(sh.Hx, dx), math.Copysign(sh.Hy, dy), math.Copysign(sh.Hz, dz)
+	case *capsule:
+		x, y, z = 0, math.Copysign(sh.H, dy), 0
+		if l := math.Sqrt(dx*dx + dy*dy + dz*dz); l > lin.Epsilon {
+			return x + dx/l*sh.R, y + dy/l*sh.R, z + dz/l*sh.R
+		}
+		return x, y, z
+	case *cylinder:
+		y = math.Copysign(sh.H, dy)
+		if l := math.Sqrt(dx*dx + dz*dz); l > lin.Epsilon {
+			return dx / l * sh.R, y, dz / l * sh.R
+		}
+		return 0, y, 0
+	case *hull:
+		return sh.support(dx, dy, dz)
+	case *triangle:
+		return sh.support(dx, dy, dz)
+	}
+	return 0, 0, 0
+}
+
+// worldSupport returns the world space point on the body furthest in
+// the world direction d, including the collision margin.
+func worldSupport(b *body, d *lin.V3) (x, y, z float64) {
+	rot := b.world.Rot
+	inv := lin.Q{X: -rot.X, Y: -rot.Y, Z: -rot.Z, W: rot.W}
+	lx, ly, lz := lin.MultSQ(d.X, d.Y, d.Z, &inv)
+	x, y, z = support(b.shape, lx, ly, lz)
+	x, y, z = b.world.AppS(x, y, z)
+	if l := d.Len(); l > lin.Epsilon {
+		x, y, z = x+d.X/l*margin, y+d.Y/l*margin, z+d.Z/l*margin
+	}
+	return x, y, z
+}
+
+// support functions
+// ============================================================================
+// contact clipping
+
+// featureSlope is the sine of the angle, about 2 degrees, within which
+// shape vertices, edges, and faces are treated as facing the same way.
+const featureSlope = 0.035
+
+// feature returns the world space points of the part of body b that
+// is furthest in unit world direction d, including the collision margin.
+// The feature is a point, a line, or a convex polygon ordered around d.
+func feature(b *body, d *lin.V3) []lin.V3 {
+	rot := b.world.Rot
+	inv := lin.Q{X: -rot.X, Y: -rot.Y, Z: -rot.Z, W: rot.W}
+	dx, dy, dz := lin.MultSQ(d.X, d.Y, d.Z, &inv)
+	pts := []lin.V3{}
+	switch s := b.shape.(type) {
+	case *box:
+		corners := make([]lin.V3, 0, 8)
+		for _, x := range []float64{-s.Hx, s.Hx} {
+			for _, y := range []float64{-s.Hy, s.Hy} {
+				for _, z := range []float64{-s.Hz, s.Hz} {
+					corners = append(corners, lin.V3{X: x, Y: y, Z: z})
+				}
+			}
+		}
+		pts = polytopeFeature(corners, dx, dy, dz)
+	case *hull:
+		pts = polytopeFeature(s.points, dx, dy, dz)
+	case *triangle:
+		pts = polytopeFeature(s.points[:], dx, dy, dz)
+	case *capsule:
+		if math.Abs(dy) <= featureSlope { // side: the line between the caps.
+			rx, ry, rz := dx*s.R, dy*s.R, dz*s.R
+			pts = append(pts, lin.V3{X: rx, Y: ry - s.H, Z: rz}, lin.V3{X: rx, Y: ry + s.H, Z: rz})
+		}
+	case *cylinder:
+		y, side := math.Copysign(s.H, dy), math.Sqrt(dx*dx+dz*dz)
+		switch {
+		case side <= featureSlope: // cap: approximate the disc with 8 points.
+			for cnt := 0; cnt < 8; cnt++ {
+				angle := float64(cnt) * math.Pi / 4
+				pts = append(pts, lin.V3{X: s.R * math.Cos(angle), Y: y, Z: s.R * math.Sin(angle)})
+			}
+		case math.Abs(dy) <= featureSlope: // side: a line along the cylinder.
+			x, z := dx/side*s.R, dz/side*s.R
+			pts = append(pts, lin.V3{X: x, Y: -s.H, Z: z}, lin.V3{X: x, Y: s.H, Z: z})
+		}
+	}
+	if len(pts) == 0 {
+		x, y, z := support(b.shape, dx, dy, dz) // single point.
+		pts = append(pts, lin.V3{X: x, Y: y, Z: z})
+	}
+
+	// move to world space and order polygons around the direction.
+	for cnt := range pts {
+		p := &pts[cnt]
+		x, y, z := b.world.AppS(p.X, p.Y, p.Z)
+		p.SetS(x+d.X*margin, y+d.Y*margin, z+d.Z*margin)
+	}
+	if len(pts) > 2 {
+		center, u, v := &lin.V3{}, &lin.V3{}, &lin.V3{}
+		for cnt := range pts {
+			center.Add(center, &pts[cnt])
+		}
+		center.Scale(center, 1/float64(len(pts)))
+		perpendicular(d, u, v)
+		angles := make([]float64, len(pts))
+		for cnt := range pts {
+			offset := lin.NewV3().Sub(&pts[cnt], center)
+			angles[cnt] = math.Atan2(offset.Dot(v), offset.Dot(u))
+		}
+		for i := 1; i < len(pts); i++ { // insertion sort: few points.
+			for j := i; j > 0 && angles[j] < angles[j-1]; j-- {
+				angles[j], angles[j-1] = angles[j-1], angles[j]
+				pts[j], pts[j-1] = pts[j-1], pts[j]
+			}
+		}
+	}
+	return pts
+}
+
+// polytopeFeature returns the points furthest in direction dx, dy, dz
+// along with any points that are almost as far.
+func polytopeFeature(points []lin.V3, dx, dy, dz float64) []lin.V3 {
+	best, far := 0, -math.MaxFloat64
+	for cnt, p := range points {
+		if dot := p.X*dx + p.Y*dy + p.Z*dz; dot > far {
+			best, far = cnt, dot
+		}
+	}
+	pts := []lin.V3{}
+	for _, p := range points {
+		gap := far - (p.X*dx + p.Y*dy + p.Z*dz)
+		if gap <= featureSlope*lin.NewV3().Sub(&p, &points[best]).Len() {
+			pts = append(pts, p)
+		}
+	}
+	return pts
+}
+
+// clipContacts finds up to 4 contact points by clipping the feature
+// of one body against the feature of the other. The normal points from
+// B towards A. Returns no contacts if clipping fails.
+func clipContacts(a, b *body, normal *lin.V3, c []*pointOfContact) []*pointOfContact {
+	fa := feature(a, lin.NewV3().Neg(normal)) // part of A facing B.
+	fb := feature(b, normal)                  // part of B facing A.
+	points, depths := []lin.V3{}, []float64{}
+	switch {
+	case len(fa) == 1 || len(fb) == 1 || len(fa) == 2 && len(fb) == 2 && crossing(fa, fb):
+		pa, pb := &fa[0], &fb[0]
+		if len(fa) == 2 && len(fb) == 2 {
+			s, t := closestSegments(&fa[0], &fa[1], &fb[0], &fb[1])
+			da, db := lin.NewV3().Sub(&fa[1], &fa[0]), lin.NewV3().Sub(&fb[1], &fb[0])
+			pa = da.Add(&fa[0], da.Scale(da, s))
+			pb = db.Add(&fb[0], db.Scale(db, t))
+		}
+		depth := lin.NewV3().Sub(pa, pb).Dot(normal)
+		if len(fb) == 1 && len(fa) > 1 {
+			points, depths = append(points, *pb), append(depths, depth)
+			break
+		}
+		p := lin.NewV3().Scale(normal, -depth)
+		points, depths = append(points, *p.Add(p, pa)), append(depths, depth)
+	case len(fb) > 2 || len(fa) == 2:
+		// clip A against B and move the A points onto B.
+		height := fb[0].Dot(normal)
+		for _, p := range clipFeature(fa, fb, normal) {
+			depth := p.Dot(normal) - height
+			p.Sub(&p, lin.NewV3().Scale(normal, depth))
+			points, depths = append(points, p), append(depths, depth)
+		}
+	default:
+		// clip B against A. The B points are already on B.
+		height := fa[0].Dot(normal)
+		for _, p := range clipFeature(fb, fa, normal) {
+			points, depths = append(points, p), append(depths, height-p.Dot(normal))
+		}
+	}
+
+	// keep the overlapping points, reducing to the 4 that cover the most area.
+	k := c[0:0]
+	for _, index := range bestContacts(points, depths) {
+		if depths[index] < 0 && len(k) < len(c) {
+			poc := c[len(k)]
+			poc.point.Set(&points[index])
+			poc.normal.Set(normal)
+			poc.depth = depths[index]
+			k = c[0 : len(k)+1]
+		}
+	}
+	return k
+}
+
+// crossing returns true if the two line segments are not parallel.
+func crossing(sa, sb []lin.V3) bool {
+	da := lin.NewV3().Sub(&sa[1], &sa[0])
+	db := lin.NewV3().Sub(&sb[1], &sb[0])
+	if da.AeqZ() || db.AeqZ() {
+		return true
+	}
+	da.Unit()
+	db.Unit()
+	return lin.NewV3().Cross(da, db).Len() > featureSlope
+}
+
+// clipFeature clips the incident points against the sides of the
+// reference points. The sides of a polygon reference are planes through
+// each edge that contain the normal. The sides of a line reference are
+// planes through each end. Based on Sutherland-Hodgman clipping.
+func clipFeature(incident, ref []lin.V3, normal *lin.V3) []lin.V3 {
+	planes := [][2]lin.V3{} // point on plane and outward plane normal.
+	if len(ref) == 2 {
+		along := lin.NewV3().Sub(&ref[1], &ref[0])
+		planes = append(planes, [2]lin.V3{ref[1], *along}, [2]lin.V3{ref[0], *along.Neg(along)})
+	} else {
+		center := &lin.V3{}
+		for cnt := range ref {
+			center.Add(center, &ref[cnt])
+		}
+		center.Scale(center, 1/float64(len(ref)))
+		for cnt := range ref {
+			r0, r1 := &ref[cnt], &ref[(cnt+1)%len(ref)]
+			side := lin.NewV3().Cross(lin.NewV3().Sub(r1, r0), normal)
+			if side.Dot(lin.NewV3().Sub(center, r0)) > 0 {
+				side.Neg(side) // point away from the center.
+			}
+			planes = append(planes, [2]lin.V3{*r0, *side})
+		}
+	}
+	out := append([]lin.V3{}, incident...)
+	for _, plane := range planes {
+		in := out
+		out = []lin.V3{}
+		for cnt := range in {
+			p, q := &in[cnt], &in[(cnt+1)%len(in)]
+			dp := plane[1].Dot(lin.NewV3().Sub(p, &plane[0]))
+			dq := plane[1].Dot(lin.NewV3().Sub(q, &plane[0]))
+			if dp <= 0 {
+				out = append(out, *p)
+			}
+			if (len(in) > 2 || cnt == 0) && (dp < 0 && dq > 0 || dp > 0 && dq < 0) {
+				edge := lin.NewV3().Sub(q, p)
+				out = append(out, *edge.Add(p, edge.Scale(edge, dp/(dp-dq))))
+			}
+		}
+	}
+	return out
+}
+
+// bestContacts returns the indicies of up to 4 points that are deepest
+// and spread the furthest apart.
+func bestContacts(points []lin.V3, depths []float64) []int {
+	if len(points) <= 4 {
+		indicies := []int{}
+		for cnt := range points {
+			indicies = append(indicies, cnt)
+		}
+		return indicies
+	}
+	deepest, far, side, opposite := 0, 0, 0, 0
+	for cnt := range depths {
+		if depths[cnt] < depths[deepest] {
+			deepest = cnt
+		}
+	}
+	best := -1.0
+	for cnt := range points {
+		if d := lin.NewV3().Sub(&points[cnt], &points[deepest]).LenSqr(); d > best {
+			far, best = cnt, d
+		}
+	}
+
+	// then the points furthest from each side of the line between the first two.
+	line, edge, up := lin.NewV3().Sub(&points[far], &points[deepest]), &lin.V3{}, &lin.V3{}
+	best = -1
+	for cnt := range points {
+		cross := lin.NewV3().Cross(line, edge.Sub(&points[cnt], &points[deepest]))
+		if d := cross.LenSqr(); d > best {
+			side, best, up = cnt, d, cross
+		}
+	}
+	least := 0.0
+	for cnt := range points {
+		cross := lin.NewV3().Cross(line, edge.Sub(&points[cnt], &points[deepest]))
+		if area := cross.Dot(up); area < least {
+			opposite, least = cnt, area
+		}
+	}
+	indicies := []int{deepest, far, side}
+	if least < 0 {
+		indicies = append(indicies, opposite)
+	}
+	return indicies
+}
+
+// perpendicular sets u and v to be unit vectors perpendicular
+// to unit vector d and to each other.
+func perpendicular(d, u, v *lin.V3) {
+	if math.Abs(d.X) < 0.6 {
+		u.Cross(d, &lin.V3{X: 1})
+	} else {
+		u.Cross(d, &lin.V3{Y: 1})
+	}
+	u.Unit()
+	v.Cross(d, u)
+}
+
+// contact clipping
+// ============================================================================
+// gjk
+
+// gjkVertex is a point on the Minkowski difference A-B along with
+// the points from each shape that were used to create it.
+type gjkVertex struct {
+	w    lin.V3 // Minkowski difference point: a-b.
+	a, b lin.V3 // Support points on shape A and shape B.
+}
+
+// gjkFace is an EPA polytope face with an outward unit normal.
+type gjkFace struct {
+	v    [3]int  // Polytope vertex indicies.
+	n    lin.V3  // Outward unit normal.
+	dist float64 // Distance from the origin to the face plane.
+}
+
+// gjk holds the working data for GJK/EPA collision checks.
+// One gjk is kept by the collider and reused for each check.
+type gjk struct {
+	a, b    *body       // Bodies being checked.
+	simplex []gjkVertex // Newest vertex is last.
+	verts   []gjkVertex // EPA polytope vertices.
+	faces   []gjkFace   // EPA polytope faces.
+	visible []gjkFace   // EPA faces removed by the newest vertex.
+	edges   [][2]int    // EPA edges of the removed faces.
+
+	// scratch variables reused for each check.
+	d, n       lin.V3 // Search direction and face normal.
+	v0, v1, v2 lin.V3 // Working vectors.
+}
+
+// expandDirs are searched for points that add a new dimension
+// to a flat simplex.
+var expandDirs = []lin.V3{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}, {Z: 1}, {Z: -1}}
+
+// Limits on the GJK and EPA iterations and precision.
+const (
+	gjkIterations = 64
+	epaIterations = 64
+	epaTolerance  = 0.0001
+)
+
+// vertex returns the Minkowski difference support point in direction d.
+func (g *gjk) vertex(d *lin.V3) gjkVertex {
+	v := gjkVertex{}
+	v.a.X, v.a.Y, v.a.Z = worldSupport(g.a, d)
+	nd := lin.V3{X: -d.X, Y: -d.Y, Z: -d.Z}
+	v.b.X, v.b.Y, v.b.Z = worldSupport(g.b, &nd)
+	v.w.Sub(&v.a, &v.b)
+	return v
+}
+
+// overlaps returns true if the Minkowski difference contains the
+// origin, leaving the final simplex for EPA.
+func (g *gjk) overlaps() bool {
+	d := &g.d
+	d.Sub(g.a.world.Loc, g.b.world.Loc)
+	if d.AeqZ() {
+		d.SetS(1, 0, 0)
+	}
+	g.simplex = append(g.simplex[:0], g.vertex(d))
+	d.Neg(&g.simplex[0].w)
+	for cnt := 0; cnt < gjkIterations; cnt++ {
+		if d.AeqZ() {
+			return true // origin is on the simplex.
+		}
+		v := g.vertex(d)
+		if v.w.Dot(d) < 0 {
+			return false // the origin was not passed: no overlap.
+		}
+		g.simplex = append(g.simplex, v)
+		if g.nearest(d) {
+			return true
+		}
+	}
+	return false // no contact if the search did not finish.
+}
+
+// nearest reduces the simplex to the feature closest to the origin
+// and updates d to point from that feature towards the origin.
+// Returns true if the simplex contains the origin.
+func (g *gjk) nearest(d *lin.V3) bool {
+	switch len(g.simplex) {
+	case 2:
+		g.line(d)
+	case 3:
+		g.triangle(d)
+	case 4:
+		return g.tetrahedron(d)
+	}
+	return false
+}
+
+// line handles a two point simplex where a is the newest point.
+func (g *gjk) line(d *lin.V3) {
+	a, b := &g.simplex[1].w, &g.simplex[0].w
+	ab, ao := g.v0.Sub(b, a), g.v1.Neg(a)
+	if ab.Dot(ao) > 0 {
+		tripleCross(d, ab, ao, ab, &g.v2) // perpendicular to ab towards origin.
+		return
+	}
+	g.simplex = append(g.simplex[:0], g.simplex[1])
+	d.Set(ao)
+}
+
+// triangle handles a three point simplex where a is the newest point.
+func (g *gjk) triangle(d *lin.V3) {
+	va, vb, vc := g.simplex[2], g.simplex[1], g.simplex[0]
+	a, b, c := &va.w, &vb.w, &vc.w
+	ab, ac, ao := g.v0.Sub(b, a), g.v1.Sub(c, a), g.v2.Neg(a)
+	abc, edge := g.n.Cross(ab, ac), lin.V3{}
+	switch {
+	case edge.Cross(abc, ac).Dot(ao) > 0: // outside edge ac.
+		if ac.Dot(ao) > 0 {
+			g.simplex = append(g.simplex[:0], vc, va)
+			tripleCross(d, ac, ao, ac, &edge)
+			return
+		}
+		g.simplex = append(g.simplex[:0], vb, va)
+		g.line(d)
+	case edge.Cross(ab, abc).Dot(ao) > 0: // outside edge ab.
+		g.simplex = append(g.simplex[:0], vb, va)
+		g.line(d)
+	case abc.Dot(ao) > 0: // above the triangle.
+		g.simplex = append(g.simplex[:0], vc, vb, va)
+		d.Set(abc)
+	default: // below the triangle.
+		g.simplex = append(g.simplex[:0], vb, vc, va)
+		d.Neg(abc)
+	}
+}
+
+// tetrahedron handles a four point simplex where a is the newest point.
+// The origin is inside unless it is outside one of the faces that
+// include the newest point.
+func (g *gjk) tetrahedron(d *lin.V3) bool {
+	va, vb, vc, vd := g.simplex[3], g.simplex[2], g.simplex[1], g.simplex[0]
+	ao := g.v0.Neg(&va.w)
+	for _, f := range [3][3]gjkVertex{{vb, vc, vd}, {vc, vd, vb}, {vd, vb, vc}} {
+		n := faceNormal(&g.n, &va.w, &f[0].w, &f[1].w)
+		if n.Dot(g.v1.Sub(&f[2].w, &va.w)) > 0 {
+			n.Neg(n) // face away from the opposite point.
+		}
+		if n.Dot(ao) > 0 {
+			g.simplex = append(g.simplex[:0], f[1], f[0], va)
+			g.triangle(d)
+			return false
+		}
+	}
+	return true
+}
+
+// gjk
+// ============================================================================
+// epa
+
+// expand turns the final GJK simplex into a tetrahedron that can be
+// expanded by EPA. Returns false if the shapes are too flat to expand.
+func (g *gjk) expand() bool {
+	for len(g.simplex) < 4 {
+		added := false
+		switch len(g.simplex) {
+		case 1, 2:
+			// search the axis directions for a point off the line.
+			for cnt := range expandDirs {
+				v := g.vertex(&expandDirs[cnt])
+				if g.independent(&v) {
+					g.simplex, added = append(g.simplex, v), true
+					break
+				}
+			}
+		case 3:
+			// search both sides of the triangle.
+			s := g.simplex
+			n := faceNormal(&g.n, &s[0].w, &s[1].w, &s[2].w)
+			for _, dir := range [2]*lin.V3{n, g.d.Neg(n)} {
+				v := g.vertex(dir)
+				if g.independent(&v) {
+					g.simplex, added = append(g.simplex, v), true
+					break
+				}
+			}
+		}
+		if !added {
+			return false
+		}
+	}
+
+	// create the starting polytope with outward facing faces.
+	g.verts = append(g.verts[:0], g.simplex...)
+	g.faces = g.faces[:0]
+	inside := g.v2.SetS(0, 0, 0)
+	for cnt := range g.verts {
+		inside.Add(inside, &g.verts[cnt].w)
+	}
+	inside.Scale(inside, 0.25)
+	for _, f := range [][3]int{{0, 1, 2}, {0, 1, 3}, {0, 2, 3}, {1, 2, 3}} {
+		if !g.addFace(f[0], f[1], f[2], inside) {
+			return false
+		}
+	}
+	return true
+}
+
+// independent returns true if vertex v adds a new dimension to the simplex.
+func (g *gjk) independent(v *gjkVertex) bool {
+	s, e := g.simplex, 0.000001
+	switch len(s) {
+	case 1:
+		return lin.NewV3().Sub(&v.w, &s[0].w).LenSqr() > e
+	case 2:
+		ab := lin.NewV3().Sub(&s[1].w, &s[0].w)
+		av := lin.NewV3().Sub(&v.w, &s[0].w)
+		return lin.NewV3().Cross(ab, av).LenSqr() > e
+	case 3:
+		n := lin.NewV3().Cross(lin.NewV3().Sub(&s[1].w, &s[0].w), lin.NewV3().Sub(&s[2].w, &s[0].w))
+		return math.Abs(n.Dot(lin.NewV3().Sub(&v.w, &s[0].w))) > e
+	}
+	return false
+}
+
+// addFace adds a polytope face oriented away from the inside point.
+// Returns false for faces without area.
+func (g *gjk) addFace(i0, i1, i2 int, inside *lin.V3) bool {
+	a, b, c := &g.verts[i0].w, &g.verts[i1].w, &g.verts[i2].w
+	n := faceNormal(&g.n, a, b, c)
+	if n.AeqZ() {
+		return false
+	}
+	if n.Dot(g.v0.Sub(a, inside)) < 0 {
+		n.Neg(n)
+		i1, i2 = i2, i1
+	}
+	g.faces = append(g.faces, gjkFace{v: [3]int{i0, i1, i2}, n: *n, dist: n.Dot(a)})
+	return true
+}
+
+// penetration expands the polytope towards the Minkowski difference
+// surface until the face closest to the origin is found. The result
+// is the unit normal and depth of the closest face, and the matching
+// point on shape B.
+func (g *gjk) penetration() (nx, ny, nz, depth, px, py, pz float64, ok bool) {
+	closest := 0
+	for cnt := 0; cnt < epaIterations; cnt++ {
+		closest = 0
+		for index := range g.faces {
+			if g.faces[index].dist < g.faces[closest].dist {
+				closest = index
+			}
+		}
+		f := g.faces[closest]
+		v := g.vertex(&f.n)
+		if v.w.Dot(&f.n)-f.dist < epaTolerance {
+			break // can't get any closer to the surface.
+		}
+
+		// remove the faces that can see the new point and
+		// patch the hole with faces that use the new point.
+		g.verts = append(g.verts, v)
+		vi := len(g.verts) - 1
+		g.edges, g.visible = g.edges[:0], g.visible[:0]
+		kept := g.faces[:0]
+		for _, face := range g.faces {
+			if face.n.Dot(g.v0.Sub(&v.w, &g.verts[face.v[0]].w)) > 0 {
+				g.visible = append(g.visible, face)
+				for k := 0; k < 3; k++ {
+					g.edges = append(g.edges, [2]int{face.v[k], face.v[(k+1)%3]})
+				}
+			} else {
+				kept = append(kept, face)
+			}
+		}
+		g.faces = kept
+		for _, face := range g.visible {
+			for k := 0; k < 3; k++ {
+				e0, e1 := face.v[k], face.v[(k+1)%3]
+				if !g.shared(e0, e1) { // horizon edge.
+					n := faceNormal(&g.n, &g.verts[e0].w, &g.verts[e1].w, &v.w)
+					if n.AeqZ() {
+						continue
+					}
+					g.faces = append(g.faces, gjkFace{v: [3]int{e0, e1, vi}, n: *n, dist: n.Dot(&v.w)})
+				}
+			}
+		}
+		if len(g.faces) == 0 {
+			return 0, 0, 0, 0, 0, 0, 0, false
+		}
+	}
+	closest = 0
+	for index := range g.faces {
+		if g.faces[index].dist < g.faces[closest].dist {
+			closest = index
+		}
+	}
+
+	// project the origin onto the closest face and use the same
+	// barycentric coordinates to find the contact point on B.
+	f := g.faces[closest]
+	va, vb, vc := &g.verts[f.v[0]], &g.verts[f.v[1]], &g.verts[f.v[2]]
+	p := g.v0.Scale(&f.n, f.dist)
+	u, v, w := barycentric(p, &va.w, &vb.w, &vc.w)
+	px = u*va.b.X + v*vb.b.X + w*vc.b.X
+	py = u*va.b.Y + v*vb.b.Y + w*vc.b.Y
+	pz = u*va.b.Z + v*vb.b.Z + w*vc.b.Z
+	return f.n.X, f.n.Y, f.n.Z, f.dist, px, py, pz, true
+}
+
+// shared returns true if the edge e0, e1 is also used, in the opposite
+// direction, by another face that can see the newest vertex.
+func (g *gjk) shared(e0, e1 int) bool {
+	for _, edge := range g.edges {
+		if edge[0] == e1 && edge[1] == e0 {
+			return true
+		}
+	}
+	return false
+}
+
+// epa
+// ============================================================================
+// utility functions.
+
+// faceNormal sets n to be the unit normal of the counter-clockwise
+// triangle a, b, c and returns n. n is set to the zero vector if
+// there is no area.
+func faceNormal(n, a, b, c *lin.V3) *lin.V3 {
+	ab, ac := lin.V3{}, lin.V3{}
+	n.Cross(ab.Sub(b, a), ac.Sub(c, a))
+	if l := n.Len(); l > lin.Epsilon*lin.Epsilon {
+		return n.Scale(n, 1/l)
+	}
+	return n.SetS(0, 0, 0)
+}
+
+// tripleCross sets v to be (a x b) x c and returns v.
+// Scratch vector ab must be different from the other vectors.
+func tripleCross(v, a, b, c, ab *lin.V3) *lin.V3 {
+	ab.Cross(a, b)
+	return v.Cross(ab, c)
+}
+
+// barycentric returns the barycentric coordinates of point p
+// projected onto the triangle a, b, c.
+//
+// Based on Real-Time Collision Detection by Christer Ericson. Section 3.4
+func barycentric(p, a, b, c *lin.V3) (u, v, w float64) {
+	v0, v1, v2 := lin.NewV3().Sub(b, a), lin.NewV3().Sub(c, a), lin.NewV3().Sub(p, a)
+	d00, d01, d11 := v0.Dot(v0), v0.Dot(v1), v1.Dot(v1)
+	d20, d21 := v2.Dot(v0), v2.Dot(v1)
+	denom := d00*d11 - d01*d01
+	if math.Abs(denom) < lin.Epsilon*lin.Epsilon {
+		return 1, 0, 0 // degenerate triangle: use the first point.
+	}
+	v = (d11*d20 - d01*d21) / denom
+	w = (d00*d21 - d01*d20) / denom
+	return 1 - v - w, v, w
+}
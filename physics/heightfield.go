@@ -0,0 +1,135 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+// heightfield.go is a static terrain shape made from a grid of heights.
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// heightfield is a static collision shape made from an evenly spaced
+// grid of heights, ie: procedurally generated land. Each grid cell is
+// two triangles facing up. Heightfields have no volume and are expected
+// to be used with bodies that have no mass.
+type heightfield struct {
+	heights [][]float64 // Y values indexed by [x][z] grid points.
+	spacing float64     // Distance between grid points.
+	ab      *Abox       // Local space bounds.
+	tris    []triangle  // Scratch triangles returned by queries.
+	tab     Abox        // Scratch triangle bounds for queries.
+}
+
+// NewHeightfield creates a static terrain shape from a grid of heights,
+// ie: the synth.Tile.Topo() height data. The grid rows are along the X
+// axis and the grid columns are along the Z axis. The grid is centered
+// on the origin in X and Z.
+//    topo    : height values indexed by [x][z]. The grid must be at least 2x2.
+//    spacing : distance between grid points.
+//    scale   : multiplies each topo height to give the local Y value.
+func NewHeightfield(topo [][]float64, spacing, scale float64) Shape {
+	h := &heightfield{spacing: math.Abs(spacing), ab: &Abox{}}
+	nz := 0
+	if len(topo) > 0 {
+		nz = len(topo[0])
+	}
+	h.heights = make([][]float64, len(topo))
+	for x := range topo {
+		h.heights[x] = make([]float64, nz)
+		for z := 0; z < nz && z < len(topo[x]); z++ {
+			h.heights[x][z] = topo[x][z] * scale
+		}
+	}
+	h.bounds()
+	return h
+}
+
+// Implements Shape.Type
+func (h *heightfield) Type() int { return HeightfieldShape }
+
+// Implements Shape.Aabb
+// The bounding box surrounds the transformed height bounds.
+func (h *heightfield) Aabb(t *lin.T, ab *Abox, margin float64) *Abox {
+	return transformAabb(h.ab, t, ab, margin)
+}
+
+// Implements Shape.Volume
+// Heightfields are a surface and have no volume.
+func (h *heightfield) Volume() float64 { return 0 }
+
+// Implements Shape.Inertia
+// Heightfields are static and have no inertia.
+func (h *heightfield) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	return inertia.SetS(0, 0, 0)
+}
+
+// size returns the number of grid points along X and Z.
+func (h *heightfield) size() (nx, nz int) {
+	if nx = len(h.heights); nx > 0 {
+		nz = len(h.heights[0])
+	}
+	return nx, nz
+}
+
+// bounds calculates the local space bounding box.
+func (h *heightfield) bounds() {
+	nx, nz := h.size()
+	if nx < 2 || nz < 2 {
+		*h.ab = Abox{}
+		return
+	}
+	h.ab.Sy, h.ab.Ly = math.MaxFloat64, -math.MaxFloat64
+	for x := range h.heights {
+		for _, y := range h.heights[x] {
+			h.ab.Sy, h.ab.Ly = math.Min(h.ab.Sy, y), math.Max(h.ab.Ly, y)
+		}
+	}
+	h.ab.Sx, h.ab.Lx = h.point(0, 0).X, h.point(nx-1, 0).X
+	h.ab.Sz, h.ab.Lz = h.point(0, 0).Z, h.point(0, nz-1).Z
+}
+
+// point returns the local space location of the given grid point.
+func (h *heightfield) point(x, z int) lin.V3 {
+	nx, nz := h.size()
+	return lin.V3{
+		X: (float64(x) - float64(nx-1)/2) * h.spacing,
+		Y: h.heights[x][z],
+		Z: (float64(z) - float64(nz-1)/2) * h.spacing,
+	}
+}
+
+// triangles implements triangleSource by returning the triangles
+// of the grid cells that are under the local space bounding box ab.
+func (h *heightfield) triangles(ab *Abox) []triangle {
+	h.tris = h.tris[:0]
+	nx, nz := h.size()
+	if nx < 2 || nz < 2 || h.spacing <= 0 || !overlaps(h.ab, ab) {
+		return h.tris
+	}
+
+	// find the range of grid cells under the box.
+	cell := func(v, offset float64, max int) int {
+		c := int(math.Floor((v + offset) / h.spacing))
+		return int(lin.Clamp(float64(c), 0, float64(max-2)))
+	}
+	ox, oz := float64(nx-1)/2*h.spacing, float64(nz-1)/2*h.spacing
+	x0, x1 := cell(ab.Sx, ox, nx), cell(ab.Lx, ox, nx)
+	z0, z1 := cell(ab.Sz, oz, nz), cell(ab.Lz, oz, nz)
+	for x := x0; x <= x1; x++ {
+		for z := z0; z <= z1; z++ {
+			p00, p10 := h.point(x, z), h.point(x+1, z)
+			p01, p11 := h.point(x, z+1), h.point(x+1, z+1)
+			first := len(h.tris) // triangles kept if they overlap.
+			h.tris = append(h.tris, triangle{[3]lin.V3{p00, p01, p10}}, triangle{[3]lin.V3{p10, p01, p11}})
+			for cnt := len(h.tris) - 1; cnt >= first; cnt-- {
+				if !overlaps(h.tris[cnt].Aabb(nil, &h.tab, 0), ab) {
+					h.tris = append(h.tris[:cnt], h.tris[cnt+1:]...)
+				}
+			}
+		}
+	}
+	return h.tris
+}
@@ -0,0 +1,262 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+// hull.go builds convex hull shapes from a set of points, ie: the
+// vertices of a model mesh. The hull is found using an incremental
+// algorithm that grows a tetrahedron by adding the points outside
+// the current hull. For background see:
+//    Real-Time Collision Detection by Christer Ericson. Section 12.4.3
+//    http://www.cs.jhu.edu/~misha/Spring16/09.pdf
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// hull is a collision shape primitive that is the smallest convex
+// shape surrounding a set of points. The points are in local space
+// where the origin is expected to be inside the hull.
+type hull struct {
+	points []lin.V3 // Hull vertices. Interior points are discarded.
+	faces  []int    // Triangle indicies into points, 3 per face.
+	volume float64  // Calculated once on creation.
+	cov    lin.V3   // Unit density second moments about each axis.
+}
+
+// NewHull creates a convex Hull shape from x, y, z point triples,
+// ie: the vertex positions, load.MshData.V, of a model mesh.
+// Points inside the hull are discarded. Points that are all in a
+// plane, or a line, give a hull with no volume that can still collide.
+func NewHull(points []float32) Shape {
+	pts := make([]lin.V3, 0, len(points)/3)
+	for cnt := 0; cnt+2 < len(points); cnt += 3 {
+		x, y, z := float64(points[cnt]), float64(points[cnt+1]), float64(points[cnt+2])
+		pts = append(pts, lin.V3{X: x, Y: y, Z: z})
+	}
+	return newHull(pts)
+}
+
+// newHull creates the hull around the given points.
+func newHull(pts []lin.V3) *hull {
+	h := &hull{}
+	faces := hullFaces(pts)
+	if faces == nil {
+		h.points = hullUnique(pts) // flat: keep all points.
+		return h
+	}
+
+	// keep only the points used by the hull faces.
+	index := map[int]int{}
+	for _, fi := range faces {
+		if _, ok := index[fi]; !ok {
+			index[fi] = len(h.points)
+			h.points = append(h.points, pts[fi])
+		}
+		h.faces = append(h.faces, index[fi])
+	}
+	h.massProperties()
+	return h
+}
+
+// Implements Shape.Type
+func (h *hull) Type() int { return HullShape }
+
+// Implements Shape.Aabb
+// The bounding box surrounds each transformed hull point.
+func (h *hull) Aabb(t *lin.T, ab *Abox, margin float64) *Abox {
+	ab.Sx, ab.Sy, ab.Sz = t.Loc.X, t.Loc.Y, t.Loc.Z
+	ab.Lx, ab.Ly, ab.Lz = t.Loc.X, t.Loc.Y, t.Loc.Z
+	for _, p := range h.points {
+		x, y, z := t.AppS(p.X, p.Y, p.Z)
+		ab.Sx, ab.Sy, ab.Sz = math.Min(ab.Sx, x), math.Min(ab.Sy, y), math.Min(ab.Sz, z)
+		ab.Lx, ab.Ly, ab.Lz = math.Max(ab.Lx, x), math.Max(ab.Ly, y), math.Max(ab.Lz, z)
+	}
+	ab.Sx, ab.Sy, ab.Sz = ab.Sx-margin, ab.Sy-margin, ab.Sz-margin
+	ab.Lx, ab.Ly, ab.Lz = ab.Lx+margin, ab.Ly+margin, ab.Lz+margin
+	return ab
+}
+
+// Implements Shape.Volume
+func (h *hull) Volume() float64 { return h.volume }
+
+// Implements Shape.Inertia
+// The inertia is about the local origin using a uniform density.
+func (h *hull) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	if h.volume <= 0 {
+		return inertia.SetS(0, 0, 0)
+	}
+	d, c := mass/h.volume, h.cov
+	inertia.SetS(d*(c.Y+c.Z), d*(c.X+c.Z), d*(c.X+c.Y))
+	return inertia
+}
+
+// massProperties calculates the volume and second moments by summing
+// the tetrahedrons formed by the origin and each outward facing face.
+// Based on "How to find the inertia tensor (or other mass properties)
+// of a 3D solid body represented by a triangle mesh" by Jonathan Blow
+// and Atman Binstock.
+func (h *hull) massProperties() {
+	h.volume, h.cov = 0, lin.V3{}
+	cross := &lin.V3{}
+	for cnt := 0; cnt+2 < len(h.faces); cnt += 3 {
+		a, b, c := &h.points[h.faces[cnt]], &h.points[h.faces[cnt+1]], &h.points[h.faces[cnt+2]]
+		det := a.Dot(cross.Cross(b, c)) // 6 times the signed volume.
+		h.volume += det / 6
+		sx, sy, sz := a.X+b.X+c.X, a.Y+b.Y+c.Y, a.Z+b.Z+c.Z
+		h.cov.X += det / 120 * (a.X*a.X + b.X*b.X + c.X*c.X + sx*sx)
+		h.cov.Y += det / 120 * (a.Y*a.Y + b.Y*b.Y + c.Y*c.Y + sy*sy)
+		h.cov.Z += det / 120 * (a.Z*a.Z + b.Z*b.Z + c.Z*c.Z + sz*sz)
+	}
+}
+
+// support returns the hull point furthest in direction dx, dy, dz.
+func (h *hull) support(dx, dy, dz float64) (x, y, z float64) {
+	best := -math.MaxFloat64
+	for cnt := range h.points {
+		p := &h.points[cnt]
+		if dot := p.X*dx + p.Y*dy + p.Z*dz; dot > best {
+			best, x, y, z = dot, p.X, p.Y, p.Z
+		}
+	}
+	return x, y, z
+}
+
+// hull
+// ============================================================================
+// hull construction
+
+// hullEpsilon is the distance that points must be outside a face
+// to be added to the hull. Closer points are treated as on the face.
+const hullEpsilon = 0.00001
+
+// hullFaces returns outward facing triangles, 3 point indicies
+// per face, for the convex hull of the given points. Nil is
+// returned if the points do not enclose a volume.
+func hullFaces(pts []lin.V3) []int {
+	i0, i1, i2, i3 := hullStart(pts)
+	if i3 < 0 {
+		return nil
+	}
+
+	// an interior point is used to orient the starting faces outwards.
+	inside := &lin.V3{}
+	for _, i := range []int{i0, i1, i2, i3} {
+		inside.Add(inside, &pts[i])
+	}
+	inside.Scale(inside, 0.25)
+	faces := []int{}
+	for _, f := range [][3]int{{i0, i1, i2}, {i0, i1, i3}, {i0, i2, i3}, {i1, i2, i3}} {
+		if hullDist(pts, f[0], f[1], f[2], inside) > 0 {
+			f[1], f[2] = f[2], f[1] // flip to face away from inside.
+		}
+		faces = append(faces, f[0], f[1], f[2])
+	}
+
+	// add each point outside the current hull by replacing the faces
+	// it can see with faces joining it to the edge of the visible area.
+	edges := map[[2]int]bool{}
+	for pi := range pts {
+		visible := []int{}
+		kept := []int{}
+		for cnt := 0; cnt < len(faces); cnt += 3 {
+			f := faces[cnt : cnt+3]
+			if hullDist(pts, f[0], f[1], f[2], &pts[pi]) > hullEpsilon {
+				visible = append(visible, f...)
+			} else {
+				kept = append(kept, f...)
+			}
+		}
+		if len(visible) == 0 {
+			continue // point is inside or on the hull.
+		}
+		for key := range edges {
+			delete(edges, key)
+		}
+		for cnt := 0; cnt < len(visible); cnt += 3 {
+			f := visible[cnt : cnt+3]
+			edges[[2]int{f[0], f[1]}] = true
+			edges[[2]int{f[1], f[2]}] = true
+			edges[[2]int{f[2], f[0]}] = true
+		}
+		for cnt := 0; cnt < len(visible); cnt += 3 {
+			f := visible[cnt : cnt+3]
+			for k := 0; k < 3; k++ {
+				a, b := f[k], f[(k+1)%3]
+				if !edges[[2]int{b, a}] { // horizon edge.
+					kept = append(kept, a, b, pi)
+				}
+			}
+		}
+		faces = kept
+	}
+	return faces
+}
+
+// hullStart finds four points that form a tetrahedron with volume.
+// The last index is -1 if the points are flat.
+func hullStart(pts []lin.V3) (i0, i1, i2, i3 int) {
+	if len(pts) < 4 {
+		return 0, 0, 0, -1
+	}
+
+	// start with the extreme x point and the point furthest from it.
+	for cnt := range pts {
+		if pts[cnt].X < pts[i0].X {
+			i0 = cnt
+		}
+	}
+	v0, v1 := &lin.V3{}, &lin.V3{}
+	best := 0.0
+	for cnt := range pts {
+		if d := v0.Sub(&pts[cnt], &pts[i0]).LenSqr(); d > best {
+			best, i1 = d, cnt
+		}
+	}
+
+	// then the point furthest from the line and from the plane.
+	best, i2 = 0, -1
+	edge := (&lin.V3{}).Sub(&pts[i1], &pts[i0])
+	for cnt := range pts {
+		if d := v1.Cross(edge, v0.Sub(&pts[cnt], &pts[i0])).LenSqr(); d > best {
+			best, i2 = d, cnt
+		}
+	}
+	if i2 < 0 || best < hullEpsilon*hullEpsilon {
+		return i0, i1, 0, -1
+	}
+	best, i3 = hullEpsilon, -1
+	for cnt := range pts {
+		if d := math.Abs(hullDist(pts, i0, i1, i2, &pts[cnt])); d > best {
+			best, i3 = d, cnt
+		}
+	}
+	return i0, i1, i2, i3
+}
+
+// hullDist returns the distance of point p from the plane of face a, b, c.
+// Positive distances are on the counter-clockwise face side.
+func hullDist(pts []lin.V3, a, b, c int, p *lin.V3) float64 {
+	ab := (&lin.V3{}).Sub(&pts[b], &pts[a])
+	ac := (&lin.V3{}).Sub(&pts[c], &pts[a])
+	n := (&lin.V3{}).Cross(ab, ac)
+	if length := n.Len(); length > 0 {
+		return n.Dot(ab.Sub(p, &pts[a])) / length
+	}
+	return 0
+}
+
+// hullUnique returns the points without duplicates.
+func hullUnique(pts []lin.V3) []lin.V3 {
+	unique := []lin.V3{}
+	seen := map[lin.V3]bool{}
+	for _, p := range pts {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
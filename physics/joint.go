@@ -0,0 +1,485 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+// joint.go constrains the motion of bodies relative to each other,
+// ie: doors on hinges, ragdoll limbs, and chains.
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Joint connects two bodies, or a body and the world, so that they
+// move together. Joints are created with the bodies in their starting
+// positions and are active once added using Physics.AddJoint.
+// Joined bodies do not collide with each other.
+//
+// Joints are solved along with contacts, so they are not perfectly
+// rigid. Expect some stretching when a joint is heavily loaded.
+type Joint interface {
+	Bodies() (a, b Body) // Joined bodies. B is nil for a world joint.
+
+	// setup adds the joint constraints to the solver.
+	setup(sol *solver, info *solverInfo)
+}
+
+// Hinge is a Joint that only allows rotation around a single axis,
+// ie: a door or a wheel.
+type Hinge interface {
+	Joint
+
+	// SetLimits restricts the hinge angle to the given range in radians.
+	// The starting angle is zero. Limits are removed when lo > hi.
+	SetLimits(lo, hi float64) Hinge
+
+	// SetMotor turns body a, relative to body b, around the hinge axis.
+	// Positive speeds turn by the right hand rule around the hinge axis.
+	// The motor is turned off by a zero maxTorque.
+	//    speed     : target angular speed in radians per second.
+	//    maxTorque : strength of the motor.
+	SetMotor(speed, maxTorque float64) Hinge
+
+	// Angle returns the current hinge angle in radians. The angle keeps
+	// counting past a half turn, ie: a full turn is 2*Pi, so that limits
+	// near Pi do not jump between the low and high limit.
+	Angle() float64
+}
+
+// Slider is a Joint that only allows movement along a single axis,
+// ie: a drawer or a piston. The bodies do not rotate relative
+// to each other.
+type Slider interface {
+	Joint
+
+	// SetLimits restricts how far body a can move along the slider axis
+	// from its starting position. Limits are removed when lo > hi.
+	SetLimits(lo, hi float64) Slider
+	Offset() float64 // Offset returns the current distance moved.
+}
+
+// Distance is a Joint that keeps anchor points on two bodies a fixed
+// distance apart, ie: a rigid rod. Giving the joint a spring allows
+// the distance to change, ie: a bungee cord or a car suspension.
+type Distance interface {
+	Joint
+
+	// SetLength changes the distance between the anchor points.
+	// The starting length is the distance at creation.
+	SetLength(length float64) Distance
+
+	// SetSpring makes the joint springy. A zero stiffness makes the
+	// joint rigid again.
+	//    stiffness : spring strength. Larger values are stiffer.
+	//    damping   : slows the spring oscillations.
+	SetSpring(stiffness, damping float64) Distance
+}
+
+// NewBallJoint joins two bodies at a shared pivot point so that they
+// can turn freely around the pivot, ie: a shoulder or a chain link.
+//    a, b  : bodies to join. Use nil b to pin body a to the world.
+//    x,y,z : pivot in world coordinates.
+func NewBallJoint(a, b Body, x, y, z float64) Joint {
+	j := newJoint(a, b, 3)
+	j.setPivots(x, y, z, x, y, z)
+	return &ball{joint: j}
+}
+
+// NewHingeJoint joins two bodies at a pivot point so that they can only
+// turn around the given axis.
+//    a, b     : bodies to join. Use nil b to hinge body a to the world.
+//    x,y,z    : pivot in world coordinates.
+//    ax,ay,az : hinge axis in world coordinates.
+func NewHingeJoint(a, b Body, x, y, z, ax, ay, az float64) Hinge {
+	h := &hinge{joint: newJoint(a, b, 7), lo: 1, hi: -1}
+	h.setPivots(x, y, z, x, y, z)
+	axis := (&lin.V3{X: ax, Y: ay, Z: az}).Unit()
+	ref := &lin.V3{}
+	perpendicular(axis, ref, &lin.V3{})
+	h.axisA.X, h.axisA.Y, h.axisA.Z = toLocalR(h.a.world, axis.X, axis.Y, axis.Z)
+	h.axisB.X, h.axisB.Y, h.axisB.Z = toLocalR(h.tb(), axis.X, axis.Y, axis.Z)
+	h.refA.X, h.refA.Y, h.refA.Z = toLocalR(h.a.world, ref.X, ref.Y, ref.Z)
+	h.refB.X, h.refB.Y, h.refB.Z = toLocalR(h.tb(), ref.X, ref.Y, ref.Z)
+	return h
+}
+
+// NewSliderJoint joins two bodies so that body a can only move along
+// the given axis relative to body b.
+//    a, b     : bodies to join. Use nil b to slide body a along the world.
+//    ax,ay,az : slider axis in world coordinates.
+func NewSliderJoint(a, b Body, ax, ay, az float64) Slider {
+	s := &slider{joint: newJoint(a, b, 6), lo: 1, hi: -1}
+	x, y, z := s.a.world.Loc.X, s.a.world.Loc.Y, s.a.world.Loc.Z
+	s.setPivots(x, y, z, x, y, z)
+	s.setBasis()
+	axis := (&lin.V3{X: ax, Y: ay, Z: az}).Unit()
+	s.axisA.X, s.axisA.Y, s.axisA.Z = toLocalR(s.a.world, axis.X, axis.Y, axis.Z)
+	return s
+}
+
+// NewFixedJoint glues two bodies together in their current positions.
+//    a, b : bodies to join. Use nil b to fix body a to the world.
+func NewFixedJoint(a, b Body) Joint {
+	f := &fixed{joint: newJoint(a, b, 6)}
+	x, y, z := f.a.world.Loc.X, f.a.world.Loc.Y, f.a.world.Loc.Z
+	f.setPivots(x, y, z, x, y, z)
+	f.setBasis()
+	return f
+}
+
+// NewDistanceJoint keeps an anchor point on each body at their current
+// distance apart. The bodies can turn freely around the anchor points.
+//    a, b     : bodies to join. Use nil b to tie body a to the world.
+//    ax,ay,az : anchor on body a in world coordinates.
+//    bx,by,bz : anchor on body b in world coordinates.
+func NewDistanceJoint(a, b Body, ax, ay, az, bx, by, bz float64) Distance {
+	d := &distance{joint: newJoint(a, b, 1)}
+	d.setPivots(ax, ay, az, bx, by, bz)
+	d.length = math.Sqrt((ax-bx)*(ax-bx) + (ay-by)*(ay-by) + (az-bz)*(az-bz))
+	return d
+}
+
+// unlimited is used for joint constraints that can push or pull
+// as much as needed.
+var unlimited = math.MaxFloat64
+
+// joint constructors
+// ============================================================================
+// joint is the common part of all joints.
+
+// joint holds the bodies and pivots shared by all joint types.
+// Pivots and directions are kept in the local space of each body
+// so that they follow the body. World joints keep the b values
+// in world space.
+type joint struct {
+	a, b           *body               // Joined bodies. B is nil for world joints.
+	pivotA, pivotB lin.V3              // Pivot in each body local space.
+	basisA, basisB [3]lin.V3           // World axes at creation in local space.
+	rows           []*solverConstraint // One solver constraint per joint axis.
+
+	// scratch variables used while setting up solver constraints.
+	pa, pb, ra, rb lin.V3 // World pivots and relative positions.
+	v0, v1, v2     lin.V3 // Scratch vectors.
+	v3, v4         lin.V3 // Scratch vectors.
+}
+
+// newJoint allocates the solver constraints for a joint.
+// Each constraint keeps its impulse between steps for warm starting.
+func newJoint(a, b Body, rows int) *joint {
+	j := &joint{a: a.(*body)}
+	if b != nil {
+		j.b = b.(*body)
+	}
+	j.rows = make([]*solverConstraint, rows)
+	for cnt := range j.rows {
+		j.rows[cnt] = newSolverConstraint()
+	}
+	return j
+}
+
+// Bodies returns the joined bodies. Body b is nil for a world joint.
+// Returns an untyped nil so that b == nil works for world joints.
+func (j *joint) Bodies() (a, b Body) {
+	if j.b == nil {
+		return j.a, nil
+	}
+	return j.a, j.b
+}
+
+// tb returns the body b world transform, or nil for world joints.
+func (j *joint) tb() *lin.T {
+	if j.b == nil {
+		return nil
+	}
+	return j.b.world
+}
+
+// setPivots saves the world pivot points in local body coordinates.
+func (j *joint) setPivots(ax, ay, az, bx, by, bz float64) {
+	j.pivotA.X, j.pivotA.Y, j.pivotA.Z = j.a.world.InvS(ax, ay, az)
+	j.pivotB.SetS(bx, by, bz)
+	if j.b != nil {
+		j.pivotB.X, j.pivotB.Y, j.pivotB.Z = j.b.world.InvS(bx, by, bz)
+	}
+}
+
+// setBasis saves the world axes in local body coordinates. This
+// is used to track the relative rotation of the joined bodies.
+func (j *joint) setBasis() {
+	axes := [3]lin.V3{{X: 1}, {Y: 1}, {Z: 1}}
+	for cnt, ax := range axes {
+		j.basisA[cnt].X, j.basisA[cnt].Y, j.basisA[cnt].Z = toLocalR(j.a.world, ax.X, ax.Y, ax.Z)
+		j.basisB[cnt].X, j.basisB[cnt].Y, j.basisB[cnt].Z = toLocalR(j.tb(), ax.X, ax.Y, ax.Z)
+	}
+}
+
+// solverBodies returns the solver bodies for the joined bodies.
+// World joints use the fixed solver body.
+func (j *joint) solverBodies() (sbodA, sbodB *solverBody) {
+	sbodA, sbodB = j.a.sbod, fixedSolverBody()
+	if j.b != nil {
+		sbodB = j.b.sbod
+	}
+	return sbodA, sbodB
+}
+
+// anchors updates the world pivots and the pivot positions relative
+// to each body center.
+func (j *joint) anchors() {
+	j.pa.X, j.pa.Y, j.pa.Z = j.a.world.AppS(j.pivotA.X, j.pivotA.Y, j.pivotA.Z)
+	j.ra.Sub(&j.pa, j.a.world.Loc)
+	j.pb.Set(&j.pivotB)
+	j.rb.SetS(0, 0, 0)
+	if j.b != nil {
+		j.pb.X, j.pb.Y, j.pb.Z = j.b.world.AppS(j.pivotB.X, j.pivotB.Y, j.pivotB.Z)
+		j.rb.Sub(&j.pb, j.b.world.Loc)
+	}
+}
+
+// linear adds a constraint that keeps the pivots together along
+// direction n. The pivot separation along n is the position error.
+func (j *joint) linear(sol *solver, slot int, n *lin.V3, lo, hi float64, info *solverInfo) {
+	sep := j.v2.Sub(&j.pa, &j.pb).Dot(n)
+	sbodA, sbodB := j.solverBodies()
+	target := -info.erp / info.timestep * sep
+	sol.setupJointConstraint(j.rows[slot], sbodA, sbodB, n, &j.ra, &j.rb, target, 0, lo, hi, info)
+}
+
+// angular adds a constraint on the relative turning of the bodies
+// around direction n. The target is a relative angular speed.
+func (j *joint) angular(sol *solver, slot int, n *lin.V3, target, lo, hi float64, info *solverInfo) {
+	sbodA, sbodB := j.solverBodies()
+	sol.setupJointConstraint(j.rows[slot], sbodA, sbodB, n, nil, nil, target, 0, lo, hi, info)
+}
+
+// skip turns off the given constraint so that it starts fresh
+// when it is next used.
+func (j *joint) skip(slot int) { j.rows[slot].appliedImpulse = 0 }
+
+// pin adds the constraints that keep the pivots together.
+// Uses the first three constraint slots.
+func (j *joint) pin(sol *solver, info *solverInfo) {
+	axes := [3]lin.V3{{X: 1}, {Y: 1}, {Z: 1}}
+	for cnt := range axes {
+		j.linear(sol, cnt, &axes[cnt], -unlimited, unlimited, info)
+	}
+}
+
+// lock adds the constraints that stop the bodies turning relative to
+// each other. Uses three constraint slots starting at the given slot.
+// The rotation error is half the sum of the cross products of
+// matching basis axes.
+func (j *joint) lock(sol *solver, slot int, info *solverInfo) {
+	err := j.v3.SetS(0, 0, 0)
+	for cnt := range j.basisA {
+		ba, bb := &j.basisA[cnt], &j.basisB[cnt]
+		j.v0.X, j.v0.Y, j.v0.Z = j.a.world.AppR(ba.X, ba.Y, ba.Z)
+		j.v1.X, j.v1.Y, j.v1.Z = toWorldR(j.tb(), bb.X, bb.Y, bb.Z)
+		err.Add(err, j.v2.Cross(&j.v1, &j.v0))
+	}
+	err.Scale(err, 0.5)
+	axes := [3]lin.V3{{X: 1}, {Y: 1}, {Z: 1}}
+	for cnt := range axes {
+		target := -info.erp / info.timestep * err.Dot(&axes[cnt])
+		j.angular(sol, slot+cnt, &axes[cnt], target, -unlimited, unlimited, info)
+	}
+}
+
+// toLocalR rotates direction x,y,z from world space into the space of
+// transform t. Nil transforms leave the direction in world space.
+func toLocalR(t *lin.T, x, y, z float64) (lx, ly, lz float64) {
+	if t == nil {
+		return x, y, z
+	}
+	return lin.MultSQ(x, y, z, &lin.Q{X: -t.Rot.X, Y: -t.Rot.Y, Z: -t.Rot.Z, W: t.Rot.W})
+}
+
+// toWorldR rotates direction x,y,z from the space of transform t into
+// world space. Nil transforms leave the direction in world space.
+func toWorldR(t *lin.T, x, y, z float64) (wx, wy, wz float64) {
+	if t == nil {
+		return x, y, z
+	}
+	return t.AppR(x, y, z)
+}
+
+// joint
+// ============================================================================
+// joint types
+
+// ball is a Joint that pins the bodies together at a pivot.
+type ball struct {
+	*joint
+}
+
+// setup implements Joint.
+func (bj *ball) setup(sol *solver, info *solverInfo) {
+	bj.anchors()
+	bj.pin(sol, info)
+}
+
+// hinge implements Hinge. The hinge axis and a reference direction
+// perpendicular to the axis are tracked in the local space of each body.
+// The angle between the reference directions is the hinge angle.
+// The angle is unwrapped each step by adding the change from the
+// previous step.
+type hinge struct {
+	*joint
+	axisA, axisB lin.V3  // Hinge axis in local body space.
+	refA, refB   lin.V3  // Zero angle direction in local body space.
+	angle        float64 // Unwrapped angle from the last step.
+	lo, hi       float64 // Angle limits. Unlimited when lo > hi.
+	speed        float64 // Motor target speed.
+	maxTorque    float64 // Motor strength. Zero for no motor.
+}
+
+// Implements Hinge.
+func (h *hinge) SetLimits(lo, hi float64) Hinge { h.lo, h.hi = lo, hi; return h }
+func (h *hinge) SetMotor(speed, maxTorque float64) Hinge {
+	h.speed, h.maxTorque = speed, math.Abs(maxTorque)
+	return h
+}
+
+// Angle implements Hinge. The wrapped angle between the reference
+// directions is unwrapped using the angle from the last step.
+func (h *hinge) Angle() float64 {
+	ax, ra, rb, c := lin.V3{}, lin.V3{}, lin.V3{}, lin.V3{}
+	ax.X, ax.Y, ax.Z = h.a.world.AppR(h.axisA.X, h.axisA.Y, h.axisA.Z)
+	ra.X, ra.Y, ra.Z = h.a.world.AppR(h.refA.X, h.refA.Y, h.refA.Z)
+	rb.X, rb.Y, rb.Z = toWorldR(h.tb(), h.refB.X, h.refB.Y, h.refB.Z)
+	wrapped := math.Atan2(c.Cross(&rb, &ra).Dot(&ax), rb.Dot(&ra))
+	return h.angle + math.Remainder(wrapped-h.angle, 2*math.Pi)
+}
+
+// setup implements Joint. The hinge axes are kept aligned by turning
+// around the two directions perpendicular to the hinge axis.
+func (h *hinge) setup(sol *solver, info *solverInfo) {
+	h.anchors()
+	h.pin(sol, info)
+
+	// align the hinge axes.
+	ax, bx, p, q := &h.v0, &h.v1, &h.v3, &h.v4
+	ax.X, ax.Y, ax.Z = h.a.world.AppR(h.axisA.X, h.axisA.Y, h.axisA.Z)
+	bx.X, bx.Y, bx.Z = toWorldR(h.tb(), h.axisB.X, h.axisB.Y, h.axisB.Z)
+	perpendicular(ax, p, q)
+	err := h.v2.Cross(bx, ax)
+	h.angular(sol, 3, p, -info.erp/info.timestep*err.Dot(p), -unlimited, unlimited, info)
+	h.angular(sol, 4, q, -info.erp/info.timestep*err.Dot(q), -unlimited, unlimited, info)
+
+	// motor is solved before the limits so that the limits win.
+	if h.maxTorque > 0 {
+		impulse := h.maxTorque * info.timestep
+		h.angular(sol, 5, ax, h.speed, -impulse, impulse, info)
+	} else {
+		h.skip(5)
+	}
+	h.angle = h.Angle()
+	angle := h.angle
+	switch {
+	case h.lo <= h.hi && angle <= h.lo:
+		h.angular(sol, 6, ax, -info.erp/info.timestep*(angle-h.lo), 0, unlimited, info)
+	case h.lo <= h.hi && angle >= h.hi:
+		h.angular(sol, 6, ax, -info.erp/info.timestep*(angle-h.hi), -unlimited, 0, info)
+	default:
+		h.skip(6)
+	}
+}
+
+// slider implements Slider. The pivot starts at the center of body a.
+type slider struct {
+	*joint
+	axisA  lin.V3  // Slider axis in body a local space.
+	lo, hi float64 // Offset limits. Unlimited when lo > hi.
+}
+
+// SetLimits implements Slider.
+func (s *slider) SetLimits(lo, hi float64) Slider { s.lo, s.hi = lo, hi; return s }
+
+// Offset implements Slider.
+func (s *slider) Offset() float64 {
+	pa, pb, ax := lin.V3{}, s.pivotB, lin.V3{}
+	pa.X, pa.Y, pa.Z = s.a.world.AppS(s.pivotA.X, s.pivotA.Y, s.pivotA.Z)
+	if s.b != nil {
+		pb.X, pb.Y, pb.Z = s.b.world.AppS(s.pivotB.X, s.pivotB.Y, s.pivotB.Z)
+	}
+	ax.X, ax.Y, ax.Z = s.a.world.AppR(s.axisA.X, s.axisA.Y, s.axisA.Z)
+	return pa.Sub(&pa, &pb).Dot(&ax)
+}
+
+// setup implements Joint. The pivots are kept together in the two
+// directions perpendicular to the slider axis.
+func (s *slider) setup(sol *solver, info *solverInfo) {
+	s.anchors()
+	offset := s.Offset()
+	ax, p, q := &s.v4, &s.v0, &s.v1 // linear uses v2, lock uses v0-v3.
+	ax.X, ax.Y, ax.Z = s.a.world.AppR(s.axisA.X, s.axisA.Y, s.axisA.Z)
+	perpendicular(ax, p, q)
+	s.linear(sol, 0, p, -unlimited, unlimited, info)
+	s.linear(sol, 1, q, -unlimited, unlimited, info)
+	s.lock(sol, 2, info)
+
+	// the limit constraint uses the distance past the limit as the error.
+	sbodA, sbodB := s.solverBodies()
+	switch {
+	case s.lo <= s.hi && offset <= s.lo:
+		target := -info.erp / info.timestep * (offset - s.lo)
+		sol.setupJointConstraint(s.rows[5], sbodA, sbodB, ax, &s.ra, &s.rb, target, 0, 0, unlimited, info)
+	case s.lo <= s.hi && offset >= s.hi:
+		target := -info.erp / info.timestep * (offset - s.hi)
+		sol.setupJointConstraint(s.rows[5], sbodA, sbodB, ax, &s.ra, &s.rb, target, 0, -unlimited, 0, info)
+	default:
+		s.skip(5)
+	}
+}
+
+// fixed is a Joint that stops all relative motion.
+type fixed struct {
+	*joint
+}
+
+// setup implements Joint.
+func (f *fixed) setup(sol *solver, info *solverInfo) {
+	f.anchors()
+	f.pin(sol, info)
+	f.lock(sol, 3, info)
+}
+
+// distance implements Distance. A springy joint is a soft constraint
+// where the stiffness and damping are converted into a constraint
+// force mixing and a target speed.
+// Based on Box2D b2DistanceJoint.
+type distance struct {
+	*joint
+	length    float64 // Distance between anchors.
+	stiffness float64 // Spring strength. Zero for a rigid joint.
+	damping   float64 // Spring damping.
+}
+
+// Implements Distance.
+func (d *distance) SetLength(length float64) Distance { d.length = math.Abs(length); return d }
+func (d *distance) SetSpring(stiffness, damping float64) Distance {
+	d.stiffness, d.damping = math.Abs(stiffness), math.Abs(damping)
+	return d
+}
+
+// setup implements Joint.
+func (d *distance) setup(sol *solver, info *solverInfo) {
+	d.anchors()
+	n := d.v0.Sub(&d.pa, &d.pb)
+	length := n.Len()
+	if length < lin.Epsilon {
+		d.skip(0) // no direction to push the anchors apart.
+		return
+	}
+	n.Scale(n, 1/length)
+	err, dt := length-d.length, info.timestep
+	target, cfm := -info.erp/dt*err, 0.0
+	if d.stiffness > 0 {
+		cfm = 1 / (dt * (d.damping + dt*d.stiffness))
+		target = -err * dt * d.stiffness * cfm
+	}
+	sbodA, sbodB := d.solverBodies()
+	sol.setupJointConstraint(d.rows[0], sbodA, sbodB, n, &d.ra, &d.rb, target, cfm, -unlimited, unlimited, info)
+}
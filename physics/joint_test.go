@@ -0,0 +1,172 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+import (
+	"math"
+	"testing"
+)
+
+// A ball pinned to the world swings like a pendulum
+// while staying close to its starting distance from the pivot.
+func TestBallJoint(t *testing.T) {
+	px := newPhysics()
+	bob := newBody(NewSphere(0.5)).SetProps(1, 0)
+	bob.World().Loc.SetS(3, 0, 0)
+	px.AddJoint(NewBallJoint(bob, nil, 0, 0, 0))
+	swung := false
+	for cnt := 0; cnt < 200; cnt++ {
+		px.Step([]Body{bob}, 0.02)
+		if length := bob.World().Loc.Len(); math.Abs(length-3) > 0.1 {
+			t.Fatalf("Pendulum length %2.2f at step %d", length, cnt)
+		}
+		swung = swung || bob.World().Loc.X < -2
+	}
+	if !swung {
+		t.Errorf("Pendulum should swing to the other side")
+	}
+}
+
+// A motor turns a door on a hinge until it reaches the hinge limit.
+func TestHingeJoint(t *testing.T) {
+	px := newPhysics()
+	door := newBody(NewBox(1, 1, 0.1)).SetProps(1, 0)
+	door.World().Loc.SetS(1, 0, 0)
+	h := NewHingeJoint(door, nil, 0, 0, 0, 0, 1, 0).SetLimits(-0.5, 0.5).SetMotor(2, 50)
+	px.AddJoint(h)
+	for cnt := 0; cnt < 100; cnt++ {
+		px.Step([]Body{door}, 0.02)
+	}
+	at := door.World().Loc
+	if angle := h.Angle(); math.Abs(angle-0.5) > 0.02 || at.Z > 0 || math.Abs(at.Y) > 0.01 {
+		t.Errorf("Expected door at upper limit, got angle %2.2f at %s", angle, dumpV3(at))
+	}
+	h.SetMotor(-2, 50)
+	for cnt := 0; cnt < 100; cnt++ {
+		px.Step([]Body{door}, 0.02)
+	}
+	if angle := h.Angle(); math.Abs(angle+0.5) > 0.02 {
+		t.Errorf("Expected door at lower limit, got angle %2.2f", angle)
+	}
+}
+
+// A hinge limit past a half turn stops the door at the limit instead
+// of flipping to the other limit when the angle passes Pi.
+func TestHingePastHalfTurn(t *testing.T) {
+	px := newPhysics()
+	door := newBody(NewBox(1, 1, 0.1)).SetProps(1, 0)
+	door.World().Loc.SetS(1, 0, 0)
+	h := NewHingeJoint(door, nil, 0, 0, 0, 0, 1, 0).SetLimits(-0.5, 3.5).SetMotor(2, 50)
+	px.AddJoint(h)
+	for cnt := 0; cnt < 200; cnt++ {
+		px.Step([]Body{door}, 0.02)
+	}
+	if angle := h.Angle(); math.Abs(angle-3.5) > 0.02 {
+		t.Errorf("Expected door at upper limit, got angle %2.2f", angle)
+	}
+	h.SetLimits(1, -1)
+	for cnt := 0; cnt < 200; cnt++ {
+		px.Step([]Body{door}, 0.02)
+	}
+	if angle := h.Angle(); angle < 2*math.Pi {
+		t.Errorf("Expected door to keep turning, got angle %2.2f", angle)
+	}
+}
+
+// Joint setup reuses the joint scratch vectors and getters leave
+// the joint scratch alone.
+func TestJointScratch(t *testing.T) {
+	px := newPhysics()
+	door := newBody(NewBox(1, 1, 0.1)).SetProps(1, 0)
+	door.World().Loc.SetS(1, 0, 0)
+	box := newBody(NewBox(0.5, 0.5, 0.5)).SetProps(1, 0)
+	box.World().Loc.SetS(5, 0, 0)
+	h := NewHingeJoint(door, nil, 0, 0, 0, 0, 1, 0).SetLimits(-0.5, 0.5).SetMotor(2, 50)
+	s := NewSliderJoint(box, nil, 0, 1, 1).SetLimits(-1, 1)
+	px.AddJoint(h)
+	px.AddJoint(s)
+	px.Step([]Body{door, box}, 0.02)
+	allocs := testing.AllocsPerRun(10, func() {
+		px.sol.constJ = px.sol.constJ[0:0]
+		px.sol.convertJoint(h, px.sol.info)
+		px.sol.convertJoint(s, px.sol.info)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %f", allocs)
+	}
+	pa := s.(*slider).pa
+	box.World().Loc.SetS(6, 1, 1)
+	if s.Offset(); s.(*slider).pa != pa {
+		t.Errorf("Expected Offset to leave the anchors alone")
+	}
+}
+
+// A box slides down a slope until it reaches the slider limit.
+func TestSliderJoint(t *testing.T) {
+	px := newPhysics()
+	box := newBody(NewBox(0.5, 0.5, 0.5)).SetProps(1, 0)
+	s := NewSliderJoint(box, nil, 0, 1, 1).SetLimits(-1, 1)
+	px.AddJoint(s)
+	for cnt := 0; cnt < 100; cnt++ {
+		px.Step([]Body{box}, 0.02)
+	}
+	at := box.World().Loc
+	if offset := s.Offset(); math.Abs(offset+1) > 0.02 || math.Abs(at.Y-at.Z) > 0.01 || math.Abs(at.X) > 0.01 {
+		t.Errorf("Expected box at slider limit, got offset %2.2f at %s", offset, dumpV3(at))
+	}
+}
+
+// Fixed bodies stay where they started.
+func TestFixedJoint(t *testing.T) {
+	px := newPhysics()
+	b0 := newBody(NewBox(0.5, 0.5, 0.5)).SetProps(1, 0)
+	b1 := newBody(NewBox(0.5, 0.5, 0.5)).SetProps(1, 0)
+	b1.World().Loc.SetS(1, 0, 0)
+	px.AddJoint(NewFixedJoint(b0, nil))
+	px.AddJoint(NewFixedJoint(b1, b0))
+	for cnt := 0; cnt < 100; cnt++ {
+		px.Step([]Body{b0, b1}, 0.02)
+	}
+	if at := b1.World().Loc; math.Abs(at.X-1) > 0.01 || math.Abs(at.Y) > 0.01 {
+		t.Errorf("Expected fixed box to stay put, got %s", dumpV3(at))
+	}
+}
+
+// A rigid distance joint keeps its length while a spring stretches.
+func TestDistanceJoint(t *testing.T) {
+	px := newPhysics()
+	w := newBody(NewSphere(0.2)).SetProps(1, 0)
+	w.World().Loc.SetS(0, -1, 0)
+	d := NewDistanceJoint(w, nil, 0, -1, 0, 0, 0, 0)
+	px.AddJoint(d)
+	for cnt := 0; cnt < 100; cnt++ {
+		px.Step([]Body{w}, 0.02)
+	}
+	if y := w.World().Loc.Y; math.Abs(y+1) > 0.02 {
+		t.Errorf("Expected rigid length 1, got %2.2f", -y)
+	}
+	d.SetSpring(50, 1)
+	for cnt := 0; cnt < 100; cnt++ {
+		px.Step([]Body{w}, 0.02)
+	}
+	if y := w.World().Loc.Y; y > -1.1 {
+		t.Errorf("Expected spring to stretch, got %2.2f", -y)
+	}
+}
+
+// Joined bodies don't collide until the joint is removed.
+func TestJoinedBroadphase(t *testing.T) {
+	px, sp := newPhysics(), NewSphere(1)
+	b0, b1 := newBody(sp).SetProps(1, 0), newBody(sp).SetProps(1, 0)
+	j := NewBallJoint(b0, b1, 0, 0, 0)
+	px.AddJoint(j)
+	px.AddJoint(j)
+	if px.broadphase([]Body{b0, b1}, px.overlapped); len(px.overlapped) != 0 {
+		t.Errorf("Joined bodies should not overlap")
+	}
+	px.RemoveJoint(j)
+	if px.broadphase([]Body{b0, b1}, px.overlapped); len(px.overlapped) != 1 || len(px.joints) != 0 {
+		t.Errorf("Unjoined bodies should overlap")
+	}
+}
@@ -49,6 +49,13 @@ package physics
 // Other physics references:
 //     http://www.geometrictools.com/Source/Physics.html
 
+import (
+	"math"
+	"sort"
+
+	"github.com/gazed/vu/math/lin"
+)
+
 // Physics simulates forces acting on moving bodies. Expected usage
 // is to simulate real-life conditions like air resistance and gravity,
 // or the lack thereof.
@@ -68,6 +75,26 @@ type Physics interface {
 	// the current physics simulation. Bodies positions and velocities
 	// are not updated. Provided for occasional or one-off checks.
 	Collide(a, b Body) bool
+
+	// RayCast returns the closest of the given bodies hit by the ray,
+	// or nil if the ray hits nothing. RayCastAll returns every hit,
+	// sorted closest first. Rays that start inside a volume shape
+	// do not hit that shape.
+	RayCast(ray Body, bodies []Body) *Hit
+	RayCastAll(ray Body, bodies []Body) []*Hit
+
+	// Sweep moves a copy of body b, ie: a sphere or box, by dx, dy, dz
+	// and returns the first of the given bodies that it touches. The hit
+	// point and normal are on the touched body and the hit distance is
+	// how far b can move. Nil is returned if b can move the full distance
+	// or if b is nil. Body b is not moved.
+	Sweep(b Body, dx, dy, dz float64, bodies []Body) *Hit
+
+	// AddJoint includes the joint in the physics simulation and
+	// RemoveJoint releases the joined bodies. Joined bodies are only
+	// moved when they are included in the Step bodies.
+	AddJoint(j Joint)
+	RemoveJoint(j Joint)
 }
 
 // Physics interface
@@ -82,6 +109,8 @@ type physics struct {
 	col        *collider               // Checks for collisions, updates collision contacts.
 	sol        *solver                 // Resolves collisions, updates bodies locations.
 	overlapped map[uint64]*contactPair // Overlapping pairs. Updated during broadphase.
+	joints     []Joint                 // Joints added by the application.
+	joined     map[uint64]int          // Joint count for joined body pairs.
 
 	// scratch variables keep memory so that temp variables
 	// don't have to be continually allocated and garbage collected
@@ -98,6 +127,7 @@ func newPhysics() *physics {
 	px.col = newCollider()
 	px.sol = newSolver()
 	px.overlapped = map[uint64]*contactPair{}
+	px.joined = map[uint64]int{}
 	px.mf0 = newManifold()
 	px.abA = &Abox{}
 	px.abB = &Abox{}
@@ -121,15 +151,18 @@ func (px *physics) Step(bodies []Body, timestep float64) {
 
 	// update overlapped pairs
 	px.broadphase(bodies, px.overlapped)
+	colliding := map[uint32]*body{}
 	if len(px.overlapped) > 0 {
 
 		// collide overlapped pairs
-		if colliding := px.narrowphase(px.overlapped); len(colliding) > 0 {
-			px.sol.info.timestep = timestep
+		colliding = px.narrowphase(px.overlapped)
+	}
 
-			// resolve all colliding pairs
-			px.sol.solve(colliding, px.overlapped)
-		}
+	// resolve all colliding pairs and joints.
+	px.jointBodies(colliding)
+	if len(colliding) > 0 {
+		px.sol.info.timestep = timestep
+		px.sol.solve(colliding, px.overlapped, px.joints)
 	}
 
 	// adjust body locations based on velocities
@@ -179,9 +212,10 @@ func (px *physics) broadphase(bodies []Body, pairs map[uint64]*contactPair) {
 			// FUTURE: Add masking feature that allows bodies to only collide
 			//         with other bodies that have matching mask types.
 
-			// check as long as one of the bodies can move.
-			if bodyA.movable || bodyB.movable {
-				pairID = bodyA.pairID(bodyB)
+			// check as long as one of the bodies can move
+			// and the bodies are not joined.
+			pairID = bodyA.pairID(bodyB)
+			if (bodyA.movable || bodyB.movable) && px.joined[pairID] == 0 {
 				pair, existing := pairs[pairID]
 				if existing {
 					pair.valid = true
@@ -227,6 +261,9 @@ func (px *physics) narrowphase(pairs map[uint64]*contactPair) (colliding map[uin
 	for _, cpair := range pairs {
 		bodyA, bodyB := cpair.bodyA, cpair.bodyB
 		algorithm := px.col.algorithms[bodyA.shape.Type()][bodyB.shape.Type()]
+		if algorithm == nil {
+			continue // ie: two static triangle meshes.
+		}
 		bA, bB, manifold := algorithm(bodyA, bodyB, scrManifold)
 		cpair.bodyA, cpair.bodyB = bA.(*body), bB.(*body) // handle potential body swaps.
 
@@ -242,6 +279,26 @@ func (px *physics) narrowphase(pairs map[uint64]*contactPair) (colliding map[uin
 	return colliding
 }
 
+// jointBodies adds the joined bodies to the solver bodies. Joined bodies
+// need inverse world inertia before the first update.
+func (px *physics) jointBodies(bodies map[uint32]*body) {
+	for _, j := range px.joints {
+		a, b := j.Bodies()
+		bodyA := a.(*body)
+		if _, ok := bodies[bodyA.bid]; !ok && bodyA.movable {
+			bodyA.updateInertiaTensor()
+		}
+		bodies[bodyA.bid] = bodyA
+		if b != nil {
+			bodyB := b.(*body)
+			if _, ok := bodies[bodyB.bid]; !ok && bodyB.movable {
+				bodyB.updateInertiaTensor()
+			}
+			bodies[bodyB.bid] = bodyB
+		}
+	}
+}
+
 // updateBodyLocations applies the updated linear and angular velocities to the
 // the bodies current position.
 func (px *physics) updateBodyLocations(bodies []Body, timestep float64) {
@@ -269,10 +326,184 @@ func (px *physics) clearForces(bodies []Body) {
 func (px *physics) Collide(a, b Body) (hit bool) {
 	aa, bb := a.(*body), b.(*body)
 	algorithm := px.col.algorithms[aa.shape.Type()][bb.shape.Type()]
+	if algorithm == nil {
+		return false
+	}
 	_, _, manifold := algorithm(aa, bb, px.mf0)
 	return len(manifold) > 0
 }
 
+// RayCast returns the closest body hit by the ray.
+func (px *physics) RayCast(ray Body, bodies []Body) *Hit {
+	var closest *Hit
+	for _, hit := range px.RayCastAll(ray, bodies) {
+		closest = hit
+		break
+	}
+	return closest
+}
+
+// RayCastAll returns all the bodies hit by the ray, closest first.
+// Bodies that have a bounding box are skipped if the ray misses the box.
+func (px *physics) RayCastAll(r Body, bodies []Body) []*Hit {
+	hits := []*Hit{}
+	if r == nil || r.Shape() == nil || r.Shape().Type() != RayShape {
+		return hits
+	}
+	sr, lr := r.Shape().(*ray), r.World().Loc
+	d := lin.NewV3S(sr.dx, sr.dy, sr.dz).Unit()
+	for _, b := range bodies {
+		if b == nil || b.Eq(r) {
+			continue
+		}
+		if ab := b.(*body).worldAabb(px.abA); ab != nil {
+			small, large := [3]float64{ab.Sx, ab.Sy, ab.Sz}, [3]float64{ab.Lx, ab.Ly, ab.Lz}
+			if near, far, _, _ := slabs(small, large, lr, d); near > far || far < 0 {
+				continue // ray misses the bounding box.
+			}
+		}
+		if hit := (&Hit{}); castRay(r, b, hit) {
+			hits = append(hits, hit)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Dist < hits[j].Dist })
+	return hits
+}
+
+// Sweep moves a copy of body b in small steps until it touches one of
+// the bodies. The steps are half the smallest size of b so that b does
+// not pass through other shapes. Bodies are touching when they are within
+// the collision margin. The touching location is then refined by repeatedly
+// halving the last step.
+func (px *physics) Sweep(b Body, dx, dy, dz float64, bodies []Body) *Hit {
+	if b == nil {
+		return nil
+	}
+	bb, dist := b.(*body), math.Sqrt(dx*dx+dy*dy+dz*dz)
+	if dist < lin.Epsilon || !IsVolume(bb.shape.Type()) && bb.shape.Type() != CompoundShape {
+		return nil
+	}
+	ab := bb.shape.Aabb(lin.NewT().SetI(), px.abA, 0)
+	step := math.Min(ab.Lx-ab.Sx, math.Min(ab.Ly-ab.Sy, ab.Lz-ab.Sz)) / 2
+	if step <= 0 {
+		return nil
+	}
+
+	// only check the bodies near the path of b.
+	start, end := bb.worldAabb(&Abox{}), &Abox{}
+	end.Sx, end.Sy, end.Sz = start.Sx+math.Min(dx, 0), start.Sy+math.Min(dy, 0), start.Sz+math.Min(dz, 0)
+	end.Lx, end.Ly, end.Lz = start.Lx+math.Max(dx, 0), start.Ly+math.Max(dy, 0), start.Lz+math.Max(dz, 0)
+	mover := newBody(bb.shape)
+	var closest *Hit
+	for _, other := range bodies {
+		if other == nil {
+			continue
+		}
+		ob := other.(*body)
+		if ob.Eq(bb) || px.col.algorithms[bb.shape.Type()][ob.shape.Type()] == nil {
+			continue
+		}
+		if oab := ob.worldAabb(px.abB); oab == nil || !oab.Overlaps(end) {
+			continue
+		}
+		limit := dist
+		if closest != nil {
+			limit = closest.Dist
+		}
+		if hit := px.sweep(bb, mover, ob, dx/dist, dy/dist, dz/dist, limit, step); hit != nil {
+			closest = hit
+		}
+	}
+	return closest
+}
+
+// sweep returns where the mover, starting at body b, first touches
+// body other while moving up to limit along unit direction dx, dy, dz.
+// The hit distance is the furthest the mover was found to be free.
+func (px *physics) sweep(b, mover, other *body, dx, dy, dz, limit, step float64) *Hit {
+	touching := func(t float64) (hit *Hit) {
+		mover.world.Set(b.world)
+		mover.world.Loc.SetS(b.world.Loc.X+dx*t, b.world.Loc.Y+dy*t, b.world.Loc.Z+dz*t)
+		algorithm := px.col.algorithms[mover.shape.Type()][other.shape.Type()]
+		i, _, manifold := algorithm(mover, other, px.mf0)
+		deepest := 0.0
+		for _, poc := range manifold {
+			if poc.depth <= 0 && (hit == nil || poc.depth < deepest) {
+				hit, deepest = &Hit{Body: other}, poc.depth
+				hit.Point.Set(poc.point)
+				hit.Normal.Set(poc.normal)
+				if i != Body(mover) {
+					// move the point from the mover to the other body.
+					hit.Point.Add(&hit.Point, lin.NewV3().Scale(poc.normal, poc.depth))
+					hit.Normal.Neg(&hit.Normal)
+				}
+			}
+		}
+		return hit
+	}
+
+	// step forward until touching, then narrow down the touching distance.
+	free, t := 0.0, 0.0
+	for {
+		if hit := touching(t); hit != nil {
+			if t == 0 {
+				hit.Dist = 0
+				return hit // already touching.
+			}
+			break
+		}
+		if free = t; t >= limit {
+			return nil
+		}
+		t = math.Min(t+step, limit)
+	}
+	for cnt := 0; cnt < sweepRefinements; cnt++ {
+		if mid := (free + t) / 2; touching(mid) != nil {
+			t = mid
+		} else {
+			free = mid
+		}
+	}
+	hit := touching(t)
+	hit.Dist = free // how far b can move without touching.
+	return hit
+}
+
+// sweepRefinements is the number of times the sweep step that
+// reaches a body is halved to find the touching distance.
+const sweepRefinements = 16
+
+// AddJoint includes the joint in the physics simulation.
+// Joints that have already been added are ignored.
+func (px *physics) AddJoint(j Joint) {
+	for _, existing := range px.joints {
+		if existing == j {
+			return
+		}
+	}
+	px.joints = append(px.joints, j)
+	if a, b := j.Bodies(); b != nil {
+		px.joined[a.(*body).pairID(b.(*body))]++
+	}
+}
+
+// RemoveJoint removes the joint from the physics simulation.
+// The joined bodies can collide once there are no joints between them.
+func (px *physics) RemoveJoint(j Joint) {
+	for cnt, existing := range px.joints {
+		if existing == j {
+			px.joints = append(px.joints[:cnt], px.joints[cnt+1:]...)
+			if a, b := j.Bodies(); b != nil {
+				pairID := a.(*body).pairID(b.(*body))
+				if px.joined[pairID]--; px.joined[pairID] <= 0 {
+					delete(px.joined, pairID)
+				}
+			}
+			return
+		}
+	}
+}
+
 // Set one or more engine attributes.
 func (px *physics) Set(attrs ...PhysAttr) {
 	for _, attr := range attrs {
@@ -303,10 +534,9 @@ func Margin(collisionMargin float64) PhysAttr {
 // nearest point of intersection if there is one. The point of contact
 // x, y, z is valid when hit is true.
 func Cast(ray, b Body) (hit bool, x, y, z float64) {
-	if ray != nil && b != nil && b.Shape() != nil {
-		if alg, ok := rayCastAlgorithms[b.Shape().Type()]; ok {
-			return alg(ray, b)
-		}
+	h := &Hit{}
+	if castRay(ray, b, h) {
+		return true, h.Point.X, h.Point.Y, h.Point.Z
 	}
 	return false, 0, 0, 0
 }
@@ -5,6 +5,7 @@ package physics
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -58,6 +59,57 @@ func TestCollide(t *testing.T) {
 	}
 }
 
+// Check that ray casts return the closest hit or all hits in order.
+func TestRayCast(t *testing.T) {
+	px := newPhysics()
+	r := newBody(NewRay(1, 0, 0))
+	near, far, behind := newBody(NewBox(1, 1, 1)), newBody(NewSphere(1)), newBody(NewSphere(1))
+	near.World().Loc.SetS(5, 0, 0)
+	far.World().Loc.SetS(10, 0.5, 0)
+	behind.World().Loc.SetS(-5, 0, 0)
+	bodies := []Body{far, r, behind, near}
+	if hit := px.RayCast(r, bodies); hit == nil || !hit.Body.Eq(near) || !lin.Aeq(hit.Dist, 4) {
+		t.Errorf("Expected closest hit on the box")
+	}
+	if hits := px.RayCastAll(r, bodies); len(hits) != 2 || !hits[0].Body.Eq(near) || !hits[1].Body.Eq(far) {
+		t.Errorf("Expected box then sphere hits, got %d hits", len(hits))
+	}
+	SetRay(r, 0, 1, 0)
+	if hit := px.RayCast(r, bodies); hit != nil {
+		t.Errorf("Expected no hits")
+	}
+}
+
+// Check that sweeps stop when touching the first body.
+func TestSweep(t *testing.T) {
+	px := newPhysics()
+	ball, wall, far := newBody(NewSphere(0.5)), newBody(NewBox(0.5, 5, 5)), newBody(NewBox(0.5, 5, 5))
+	wall.World().Loc.SetS(5, 0, 0)
+	far.World().Loc.SetS(8, 0, 0)
+	bodies := []Body{ball, far, wall}
+	hit := px.Sweep(ball, 10, 0, 0, bodies)
+	if hit == nil || !hit.Body.Eq(wall) || math.Abs(hit.Dist-(4-margin)) > 0.01 || !lin.Aeq(hit.Normal.X, -1) {
+		t.Fatalf("Expected ball to touch the wall %+v", hit)
+	}
+	if math.Abs(hit.Point.X-4.5) > margin+0.01 || ball.World().Loc.X != 0 {
+		t.Errorf("Expected touching point on the wall %s", dumpV3(&hit.Point))
+	}
+	if hit := px.Sweep(ball, 3, 0, 0, bodies); hit != nil {
+		t.Errorf("Expected ball to move freely %+v", hit)
+	}
+	if px.Sweep(nil, 1, 0, 0, bodies) != nil || px.Sweep(ball, 10, 0, 0, []Body{nil, wall}) == nil {
+		t.Errorf("Expected nil bodies to be ignored")
+	}
+
+	// box sweeps work against static triangles.
+	box, floor := newBody(NewBox(0.5, 0.5, 0.5)), newBody(NewTriMesh(floorVerts, floorFaces))
+	box.World().Loc.SetS(0, 5, 0)
+	if hit := px.Sweep(box, 0, -10, 0, []Body{floor}); hit == nil || math.Abs(hit.Dist-4.5) > 2*margin+0.01 ||
+		!lin.Aeq(hit.Normal.Y, 1) {
+		t.Errorf("Expected box to land on the floor %+v", hit)
+	}
+}
+
 // Testing
 // ============================================================================
 // Utility functions for all package testcases.
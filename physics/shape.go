@@ -34,24 +34,106 @@ type Shape interface {
 
 // Enumerate the shapes handled by physics and returned
 // by Shape.Type(). Currently volume shapes are used in physics collision
-// and the non-volume shapes are used in ray-casting.
+// and the plane and ray shapes are used in ray-casting. The triangle mesh
+// and heightfield shapes are static level geometry that collide with
+// the volume shapes. Compound shapes are made from volume shapes.
+// New shapes are added at the end so that saved shape values remain valid.
 const (
-	SphereShape  = iota // Considered convex (curving outwards).
-	BoxShape            // Polyhedral (flat faces, straight edges). Convex.
-	VolumeShapes        // Separates shapes with volume from those without.
-	PlaneShape          // Area, no volume or mass.
-	RayShape            // Points on a line, no area, volume or mass.
-	NumShapes           // Keep this last.
+	SphereShape      = iota // Considered convex (curving outwards).
+	BoxShape                // Polyhedral (flat faces, straight edges). Convex.
+	VolumeShapes            // Deprecated: use IsVolume. Not all volume shapes are before it.
+	PlaneShape              // Area, no volume or mass.
+	RayShape                // Points on a line, no area, volume or mass.
+	CapsuleShape            // Cylinder with hemisphere ends. Convex.
+	CylinderShape           // Circular ends with a curved side. Convex.
+	HullShape               // Convex hull around a set of points.
+	TriMeshShape            // Static triangles, no volume or mass.
+	HeightfieldShape        // Static grid of heights, no volume or mass.
+	CompoundShape           // Group of offset child shapes.
+	NumShapes               // Keep this last.
 )
 
-// Currently the shapes are so simple they are all kept in this one file.
-// Future shapes get crazy complex. For example:
-//    FUTURE: Capsule
-//    FUTURE: Cylinder
+// volumes lists the shape types that have volume and can be given mass.
+// Volume shapes are not grouped by value, so check them using IsVolume
+// rather than comparing against VolumeShapes.
+var volumes = []int{SphereShape, BoxShape, CapsuleShape, CylinderShape, HullShape}
+
+// IsVolume returns true if the given shape type has volume.
+// Volume shapes can be given mass and collide with each other.
+func IsVolume(shapeType int) bool {
+	switch shapeType {
+	case SphereShape, BoxShape, CapsuleShape, CylinderShape, HullShape:
+		return true
+	}
+	return false
+}
+
+// Dims returns the values used to create the given shape.
+// These are the half-extents for a box, the radius for a sphere,
+// the radius and half-height for a capsule or cylinder, the x,y,z
+// hull points, the normal for a plane, and the direction for a ray.
+// A triangle mesh is the number of vertices, the x,y,z vertices, then
+// the face indicies. A heightfield is the grid spacing, the number of
+// grid points along X, then the grid heights. A compound is the number
+// of children then, for each child, the shape type, the x,y,z offset
+// location, the x,y,z,w offset rotation, the number of child dims,
+// and the child dims.
+func Dims(s Shape) []float64 {
+	switch sh := s.(type) {
+	case *box:
+		return []float64{sh.Hx, sh.Hy, sh.Hz}
+	case *sphere:
+		return []float64{sh.R}
+	case *capsule:
+		return []float64{sh.R, sh.H}
+	case *cylinder:
+		return []float64{sh.R, sh.H}
+	case *hull:
+		dims := make([]float64, 0, len(sh.points)*3)
+		for _, p := range sh.points {
+			dims = append(dims, p.X, p.Y, p.Z)
+		}
+		return dims
+	case *plane:
+		return []float64{sh.nx, sh.ny, sh.nz}
+	case *ray:
+		return []float64{sh.dx, sh.dy, sh.dz}
+	case *trimesh:
+		dims := make([]float64, 0, 1+len(sh.verts)*3+len(sh.faces))
+		dims = append(dims, float64(len(sh.verts)))
+		for _, v := range sh.verts {
+			dims = append(dims, v.X, v.Y, v.Z)
+		}
+		for _, f := range sh.faces {
+			dims = append(dims, float64(f))
+		}
+		return dims
+	case *heightfield:
+		dims := []float64{sh.spacing, float64(len(sh.heights))}
+		for _, column := range sh.heights {
+			dims = append(dims, column...)
+		}
+		return dims
+	case *compound:
+		dims := []float64{float64(len(sh.children))}
+		for _, ch := range sh.children {
+			cd := Dims(ch.shape)
+			l, r := ch.offset.Loc, ch.offset.Rot
+			dims = append(dims, float64(ch.shape.Type()), l.X, l.Y, l.Z, r.X, r.Y, r.Z, r.W)
+			dims = append(dims, float64(len(cd)))
+			dims = append(dims, cd...)
+		}
+		return dims
+	}
+	return nil
+}
+
+// The simple shapes are all kept in this one file. Convex hulls are
+// in hull.go. Static triangle meshes and heightfields are in trimesh.go
+// and heightfield.go. Compound shapes are in compound.go. Future shapes
+// get crazy complex. For example:
 //    FUTURE: Cone
 //    FUTURE: Multi sphere
-//    FUTURE: Compound shape of multiple primitives.
-// 	  FUTURE: Convex hull shapes created from triangle meshes.
 //    FUTURE: and so on to soft bodies.
 
 // Shape interface
@@ -145,6 +227,102 @@ func (s *sphere) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
 
 // sphere
 // ============================================================================
+// capsule shape
+
+// capsule is a collision shape primitive that is a cylinder with a
+// hemisphere on each end. It is centered at the origin and aligned
+// along the Y axis. Capsules are often used for characters since
+// they slide smoothly over edges and steps.
+type capsule struct {
+	R float64 // Radius of the hemispheres and cylinder.
+	H float64 // Half-height of the cylinder, excluding the hemispheres.
+}
+
+// NewCapsule creates a Capsule shape aligned along the Y axis with
+// a total height of 2*(radius+halfHeight). Negative input values are
+// turned positive.
+func NewCapsule(radius, halfHeight float64) Shape {
+	return &capsule{math.Abs(radius), math.Abs(halfHeight)}
+}
+
+// Implements Shape.Type
+func (c *capsule) Type() int { return CapsuleShape }
+
+// Implements Shape.Aabb
+// The bounding box surrounds spheres at each end of the rotated axis.
+func (c *capsule) Aabb(t *lin.T, ab *Abox, margin float64) *Abox {
+	ax, ay, az := lin.MultSQ(0, c.H, 0, t.Rot)
+	ax, ay, az = math.Abs(ax), math.Abs(ay), math.Abs(az)
+	sides := c.R + margin
+	ab.Sx, ab.Sy, ab.Sz = t.Loc.X-ax-sides, t.Loc.Y-ay-sides, t.Loc.Z-az-sides
+	ab.Lx, ab.Ly, ab.Lz = t.Loc.X+ax+sides, t.Loc.Y+ay+sides, t.Loc.Z+az+sides
+	return ab
+}
+
+// Implements Shape.Volume
+func (c *capsule) Volume() float64 {
+	return math.Pi*c.R*c.R*c.H*2 + 4.0/3.0*math.Pi*c.R*c.R*c.R
+}
+
+// Implements Shape.Inertia
+// The cylinder and hemisphere inertias are combined, where the
+// hemispheres are offset from the center.
+func (c *capsule) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	r2, vol := c.R*c.R, c.Volume()
+	mc := mass * math.Pi * r2 * c.H * 2 / vol // cylinder mass.
+	ms := mass - mc                           // mass of both hemispheres.
+	iy := mc*r2/2 + ms*r2*2/5
+	ixz := mc*(c.H*c.H/3+r2/4) + ms*(r2*2/5+c.H*c.H+c.H*c.R*3/4)
+	inertia.SetS(ixz, iy, ixz)
+	return inertia
+}
+
+// capsule
+// ============================================================================
+// cylinder shape
+
+// cylinder is a collision shape primitive that is centered at
+// the origin and aligned along the Y axis.
+type cylinder struct {
+	R float64 // Radius of the circular ends.
+	H float64 // Half-height.
+}
+
+// NewCylinder creates a Cylinder shape aligned along the Y axis with
+// a total height of 2*halfHeight. Negative input values are turned positive.
+func NewCylinder(radius, halfHeight float64) Shape {
+	return &cylinder{math.Abs(radius), math.Abs(halfHeight)}
+}
+
+// Implements Shape.Type
+func (c *cylinder) Type() int { return CylinderShape }
+
+// Implements Shape.Aabb
+// The extent along each axis is the rotated half-height plus
+// the furthest extent of the circular end in that direction.
+func (c *cylinder) Aabb(t *lin.T, ab *Abox, margin float64) *Abox {
+	ux, uy, uz := lin.MultSQ(0, 1, 0, t.Rot) // rotated cylinder axis.
+	ex := c.H*math.Abs(ux) + c.R*math.Sqrt(math.Max(0, 1-ux*ux)) + margin
+	ey := c.H*math.Abs(uy) + c.R*math.Sqrt(math.Max(0, 1-uy*uy)) + margin
+	ez := c.H*math.Abs(uz) + c.R*math.Sqrt(math.Max(0, 1-uz*uz)) + margin
+	ab.Sx, ab.Sy, ab.Sz = t.Loc.X-ex, t.Loc.Y-ey, t.Loc.Z-ez
+	ab.Lx, ab.Ly, ab.Lz = t.Loc.X+ex, t.Loc.Y+ey, t.Loc.Z+ez
+	return ab
+}
+
+// Implements Shape.Volume
+func (c *cylinder) Volume() float64 { return math.Pi * c.R * c.R * c.H * 2 }
+
+// Implements Shape.Inertia
+func (c *cylinder) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	r2, h2 := c.R*c.R, 4*c.H*c.H
+	ixz := mass / 12.0 * (3*r2 + h2)
+	inertia.SetS(ixz, mass/2.0*r2, ixz)
+	return inertia
+}
+
+// cylinder
+// ============================================================================
 // Abox
 
 // Abox is an axis aligned bounding box used with the Shape interface.
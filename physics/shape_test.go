@@ -4,6 +4,7 @@
 package physics
 
 import (
+	"math"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -16,6 +17,15 @@ func TestBox(t *testing.T) {
 	}
 }
 
+func TestDims(t *testing.T) {
+	if d := Dims(NewBox(1, 2, 3)); len(d) != 3 || d[0] != 1 || d[1] != 2 || d[2] != 3 {
+		t.Errorf("Expected box dims 1 2 3, got %v", d)
+	}
+	if d := Dims(NewSphere(2)); len(d) != 1 || d[0] != 2 {
+		t.Errorf("Expected sphere radius 2, got %v", d)
+	}
+}
+
 func TestBoxAabb(t *testing.T) {
 	bx := Shape(NewBox(1, 1, 1))
 	ab := bx.Aabb(lin.NewT().SetI(), &Abox{}, 0.01)
@@ -67,6 +77,81 @@ func TestSphereInertia(t *testing.T) {
 	}
 }
 
+func TestCapsule(t *testing.T) {
+	cp := Shape(NewCapsule(0.5, 1)) // compiler checks Shape interface.
+	if cp.Type() != CapsuleShape {
+		t.Error("Invalid capsule shape")
+	}
+	if d := Dims(cp); len(d) != 2 || d[0] != 0.5 || d[1] != 1 {
+		t.Errorf("Expected capsule dims 0.5 1, got %v", d)
+	}
+	ab := cp.Aabb(lin.NewT().SetI(), &Abox{}, 0.01)
+	if !lin.Aeq(ab.Sx, -0.51) || !lin.Aeq(ab.Sy, -1.51) || !lin.Aeq(ab.Lz, 0.51) || !lin.Aeq(ab.Ly, 1.51) {
+		t.Errorf("Invalid bounding box for Capsule %v", ab)
+	}
+	want := math.Pi*0.25*2 + 4.0/3.0*math.Pi*0.125
+	if !lin.Aeq(cp.Volume(), want) {
+		t.Errorf("Expected capsule volume %f, got %f", want, cp.Volume())
+	}
+}
+
+func TestCylinder(t *testing.T) {
+	cy := Shape(NewCylinder(1, 2)) // compiler checks Shape interface.
+	if cy.Type() != CylinderShape {
+		t.Error("Invalid cylinder shape")
+	}
+	rot := lin.NewT().SetI()
+	rot.Rot.SetAa(0, 0, 1, lin.Rad(90)) // lying along the X axis.
+	ab := cy.Aabb(rot, &Abox{}, 0)
+	if !lin.Aeq(ab.Sx, -2) || !lin.Aeq(ab.Ly, 1) || !lin.Aeq(ab.Lz, 1) {
+		t.Errorf("Invalid bounding box for rotated Cylinder %v", ab)
+	}
+	if !lin.Aeq(cy.Volume(), 4*math.Pi) {
+		t.Errorf("Expected cylinder volume %f, got %f", 4*math.Pi, cy.Volume())
+	}
+	inertia, want := cy.Inertia(12, lin.NewV3()), "{19.0 6.0 19.0}"
+	if dumpV3(inertia) != want {
+		t.Errorf("Expected cylinder inertia %s, got %s", want, dumpV3(inertia))
+	}
+}
+
+// A hull of the cube corners, plus interior points, matches a box.
+func TestHull(t *testing.T) {
+	points := []float32{0, 0, 0, 0.5, 0.5, 0.5}
+	for _, x := range []float32{-1, 1} {
+		for _, y := range []float32{-1, 1} {
+			for _, z := range []float32{-1, 1} {
+				points = append(points, x, y, z, x*0.5, y*0.5, z*0.5)
+			}
+		}
+	}
+	hl := Shape(NewHull(points)) // compiler checks Shape interface.
+	if hl.Type() != HullShape {
+		t.Error("Invalid hull shape")
+	}
+	if d := Dims(hl); len(d) != 24 {
+		t.Errorf("Expected 8 hull points, got %d", len(d)/3)
+	}
+	if !lin.Aeq(hl.Volume(), 8) {
+		t.Errorf("Expected hull volume 8, got %f", hl.Volume())
+	}
+	inertia, want := hl.Inertia(1, lin.NewV3()), "{0.7 0.7 0.7}"
+	if dumpV3(inertia) != want {
+		t.Errorf("Expected hull inertia %s, got %s", want, dumpV3(inertia))
+	}
+	ab := hl.Aabb(lin.NewT().SetI(), &Abox{}, 0.01)
+	if ab.Sx != -1.01 || ab.Sy != -1.01 || ab.Sz != -1.01 || ab.Lx != 1.01 || ab.Ly != 1.01 || ab.Lz != 1.01 {
+		t.Errorf("Invalid bounding box for Hull %v", ab)
+	}
+}
+
+func TestFlatHull(t *testing.T) {
+	hl := Shape(NewHull([]float32{0, 0, 0, 1, 0, 0, 0, 0, 1, 1, 0, 1, 1, 0, 1}))
+	if hl.Volume() != 0 || len(Dims(hl)) != 12 {
+		t.Errorf("Expected flat hull with 4 points, got %f %v", hl.Volume(), Dims(hl))
+	}
+}
+
 func TestAboxOverlap(t *testing.T) {
 	var a, b, c, d *Abox
 	a, b = &Abox{0, 0, 0, 1, 1, 1}, &Abox{-1, -1, -1, 0, 0, 0}
@@ -90,3 +175,116 @@ func TestAboxOverlap(t *testing.T) {
 		t.Error("Overlapping")
 	}
 }
+
+// A square floor made of two triangles.
+var floorVerts, floorFaces = []float32{-1, 0, -1, -1, 0, 1, 1, 0, 1, 1, 0, -1}, []uint32{0, 1, 2, 0, 2, 3}
+
+func TestTriMesh(t *testing.T) {
+	tm := Shape(NewTriMesh(floorVerts, floorFaces)) // compiler checks Shape interface.
+	if tm.Type() != TriMeshShape || tm.Volume() != 0 {
+		t.Error("Invalid triangle mesh shape")
+	}
+	if d := Dims(tm); len(d) != 19 || d[0] != 4 {
+		t.Errorf("Expected 4 vertices and 6 face indicies, got %v", d)
+	}
+	ab := tm.Aabb(lin.NewT().SetLoc(0, 2, 0), &Abox{}, 0.01)
+	if ab.Sx != -1.01 || ab.Sy != 1.99 || ab.Sz != -1.01 || ab.Lx != 1.01 || ab.Ly != 2.01 || ab.Lz != 1.01 {
+		t.Errorf("Invalid bounding box for TriMesh %v", ab)
+	}
+}
+
+func TestTriMeshTriangles(t *testing.T) {
+	verts, faces := []float32{}, []uint32{}
+	for x := 0; x < 10; x++ { // a row of 10 squares.
+		fx, base := float32(x), uint32(len(verts)/3)
+		verts = append(verts, fx, 0, 0, fx, 0, 1, fx+1, 0, 1, fx+1, 0, 0)
+		faces = append(faces, base, base+1, base+2, base, base+2, base+3)
+	}
+	tm := NewTriMesh(verts, faces).(*trimesh)
+	if len(tm.nodes) < 3 {
+		t.Errorf("Expected a bvh for 20 triangles, got %d nodes", len(tm.nodes))
+	}
+	if tris := tm.triangles(&Abox{3.2, -1, 0.2, 3.8, 1, 0.8}); len(tris) != 2 {
+		t.Errorf("Expected the 2 triangles in one square, got %d", len(tris))
+	}
+	if tris := tm.triangles(&Abox{2.5, -1, 0.2, 4.5, 1, 0.8}); len(tris) != 6 {
+		t.Errorf("Expected the 6 triangles in three squares, got %d", len(tris))
+	}
+	if tris := tm.triangles(&Abox{2.5, 0.5, 0.2, 4.5, 1, 0.8}); len(tris) != 0 {
+		t.Errorf("Expected no triangles above the mesh, got %d", len(tris))
+	}
+	query := &Abox{2.5, -1, 0.2, 4.5, 1, 0.8}
+	if allocs := testing.AllocsPerRun(10, func() { tm.triangles(query) }); allocs != 0 {
+		t.Errorf("Expected queries to reuse memory, got %f allocations", allocs)
+	}
+}
+
+func TestHeightfield(t *testing.T) {
+	topo := [][]float64{{0, 1, 0}, {1, 2, 1}, {0, 1, 0}}
+	hf := Shape(NewHeightfield(topo, 2, 0.5)) // compiler checks Shape interface.
+	if hf.Type() != HeightfieldShape || hf.Volume() != 0 {
+		t.Error("Invalid heightfield shape")
+	}
+	if d := Dims(hf); len(d) != 11 || d[0] != 2 || d[1] != 3 || d[6] != 1 {
+		t.Errorf("Expected spacing, rows, and 9 heights, got %v", d)
+	}
+	ab := hf.Aabb(lin.NewT().SetI(), &Abox{}, 0)
+	if ab.Sx != -2 || ab.Sy != 0 || ab.Sz != -2 || ab.Lx != 2 || ab.Ly != 1 || ab.Lz != 2 {
+		t.Errorf("Invalid bounding box for Heightfield %v", ab)
+	}
+	if tris := hf.(*heightfield).triangles(&Abox{0.5, -1, 0.5, 1.5, 2, 1.5}); len(tris) != 2 {
+		t.Errorf("Expected the 2 triangles in one cell, got %d", len(tris))
+	}
+	query := &Abox{-1.5, -1, -1.5, 1.5, 2, 1.5}
+	if tris := hf.(*heightfield).triangles(query); len(tris) != 8 {
+		t.Errorf("Expected the 8 triangles in four cells, got %d", len(tris))
+	}
+	if allocs := testing.AllocsPerRun(10, func() { hf.(*heightfield).triangles(query) }); allocs != 0 {
+		t.Errorf("Expected queries to reuse memory, got %f allocations", allocs)
+	}
+}
+
+// An L-shape made from a base box and an upright box.
+func newLShape() Compound {
+	upright := lin.NewT().SetLoc(-0.75, 0.75, 0).SetAa(0, 0, 1, lin.Rad(90))
+	return NewCompound().Add(NewBox(1, 0.25, 0.25), nil).Add(NewBox(1, 0.25, 0.25), upright)
+}
+
+func TestCompound(t *testing.T) {
+	cm := Shape(newLShape()) // compiler checks Shape interface.
+	if cm.Type() != CompoundShape || cm.(Compound).Size() != 2 {
+		t.Error("Invalid compound shape")
+	}
+	if d := Dims(cm); len(d) != 25 || d[0] != 2 || d[1] != BoxShape || d[9] != 3 {
+		t.Errorf("Expected 2 children with offsets and box dims, got %v", d)
+	}
+	if !lin.Aeq(cm.Volume(), 1) {
+		t.Errorf("Expected compound volume 1, got %f", cm.Volume())
+	}
+	inertia, want := cm.Inertia(2, lin.NewV3()), "{1.0 1.0 1.8}"
+	if dumpV3(inertia) != want {
+		t.Errorf("Expected compound inertia %s, got %s", want, dumpV3(inertia))
+	}
+	ab := cm.Aabb(lin.NewT().SetLoc(0, 1, 0), &Abox{}, 0)
+	if !lin.Aeq(ab.Sx, -1) || !lin.Aeq(ab.Sy, 0.75) || !lin.Aeq(ab.Sz, -0.25) ||
+		!lin.Aeq(ab.Lx, 1) || !lin.Aeq(ab.Ly, 2.75) || !lin.Aeq(ab.Lz, 0.25) {
+		t.Errorf("Invalid bounding box for Compound %v", ab)
+	}
+	ab = cm.Aabb(lin.NewT().SetAa(0, 0, 1, lin.Rad(180)), &Abox{}, 0) // upside down.
+	if !lin.Aeq(ab.Sy, -1.75) || !lin.Aeq(ab.Ly, 0.25) || !lin.Aeq(ab.Sx, -1) || !lin.Aeq(ab.Lx, 1) {
+		t.Errorf("Invalid bounding box for rotated Compound %v", ab)
+	}
+	if NewCompound().Add(NewPlane(0, 1, 0), nil).Size() != 0 {
+		t.Error("Compounds should only contain volume shapes")
+	}
+}
+
+// Shape types are saved, so existing values must not change.
+func TestShapeTypes(t *testing.T) {
+	if SphereShape != 0 || BoxShape != 1 || PlaneShape != 3 || RayShape != 4 {
+		t.Error("Existing shape type values changed")
+	}
+	if !IsVolume(HullShape) || IsVolume(RayShape) || IsVolume(CompoundShape) {
+		t.Error("Invalid volume shapes")
+	}
+}
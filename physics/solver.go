@@ -46,6 +46,7 @@ type solver struct {
 	info   *solverInfo         // Constants for the solver.
 	constC []*solverConstraint // Contact related equations.
 	constF []*solverConstraint // Friction related equations.
+	constJ []*solverConstraint // Joint related equations.
 
 	// scratch variables are optimizations that avoid creating/destroying
 	// temporary objects that are needed each timestep.
@@ -60,6 +61,7 @@ func newSolver() *solver {
 	sol.info = newSolverInfo()
 	sol.constC = []*solverConstraint{}
 	sol.constF = []*solverConstraint{}
+	sol.constJ = []*solverConstraint{}
 	sol.v0 = lin.NewV3()
 	sol.v1 = lin.NewV3()
 	sol.v2 = lin.NewV3()
@@ -69,10 +71,10 @@ func newSolver() *solver {
 }
 
 // solve is expected to be called each physics update. It creates constraints
-// based on contact points and then solves the constraints by adjusting bodies
-// velocities to satisfy the constraints.
-func (sol *solver) solve(bodies map[uint32]*body, contactPairs map[uint64]*contactPair) {
-	sol.setupConstraints(bodies, contactPairs)
+// based on contact points and joints and then solves the constraints by
+// adjusting bodies velocities to satisfy the constraints.
+func (sol *solver) solve(bodies map[uint32]*body, contactPairs map[uint64]*contactPair, joints []Joint) {
+	sol.setupConstraints(bodies, contactPairs, joints)
 	sol.solveIterations(sol.info)
 	sol.finish(bodies, sol.info)
 }
@@ -84,8 +86,9 @@ func (sol *solver) solve(bodies map[uint32]*body, contactPairs map[uint64]*conta
 
 // setupConstraints ensures all data is properly initialized before the solver
 // starts. It sets up the contact and friction constraints based on a list of
-// bodies and the complete list of all contact information.
-func (sol *solver) setupConstraints(bodies map[uint32]*body, contactPairs map[uint64]*contactPair) {
+// bodies and the complete list of all contact information. Joints add
+// their own constraints.
+func (sol *solver) setupConstraints(bodies map[uint32]*body, contactPairs map[uint64]*contactPair, joints []Joint) {
 
 	// Create solver specific information for each movable body.
 	// Static bodies do not have associated solver bodies.
@@ -101,11 +104,27 @@ func (sol *solver) setupConstraints(bodies map[uint32]*body, contactPairs map[ui
 	// Reset the solver constraint holders, keeping allocated memory.
 	sol.constC = sol.constC[0:0]
 	sol.constF = sol.constF[0:0]
+	sol.constJ = sol.constJ[0:0]
 
 	// Generate the solver constraints for each contact pair.
 	for _, contactPair := range contactPairs {
 		sol.convertContacts(contactPair, sol.info)
 	}
+
+	// Generate the solver constraints for each joint.
+	for _, j := range joints {
+		sol.convertJoint(j, sol.info)
+	}
+}
+
+// convertJoint generates solver constraints from the given joint.
+// Joints that can't move either body are ignored.
+func (sol *solver) convertJoint(j Joint, info *solverInfo) {
+	a, b := j.Bodies()
+	if !a.(*body).movable && (b == nil || !b.(*body).movable) {
+		return
+	}
+	j.setup(sol, info)
 }
 
 // convertContacts generates solver constraints from the given contacting pair.
@@ -313,6 +332,74 @@ func (sol *solver) setupFrictionConstraint(sc *solverConstraint, normalAxis *lin
 	sc.rhsPenetration = 0
 }
 
+// setupJointConstraint initializes a joint constraint along direction n.
+// Linear constraints use the pivot positions relPosA, relPosB relative to
+// each body. Angular constraints have nil relative positions and limit
+// the relative turning around n. Expected to be called on solver setup
+// for each joint constraint.
+//    target : relative speed along n that the constraint aims for.
+//    cfm    : constraint force mixing. Zero for a hard constraint.
+//    lo, hi : impulse limits.
+func (sol *solver) setupJointConstraint(sc *solverConstraint, sbodA, sbodB *solverBody, n, relPosA, relPosB *lin.V3,
+	target, cfm, lo, hi float64, info *solverInfo) {
+	bodyA, bodyB := sbodA.oBody, sbodB.oBody // either may be nil if body is static.
+	sc.sbodA, sc.sbodB = sbodA, sbodB
+	sc.oPoint = nil
+	sc.frictionIndex = nil
+	if relPosA != nil {
+		sc.normal.Set(n)
+		sc.relpos1CrossNormal.Cross(relPosA, n)
+		sc.relpos2CrossNormal.Cross(relPosB, n).Neg(sc.relpos2CrossNormal)
+	} else {
+		sc.normal.SetS(0, 0, 0)
+		sc.relpos1CrossNormal.Set(n)
+		sc.relpos2CrossNormal.Neg(n)
+	}
+
+	// compute sc.jacDiagABInv
+	denom := cfm
+	sc.angularComponentA.SetS(0, 0, 0)
+	if bodyA != nil {
+		sc.angularComponentA.MultMv(bodyA.iitw, sc.relpos1CrossNormal)
+		denom += bodyA.imass*sc.normal.Dot(sc.normal) + sc.relpos1CrossNormal.Dot(sc.angularComponentA)
+	}
+	sc.angularComponentB.SetS(0, 0, 0)
+	if bodyB != nil {
+		sc.angularComponentB.MultMv(bodyB.iitw, sc.relpos2CrossNormal)
+		denom += bodyB.imass*sc.normal.Dot(sc.normal) + sc.relpos2CrossNormal.Dot(sc.angularComponentB)
+	}
+	sc.jacDiagABInv = 0
+	if denom > lin.Epsilon {
+		sc.jacDiagABInv = 1 / denom
+	}
+	sc.cfm = cfm * sc.jacDiagABInv
+	sc.lowerLimit, sc.upperLimit = lo, hi
+	sc.rhsPenetration = 0
+
+	// Warm start uses the previously applied impulse as an initial guess.
+	sc.appliedImpulse = math.Max(lo, math.Min(hi, sc.appliedImpulse*info.warmstartingFactor))
+	{ // scratch v0, v1
+		linc, angc := sol.v0, sol.v1
+		if bodyA != nil {
+			sbodA.applyImpulse(linc.Scale(sc.normal, bodyA.imass), angc.Set(sc.angularComponentA), sc.appliedImpulse)
+		}
+		if bodyB != nil {
+			sbodB.applyImpulse(linc.Scale(sc.normal, -bodyB.imass), angc.Set(sc.angularComponentB), sc.appliedImpulse)
+		}
+	} // scratch v0, v1 free
+	sc.appliedPushImpulse = 0.0
+
+	vel1Dotn, vel2Dotn := 0.0, 0.0
+	if bodyA != nil {
+		vel1Dotn = sc.normal.Dot(sbodA.linearVelocity) + sc.relpos1CrossNormal.Dot(sbodA.angularVelocity)
+	}
+	if bodyB != nil { // scratch v0
+		vel2Dotn = sol.v0.Neg(sc.normal).Dot(sbodB.linearVelocity) + sc.relpos2CrossNormal.Dot(sbodB.angularVelocity)
+	} // scratch v0 free
+	sc.rhs = (target - (vel1Dotn + vel2Dotn)) * sc.jacDiagABInv
+	sol.constJ = append(sol.constJ, sc)
+}
+
 // solver setup and initialization
 // =============================================================================
 // solver solution methods are used iteratively once the system of equations
@@ -342,6 +429,9 @@ func (sol *solver) solveIterations(info *solverInfo) {
 // solverBody deltaVelocity values that better match all the constraints.
 func (sol *solver) solveSingleIteration(iteration int, info *solverInfo) {
 	if iteration < info.numIterations {
+		for _, sc := range sol.constJ {
+			sol.resolveSingleConstraint(sc.sbodA, sc.sbodB, sc, true)
+		}
 		for _, sc := range sol.constC {
 			sol.resolveSingleConstraint(sc.sbodA, sc.sbodB, sc, true)
 		}
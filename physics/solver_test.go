@@ -44,7 +44,7 @@ func TestBoxHit0(t *testing.T) {
 
 	// run the solver once to get updated velocities.
 	sol := newSolver()
-	sol.solve(bodies, pairs)
+	sol.solve(bodies, pairs, nil)
 	lv, av := box.lvel, box.avel
 
 	// check the linear velocity
@@ -108,7 +108,7 @@ func TestBoxHit2(t *testing.T) {
 
 	// run the solver once to get updated velocities.
 	sol := newSolver()
-	sol.solve(bodies, pairs)
+	sol.solve(bodies, pairs, nil)
 	lv, av := box.lvel, box.avel
 
 	// check the linear velocity
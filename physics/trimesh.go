@@ -0,0 +1,410 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+// trimesh.go holds static triangle shapes used for level geometry.
+// A triangle mesh keeps its triangles in a bounding volume hierarchy
+// so that narrowphase only checks the triangles near a moving body.
+// Each nearby triangle is collided as a thin convex shape.
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// trimesh is a static collision shape made from triangles, ie: the
+// floors and walls of a level model. Trimeshes have no volume and
+// are expected to be used with bodies that have no mass.
+type trimesh struct {
+	verts []lin.V3   // Triangle corners.
+	faces []int      // 3 vertex indicies per triangle, ordered by the bvh.
+	nodes []bvhNode  // Bounding volume hierarchy. The root is first.
+	tris  []triangle // Scratch triangles returned by queries.
+	stack []int      // Scratch nodes for queries.
+	tab   Abox       // Scratch triangle bounds for queries.
+	ab    *Abox      // Local space bounds.
+}
+
+// NewTriMesh creates a static triangle mesh shape from x, y, z vertex
+// triples and counter-clockwise triangle faces, ie: the vertex positions
+// and faces, load.MshData.V and load.MshData.F, of a level model.
+// Triangles collide from their front side only. Triangles without
+// area are ignored.
+func NewTriMesh(vertices []float32, faces []uint32) Shape {
+	m := &trimesh{ab: &Abox{}}
+	for cnt := 0; cnt+2 < len(vertices); cnt += 3 {
+		x, y, z := float64(vertices[cnt]), float64(vertices[cnt+1]), float64(vertices[cnt+2])
+		m.verts = append(m.verts, lin.V3{X: x, Y: y, Z: z})
+	}
+	nv, n := len(m.verts), &lin.V3{}
+	for cnt := 0; cnt+2 < len(faces); cnt += 3 {
+		f0, f1, f2 := int(faces[cnt]), int(faces[cnt+1]), int(faces[cnt+2])
+		if f0 >= nv || f1 >= nv || f2 >= nv {
+			continue // ignore bad faces.
+		}
+		if faceNormal(n, &m.verts[f0], &m.verts[f1], &m.verts[f2]).AeqZ() {
+			continue // ignore triangles without area.
+		}
+		m.faces = append(m.faces, f0, f1, f2)
+	}
+	m.build()
+	return m
+}
+
+// Implements Shape.Type
+func (m *trimesh) Type() int { return TriMeshShape }
+
+// Implements Shape.Aabb
+// The bounding box surrounds the transformed mesh bounds.
+func (m *trimesh) Aabb(t *lin.T, ab *Abox, margin float64) *Abox {
+	return transformAabb(m.ab, t, ab, margin)
+}
+
+// Implements Shape.Volume
+// Triangle meshes are not solid and have no volume.
+func (m *trimesh) Volume() float64 { return 0 }
+
+// Implements Shape.Inertia
+// Triangle meshes are static and have no inertia.
+func (m *trimesh) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	return inertia.SetS(0, 0, 0)
+}
+
+// triangles implements triangleSource by returning the triangles
+// whose bounds overlap the local space bounding box ab.
+func (m *trimesh) triangles(ab *Abox) []triangle {
+	m.tris = m.tris[:0]
+	if len(m.nodes) == 0 {
+		return m.tris
+	}
+	m.stack = append(m.stack[:0], 0)
+	for len(m.stack) > 0 {
+		n := &m.nodes[m.stack[len(m.stack)-1]]
+		m.stack = m.stack[:len(m.stack)-1]
+		if !overlaps(&n.ab, ab) {
+			continue
+		}
+		if n.count == 0 {
+			m.stack = append(m.stack, n.left, n.right)
+			continue
+		}
+		for cnt := n.first; cnt < n.first+n.count; cnt++ {
+			m.tris = append(m.tris, triangle{}) // kept if it overlaps.
+			t := &m.tris[len(m.tris)-1]
+			t.points[0] = m.verts[m.faces[cnt*3]]
+			t.points[1] = m.verts[m.faces[cnt*3+1]]
+			t.points[2] = m.verts[m.faces[cnt*3+2]]
+			if !overlaps(t.Aabb(nil, &m.tab, 0), ab) {
+				m.tris = m.tris[:len(m.tris)-1]
+			}
+		}
+	}
+	return m.tris
+}
+
+// trimesh
+// ============================================================================
+// bvh
+
+// bvhNode is a node in a bounding volume hierarchy. Leaf nodes
+// refer to a range of triangles. Other nodes have two children.
+type bvhNode struct {
+	ab          Abox // Bounds of all triangles below this node.
+	left, right int  // Child node indicies.
+	first       int  // First triangle of a leaf node.
+	count       int  // Number of triangles in a leaf. Zero for non-leaves.
+}
+
+// bvhLeafSize is the most triangles kept in a leaf node.
+const bvhLeafSize = 4
+
+// build creates the bounding volume hierarchy by recursively splitting
+// the triangles in half along the longest axis of their centers.
+func (m *trimesh) build() {
+	nt := len(m.faces) / 3
+	order := make([]int, nt)
+	centers := make([]lin.V3, nt)
+	for cnt := range order {
+		order[cnt] = cnt
+		a, b, c := &m.verts[m.faces[cnt*3]], &m.verts[m.faces[cnt*3+1]], &m.verts[m.faces[cnt*3+2]]
+		centers[cnt].Add(a, b).Add(&centers[cnt], c).Scale(&centers[cnt], 1.0/3.0)
+	}
+	m.nodes = m.nodes[:0]
+	if nt > 0 {
+		m.split(order, centers, 0)
+		*m.ab = m.nodes[0].ab
+	}
+
+	// reorder the faces so that each leaf is a contiguous range.
+	faces := make([]int, 0, len(m.faces))
+	for _, index := range order {
+		faces = append(faces, m.faces[index*3:index*3+3]...)
+	}
+	m.faces = faces
+}
+
+// split creates the node for the given triangles and returns its index.
+// The triangles are sorted so that each child holds a contiguous range.
+func (m *trimesh) split(order []int, centers []lin.V3, first int) int {
+	index := len(m.nodes)
+	m.nodes = append(m.nodes, bvhNode{})
+	n := bvhNode{ab: Abox{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64,
+		-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}}
+	cb := n.ab // bounds of the triangle centers.
+	for _, ti := range order {
+		for k := 0; k < 3; k++ {
+			growAabb(&n.ab, &m.verts[m.faces[ti*3+k]])
+		}
+		growAabb(&cb, &centers[ti])
+	}
+	if len(order) <= bvhLeafSize {
+		n.first, n.count = first, len(order)
+		m.nodes[index] = n
+		return index
+	}
+
+	// split along the longest axis.
+	axis := func(v *lin.V3) float64 { return v.X }
+	dx, dy, dz := cb.Lx-cb.Sx, cb.Ly-cb.Sy, cb.Lz-cb.Sz
+	switch {
+	case dy > dx && dy > dz:
+		axis = func(v *lin.V3) float64 { return v.Y }
+	case dz > dx && dz > dy:
+		axis = func(v *lin.V3) float64 { return v.Z }
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return axis(&centers[order[i]]) < axis(&centers[order[j]])
+	})
+	half := len(order) / 2
+	n.left = m.split(order[:half], centers, first)
+	n.right = m.split(order[half:], centers, first+half)
+	m.nodes[index] = n
+	return index
+}
+
+// bvh
+// ============================================================================
+// triangle
+
+// triangle is a single triangle from a static shape. It is used as
+// a temporary convex shape during narrowphase. The points are counter
+// clockwise when viewed from the front.
+type triangle struct {
+	points [3]lin.V3 // In the local space of the static shape.
+}
+
+// Implements Shape.Type
+func (t *triangle) Type() int { return TriMeshShape }
+
+// Implements Shape.Aabb
+// A nil transform leaves the bounding box in local space.
+func (t *triangle) Aabb(tr *lin.T, ab *Abox, margin float64) *Abox {
+	ab.Sx, ab.Sy, ab.Sz = math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	ab.Lx, ab.Ly, ab.Lz = -math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+	for cnt := range t.points {
+		p := t.points[cnt]
+		if tr != nil {
+			p.X, p.Y, p.Z = tr.AppS(p.X, p.Y, p.Z)
+		}
+		growAabb(ab, &p)
+	}
+	ab.Sx, ab.Sy, ab.Sz = ab.Sx-margin, ab.Sy-margin, ab.Sz-margin
+	ab.Lx, ab.Ly, ab.Lz = ab.Lx+margin, ab.Ly+margin, ab.Lz+margin
+	return ab
+}
+
+// Implements Shape.Volume
+func (t *triangle) Volume() float64 { return 0 }
+
+// Implements Shape.Inertia
+func (t *triangle) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	return inertia.SetS(0, 0, 0)
+}
+
+// support returns the triangle point furthest in direction dx, dy, dz.
+func (t *triangle) support(dx, dy, dz float64) (x, y, z float64) {
+	best := -math.MaxFloat64
+	for cnt := range t.points {
+		p := &t.points[cnt]
+		if dot := p.X*dx + p.Y*dy + p.Z*dz; dot > best {
+			best, x, y, z = dot, p.X, p.Y, p.Z
+		}
+	}
+	return x, y, z
+}
+
+// triangle
+// ============================================================================
+// static shape collision
+
+// triangleSource is implemented by static shapes made of triangles.
+type triangleSource interface {
+
+	// triangles returns the triangles that overlap the bounding box.
+	// The box and triangles are in the local space of the shape.
+	// The returned triangles are only valid until the next call.
+	triangles(ab *Abox) []triangle
+}
+
+// duplicateContact is the square of the distance within which
+// contacts from different triangles are treated as the same contact.
+const duplicateContact = 0.0001
+
+// collideTriangles returns up to 4 contact points between volume body a
+// and the nearby triangles of static body b. Each triangle is collided
+// using GJK/EPA. Contacts from behind a triangle are ignored so that
+// bodies are only pushed out of the front of the static shape.
+func (col *collider) collideTriangles(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	aa, bb := a.(*body), b.(*body)
+	source, ok := bb.shape.(triangleSource)
+	if !ok {
+		return a, b, c[0:0]
+	}
+
+	// find the nearby triangles using the volume bounds in the static space.
+	ts := &col.tris
+	ab, local := aa.shape.Aabb(aa.world, &ts.ab, margin), &ts.local
+	*local = Abox{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64,
+		-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for _, x := range []float64{ab.Sx, ab.Lx} {
+		for _, y := range []float64{ab.Sy, ab.Ly} {
+			for _, z := range []float64{ab.Sz, ab.Lz} {
+				lx, ly, lz := bb.world.InvS(x, y, z)
+				growAabb(local, &lin.V3{X: lx, Y: ly, Z: lz})
+			}
+		}
+	}
+	local.Sx, local.Sy, local.Sz = local.Sx-margin, local.Sy-margin, local.Sz-margin
+	local.Lx, local.Ly, local.Lz = local.Lx+margin, local.Ly+margin, local.Lz+margin
+
+	// collide with each triangle, keeping the front facing contacts.
+	if ts.pocs == nil {
+		ts.pocs = newManifold()
+	}
+	found, tb := ts.found[:0], &ts.body
+	tb.world = bb.world
+	front, n := &ts.front, &ts.n
+	tris := source.triangles(local)
+	for cnt := range tris {
+		tri := &tris[cnt]
+		tb.shape = tri
+		faceNormal(n, &tri.points[0], &tri.points[1], &tri.points[2])
+		front.SetS(bb.world.AppR(n.X, n.Y, n.Z))
+		_, _, pocs := col.collideConvex(aa, tb, ts.pocs)
+		for _, poc := range pocs {
+			if facing := poc.normal.Dot(front); facing > 0 { // not behind the triangle.
+				face := facing > 1-featureSlope*featureSlope/2
+				found = append(found, triContact{*poc.point, *poc.normal, poc.depth, face})
+			}
+		}
+	}
+	ts.found, tb.shape, tb.world = found, nil, nil // keep the memory, not the shapes.
+
+	// Ignore edge and corner contacts that are close to a face contact.
+	// These are usually the internal edges between neighbouring triangles
+	// and would bump bodies sliding over the mesh.
+	points, normals, depths := []lin.V3{}, []lin.V3{}, []float64{}
+	for _, tc := range found {
+		if !tc.face && tc.nearFace(found) {
+			continue
+		}
+
+		// triangles sharing an edge or corner can find the same point.
+		index := len(points)
+		for cnt := range points {
+			if lin.NewV3().Sub(&points[cnt], &tc.point).LenSqr() < duplicateContact {
+				index = cnt
+				break
+			}
+		}
+		switch {
+		case index == len(points):
+			points = append(points, tc.point)
+			normals = append(normals, tc.normal)
+			depths = append(depths, tc.depth)
+		case tc.depth < depths[index]:
+			points[index], normals[index], depths[index] = tc.point, tc.normal, tc.depth
+		}
+	}
+	k = c[0:0]
+	for _, index := range bestContacts(points, depths) {
+		poc := c[len(k)]
+		poc.point.Set(&points[index])
+		poc.normal.Set(&normals[index])
+		poc.depth = depths[index]
+		k = c[0 : len(k)+1]
+	}
+	return a, b, k
+}
+
+// triScratch holds the memory reused by each static shape collision.
+type triScratch struct {
+	ab, local Abox              // Volume bounds in world and static space.
+	front, n  lin.V3            // Triangle normals.
+	body      body              // Wraps each triangle for collideConvex.
+	pocs      []*pointOfContact // Contacts for each triangle.
+	found     []triContact      // Contacts for all triangles.
+}
+
+// triContact is a contact between a volume body and a triangle.
+type triContact struct {
+	point, normal lin.V3  // Point on the triangle and normal towards the body.
+	depth         float64 // Negative for penetration.
+	face          bool    // True if the normal is the triangle normal.
+}
+
+// nearFace returns true if one of the face contacts has a similar normal.
+func (tc *triContact) nearFace(contacts []triContact) bool {
+	for cnt := range contacts {
+		if contacts[cnt].face && contacts[cnt].normal.Dot(&tc.normal) > internalEdge {
+			return true
+		}
+	}
+	return false
+}
+
+// internalEdge is the cosine of the angle, about 25 degrees, within which
+// edge contacts are ignored in favour of neighbouring face contacts.
+const internalEdge = 0.9
+
+// collideStaticVolume reverses the collision so the volume body is first.
+func (col *collider) collideStaticVolume(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	return col.collideTriangles(b, a, c)
+}
+
+// static shape collision
+// ============================================================================
+// utility functions.
+
+// transformAabb sets ab to surround the local bounds lb after
+// lb is transformed by t. The bounds are enlarged by margin.
+func transformAabb(lb *Abox, t *lin.T, ab *Abox, margin float64) *Abox {
+	ab.Sx, ab.Sy, ab.Sz = math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	ab.Lx, ab.Ly, ab.Lz = -math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+	for _, x := range []float64{lb.Sx, lb.Lx} {
+		for _, y := range []float64{lb.Sy, lb.Ly} {
+			for _, z := range []float64{lb.Sz, lb.Lz} {
+				wx, wy, wz := t.AppS(x, y, z)
+				growAabb(ab, &lin.V3{X: wx, Y: wy, Z: wz})
+			}
+		}
+	}
+	ab.Sx, ab.Sy, ab.Sz = ab.Sx-margin, ab.Sy-margin, ab.Sz-margin
+	ab.Lx, ab.Ly, ab.Lz = ab.Lx+margin, ab.Ly+margin, ab.Lz+margin
+	return ab
+}
+
+// growAabb enlarges ab, if necessary, to include point p.
+func growAabb(ab *Abox, p *lin.V3) {
+	ab.Sx, ab.Sy, ab.Sz = math.Min(ab.Sx, p.X), math.Min(ab.Sy, p.Y), math.Min(ab.Sz, p.Z)
+	ab.Lx, ab.Ly, ab.Lz = math.Max(ab.Lx, p.X), math.Max(ab.Ly, p.Y), math.Max(ab.Lz, p.Z)
+}
+
+// overlaps returns true if the boxes overlap or touch. Unlike
+// Abox.Overlaps touching counts since triangles can be flat.
+func overlaps(a, b *Abox) bool {
+	return a.Sx <= b.Lx && a.Lx >= b.Sx && a.Sy <= b.Ly && a.Ly >= b.Sy && a.Sz <= b.Lz && a.Lz >= b.Sz
+}
@@ -0,0 +1,165 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// post.go holds code for full screen post processing effects.
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gazed/vu/render"
+)
+
+// AddPost appends a full screen post processing pass to a scene.
+// Once a scene has a post pass the scene is rendered to a texture and
+// each pass, in the order added, draws the previous result to the
+// next texture. The last pass draws to the display. The scene textures
+// alternate between two display sized render targets so the chain can
+// be any length. The targets are recreated when the display is resized.
+//
+// The returned pass entity is a model using the given shader and any
+// extra model assets. Pass values are changed using the pass SetUniform.
+// The shader samples the previous result using "uv" and any extra
+// textures using "uv1", "uv2"... Extra textures are clamped and smoothed.
+// The built in post processing shaders, and their uniforms, are:
+//    bloom     : brightens areas above threshold 0.8 using spread 2.0 texel
+//                samples and intensity 1.0.
+//    toneMap   : maps colors using exposure 1.0 and gamma 2.2.
+//    colorGrade: remaps colors using a 256x16 lookup table strip texture,
+//                ie: AddPost("colorGrade", "tex:lut"). Blend 1.0 is
+//                fully graded, 0 is ungraded.
+//    fxaa      : smooths jagged edges. No uniforms.
+//    vignette  : darkens towards the edges using radius 0.75, softness
+//                0.45 and strength 0.5.
+// A typical order is bloom, toneMap, colorGrade, fxaa, then vignette:
+//    scene.AddPost("bloom").SetUniform("threshold", 0.7)
+//    scene.AddPost("toneMap").SetUniform("exposure", 1.2)
+//    scene.AddPost("fxaa")
+// The post processed scene covers any scenes drawn at the same overlay.
+// Passes are disposed with the scene. Nil is returned if the entity is
+// not a scene or if the scene is already rendered to a texture by AsTex.
+//
+// Depends on Eng.AddScene.
+func (e *Ent) AddPost(shader string, attrs ...string) *Ent {
+	if scene := e.app.scenes.get(e.eid); scene != nil {
+		if _, ok := e.app.scenes.targets[e.eid]; ok {
+			log.Printf("AddPost ignored for AsTex scene %d", e.eid)
+			return nil
+		}
+		p := e.app.scenes.createPost(scene, e.app.state.W, e.app.state.H)
+		scene.setProjection(e.app.state.W, e.app.state.H)
+		return p.addPass(e.app, shader, attrs...)
+	}
+	log.Printf("AddPost needs AddScene %d", e.eid)
+	return nil
+}
+
+// =============================================================================
+
+// post holds the render targets and the ordered full screen passes
+// for a post processed scene. The scene is drawn to the first target.
+type post struct {
+	passes  []eid      // Pass models in draw order.
+	targets [2]*target // Ping-pong render targets.
+}
+
+// newPost creates the display sized render targets for a post
+// processing chain. The targets are created on the GPU by the
+// scene rebind.
+func newPost(w, h int) *post {
+	p := &post{targets: [2]*target{newTarget(), newTarget()}}
+	p.resize(w, h)
+	return p
+}
+
+// resize sets the render target size to match the display.
+// Returns true if the size changed and the targets need
+// to be recreated.
+func (p *post) resize(w, h int) bool {
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	if p.targets[0].w == w && p.targets[0].h == h {
+		return false
+	}
+	for _, t := range p.targets {
+		t.w, t.h = w, h
+	}
+	return true
+}
+
+// postDefaults are the initial uniform values for the built in
+// post processing shaders.
+var postDefaults = map[string]map[string][]float32{
+	"bloom":      {"threshold": {0.8}, "intensity": {1.0}, "spread": {2.0}},
+	"toneMap":    {"exposure": {1.0}, "gamma": {2.2}},
+	"colorGrade": {"blend": {1.0}},
+	"vignette":   {"radius": {0.75}, "softness": {0.45}, "strength": {0.5}},
+}
+
+// addPass creates a pass entity outside the scene graph hierarchy.
+// The pass input texture is the output of the previous pass.
+func (p *post) addPass(app *application, shader string, attrs ...string) *Ent {
+	pass := &Ent{app: app, eid: app.eids.create()}
+	app.povs.create(pass.eid, 0) // Keep outside scene graph hierarchy.
+	m := app.models.create(pass)
+	in := p.targets[len(p.passes)%2].tex
+	m.texs = append(m.texs, in)
+	m.tpos[in.name] = 0
+	m.track[tex] = 1
+
+	// full screen quad in clip space.
+	quad := pass.GenMesh("postQuad")
+	quad.InitData(0, 2, render.StaticDraw, false).SetData(0, []float32{-1, -1, 1, -1, 1, 1, -1, 1})
+	quad.InitData(2, 2, render.StaticDraw, false).SetData(2, []float32{0, 0, 1, 0, 1, 1, 0, 1})
+	quad.InitFaces(render.StaticDraw).SetFaces([]uint16{0, 1, 2, 0, 2, 3})
+
+	// lookup textures are sampled between texels and not repeated.
+	for _, attr := range attrs {
+		if name := strings.TrimPrefix(attr, "tex:"); name != attr {
+			pass.Clamp(name).Filter(name, render.Linear)
+		}
+	}
+	for key, values := range postDefaults[shader] {
+		m.uniforms[key] = append([]float32{}, values...)
+	}
+	app.models.loadAssets(pass, m, append(attrs, "shd:"+shader)...)
+	p.passes = append(p.passes, pass.eid)
+	return pass
+}
+
+// draw adds a full screen draw call for each pass. Passes are drawn
+// after the scene, in the order they were added, reading one target
+// and writing the other. The last pass writes to the display.
+func (p *post) draw(app *application, sc *scene, f frame) frame {
+	if p.targets[0].bid == 0 || p.targets[1].bid == 0 {
+		return f // targets not yet created.
+	}
+	for _, id := range p.passes {
+		if m := app.models.getReady(id); m == nil || m.msh == nil || m.msh.vao == 0 {
+			return f // wait for all passes to load.
+		}
+	}
+	var draw **render.Draw
+	last := len(p.passes) - 1
+	for cnt, id := range p.passes {
+		m, pv := app.models.getReady(id), app.povs.get(id)
+		if f, draw = f.getDraw(); draw != nil {
+			m.draw(*draw, nil, pv, sc.cam)
+			(*draw).Depth = false
+			(*draw).Fbo = 0 // last pass draws to the display.
+			if cnt < last {
+				(*draw).Fbo = p.targets[(cnt+1)%2].bid
+			}
+
+			// Draw after the scene models in pass order.
+			(*draw).Bucket = setBucket(0, sc.overlay) | uint64(len(p.passes)-cnt)
+		}
+	}
+	return f
+}
@@ -63,3 +63,14 @@ func (p *Profile) Rendered(eng Eng) (models, rendered int) {
 	rendered = len(eng.(*application).frame)
 	return models, rendered
 }
+
+// =============================================================================
+
+// Resource describes an asset that is held by the engine.
+// Resources are reported by Eng.Resident.
+type Resource struct {
+	Kind  string // Asset type, ie: "msh", "tex", "shd".
+	Name  string // Asset name.
+	Refs  int    // Number of models and preload groups using the asset.
+	Bytes int    // Approximate data size in bytes.
+}
@@ -5,6 +5,7 @@ package render
 
 import (
 	"log"
+	"math"
 )
 
 // Data carries the buffer data that is bound/copied to the GPU.
@@ -53,11 +54,14 @@ func NewInstancedData(lloc uint32) Data {
 
 // NewFaceData creates and specifies usagefor a set of triangle faces.
 // Triangle faces contain vertex indicies ordered to draw triangles.
-// Data can now be loaded and updated using Data.Set().
+// Data can now be loaded and updated using Data.Set() with either
+// []uint16 or []uint32 indicies. 32-bit indicies are only kept
+// when they reference more verticies than fit in 16-bits.
 //     usage     : STATIC or DYNAMIC
 func NewFaceData(usage uint32) Data {
 	fd := &faceData{}
 	fd.data = []uint16{}
+	fd.wide = []uint32{}
 	fd.usage = usage
 	return fd
 }
@@ -87,10 +91,16 @@ type vertexData struct {
 }
 
 // Set makes a copy of the given data, replacing any existing data, and marks
-// the data as needing to be resent to the GPU.
+// the data as needing to be resent to the GPU. Copying another vertex Data
+// also copies its layout while keeping this data's GPU buffer.
 func (vd *vertexData) Set(data interface{}) {
 	vd.vcnt = 0
 	switch d := data.(type) {
+	case *vertexData:
+		vd.floats = append(vd.floats[:0], d.floats...)
+		vd.bytes = append(vd.bytes[:0], d.bytes...)
+		vd.span, vd.normalize, vd.vcnt = d.span, d.normalize, d.vcnt
+		vd.rebind = true
 	case []float32:
 		vd.floats = vd.floats[:0]           // keep allocated memory.
 		vd.floats = append(vd.floats, d...) // copy in new data.
@@ -137,31 +147,68 @@ func (vd *vertexData) Clone() Data {
 
 // faceData contains the vertex draw order. The values specify the
 // order the GPU should render/processes the vertex data.
+// Only one of data or wide is used.
 type faceData struct {
 	data   []uint16 // Vertex buffer arranged as [][span]uint16.
+	wide   []uint32 // Vertex buffer for indicies past 16-bits.
 	ref    uint32   // Vertex GPU buffer reference.
 	usage  uint32   // STATIC_DRAW, DYNAMIC_DRAW.
 	rebind bool     // True when data has changed and needs rebinding.
 }
 
 // Set makes a copy of the given data, replacing any existing data, and marks
-// the data as needing to be resent to the GPU. Data is expected as []uint16.
+// the data as needing to be resent to the GPU. Data is expected as []uint16
+// or []uint32. Data of []uint32 is stored as []uint16 when possible.
+// Copying another face Data keeps this data's GPU buffer.
 func (fd *faceData) Set(data interface{}) {
 	switch d := data.(type) {
+	case *faceData:
+		fd.data = append(fd.data[:0], d.data...)
+		fd.wide = append(fd.wide[:0], d.wide...)
+		fd.rebind = true
 	case []uint16:
+		fd.wide = fd.wide[:0]
 		fd.data = fd.data[:0]           // keep allocated memory.
 		fd.data = append(fd.data, d...) // copy in new data.
 		fd.rebind = true                // Set to false when rebound.
+	case []uint32:
+		fd.wide = fd.wide[:0]
+		fd.data = fd.data[:0]
+		max := uint32(0)
+		for _, index := range d {
+			if index > max {
+				max = index
+			}
+		}
+		if max > math.MaxUint16 {
+			fd.wide = append(fd.wide, d...) // needs 32-bit indicies.
+		} else {
+			for _, index := range d {
+				fd.data = append(fd.data, uint16(index))
+			}
+		}
+		fd.rebind = true
 	default:
 		log.Printf("faceData.Set: invalid data type %t", d)
 	}
 }
 
 // Size returns the size of the face data in bytes.
-func (fd *faceData) Size() uint32 { return uint32(len(fd.data)) * 2 }
+func (fd *faceData) Size() uint32 { return uint32(len(fd.data))*2 + uint32(len(fd.wide))*4 }
 
 // Len returns the number of face indicies.
-func (fd *faceData) Len() int { return len(fd.data) }
+func (fd *faceData) Len() int { return len(fd.data) + len(fd.wide) }
+
+// isWide returns true if the face data uses 32-bit indicies.
+func (fd *faceData) isWide() bool { return len(fd.wide) > 0 }
+
+// index returns the vertex index at the given position.
+func (fd *faceData) index(at int) uint32 {
+	if len(fd.wide) > 0 {
+		return fd.wide[at]
+	}
+	return uint32(fd.data[at])
+}
 
 // Clone returns a copy of the Data, including any GPU refs.
 func (fd *faceData) Clone() Data {
@@ -169,5 +216,7 @@ func (fd *faceData) Clone() Data {
 	*c = *fd // copy by value
 	c.data = make([]uint16, len(fd.data))
 	copy(c.data, fd.data)
+	c.wide = make([]uint32, len(fd.wide))
+	copy(c.wide, fd.wide)
 	return c
 }
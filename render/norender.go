@@ -0,0 +1,147 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package render
+
+// norender.go provides a render Context that does not need a GPU.
+
+import (
+	"image"
+	"strconv"
+	"strings"
+)
+
+// NoRender can be used to mock out graphics when there is no graphics
+// card, ie: running the engine headless for tests. It generates unique
+// non-zero references for bound data and discovers shader uniforms and
+// layouts from the shader source. Nothing is drawn.
+type NoRender struct {
+	refs uint32 // Last generated bind reference.
+}
+
+// Init is mocked method for Context interface.
+func (nr *NoRender) Init() error { return nil }
+
+// Clear is mocked method for Context interface.
+func (nr *NoRender) Clear() {}
+
+// Color is mocked method for Context interface.
+func (nr *NoRender) Color(r, g, b, a float32) {}
+
+// Enable is mocked method for Context interface.
+func (nr *NoRender) Enable(attr uint32, enable bool) {}
+
+// Viewport is mocked method for Context interface.
+func (nr *NoRender) Viewport(width int, height int) {}
+
+// BindMesh generates a vao reference if one does not already exist.
+func (nr *NoRender) BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error {
+	nr.setRef(vao)
+	return nil
+}
+
+// BindShader generates a program reference and fills in the uniform
+// and layout references by scanning the shader source.
+func (nr *NoRender) BindShader(vsh, fsh []string, uniforms map[string]int32,
+	layouts map[string]uint32) (program uint32, err error) {
+	nr.setRef(&program)
+	shaderRefs(vsh, fsh, uniforms, layouts)
+	return program, nil
+}
+
+// BindTexture generates a texture reference if one does not already exist.
+func (nr *NoRender) BindTexture(tid *uint32, img image.Image, mips ...image.Image) error {
+	nr.setRef(tid)
+	return nil
+}
+
+// BindCubemap generates a texture reference if one does not already exist.
+func (nr *NoRender) BindCubemap(tid *uint32, faces []image.Image) error {
+	nr.setRef(tid)
+	return nil
+}
+
+// SetTextureMode is mocked method for Context interface.
+func (nr *NoRender) SetTextureMode(tid uint32, clamp bool, filter int) {}
+
+// Render is mocked method for Context interface.
+func (nr *NoRender) Render(d *Draw) {}
+
+// BindMap generates framebuffer and texture references.
+func (nr *NoRender) BindMap(fbo, tid *uint32) error {
+	nr.setRef(fbo)
+	nr.setRef(tid)
+	return nil
+}
+
+// BindTarget generates framebuffer, texture, and depth buffer references.
+func (nr *NoRender) BindTarget(fbo, tid, db *uint32) error {
+	nr.setRef(fbo)
+	nr.setRef(tid)
+	nr.setRef(db)
+	return nil
+}
+
+// BindPostTarget generates framebuffer, texture, and depth buffer references.
+func (nr *NoRender) BindPostTarget(fbo, tid, db *uint32, w, h int) error {
+	return nr.BindTarget(fbo, tid, db)
+}
+
+// ReleaseMesh is mocked method for Context interface.
+func (nr *NoRender) ReleaseMesh(vao uint32) {}
+
+// ReleaseShader is mocked method for Context interface.
+func (nr *NoRender) ReleaseShader(sid uint32) {}
+
+// ReleaseTexture is mocked method for Context interface.
+func (nr *NoRender) ReleaseTexture(tid uint32) {}
+
+// ReleaseMap is mocked method for Context interface.
+func (nr *NoRender) ReleaseMap(fbo, tid uint32) {}
+
+// ReleaseTarget is mocked method for Context interface.
+func (nr *NoRender) ReleaseTarget(fbo, tid, db uint32) {}
+
+// setRef assigns a new reference unless the reference is already set.
+func (nr *NoRender) setRef(ref *uint32) {
+	if *ref == 0 {
+		nr.refs++
+		*ref = nr.refs
+	}
+}
+
+// NoRender
+// =============================================================================
+// shader source scanning.
+
+// shaderRefs mimics the uniform and layout discovery that a GPU does
+// when a shader program is linked. Uniform references are assigned in
+// the order they are found. Layout references are the declared
+// layout locations, ie:
+//     layout(location=0) in vec3 in_v;
+//     uniform mat4 pm;
+func shaderRefs(vsh, fsh []string, uniforms map[string]int32, layouts map[string]uint32) {
+	for _, src := range [][]string{vsh, fsh} {
+		for _, line := range src {
+			if cmt := strings.Index(line, "//"); cmt >= 0 {
+				line = line[:cmt] // ignore comments.
+			}
+			fields := strings.Fields(strings.Replace(line, ";", " ", -1))
+			switch {
+			case len(fields) >= 3 && fields[0] == "uniform":
+				name := fields[2]
+				if br := strings.Index(name, "["); br >= 0 {
+					name = name[:br] // arrays use the base name.
+				}
+				if _, ok := uniforms[name]; !ok {
+					uniforms[name] = int32(len(uniforms))
+				}
+			case len(fields) >= 4 && strings.HasPrefix(fields[0], "layout"):
+				loc := strings.TrimSuffix(strings.TrimPrefix(fields[0], "layout(location="), ")")
+				if lloc, err := strconv.Atoi(loc); err == nil && fields[1] == "in" {
+					layouts[fields[3]] = uint32(lloc)
+				}
+			}
+		}
+	}
+}
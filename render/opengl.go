@@ -6,6 +6,7 @@ package render
 import (
 	"fmt"
 	"image"
+	"image/draw"
 	"log"
 	"strings"
 
@@ -15,14 +16,21 @@ import (
 // opengl is the OpenGL implementation of Renderer. See the Renderer interface
 // for comments. See the OpenGL documentation for OpenGL methods and constants.
 type opengl struct {
-	depthTest bool   // Track current depth setting to reduce state switching.
-	shader    uint32 // Track the current shader to reduce shader switching.
-	fbo       uint32 // Track current framebuffer object to reduce switching.
-	vw, vh    int32  // Remember the viewport size for framebuffer switching.
+	depthTest bool                // Track current depth setting to reduce state switching.
+	shader    uint32              // Track the current shader to reduce shader switching.
+	fbo       uint32              // Track current framebuffer object to reduce switching.
+	vw, vh    int32               // Remember the viewport size for framebuffer switching.
+	wide      map[uint32]bool     // Vao's with 32-bit face indicies.
+	cubes     map[uint32]bool     // Cube map textures.
+	buffers   map[uint32][]uint32 // Vertex and face buffers for each vao.
+	posts     map[uint32][2]int32 // Post processing target sizes by fbo.
 }
 
 // newRenderer returns an OpenGL Context.
-func newRenderer() Context { return &opengl{} }
+func newRenderer() Context {
+	return &opengl{wide: map[uint32]bool{}, cubes: map[uint32]bool{},
+		buffers: map[uint32][]uint32{}, posts: map[uint32][2]int32{}}
+}
 
 // Renderer implementation specific constants.
 const (
@@ -103,9 +111,14 @@ func (gc *opengl) Render(d *Draw) {
 	// is used to render to a texture associated with a framebuffer.
 	if gc.fbo != d.Fbo {
 		gl.BindFramebuffer(gl.FRAMEBUFFER, d.Fbo)
-		if d.Fbo == 0 {
+		size, post := gc.posts[d.Fbo]
+		switch {
+		case d.Fbo == 0:
 			gl.Viewport(0, 0, gc.vw, gc.vh)
-		} else {
+		case post:
+			gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+			gl.Viewport(0, 0, size[0], size[1]) // post processing texture.
+		default:
 			gl.Clear(gl.DEPTH_BUFFER_BIT)
 			gl.Viewport(0, 0, LayerSize, LayerSize) // framebuffer texture.
 		}
@@ -125,10 +138,14 @@ func (gc *opengl) Render(d *Draw) {
 	// bind the data buffers and render.
 	// FUTURE: support instanced for more than triangles.
 	gl.BindVertexArray(d.Vao)
+	indexType := uint32(gl.UNSIGNED_SHORT)
+	if gc.wide[d.Vao] {
+		indexType = gl.UNSIGNED_INT
+	}
 	switch d.Mode {
 	case Lines:
 		gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
-		gl.DrawElements(gl.LINES, d.FaceCnt, gl.UNSIGNED_SHORT, 0)
+		gl.DrawElements(gl.LINES, d.FaceCnt, indexType, 0)
 		gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
 	case Points:
 		gl.Enable(gl.PROGRAM_POINT_SIZE)
@@ -136,9 +153,9 @@ func (gc *opengl) Render(d *Draw) {
 		gl.Disable(gl.PROGRAM_POINT_SIZE)
 	case Triangles:
 		if d.Instances > 0 {
-			gl.DrawElementsInstanced(gl.TRIANGLES, d.FaceCnt, gl.UNSIGNED_SHORT, 0, d.Instances)
+			gl.DrawElementsInstanced(gl.TRIANGLES, d.FaceCnt, indexType, 0, d.Instances)
 		} else {
-			gl.DrawElements(gl.TRIANGLES, d.FaceCnt, gl.UNSIGNED_SHORT, 0)
+			gl.DrawElements(gl.TRIANGLES, d.FaceCnt, indexType, 0)
 		}
 	}
 	gl.BindVertexArray(0)
@@ -172,7 +189,7 @@ func (gc *opengl) bindUniforms(d *Draw) {
 				if t.order == index {
 					gl.Uniform1i(ref, int32(t.order))
 					gl.ActiveTexture(gl.TEXTURE0 + uint32(t.order))
-					gl.BindTexture(gl.TEXTURE_2D, t.tid)
+					gl.BindTexture(gc.texTarget(t.tid), t.tid)
 					break
 				}
 			}
@@ -246,6 +263,7 @@ func (gc *opengl) BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error
 		vd, ok := vbuff.(*vertexData)
 		if ok && vd.rebind {
 			gc.bindVertexBuffer(vd)
+			gc.track(*vao, vd.ref)
 			vd.rebind = false
 		}
 	}
@@ -255,8 +273,10 @@ func (gc *opengl) BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error
 	if fd, ok := fdata.(*faceData); ok {
 		if fd.rebind {
 			gc.bindFaceBuffer(fd)
+			gc.track(*vao, fd.ref)
 			fd.rebind = false
 		}
+		gc.wide[*vao] = fd.isWide()
 	}
 	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
 		return fmt.Errorf("BindMesh failed to bind fb %X", glerr)
@@ -265,6 +285,17 @@ func (gc *opengl) BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error
 	return nil
 }
 
+// track remembers the buffers bound to a vao so that
+// they can be deleted when the vao is released.
+func (gc *opengl) track(vao, buffer uint32) {
+	for _, b := range gc.buffers[vao] {
+		if b == buffer {
+			return
+		}
+	}
+	gc.buffers[vao] = append(gc.buffers[vao], buffer)
+}
+
 // bindVertexBuffer copies per-vertex data from the CPU to the GPU.
 // The vao is needed only for instanced meshes.
 func (gc *opengl) bindVertexBuffer(vdata Data) {
@@ -332,6 +363,14 @@ func (gc *opengl) bindFaceBuffer(fdata Data) {
 		bytes := 2 // 2 bytes for uint16 (gl.UNSIGNED_SHORT)
 		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int64(len(fd.data)*bytes), gl.Pointer(&(fd.data[0])), fd.usage)
 	}
+	if len(fd.wide) > 0 {
+		if fd.ref == 0 {
+			gl.GenBuffers(1, &fd.ref)
+		}
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, fd.ref)
+		bytes := 4 // 4 bytes for uint32 (gl.UNSIGNED_INT)
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int64(len(fd.wide)*bytes), gl.Pointer(&(fd.wide[0])), fd.usage)
+	}
 }
 
 // Renderer implementation.
@@ -365,7 +404,7 @@ func (gc *opengl) BindShader(vsh, fsh []string, uniforms map[string]int32,
 
 // Renderer implementation.
 // BindTexture makes the texture available on the GPU.
-func (gc *opengl) BindTexture(tid *uint32, img image.Image) (err error) {
+func (gc *opengl) BindTexture(tid *uint32, img image.Image, mips ...image.Image) (err error) {
 	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
 		log.Printf("opengl:bindTexture find and fix prior error %X", glerr)
 	}
@@ -374,45 +413,108 @@ func (gc *opengl) BindTexture(tid *uint32, img image.Image) (err error) {
 	}
 	gl.BindTexture(gl.TEXTURE_2D, *tid)
 
+	// Upload the precomputed mipmaps if there are any,
+	// otherwise have the graphics card generate them.
+	for level, mip := range append([]image.Image{img}, mips...) {
+		bounds := mip.Bounds()
+		width, height := int32(bounds.Dx()), int32(bounds.Dy())
+		gl.TexImage2D(gl.TEXTURE_2D, int32(level), gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, texPixels(mip))
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_BASE_LEVEL, 0)
+	if len(mips) > 0 {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(len(mips)))
+	} else {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, 1000) // GL default.
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	gc.SetTextureMode(*tid, false, Trilinear) // repeat by default.
+	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
+		err = fmt.Errorf("Failed binding texture %d\n", glerr)
+	}
+	return err
+}
+
+// texPixels returns a pointer to the image pixels in RGBA order.
+// Images in other formats, ie: JPEG YCbCr, are converted.
+func texPixels(img image.Image) gl.Pointer {
 	// FUTURE: check if RGBA, or NRGBA are alpha pre-multiplied. The docs say
 	// yes for RGBA but the data is from PNG files which are not pre-multiplied
 	// and the go png Decode looks like its reading values directly.
-	var ptr gl.Pointer
-	bounds := img.Bounds()
-	width, height := int32(bounds.Dx()), int32(bounds.Dy())
-	switch imgType := img.(type) {
+	switch i := img.(type) {
 	case *image.RGBA:
-		i := img.(*image.RGBA)
-		ptr = gl.Pointer(&(i.Pix[0]))
+		return gl.Pointer(&(i.Pix[0]))
 	case *image.NRGBA:
-		i := img.(*image.NRGBA)
-		ptr = gl.Pointer(&(i.Pix[0]))
-	default:
-		return fmt.Errorf("Unsupported image format %T", imgType)
-	}
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, ptr)
-	gl.GenerateMipmap(gl.TEXTURE_2D)
-	gc.SetTextureMode(*tid, false) // no repeat by default.
+		return gl.Pointer(&(i.Pix[0]))
+	}
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	return gl.Pointer(&(nrgba.Pix[0]))
+}
+
+// SetTextureMode is used to switch between a clamped or repeating
+// texture and to pick how the texture is filtered.
+// Cube maps are always clamped.
+func (gc *opengl) SetTextureMode(tid uint32, clamp bool, filter int) {
+	target := gc.texTarget(tid)
+	gl.BindTexture(target, tid)
+	if clamp || target == gl.TEXTURE_CUBE_MAP {
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	} else {
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.REPEAT)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	}
+	mag, min := int32(gl.LINEAR), int32(gl.LINEAR_MIPMAP_LINEAR)
+	switch filter {
+	case Bilinear:
+		min = gl.LINEAR_MIPMAP_NEAREST
+	case Linear:
+		min = gl.LINEAR
+	case Nearest:
+		mag, min = gl.NEAREST, gl.NEAREST
+	}
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, mag)
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, min)
+}
+
+// BindCubemap uploads the six cube map faces and generates mipmaps.
+// Seamless filtering is enabled so that edges between faces are
+// not visible.
+func (gc *opengl) BindCubemap(tid *uint32, faces []image.Image) (err error) {
+	if len(faces) != 6 {
+		return fmt.Errorf("BindCubemap expected 6 faces, got %d", len(faces))
+	}
 	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
-		err = fmt.Errorf("Failed binding texture %d\n", glerr)
+		log.Printf("opengl:bindCubemap find and fix prior error %X", glerr)
+	}
+	if *tid == 0 {
+		gl.GenTextures(1, tid)
+	}
+	gc.cubes[*tid] = true
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, *tid)
+	for cnt, face := range faces {
+		bounds := face.Bounds()
+		width, height := int32(bounds.Dx()), int32(bounds.Dy())
+		gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(cnt), 0, gl.RGBA,
+			width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, texPixels(face))
+	}
+	gl.GenerateMipmap(gl.TEXTURE_CUBE_MAP)
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+	gc.SetTextureMode(*tid, true, Trilinear)
+	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
+		err = fmt.Errorf("Failed binding cube map %d\n", glerr)
 	}
 	return err
 }
 
-// SetTextureMode is used to switch to a clamped
-// texture instead of a repeating texture.
-func (gc *opengl) SetTextureMode(tid uint32, clamp bool) {
-	gl.BindTexture(gl.TEXTURE_2D, tid)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, 7)
-	if clamp {
-		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
-	} else {
-		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
-		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+// texTarget returns the texture type for the given texture.
+func (gc *opengl) texTarget(tid uint32) uint32 {
+	if gc.cubes[tid] {
+		return gl.TEXTURE_CUBE_MAP
 	}
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST_MIPMAP_LINEAR)
+	return gl.TEXTURE_2D
 }
 
 // BindTarget creates a framebuffer object that can be used as render
@@ -430,15 +532,41 @@ func (gc *opengl) BindTarget(fbo, tid, db *uint32) (err error) {
 		0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Pointer(nil))
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	return gc.bindDepth(*tid, db, size, size)
+}
+
+// BindPostTarget creates a framebuffer object for post processing.
+// The color is stored as half floats that are smoothed and clamped
+// when sampled by the next pass.
+func (gc *opengl) BindPostTarget(fbo, tid, db *uint32, w, h int) (err error) {
+	width, height := int32(w), int32(h)
+	gl.GenFramebuffers(1, fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, *fbo)
 
+	// Create a texture specifically for the framebuffer.
+	gl.GenTextures(1, tid)
+	gl.BindTexture(gl.TEXTURE_2D, *tid)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, width, height,
+		0, gl.RGBA, gl.HALF_FLOAT, gl.Pointer(nil))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gc.posts[*fbo] = [2]int32{width, height}
+	return gc.bindDepth(*tid, db, width, height)
+}
+
+// bindDepth completes a BindTarget or BindPostTarget framebuffer by
+// adding a depth buffer and attaching the framebuffer texture.
+func (gc *opengl) bindDepth(tid uint32, db *uint32, w, h int32) (err error) {
 	// Add a depth buffer to mimic the normal framebuffer behaviour for 3D objects.
 	gl.GenRenderbuffers(1, db)
 	gl.BindRenderbuffer(gl.RENDERBUFFER, *db)
-	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT, size, size)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT, w, h)
 	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, *db)
 
 	// Associate the texture with the framebuffer.
-	gl.FramebufferTexture(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, *tid, 0)
+	gl.FramebufferTexture(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, tid, 0)
 	buffType := uint32(gl.COLOR_ATTACHMENT0)
 	gl.DrawBuffers(1, &buffType)
 
@@ -491,10 +619,21 @@ func (gc *opengl) BindMap(fbo, tid *uint32) (err error) {
 }
 
 // Remove graphic resources.
-func (gc *opengl) ReleaseMesh(vao uint32)    { gl.DeleteVertexArrays(1, &vao) }
-func (gc *opengl) ReleaseShader(sid uint32)  { gl.DeleteProgram(sid) }
-func (gc *opengl) ReleaseTexture(tid uint32) { gl.DeleteTextures(1, &tid) }
+func (gc *opengl) ReleaseMesh(vao uint32) {
+	if buffers := gc.buffers[vao]; len(buffers) > 0 {
+		gl.DeleteBuffers(int32(len(buffers)), &buffers[0])
+	}
+	delete(gc.buffers, vao)
+	delete(gc.wide, vao)
+	gl.DeleteVertexArrays(1, &vao)
+}
+func (gc *opengl) ReleaseShader(sid uint32) { gl.DeleteProgram(sid) }
+func (gc *opengl) ReleaseTexture(tid uint32) {
+	delete(gc.cubes, tid)
+	gl.DeleteTextures(1, &tid)
+}
 func (gc *opengl) ReleaseTarget(fbo, tid, db uint32) {
+	delete(gc.posts, fbo)
 	gl.DeleteFramebuffers(1, &fbo)
 	gl.DeleteTextures(1, &tid)
 	gl.DeleteRenderbuffers(1, &db)
@@ -0,0 +1,265 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package render
+
+// recorder.go logs the calls made to a render Context.
+
+import (
+	"encoding/json"
+	"image"
+)
+
+// Recorder is a render Context that logs every call before passing
+// it on to the wrapped Context. The log is intended for tests that
+// check what was drawn and in what order, ie: that shadow passes are
+// drawn before the scene, or that a UI overlay is drawn after the 3D
+// scene. Each Clear starts a new frame. The log is JSON serializable.
+//
+// Recorder is not safe for concurrent use. The engine only calls the
+// render Context from the main thread.
+type Recorder struct {
+	gc    Context // Wrapped render context.
+	Calls []Call  `json:"calls"` // Recorded calls in call order.
+	Frame int     `json:"frame"` // Number of Clear calls so far.
+}
+
+// NewRecorder returns a Recorder that passes calls on to the given
+// render Context, ie: NewRecorder(NewSoftware()).
+func NewRecorder(gc Context) *Recorder { return &Recorder{gc: gc} }
+
+// Call is one recorded render Context call.
+type Call struct {
+	Op     string    `json:"op"`               // Context method name.
+	Frame  int       `json:"frame"`            // Frame, ie: Clear count, of the call.
+	Name   string    `json:"name,omitempty"`   // Named shader.
+	Draw   *Drawn    `json:"draw,omitempty"`   // Render call state.
+	Refs   []int     `json:"refs,omitempty"`   // Bind references or other values.
+	Attrs  []int     `json:"attrs,omitempty"`  // Enable, Viewport, and mode values.
+	Values []float32 `json:"values,omitempty"` // Color values.
+	Err    string    `json:"err,omitempty"`    // Error returned by the call.
+}
+
+// Drawn is the state of a Render call. Uniform values are keyed by
+// the uniform name. Textures are keyed by texture order.
+type Drawn struct {
+	Tag       uint32               `json:"tag"`
+	Shader    uint32               `json:"shader"`
+	Vao       uint32               `json:"vao"`
+	Mode      int                  `json:"mode"`
+	Bucket    uint64               `json:"bucket"`
+	Depth     bool                 `json:"depth"`
+	Scissor   []int32              `json:"scissor,omitempty"`
+	Fbo       uint32               `json:"fbo"`
+	FaceCnt   int32                `json:"faces"`
+	VertCnt   int32                `json:"verts"`
+	Instances int32                `json:"instances,omitempty"`
+	Textures  map[int]uint32       `json:"textures,omitempty"`
+	Shadowmap uint32               `json:"shadowmap,omitempty"`
+	Uniforms  map[string][]float32 `json:"uniforms,omitempty"`
+	NumPoses  int                  `json:"poses,omitempty"`
+}
+
+// Draws returns the Render calls for the given frame in draw order.
+func (r *Recorder) Draws(frame int) []*Drawn {
+	draws := []*Drawn{}
+	for _, c := range r.Calls {
+		if c.Frame == frame && c.Draw != nil {
+			draws = append(draws, c.Draw)
+		}
+	}
+	return draws
+}
+
+// Reset discards the recorded calls. The frame count is kept.
+func (r *Recorder) Reset() { r.Calls = r.Calls[:0] }
+
+// JSON returns the recorded calls as JSON.
+func (r *Recorder) JSON() ([]byte, error) { return json.Marshal(r) }
+
+// record adds a call to the log.
+func (r *Recorder) record(op string, err error, refs ...uint32) *Call {
+	c := Call{Op: op, Frame: r.Frame}
+	for _, ref := range refs {
+		c.Refs = append(c.Refs, int(ref))
+	}
+	if err != nil {
+		c.Err = err.Error()
+	}
+	r.Calls = append(r.Calls, c)
+	return &r.Calls[len(r.Calls)-1]
+}
+
+// Init implements Context.
+func (r *Recorder) Init() error {
+	err := r.gc.Init()
+	r.record("Init", err)
+	return err
+}
+
+// Clear implements Context. It starts a new frame.
+func (r *Recorder) Clear() {
+	r.Frame++
+	r.record("Clear", nil)
+	r.gc.Clear()
+}
+
+// Color implements Context.
+func (r *Recorder) Color(red, g, b, a float32) {
+	r.record("Color", nil).Values = []float32{red, g, b, a}
+	r.gc.Color(red, g, b, a)
+}
+
+// Enable implements Context.
+func (r *Recorder) Enable(attr uint32, enable bool) {
+	on := 0
+	if enable {
+		on = 1
+	}
+	r.record("Enable", nil).Attrs = []int{int(attr), on}
+	r.gc.Enable(attr, enable)
+}
+
+// Viewport implements Context.
+func (r *Recorder) Viewport(width int, height int) {
+	r.record("Viewport", nil).Attrs = []int{width, height}
+	r.gc.Viewport(width, height)
+}
+
+// BindMesh implements Context.
+func (r *Recorder) BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error {
+	err := r.gc.BindMesh(vao, vdata, fdata)
+	r.record("BindMesh", err, *vao)
+	return err
+}
+
+// BindShader implements Context.
+func (r *Recorder) BindShader(vsh, fsh []string, uniforms map[string]int32,
+	layouts map[string]uint32) (program uint32, err error) {
+	program, err = r.gc.BindShader(vsh, fsh, uniforms, layouts)
+	r.record("BindShader", err, program)
+	return program, err
+}
+
+// BindNamedShader implements NamedShaders. Shaders are bound by name
+// if the wrapped Context supports it, otherwise they are compiled.
+func (r *Recorder) BindNamedShader(name string, vsh, fsh []string,
+	uniforms map[string]int32, layouts map[string]uint32) (program uint32, err error) {
+	if ns, ok := r.gc.(NamedShaders); ok {
+		program, err = ns.BindNamedShader(name, vsh, fsh, uniforms, layouts)
+	} else {
+		program, err = r.gc.BindShader(vsh, fsh, uniforms, layouts)
+	}
+	r.record("BindShader", err, program).Name = name
+	return program, err
+}
+
+// BindTexture implements Context. The image size
+// and number of mipmaps are recorded.
+func (r *Recorder) BindTexture(tid *uint32, img image.Image, mips ...image.Image) error {
+	err := r.gc.BindTexture(tid, img, mips...)
+	r.record("BindTexture", err, *tid).Attrs = []int{img.Bounds().Dx(), img.Bounds().Dy(), len(mips)}
+	return err
+}
+
+// BindCubemap implements Context. The face size is recorded.
+func (r *Recorder) BindCubemap(tid *uint32, faces []image.Image) error {
+	err := r.gc.BindCubemap(tid, faces)
+	size := 0
+	if len(faces) > 0 {
+		size = faces[0].Bounds().Dx()
+	}
+	r.record("BindCubemap", err, *tid).Attrs = []int{size, len(faces)}
+	return err
+}
+
+// SetTextureMode implements Context. The clamp
+// is recorded as 1 or 0 followed by the filter.
+func (r *Recorder) SetTextureMode(tid uint32, clamp bool, filter int) {
+	mode := 0
+	if clamp {
+		mode = 1
+	}
+	r.record("SetTextureMode", nil, tid).Attrs = []int{mode, filter}
+	r.gc.SetTextureMode(tid, clamp, filter)
+}
+
+// Render implements Context. The draw call state is copied.
+func (r *Recorder) Render(d *Draw) {
+	dr := &Drawn{
+		Tag: d.Tag, Shader: d.Shader, Vao: d.Vao, Mode: d.Mode,
+		Bucket: d.Bucket, Depth: d.Depth, Fbo: d.Fbo,
+		FaceCnt: d.FaceCnt, VertCnt: d.VertCnt, Instances: d.Instances,
+		Shadowmap: d.Shtex, NumPoses: d.NumPoses,
+	}
+	if d.Scissor {
+		dr.Scissor = []int32{d.Sx, d.Sy, d.Sw, d.Sh}
+	}
+	if len(d.Texs) > 0 {
+		dr.Textures = map[int]uint32{}
+		for _, t := range d.Texs {
+			dr.Textures[t.order] = t.tid
+		}
+	}
+	if len(d.Uniforms) > 0 {
+		dr.Uniforms = map[string][]float32{}
+		for name, ref := range d.Uniforms {
+			if data, ok := d.UniformData[ref]; ok {
+				dr.Uniforms[name] = append([]float32{}, data...)
+			}
+		}
+	}
+	r.record("Render", nil).Draw = dr
+	r.gc.Render(d)
+}
+
+// BindMap implements Context.
+func (r *Recorder) BindMap(fbo, tid *uint32) error {
+	err := r.gc.BindMap(fbo, tid)
+	r.record("BindMap", err, *fbo, *tid)
+	return err
+}
+
+// BindTarget implements Context.
+func (r *Recorder) BindTarget(fbo, tid, db *uint32) error {
+	err := r.gc.BindTarget(fbo, tid, db)
+	r.record("BindTarget", err, *fbo, *tid, *db)
+	return err
+}
+
+// BindPostTarget implements Context.
+func (r *Recorder) BindPostTarget(fbo, tid, db *uint32, w, h int) error {
+	err := r.gc.BindPostTarget(fbo, tid, db, w, h)
+	r.record("BindPostTarget", err, *fbo, *tid, *db).Attrs = []int{w, h}
+	return err
+}
+
+// ReleaseMesh implements Context.
+func (r *Recorder) ReleaseMesh(vao uint32) {
+	r.record("ReleaseMesh", nil, vao)
+	r.gc.ReleaseMesh(vao)
+}
+
+// ReleaseShader implements Context.
+func (r *Recorder) ReleaseShader(sid uint32) {
+	r.record("ReleaseShader", nil, sid)
+	r.gc.ReleaseShader(sid)
+}
+
+// ReleaseTexture implements Context.
+func (r *Recorder) ReleaseTexture(tid uint32) {
+	r.record("ReleaseTexture", nil, tid)
+	r.gc.ReleaseTexture(tid)
+}
+
+// ReleaseMap implements Context.
+func (r *Recorder) ReleaseMap(fbo, tid uint32) {
+	r.record("ReleaseMap", nil, fbo, tid)
+	r.gc.ReleaseMap(fbo, tid)
+}
+
+// ReleaseTarget implements Context.
+func (r *Recorder) ReleaseTarget(fbo, tid, db uint32) {
+	r.record("ReleaseTarget", nil, fbo, tid, db)
+	r.gc.ReleaseTarget(fbo, tid, db)
+}
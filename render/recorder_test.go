@@ -0,0 +1,134 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package render
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Check that a known call sequence is recorded in order
+// and split into frames by Clear.
+func TestRecorderCalls(t *testing.T) {
+	r := recordFrames()
+	ops := []string{}
+	frames := []int{}
+	for _, c := range r.Calls {
+		ops = append(ops, c.Op)
+		frames = append(frames, c.Frame)
+	}
+	wantOps := []string{"Init", "Viewport", "Clear", "Color", "Enable", "Render",
+		"Clear", "SetTextureMode", "Render", "Render"}
+	if !reflect.DeepEqual(ops, wantOps) {
+		t.Errorf("Expected ops %v, got %v", wantOps, ops)
+	}
+	if want := []int{0, 0, 1, 1, 1, 1, 2, 2, 2, 2}; !reflect.DeepEqual(frames, want) {
+		t.Errorf("Expected frames %v, got %v", want, frames)
+	}
+	if r.Frame != 2 {
+		t.Errorf("Expected frame 2, got %d", r.Frame)
+	}
+	if c := r.Calls[1]; !reflect.DeepEqual(c.Attrs, []int{4, 3}) {
+		t.Errorf("Expected viewport attrs [4 3], got %v", c.Attrs)
+	}
+	if c := r.Calls[3]; !reflect.DeepEqual(c.Values, []float32{0.1, 0.2, 0.3, 1}) {
+		t.Errorf("Expected color values, got %v", c.Values)
+	}
+	if c := r.Calls[4]; !reflect.DeepEqual(c.Attrs, []int{int(Blend), 1}) {
+		t.Errorf("Expected enable attrs, got %v", c.Attrs)
+	}
+	if c := r.Calls[7]; !reflect.DeepEqual(c.Refs, []int{7}) || !reflect.DeepEqual(c.Attrs, []int{1, Nearest}) {
+		t.Errorf("Expected texture mode refs [7] and attrs [1 %d], got %v %v", Nearest, c.Refs, c.Attrs)
+	}
+
+	// draws are split by frame and kept in draw order.
+	if draws := r.Draws(0); len(draws) != 0 {
+		t.Errorf("Expected no draws before the first Clear, got %d", len(draws))
+	}
+	if draws := r.Draws(1); len(draws) != 1 || draws[0].Tag != 1 {
+		t.Errorf("Expected one frame 1 draw with tag 1, got %v", draws)
+	}
+	draws := r.Draws(2)
+	if len(draws) != 2 || draws[0].Tag != 2 || draws[1].Tag != 3 {
+		t.Fatalf("Expected frame 2 draws with tags 2 and 3, got %v", draws)
+	}
+	if !reflect.DeepEqual(draws[1].Scissor, []int32{1, 2, 3, 4}) || draws[1].Mode != Lines {
+		t.Errorf("Expected scissored lines, got %v %d", draws[1].Scissor, draws[1].Mode)
+	}
+
+	// reset keeps the frame count.
+	r.Reset()
+	if len(r.Calls) != 0 || r.Frame != 2 {
+		t.Errorf("Expected no calls at frame 2, got %d at %d", len(r.Calls), r.Frame)
+	}
+}
+
+// Check the JSON shape of the recorded calls.
+func TestRecorderJSON(t *testing.T) {
+	r := recordFrames()
+	data, err := r.JSON()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	log := map[string]interface{}{}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(log) != 2 || log["frame"] != float64(2) {
+		t.Errorf("Expected calls and frame 2, got %v", log)
+	}
+	calls, ok := log["calls"].([]interface{})
+	if !ok || len(calls) != len(r.Calls) {
+		t.Fatalf("Expected %d calls, got %v", len(r.Calls), log["calls"])
+	}
+
+	// unused fields are omitted.
+	init := calls[0].(map[string]interface{})
+	if len(init) != 2 || init["op"] != "Init" || init["frame"] != float64(0) {
+		t.Errorf("Expected only op and frame, got %v", init)
+	}
+	color := calls[3].(map[string]interface{})
+	if values := color["values"].([]interface{}); len(values) != 4 {
+		t.Errorf("Expected 4 color values, got %v", values)
+	}
+	render := calls[9].(map[string]interface{})
+	draw, ok := render["draw"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected draw object, got %v", render)
+	}
+	for _, key := range []string{"tag", "shader", "vao", "mode", "bucket", "depth", "fbo", "faces", "verts", "scissor"} {
+		if _, ok := draw[key]; !ok {
+			t.Errorf("Expected draw key %s in %v", key, draw)
+		}
+	}
+	if draw["tag"] != float64(3) || draw["faces"] != float64(6) {
+		t.Errorf("Expected tag 3 with 6 faces, got %v", draw)
+	}
+}
+
+// recordFrames records a known call sequence over two frames.
+func recordFrames() *Recorder {
+	r := NewRecorder(&NoRender{})
+	r.Init()
+	r.Viewport(4, 3)
+	r.Clear()
+	r.Color(0.1, 0.2, 0.3, 1)
+	r.Enable(Blend, true)
+	d := NewDraw()
+	d.Tag = 1
+	r.Render(d)
+	r.Clear()
+	r.SetTextureMode(7, true, Nearest)
+	d = NewDraw()
+	d.Tag = 2
+	r.Render(d)
+	d = NewDraw()
+	d.Tag = 3
+	d.SetRefs(5, 6, Lines)
+	d.SetCounts(6, 4)
+	d.Scissor, d.Sx, d.Sy, d.Sw, d.Sh = true, 1, 2, 3, 4
+	r.Render(d)
+	return r
+}
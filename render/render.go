@@ -34,8 +34,14 @@ type Context interface {
 	BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error
 	BindShader(vsh, fsh []string, uniforms map[string]int32,
 		layouts map[string]uint32) (program uint32, err error)
-	BindTexture(tid *uint32, img image.Image) error
-	SetTextureMode(tid uint32, clamp bool)
+	BindTexture(tid *uint32, img image.Image, mips ...image.Image) error
+	SetTextureMode(tid uint32, clamp bool, filter int)
+
+	// BindCubemap binds six square images as one cube map texture.
+	// The faces are ordered +X, -X, +Y, -Y, +Z, -Z. Cube maps are
+	// sampled in shaders using a samplerCube texture uniform.
+	//   tid   : returned texture identifier.
+	BindCubemap(tid *uint32, faces []image.Image) error
 	Render(d *Draw) // Render bound data, textures with bound shaders.
 
 	// BindMap creates a framebuffer object with an associated
@@ -51,6 +57,17 @@ type Context interface {
 	//   db  : returned depth buffer render buffer.
 	BindTarget(fbo, tid, db *uint32) error
 
+	// BindPostTarget creates a framebuffer object that can be used
+	// as a full screen post processing target. The color is stored
+	// as half floats so that passes can use values over 1, and the
+	// color and depth are cleared each time the target is drawn to.
+	// Release the target using ReleaseTarget.
+	//   fbo  : returned frame buffer object identifier.
+	//   tid  : returned texture identifier.
+	//   db   : returned depth buffer render buffer.
+	//   w, h : target size, normally the viewport size.
+	BindPostTarget(fbo, tid, db *uint32, w, h int) error
+
 	// Releasing frees up previous bound graphics card data.
 	ReleaseMesh(vao uint32)            // Free bound vao reference.
 	ReleaseShader(sid uint32)          // Free bound shader reference.
@@ -59,6 +76,14 @@ type Context interface {
 	ReleaseTarget(fbo, tid, db uint32) // Free render target framebuffer.
 }
 
+// NamedShaders is implemented by render Contexts that provide their
+// own shader implementations instead of compiling shader source.
+// The engine binds shaders using BindNamedShader when available.
+type NamedShaders interface {
+	BindNamedShader(name string, vsh, fsh []string, uniforms map[string]int32,
+		layouts map[string]uint32) (program uint32, err error)
+}
+
 // New provides the render implementation as determined by the build.
 func New() Context { return newRenderer() }
 
@@ -74,3 +99,12 @@ const (
 	ImageBuffer        // For color and depth.
 	LayerSize   = 1024 // Render pass texture size.
 )
+
+// Texture filtering modes used in SetTextureMode. Mipmaps are
+// generated when BindTexture is not given precomputed mipmaps.
+const (
+	Trilinear = iota // Default. Blend between the nearest mipmaps.
+	Bilinear         // Linear filtering on the nearest mipmap.
+	Linear           // Linear filtering without mipmaps.
+	Nearest          // Nearest pixel without mipmaps, ie: pixel art.
+)
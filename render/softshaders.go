@@ -0,0 +1,115 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package render
+
+// softshaders.go are Go versions of commonly used shaders. Each matches
+// the GLSL shader of the same name, ie: the vu shader library and the
+// examples in vu/eg/source.
+
+// softShaders are the initial Software shaders.
+var softShaders = map[string]*Shader{
+	"colored":  coloredSoftShader,
+	"textured": texturedSoftShader,
+	"tuv":      tuvSoftShader,
+	"vcolor":   vcolorSoftShader,
+	"nshade":   nshadeSoftShader,
+}
+
+// pvmTransform is gl_Position = pm * vm * mm * vec4(in_v, 1.0).
+func pvmTransform(u *Uniforms, in [][]float32) [4]float32 {
+	pos := u.in(in, 0, 3)
+	v := TransformV4(u.Get("mm"), pos[0], pos[1], pos[2], 1)
+	v = TransformV4(u.Get("vm"), v[0], v[1], v[2], v[3])
+	return TransformV4(u.Get("pm"), v[0], v[1], v[2], v[3])
+}
+
+// mvpTransform is gl_Position = mvpm * vec4(in_v, 1.0).
+func mvpTransform(u *Uniforms, in [][]float32) [4]float32 {
+	pos := u.in(in, 0, 3)
+	return TransformV4(u.Get("mvpm"), pos[0], pos[1], pos[2], 1)
+}
+
+// in returns vertex data from the given layout location padded
+// with zeros to the given size.
+func (u *Uniforms) in(in [][]float32, lloc, size int) []float32 {
+	if lloc < len(in) && len(in[lloc]) >= size {
+		return in[lloc]
+	}
+	pad := make([]float32, size)
+	if lloc < len(in) {
+		copy(pad, in[lloc])
+	}
+	return pad
+}
+
+// uniform returns uniform data padded with zeros to the given size.
+func (u *Uniforms) uniform(name string, size int) []float32 {
+	if data := u.Get(name); len(data) >= size {
+		return data
+	}
+	return make([]float32, size)
+}
+
+// coloredSoftShader shades all verticies the given color and alpha value.
+var coloredSoftShader = &Shader{
+	Vertex: func(u *Uniforms, in [][]float32, v *Vertex) { v.Pos = pvmTransform(u, in) },
+	Fragment: func(u *Uniforms, in []float32) [4]float32 {
+		kd, alpha := u.uniform("kd", 3), u.uniform("alpha", 1)
+		return [4]float32{kd[0], kd[1], kd[2], alpha[0]}
+	},
+}
+
+// texturedSoftShader combines the texture alpha with the alpha value.
+var texturedSoftShader = &Shader{
+	Outs: 2,
+	Vertex: func(u *Uniforms, in [][]float32, v *Vertex) {
+		copy(v.Out, u.in(in, 2, 2))
+		v.Pos = pvmTransform(u, in)
+	},
+	Fragment: func(u *Uniforms, in []float32) [4]float32 {
+		c := u.Sample("uv", in[0], in[1])
+		c[3] *= u.uniform("alpha", 1)[0]
+		return c
+	},
+}
+
+// tuvSoftShader is a texture with a combined model-view-projection matrix.
+var tuvSoftShader = &Shader{
+	Outs: 2,
+	Vertex: func(u *Uniforms, in [][]float32, v *Vertex) {
+		copy(v.Out, u.in(in, 2, 2))
+		v.Pos = mvpTransform(u, in)
+	},
+	Fragment: func(u *Uniforms, in []float32) [4]float32 {
+		return u.Sample("uv", in[0], in[1])
+	},
+}
+
+// vcolorSoftShader uses colors assigned to each vertex.
+var vcolorSoftShader = &Shader{
+	Outs: 4,
+	Vertex: func(u *Uniforms, in [][]float32, v *Vertex) {
+		c := u.in(in, 3, 3)
+		v.Out[3] = 1 // alpha defaults to 1 like vec4 attributes.
+		copy(v.Out, c)
+		v.Pos = mvpTransform(u, in)
+	},
+	Fragment: func(u *Uniforms, in []float32) [4]float32 {
+		return [4]float32{in[0], in[1], in[2], in[3]}
+	},
+}
+
+// nshadeSoftShader shades based on the direction of the vertex normal.
+var nshadeSoftShader = &Shader{
+	Outs: 3,
+	Vertex: func(u *Uniforms, in [][]float32, v *Vertex) {
+		n := u.in(in, 1, 3)
+		shade := n[0] + n[1] + n[2]
+		v.Out[0], v.Out[1], v.Out[2] = 0.7*shade, 0.6*shade, 0.4*shade
+		v.Pos = pvmTransform(u, in)
+	},
+	Fragment: func(u *Uniforms, in []float32) [4]float32 {
+		return [4]float32{in[0], in[1], in[2], 1}
+	},
+}
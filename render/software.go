@@ -0,0 +1,821 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package render
+
+// software.go rasterizes draw calls into memory without a GPU.
+// FUTURE: top-left fill rule, mipmaps, texture filtering, and clipping
+//         against planes other than the near plane.
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"log"
+	"math"
+)
+
+// Software is a render Context that draws into an in-memory image
+// instead of using a graphics card. It is intended for machines without
+// a GPU, ie: golden image tests on build machines. Software handles
+// triangles, points, and lines with depth testing, alpha blending,
+// backface culling, scissoring, instancing, textures, and render targets.
+//
+// Software does not compile shader source. Instead shaders are Go
+// functions that are registered with SetShader using the same name
+// that is used to load the shader, ie: the name given to Ent.MakeModel.
+// Software starts with Go versions of the colored, textured, tuv,
+// vcolor, and nshade shaders.
+type Software struct {
+	clear [4]float32 // Clear color.
+	blend bool       // True when alpha blending.
+	cull  bool       // True when culling backfaces.
+	fbo   uint32     // Current framebuffer. 0 is the viewport image.
+	refs  uint32     // Last generated bind reference.
+
+	// Bound data is copied, mimicking data copied to a GPU.
+	shaders  map[string]*Shader    // Go shaders by name.
+	programs map[uint32]*swProgram // Bound shaders.
+	meshes   map[uint32]*swMesh    // Bound vertex data.
+	textures map[uint32]*swTexture // Bound textures.
+	targets  map[uint32]*swTarget  // Framebuffers. 0 is the viewport.
+	buffers  map[uint32][]uint32   // Vertex and face buffers for each vao.
+
+	// Scratch space reused for each draw call.
+	verts []Vertex    // Shaded verticies.
+	outs  []float32   // Memory for shaded vertex outputs.
+	frag  []float32   // Interpolated fragment shader input.
+	in    [][]float32 // Vertex shader input by layout location.
+}
+
+// NewSoftware returns a software render Context that has been
+// initialized with the default shaders. The image is allocated
+// when the Viewport size is set.
+func NewSoftware() *Software {
+	sw := &Software{}
+	sw.shaders = map[string]*Shader{}
+	for name, sh := range softShaders {
+		sw.shaders[name] = sh
+	}
+	sw.programs = map[uint32]*swProgram{}
+	sw.meshes = map[uint32]*swMesh{}
+	sw.textures = map[uint32]*swTexture{}
+	sw.targets = map[uint32]*swTarget{0: newTarget(0, 0, true)}
+	sw.buffers = map[uint32][]uint32{}
+	return sw
+}
+
+// SetShader registers a Go shader under the given shader name.
+// Shaders must be registered before they are bound.
+func (sw *Software) SetShader(name string, sh *Shader) { sw.shaders[name] = sh }
+
+// Image returns the viewport image. The image is updated as
+// draw calls are rendered.
+func (sw *Software) Image() *image.RGBA { return sw.targets[0].color }
+
+// Init implements Context.
+func (sw *Software) Init() error { return nil }
+
+// Color implements Context.
+func (sw *Software) Color(r, g, b, a float32) { sw.clear = [4]float32{r, g, b, a} }
+
+// Clear implements Context. It clears the current framebuffer.
+func (sw *Software) Clear() {
+	t, ok := sw.targets[sw.fbo]
+	if !ok {
+		return
+	}
+	if t.color != nil {
+		c := [4]uint8{}
+		for cnt, v := range sw.clear {
+			c[cnt] = toByte(v)
+		}
+		for i := 0; i < len(t.color.Pix); i += 4 {
+			copy(t.color.Pix[i:i+4], c[:])
+		}
+	}
+	t.clearDepth()
+}
+
+// Enable implements Context.
+func (sw *Software) Enable(attr uint32, enable bool) {
+	switch attr {
+	case Blend:
+		sw.blend = enable
+	case CullFace:
+		sw.cull = enable
+	}
+}
+
+// Viewport implements Context. It resizes the viewport image.
+func (sw *Software) Viewport(width int, height int) {
+	if t := sw.targets[0]; t.w != width || t.h != height {
+		sw.targets[0] = newTarget(width, height, true)
+	}
+}
+
+// BindMesh implements Context. Changed vertex data is copied.
+func (sw *Software) BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error {
+	sw.setRef(vao)
+	m, ok := sw.meshes[*vao]
+	if !ok {
+		m = &swMesh{vdata: map[uint32]*vertexData{}}
+		sw.meshes[*vao] = m
+	}
+	for _, vbuff := range vdata {
+		if vd, ok := vbuff.(*vertexData); ok && vd.rebind {
+			sw.track(*vao, &vd.ref)
+			m.vdata[vd.lloc] = vd.Clone().(*vertexData)
+			vd.rebind = false
+		}
+	}
+	if fd, ok := fdata.(*faceData); ok && fd.rebind {
+		sw.track(*vao, &fd.ref)
+		m.faces = m.faces[:0]
+		for cnt := 0; cnt < fd.Len(); cnt++ {
+			m.faces = append(m.faces, fd.index(cnt))
+		}
+		fd.rebind = false
+	}
+	return nil
+}
+
+// BindShader implements Context. Software can't compile shader source
+// so shaders must be bound by name using BindNamedShader.
+func (sw *Software) BindShader(vsh, fsh []string, uniforms map[string]int32,
+	layouts map[string]uint32) (program uint32, err error) {
+	return 0, fmt.Errorf("BindShader: software shaders are bound by name")
+}
+
+// BindNamedShader implements NamedShaders. The uniforms and layouts
+// are discovered from the shader source, while the shader itself
+// must have been registered using SetShader.
+func (sw *Software) BindNamedShader(name string, vsh, fsh []string,
+	uniforms map[string]int32, layouts map[string]uint32) (program uint32, err error) {
+	sh, ok := sw.shaders[name]
+	if !ok {
+		return 0, fmt.Errorf("BindNamedShader: no software shader %s", name)
+	}
+	sw.setRef(&program)
+	shaderRefs(vsh, fsh, uniforms, layouts)
+	sw.programs[program] = &swProgram{name: name, sh: sh}
+	return program, nil
+}
+
+// BindTexture implements Context. The image is copied. Mipmaps are
+// ignored since textures are sampled using the nearest pixel.
+func (sw *Software) BindTexture(tid *uint32, img image.Image, mips ...image.Image) error {
+	sw.setRef(tid)
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	sw.textures[*tid] = &swTexture{img: rgba}
+	return nil
+}
+
+// BindCubemap implements Context. Cube maps are not sampled
+// by the software shaders so the faces are not kept.
+func (sw *Software) BindCubemap(tid *uint32, faces []image.Image) error {
+	sw.setRef(tid)
+	sw.textures[*tid] = &swTexture{}
+	return nil
+}
+
+// SetTextureMode implements Context. The filter is ignored.
+func (sw *Software) SetTextureMode(tid uint32, clamp bool, filter int) {
+	if t, ok := sw.textures[tid]; ok {
+		t.clamp = clamp
+	}
+}
+
+// BindMap implements Context. The framebuffer has depth and no color.
+func (sw *Software) BindMap(fbo, tid *uint32) error {
+	sw.setRef(fbo)
+	sw.setRef(tid)
+	t := newTarget(LayerSize, LayerSize, false)
+	sw.targets[*fbo] = t
+	sw.textures[*tid] = &swTexture{depth: t, clamp: true}
+	return nil
+}
+
+// BindTarget implements Context. The framebuffer color is the texture.
+func (sw *Software) BindTarget(fbo, tid, db *uint32) error {
+	sw.setRef(fbo)
+	sw.setRef(tid)
+	sw.setRef(db)
+	t := newTarget(LayerSize, LayerSize, true)
+	sw.targets[*fbo] = t
+	sw.textures[*tid] = &swTexture{img: t.color}
+	return nil
+}
+
+// BindPostTarget implements Context. The framebuffer color is the
+// texture and is cleared each time the framebuffer is drawn to.
+func (sw *Software) BindPostTarget(fbo, tid, db *uint32, w, h int) error {
+	sw.setRef(fbo)
+	sw.setRef(tid)
+	sw.setRef(db)
+	t := newTarget(w, h, true)
+	t.post = true
+	sw.targets[*fbo] = t
+	sw.textures[*tid] = &swTexture{img: t.color, clamp: true}
+	return nil
+}
+
+// ReleaseMesh implements Context.
+func (sw *Software) ReleaseMesh(vao uint32) {
+	delete(sw.meshes, vao)
+	delete(sw.buffers, vao)
+}
+
+// Buffers returns the number of vertex and face buffers that
+// have been bound and not released. Used to check for leaks.
+func (sw *Software) Buffers() (count int) {
+	for _, buffers := range sw.buffers {
+		count += len(buffers)
+	}
+	return count
+}
+
+// track generates a buffer reference, like a GPU would, and
+// remembers it so that it can be released along with the vao.
+func (sw *Software) track(vao uint32, buffer *uint32) {
+	if *buffer == 0 {
+		sw.setRef(buffer)
+		sw.buffers[vao] = append(sw.buffers[vao], *buffer)
+	}
+}
+
+// ReleaseShader implements Context.
+func (sw *Software) ReleaseShader(sid uint32) { delete(sw.programs, sid) }
+
+// ReleaseTexture implements Context.
+func (sw *Software) ReleaseTexture(tid uint32) { delete(sw.textures, tid) }
+
+// ReleaseMap implements Context.
+func (sw *Software) ReleaseMap(fbo, tid uint32) {
+	delete(sw.targets, fbo)
+	delete(sw.textures, tid)
+}
+
+// ReleaseTarget implements Context.
+func (sw *Software) ReleaseTarget(fbo, tid, db uint32) {
+	delete(sw.targets, fbo)
+	delete(sw.textures, tid)
+}
+
+// setRef assigns a new reference unless the reference is already set.
+func (sw *Software) setRef(ref *uint32) {
+	if *ref == 0 {
+		sw.refs++
+		*ref = sw.refs
+	}
+}
+
+// Render implements Context. It runs the Go shader for each vertex
+// and each covered pixel, writing to the current framebuffer.
+func (sw *Software) Render(d *Draw) {
+	if sw.fbo != d.Fbo {
+		sw.fbo = d.Fbo
+		if t, ok := sw.targets[d.Fbo]; ok && d.Fbo != 0 {
+			if t.post {
+				sw.Clear() // mimic the opengl renderer.
+			} else {
+				t.clearDepth()
+			}
+		}
+	}
+	t, tok := sw.targets[sw.fbo]
+	p, pok := sw.programs[d.Shader]
+	m, mok := sw.meshes[d.Vao]
+	if !tok || !pok || !mok {
+		log.Printf("Render: %d unbound shader %d, mesh %d, or framebuffer %d",
+			d.Tag, d.Shader, d.Vao, d.Fbo)
+		return
+	}
+
+	// Scissor coordinates start at the bottom left.
+	r := image.Rect(0, 0, t.w, t.h)
+	if d.Scissor {
+		x, y, w, h := int(d.Sx), int(d.Sy), int(d.Sw), int(d.Sh)
+		r = r.Intersect(image.Rect(x, t.h-y-h, x+w, t.h-y))
+	}
+	rs := &raster{sw: sw, t: t, d: d, sh: p.sh, clip: r}
+	rs.u = &Uniforms{d: d, sw: sw}
+	switch d.Mode {
+	case Triangles:
+		instances := 1
+		if d.Instances > 0 {
+			instances = int(d.Instances)
+		}
+		for inst := 0; inst < instances; inst++ {
+			sw.shade(p.sh, rs.u, m, inst)
+			faces := m.faces
+			if int(d.FaceCnt) < len(faces) {
+				faces = faces[:d.FaceCnt]
+			}
+			for f := 0; f+2 < len(faces); f += 3 {
+				rs.triangle(faces[f], faces[f+1], faces[f+2])
+			}
+		}
+	case Lines:
+		sw.shade(p.sh, rs.u, m, 0)
+		faces := m.faces
+		if int(d.FaceCnt) < len(faces) {
+			faces = faces[:d.FaceCnt]
+		}
+		for f := 0; f+1 < len(faces); f += 2 {
+			rs.line(faces[f], faces[f+1])
+		}
+	case Points:
+		sw.shade(p.sh, rs.u, m, 0)
+		for v := 0; v < len(sw.verts) && v < int(d.VertCnt); v++ {
+			rs.point(uint32(v))
+		}
+	}
+}
+
+// shade runs the vertex shader over all the mesh verticies.
+// Instanced data uses the matrix of the given instance.
+func (sw *Software) shade(sh *Shader, u *Uniforms, m *swMesh, inst int) {
+	vcnt, maxLoc := -1, uint32(0)
+	for lloc, vd := range m.vdata {
+		if vd.instanced {
+			lloc += 3 // matrix rows use 4 locations.
+		} else if vcnt < 0 || vd.vcnt < vcnt {
+			vcnt = vd.vcnt
+		}
+		if lloc > maxLoc {
+			maxLoc = lloc
+		}
+	}
+	if vcnt < 0 {
+		vcnt = 0
+	}
+	if cap(sw.in) < int(maxLoc)+1 {
+		sw.in = make([][]float32, maxLoc+1)
+	}
+	sw.in = sw.in[:maxLoc+1]
+	if cap(sw.verts) < vcnt {
+		sw.verts = make([]Vertex, vcnt)
+	}
+	sw.verts = sw.verts[:vcnt]
+	if cap(sw.outs) < vcnt*sh.Outs {
+		sw.outs = make([]float32, vcnt*sh.Outs)
+	}
+	sw.outs = sw.outs[:vcnt*sh.Outs]
+	for i := range sw.in {
+		sw.in[i] = nil
+	}
+	for lloc, vd := range m.vdata {
+		if vd.instanced && (inst+1)*16 <= len(vd.floats) {
+			for row := 0; row < 4; row++ {
+				start := inst*16 + row*4
+				sw.in[lloc+uint32(row)] = vd.floats[start : start+4]
+			}
+		}
+	}
+	for v := range sw.verts {
+		for lloc, vd := range m.vdata {
+			if !vd.instanced {
+				sw.in[lloc] = vd.vertex(v, sw.in[lloc])
+			}
+		}
+		vert := &sw.verts[v]
+		vert.Pos, vert.Size = [4]float32{0, 0, 0, 1}, 1
+		vert.Out = sw.outs[v*sh.Outs : (v+1)*sh.Outs]
+		sh.Vertex(u, sw.in, vert)
+	}
+}
+
+// Software
+// =============================================================================
+// Shader
+
+// Shader is a Go shader for the Software renderer. It mimics a GPU
+// shader program where Vertex is run once for each vertex and
+// Fragment is run once for each covered pixel.
+type Shader struct {
+	Outs int // Number of values the Vertex shader passes to Fragment.
+
+	// Vertex sets the clip space position and fragment shader inputs
+	// for one vertex. The vertex data is indexed by shader layout
+	// location, ie: in[0] is the vertex position. Data that is not
+	// present is nil.
+	Vertex func(u *Uniforms, in [][]float32, v *Vertex)
+
+	// Fragment returns the RGBA color, in the range 0-1, for one pixel.
+	// The input values are interpolated from the Vertex outputs.
+	Fragment func(u *Uniforms, in []float32) [4]float32
+}
+
+// Vertex is the output of a Vertex shader.
+type Vertex struct {
+	Pos  [4]float32 // Clip space position, ie: gl_Position.
+	Size float32    // Point size in pixels, ie: gl_PointSize.
+	Out  []float32  // Shader.Outs values for the fragment shader.
+}
+
+// Uniforms gives shaders access to the uniform and texture
+// data for the current draw call.
+type Uniforms struct {
+	d  *Draw     // Current draw call.
+	sw *Software // Bound textures.
+}
+
+// Get returns the named uniform data, or nil if there is no data.
+// Animation poses are named "bpos".
+func (u *Uniforms) Get(name string) []float32 {
+	if name == "bpos" {
+		return u.d.Poses
+	}
+	if ref, ok := u.d.Uniforms[name]; ok {
+		return u.d.UniformData[ref]
+	}
+	return nil
+}
+
+// Sample returns the RGBA texel from the named texture sampler at
+// the given texture coordinates. Texture samplers are named uv, uv0,
+// uv1, etc. The shadow map sampler is named sm and returns depth.
+// Sampling uses the nearest texel.
+func (u *Uniforms) Sample(name string, s, t float32) [4]float32 {
+	tid := u.d.Shtex
+	if name != "sm" {
+		index := 0
+		fmt.Sscanf(name, "uv%d", &index)
+		tid = 0
+		for _, t := range u.d.Texs {
+			if t.order == index {
+				tid = t.tid
+				break
+			}
+		}
+	}
+	if tex, ok := u.sw.textures[tid]; ok {
+		return tex.sample(s, t)
+	}
+	return [4]float32{0, 0, 0, 1}
+}
+
+// TransformV4 multiplies the vector by the 4x4 uniform matrix data
+// the same way a shader does, ie: mvpm * vec4(in_v, 1.0).
+func TransformV4(m []float32, x, y, z, w float32) [4]float32 {
+	if len(m) < 16 {
+		return [4]float32{x, y, z, w}
+	}
+	return [4]float32{
+		m[0]*x + m[4]*y + m[8]*z + m[12]*w,
+		m[1]*x + m[5]*y + m[9]*z + m[13]*w,
+		m[2]*x + m[6]*y + m[10]*z + m[14]*w,
+		m[3]*x + m[7]*y + m[11]*z + m[15]*w,
+	}
+}
+
+// Shader
+// =============================================================================
+// raster
+
+// raster draws shaded verticies for one draw call.
+type raster struct {
+	sw   *Software       // Shaded verticies and scratch space.
+	t    *swTarget       // Framebuffer being drawn.
+	d    *Draw           // Draw call being rendered.
+	sh   *Shader         // Draw call shader.
+	u    *Uniforms       // Draw call uniforms.
+	clip image.Rectangle // Drawable area in image coordinates.
+}
+
+// nearW is the smallest clip space w that is drawn.
+const nearW = 1e-5
+
+// screen is a vertex in image coordinates.
+type screen struct {
+	x, y, z float32 // Image position and 0-1 depth.
+	iw      float32 // 1/w for perspective correct interpolation.
+	out     []float32
+}
+
+// toScreen converts a clip space vertex to image coordinates.
+func (rs *raster) toScreen(v *Vertex) screen {
+	iw := 1 / v.Pos[3]
+	x, y, z := v.Pos[0]*iw, v.Pos[1]*iw, v.Pos[2]*iw
+	return screen{
+		x:   (x + 1) * 0.5 * float32(rs.t.w),
+		y:   (1 - y) * 0.5 * float32(rs.t.h), // image y is down.
+		z:   (z + 1) * 0.5,
+		iw:  iw,
+		out: v.Out,
+	}
+}
+
+// triangle clips a triangle to the near plane and draws it.
+func (rs *raster) triangle(i0, i1, i2 uint32) {
+	verts := rs.sw.verts
+	if int(i0) >= len(verts) || int(i1) >= len(verts) || int(i2) >= len(verts) {
+		return
+	}
+	tri := []*Vertex{&verts[i0], &verts[i1], &verts[i2]}
+	inside := 0
+	for _, v := range tri {
+		if v.Pos[2] >= -v.Pos[3] && v.Pos[3] > nearW {
+			inside++
+		}
+	}
+	switch inside {
+	case 0:
+		return
+	case 3:
+		rs.fill(rs.toScreen(tri[0]), rs.toScreen(tri[1]), rs.toScreen(tri[2]))
+		return
+	}
+	poly := rs.clipNear(tri)
+	for cnt := 1; cnt+1 < len(poly); cnt++ {
+		rs.fill(rs.toScreen(poly[0]), rs.toScreen(poly[cnt]), rs.toScreen(poly[cnt+1]))
+	}
+}
+
+// clipNear clips a polygon to the near plane, z >= -w, returning
+// the clipped polygon. New verticies are interpolated.
+func (rs *raster) clipNear(poly []*Vertex) []*Vertex {
+	dist := func(v *Vertex) float32 { return v.Pos[2] + v.Pos[3] - nearW }
+	clipped := []*Vertex{}
+	for cnt, a := range poly {
+		b := poly[(cnt+1)%len(poly)]
+		da, db := dist(a), dist(b)
+		if da >= 0 {
+			clipped = append(clipped, a)
+		}
+		if (da >= 0) != (db >= 0) {
+			t := da / (da - db)
+			v := &Vertex{Out: make([]float32, len(a.Out))}
+			for i := range v.Pos {
+				v.Pos[i] = a.Pos[i] + t*(b.Pos[i]-a.Pos[i])
+			}
+			for i := range v.Out {
+				v.Out[i] = a.Out[i] + t*(b.Out[i]-a.Out[i])
+			}
+			clipped = append(clipped, v)
+		}
+	}
+	return clipped
+}
+
+// fill draws the pixels covered by a screen triangle.
+func (rs *raster) fill(a, b, c screen) {
+	area := edge(a, b, c.x, c.y)
+	if area == 0 {
+		return
+	}
+
+	// Image coordinates flip the winding. Counter-clockwise
+	// triangles are front facing in clip space.
+	if rs.sw.cull && area > 0 {
+		return
+	}
+	minx := int(math.Floor(float64(min3(a.x, b.x, c.x))))
+	maxx := int(math.Ceil(float64(max3(a.x, b.x, c.x))))
+	miny := int(math.Floor(float64(min3(a.y, b.y, c.y))))
+	maxy := int(math.Ceil(float64(max3(a.y, b.y, c.y))))
+	box := image.Rect(minx, miny, maxx+1, maxy+1).Intersect(rs.clip)
+	frag := rs.fragBuffer()
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		py := float32(y) + 0.5
+		for x := box.Min.X; x < box.Max.X; x++ {
+			px := float32(x) + 0.5
+			w0 := edge(b, c, px, py) / area
+			w1 := edge(c, a, px, py) / area
+			w2 := edge(a, b, px, py) / area
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+			z := w0*a.z + w1*b.z + w2*c.z
+			if !rs.depthTest(x, y, z) {
+				continue
+			}
+			iw := w0*a.iw + w1*b.iw + w2*c.iw
+			for i := range frag {
+				frag[i] = (w0*a.out[i]*a.iw + w1*b.out[i]*b.iw + w2*c.out[i]*c.iw) / iw
+			}
+			rs.write(x, y, z, rs.sh.Fragment(rs.u, frag))
+		}
+	}
+}
+
+// line draws a line between two verticies. Lines with a vertex
+// behind the camera are not drawn. Values are interpolated
+// linearly in screen space.
+func (rs *raster) line(i0, i1 uint32) {
+	verts := rs.sw.verts
+	if int(i0) >= len(verts) || int(i1) >= len(verts) {
+		return
+	}
+	if verts[i0].Pos[3] <= nearW || verts[i1].Pos[3] <= nearW {
+		return
+	}
+	a, b := rs.toScreen(&verts[i0]), rs.toScreen(&verts[i1])
+	steps := int(math.Ceil(float64(max3(abs(b.x-a.x), abs(b.y-a.y), 1))))
+	frag := rs.fragBuffer()
+	for step := 0; step <= steps; step++ {
+		t := float32(step) / float32(steps)
+		x := int(math.Floor(float64(a.x + t*(b.x-a.x))))
+		y := int(math.Floor(float64(a.y + t*(b.y-a.y))))
+		z := a.z + t*(b.z-a.z)
+		if !image.Pt(x, y).In(rs.clip) || !rs.depthTest(x, y, z) {
+			continue
+		}
+		for i := range frag {
+			frag[i] = a.out[i] + t*(b.out[i]-a.out[i])
+		}
+		rs.write(x, y, z, rs.sh.Fragment(rs.u, frag))
+	}
+}
+
+// point draws a square point of the vertex point size.
+func (rs *raster) point(i uint32) {
+	v := &rs.sw.verts[i]
+	if v.Pos[3] <= nearW {
+		return
+	}
+	p := rs.toScreen(v)
+	half := v.Size * 0.5
+	r := image.Rect(int(p.x-half+0.5), int(p.y-half+0.5), int(p.x+half+0.5), int(p.y+half+0.5))
+	r = r.Intersect(rs.clip)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if rs.depthTest(x, y, p.z) {
+				rs.write(x, y, p.z, rs.sh.Fragment(rs.u, p.out))
+			}
+		}
+	}
+}
+
+// fragBuffer returns scratch space for fragment shader input.
+func (rs *raster) fragBuffer() []float32 {
+	if cap(rs.sw.frag) < rs.sh.Outs {
+		rs.sw.frag = make([]float32, rs.sh.Outs)
+	}
+	return rs.sw.frag[:rs.sh.Outs]
+}
+
+// depthTest returns true if the depth is closer than the
+// current pixel depth. Always true when depth is disabled.
+func (rs *raster) depthTest(x, y int, z float32) bool {
+	if !rs.d.Depth {
+		return true
+	}
+	return z >= 0 && z <= 1 && z < rs.t.depth[y*rs.t.w+x]
+}
+
+// write blends the color into the framebuffer and updates depth.
+func (rs *raster) write(x, y int, z float32, c [4]float32) {
+	if rs.d.Depth {
+		rs.t.depth[y*rs.t.w+x] = z
+	}
+	img := rs.t.color
+	if img == nil {
+		return // depth only framebuffer.
+	}
+	pix := img.Pix[img.PixOffset(x, y):]
+	if rs.sw.blend {
+		a := clamp01(c[3])
+		for i := range c {
+			c[i] = clamp01(c[i])*a + float32(pix[i])/255*(1-a)
+		}
+	}
+	for i := range c {
+		pix[i] = toByte(c[i])
+	}
+}
+
+// edge returns twice the signed area of the triangle a, b, p.
+func edge(a, b screen, px, py float32) float32 {
+	return (b.x-a.x)*(py-a.y) - (b.y-a.y)*(px-a.x)
+}
+
+// raster
+// =============================================================================
+// bound data.
+
+// swProgram is a bound shader.
+type swProgram struct {
+	name string  // Shader name.
+	sh   *Shader // Go shader.
+}
+
+// swMesh is bound vertex and face data.
+type swMesh struct {
+	vdata map[uint32]*vertexData // Vertex data by layout location.
+	faces []uint32               // Vertex indicies.
+}
+
+// vertex returns the data for vertex v. Byte data is
+// converted to floats using the given buffer.
+func (vd *vertexData) vertex(v int, buff []float32) []float32 {
+	span := int(vd.span)
+	start, end := v*span, (v+1)*span
+	if len(vd.floats) >= end {
+		return vd.floats[start:end]
+	}
+	if len(vd.bytes) >= end {
+		buff = buff[:0]
+		for _, b := range vd.bytes[start:end] {
+			if vd.normalize {
+				buff = append(buff, float32(b)/255)
+			} else {
+				buff = append(buff, float32(b))
+			}
+		}
+		return buff
+	}
+	return nil
+}
+
+// swTexture is a bound texture. Shadow map textures use
+// the framebuffer depth instead of an image.
+type swTexture struct {
+	img   *image.RGBA // Texture data.
+	depth *swTarget   // Depth texture.
+	clamp bool        // True to clamp, false to repeat.
+}
+
+// sample returns the nearest texel color, or depth for depth textures.
+func (tex *swTexture) sample(s, t float32) [4]float32 {
+	w, h := 0, 0
+	switch {
+	case tex.img != nil:
+		w, h = tex.img.Bounds().Dx(), tex.img.Bounds().Dy()
+	case tex.depth != nil:
+		w, h = tex.depth.w, tex.depth.h
+	}
+	if w == 0 || h == 0 {
+		return [4]float32{0, 0, 0, 1}
+	}
+	x, y := wrap(s, w, tex.clamp), wrap(t, h, tex.clamp)
+	if tex.img == nil {
+		z := tex.depth.depth[y*w+x]
+		return [4]float32{z, z, z, 1}
+	}
+	pix := tex.img.Pix[tex.img.PixOffset(x, y):]
+	return [4]float32{
+		float32(pix[0]) / 255, float32(pix[1]) / 255,
+		float32(pix[2]) / 255, float32(pix[3]) / 255,
+	}
+}
+
+// wrap converts a texture coordinate to a texel index.
+func wrap(s float32, size int, clamp bool) int {
+	if clamp {
+		s = clamp01(s)
+	} else {
+		s -= float32(math.Floor(float64(s)))
+	}
+	i := int(s * float32(size))
+	if i >= size {
+		i = size - 1
+	}
+	return i
+}
+
+// swTarget is a framebuffer. Depth only framebuffers have no color.
+type swTarget struct {
+	w, h  int         // Size in pixels.
+	color *image.RGBA // Color buffer. Nil for depth only.
+	depth []float32   // Depth buffer.
+	post  bool        // True if color is cleared when drawn to.
+}
+
+// newTarget allocates a framebuffer of the given size.
+func newTarget(w, h int, color bool) *swTarget {
+	t := &swTarget{w: w, h: h, depth: make([]float32, w*h)}
+	if color {
+		t.color = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	t.clearDepth()
+	return t
+}
+
+// clearDepth resets the depth buffer to the farthest value.
+func (t *swTarget) clearDepth() {
+	for i := range t.depth {
+		t.depth[i] = 1
+	}
+}
+
+// bound data.
+// =============================================================================
+// float32 helpers.
+
+func abs(a float32) float32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+func min3(a, b, c float32) float32 {
+	return float32(math.Min(float64(a), math.Min(float64(b), float64(c))))
+}
+func max3(a, b, c float32) float32 {
+	return float32(math.Max(float64(a), math.Max(float64(b), float64(c))))
+}
+func clamp01(a float32) float32 { return float32(math.Max(0, math.Min(1, float64(a)))) }
+func toByte(a float32) uint8    { return uint8(clamp01(a)*255 + 0.5) }
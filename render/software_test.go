@@ -0,0 +1,216 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// The tests draw into a 4x4 viewport. Pixel centers are at clip
+// space -0.75, -0.25, 0.25, 0.75 with image row 0 at the top.
+
+// Check that a triangle covers the pixels on and inside its edges
+// and writes the expected depth.
+func TestSoftwareTriangle(t *testing.T) {
+	sw := newSoftTest(4, 4)
+	sw.draw(Triangles, []float32{-1, -1, 0.5, 1, 1, -1, 0.5, 1, -1, 1, 0.5, 1}, red, []uint16{0, 1, 2}, func(d *Draw) { d.Depth = true })
+	sw.expect(t, "xooo", "xxoo", "xxxo", "xxxx")
+	if z := sw.targets[0].depth[3*4]; z != 0.75 {
+		t.Errorf("Expected depth 0.75, got %f", z)
+	}
+	if z := sw.targets[0].depth[3]; z != 1 {
+		t.Errorf("Expected cleared depth 1, got %f", z)
+	}
+}
+
+// Check that triangles crossing the near plane are clipped
+// instead of projected through the camera.
+func TestSoftwareNearClip(t *testing.T) {
+	sw := newSoftTest(4, 4)
+	sw.draw(Triangles, []float32{-1, -1, 0, 1, 1, -1, 0, 1, -1, 1, -3, 1}, red, []uint16{0, 1, 2}, nil)
+	sw.expect(t, "oooo", "oooo", "oooo", "xxxx")
+}
+
+// Check that back facing, clockwise, triangles are culled.
+func TestSoftwareCull(t *testing.T) {
+	sw := newSoftTest(4, 4)
+	sw.Enable(CullFace, true)
+	sw.draw(Triangles, []float32{-1, -1, 0, 1, -1, 1, 0, 1, 1, -1, 0, 1}, red, []uint16{0, 1, 2}, nil)
+	sw.expect(t, "oooo", "oooo", "oooo", "oooo")
+	sw.draw(Triangles, []float32{-1, -1, 0, 1, 1, -1, 0, 1, -1, 1, 0, 1}, red, []uint16{0, 1, 2}, nil)
+	sw.expect(t, "xooo", "xxoo", "xxxo", "xxxx")
+}
+
+// Check that closer pixels are kept when depth is enabled.
+func TestSoftwareDepth(t *testing.T) {
+	sw := newSoftTest(4, 4)
+	depth := func(d *Draw) { d.Depth = true }
+	sw.draw(Triangles, quad(-0.5), red, quadFaces, depth)
+	sw.draw(Triangles, quad(0.5), green, quadFaces, depth)
+	if c := sw.Image().RGBAAt(1, 1); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected closer red pixel, got %v", c)
+	}
+	sw.draw(Triangles, quad(-0.75), green, quadFaces, depth)
+	if c := sw.Image().RGBAAt(1, 1); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("Expected closer green pixel, got %v", c)
+	}
+	if z := sw.targets[0].depth[5]; z != 0.125 {
+		t.Errorf("Expected depth 0.125, got %f", z)
+	}
+}
+
+// Check that colors are blended using the source alpha.
+func TestSoftwareBlend(t *testing.T) {
+	sw := newSoftTest(4, 4)
+	sw.Enable(Blend, true)
+	sw.draw(Triangles, quad(0), []float32{1, 0, 0, 0.5}, quadFaces, nil)
+	if c := sw.Image().RGBAAt(2, 2); c != (color.RGBA{128, 0, 0, 191}) {
+		t.Errorf("Expected half red over black, got %v", c)
+	}
+}
+
+// Check that the scissor, which starts at the bottom left,
+// limits the drawn pixels.
+func TestSoftwareScissor(t *testing.T) {
+	sw := newSoftTest(4, 4)
+	scissor := func(d *Draw) { d.Scissor, d.Sx, d.Sy, d.Sw, d.Sh = true, 1, 0, 2, 3 }
+	sw.draw(Triangles, quad(0), red, quadFaces, scissor)
+	sw.expect(t, "oooo", "oxxo", "oxxo", "oxxo")
+}
+
+// Check that points and lines cover the expected pixels.
+func TestSoftwarePointsLines(t *testing.T) {
+	sw := newSoftTest(4, 4)
+	sw.draw(Points, []float32{-0.25, 0.25, 0, 1, 0.75, -0.75, 0, 1}, red, nil, nil)
+	sw.expect(t, "oooo", "oxoo", "oooo", "ooox")
+	sw = newSoftTest(4, 4)
+	sw.draw(Lines, []float32{-0.75, 0.75, 0, 1, 0.75, -0.75, 0, 1}, red, []uint16{0, 1}, nil)
+	sw.expect(t, "xooo", "oxoo", "ooxo", "ooox")
+}
+
+// Check that a black and green texture is sampled using the nearest
+// texel and that coordinates outside 0-1 repeat or clamp.
+func TestSoftwareTexture(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	for _, clamp := range []bool{false, true} {
+		sw := newSoftTest(4, 4)
+		var tid uint32
+		if err := sw.BindTexture(&tid, img); err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+		sw.SetTextureMode(tid, clamp, Nearest)
+		texture := func(d *Draw) { d.SetTex(1, 0, 0, tid) }
+		sw.draw(Triangles, quad(0), nil, quadFaces, texture)
+		want := "oxox" // u from 0 to 2 repeats.
+		if clamp {
+			want = "oxxx"
+		}
+		sw.expect(t, want, want, want, want)
+	}
+}
+
+// =============================================================================
+// software test helpers.
+
+// softTest is a software context with a shader that uses clip
+// space positions and either per-vertex colors or texture
+// coordinates taken from the x position.
+type softTest struct {
+	*Software
+	program uint32
+}
+
+var red, green = []float32{1, 0, 0, 1}, []float32{0, 1, 0, 1}
+
+// quadFaces are the faces for a quad.
+var quadFaces = []uint16{0, 1, 2, 0, 2, 3}
+
+// quad returns a counter-clockwise quad covering
+// the viewport at the given clip space depth.
+func quad(z float32) []float32 {
+	return []float32{-1, -1, z, 1, 1, -1, z, 1, 1, 1, z, 1, -1, 1, z, 1}
+}
+
+// newSoftTest returns a cleared software context of the given size.
+func newSoftTest(w, h int) *softTest {
+	sw := &softTest{Software: NewSoftware()}
+	sw.SetShader("test", &Shader{
+		Outs: 4,
+		Vertex: func(u *Uniforms, in [][]float32, v *Vertex) {
+			copy(v.Pos[:], in[0])
+			if len(in) > 1 {
+				copy(v.Out, in[1])
+			} else {
+				v.Out[0] = v.Pos[0] + 1 // texture u from 0 to 2.
+			}
+		},
+		Fragment: func(u *Uniforms, in []float32) [4]float32 {
+			if len(u.d.Texs) > 0 {
+				return u.Sample("uv", in[0], 0)
+			}
+			return [4]float32{in[0], in[1], in[2], in[3]}
+		},
+	})
+	sw.program, _ = sw.BindNamedShader("test", nil, nil, map[string]int32{}, map[string]uint32{})
+	sw.Viewport(w, h)
+	sw.Color(0, 0, 0, 1)
+	sw.Clear()
+	return sw
+}
+
+// draw renders the clip space positions with the given vertex color,
+// or no color for textures. The draw call can be adjusted before
+// it is rendered.
+func (sw *softTest) draw(mode int, pos, rgba []float32, faces []uint16, adjust func(d *Draw)) {
+	vcnt := len(pos) / 4
+	vdata := map[uint32]Data{0: NewVertexData(0, 4, StaticDraw, false)}
+	vdata[0].Set(pos)
+	if rgba != nil {
+		colors := []float32{}
+		for cnt := 0; cnt < vcnt; cnt++ {
+			colors = append(colors, rgba...)
+		}
+		vdata[1] = NewVertexData(1, 4, StaticDraw, false)
+		vdata[1].Set(colors)
+	}
+	fdata := NewFaceData(StaticDraw)
+	fdata.Set(faces)
+	var vao uint32
+	sw.BindMesh(&vao, vdata, fdata)
+	d := NewDraw()
+	d.SetRefs(sw.program, vao, mode)
+	d.SetCounts(len(faces), vcnt)
+	if adjust != nil {
+		adjust(d)
+	}
+	sw.Render(d)
+}
+
+// expect checks the drawn pixels row by row from the top. Pixels
+// marked x are expected to differ from the clear color.
+func (sw *softTest) expect(t *testing.T, rows ...string) {
+	t.Helper()
+	img, got := sw.Image(), []string{}
+	for y := range rows {
+		row := []byte{}
+		for x := range rows[y] {
+			if c := img.RGBAAt(x, y); c.R != 0 || c.G != 0 || c.B != 0 {
+				row = append(row, 'x')
+			} else {
+				row = append(row, 'o')
+			}
+		}
+		got = append(got, string(row))
+	}
+	for y := range rows {
+		if got[y] != rows[y] {
+			t.Errorf("Expected pixels %v, got %v", rows, got)
+			return
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// replay.go records user input so that it can be played back later.
+// Combined with the fixed update timestep, played back input reproduces
+// an application session, ie: for bug reports or regression tests.
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+
+	"github.com/gazed/vu/device"
+)
+
+// Record starts recording the user input for each update to the given
+// file. The file is replaced if it exists. An empty file name stops
+// recording. Recording also stops when the engine shuts down.
+// Engine attribute for use in Eng.Set().
+func Record(file string) EngAttr {
+	return func(eng *engine) { eng.replay.record(file) }
+}
+
+// Playback replaces user input with the input previously saved to the
+// given file using Record. Device input is used again once all the
+// recorded input has been played. An empty file name stops playback.
+// Played back window resizes use the recorded window size.
+// Engine attribute for use in Eng.Set().
+func Playback(file string) EngAttr {
+	return func(eng *engine) { eng.replay.playback(file) }
+}
+
+// Record, Playback
+// =============================================================================
+// replay
+
+// replay records and plays back user input. It is only used on the main
+// engine thread when polling for user input before each update.
+type replay struct {
+	file   *os.File        // Recording file. Nil if not recording.
+	out    *bufio.Writer   // Buffered recording file.
+	enc    *json.Encoder   // One JSON recorded input per line.
+	played []recorded      // Recorded input being played back.
+	down   *device.Pressed // Reused for played back input.
+	win    window          // Last device or played back window.
+}
+
+// recorded is the user input for one update.
+type recorded struct {
+	Ut      uint64      `json:"ut"`                // Update tick, not played back.
+	Mx      int         `json:"mx"`                // Mouse location.
+	My      int         `json:"my"`                //   "
+	Down    map[int]int `json:"down,omitempty"`    // Pressed keys.
+	Scroll  int         `json:"scroll,omitempty"`  // Scroll amount.
+	Resized bool        `json:"resized,omitempty"` // Window changed.
+	Win     *window     `json:"window,omitempty"`  // Window when resized.
+	Focus   bool        `json:"focus"`             // Window has focus.
+}
+
+// window is the window size and position recorded with resized input
+// so that playback does not depend on the current device window.
+type window struct {
+	X    int  `json:"x"`              // Bottom left corner.
+	Y    int  `json:"y"`              //   "
+	W    int  `json:"w"`              // Width and height in pixels.
+	H    int  `json:"h"`              //   "
+	Full bool `json:"full,omitempty"` // Full screen.
+}
+
+// input is called each update with the device. It returns either the
+// device input or the played back input along with the matching window.
+// The window is only updated when the input is resized. Played back
+// input without a recorded window uses the device window. Device input
+// is saved, with the update tick, when recording.
+func (r *replay) input(dev device.Device, ut uint64) (*device.Pressed, *window) {
+	pressed := dev.Down()
+	if pressed.Resized || len(r.played) > 0 && r.played[0].Resized {
+		r.win.X, r.win.Y, r.win.W, r.win.H = dev.Size()
+		r.win.Full = dev.IsFullScreen()
+	}
+	if len(r.played) > 0 {
+		in := r.played[0]
+		r.played = r.played[1:]
+		if r.down == nil {
+			r.down = &device.Pressed{Down: map[int]int{}}
+		}
+		r.down.Mx, r.down.My = in.Mx, in.My
+		r.down.Scroll, r.down.Resized, r.down.Focus = in.Scroll, in.Resized, in.Focus
+		for key := range r.down.Down {
+			delete(r.down.Down, key)
+		}
+		for key, val := range in.Down {
+			r.down.Down[key] = val
+		}
+		if in.Resized && in.Win != nil {
+			r.win = *in.Win // otherwise use the device window.
+		}
+		pressed = r.down
+	}
+	if r.enc != nil {
+		in := recorded{Ut: ut, Mx: pressed.Mx, My: pressed.My, Down: pressed.Down,
+			Scroll: pressed.Scroll, Resized: pressed.Resized, Focus: pressed.Focus}
+		if pressed.Resized {
+			in.Win = &r.win
+		}
+		if err := r.enc.Encode(&in); err != nil {
+			log.Printf("Record stopped: %s", err)
+			r.record("")
+		}
+	}
+	return pressed, &r.win
+}
+
+// record starts recording to the given file, stopping any
+// previous recording. Recording stops if the file is empty.
+func (r *replay) record(file string) {
+	if r.file != nil {
+		if err := r.out.Flush(); err != nil {
+			log.Printf("Record %s: %s", r.file.Name(), err)
+		}
+		r.file.Close()
+		r.file, r.out, r.enc = nil, nil, nil
+	}
+	if file == "" {
+		return
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		log.Printf("Record: %s", err)
+		return
+	}
+	r.file, r.out = f, bufio.NewWriter(f)
+	r.enc = json.NewEncoder(r.out)
+}
+
+// playback reads all the recorded input from the given file.
+// Playback stops if the file is empty.
+func (r *replay) playback(file string) {
+	r.played = r.played[:0]
+	if file == "" {
+		return
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		log.Printf("Playback: %s", err)
+		return
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		in := recorded{}
+		if err := dec.Decode(&in); err != nil {
+			if err != io.EOF {
+				log.Printf("Playback %s: %s", file, err)
+				r.played = r.played[:0]
+			}
+			return
+		}
+		r.played = append(r.played, in)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Check that played back input reaches the application
+// and that it can be recorded again. The recorded update ticks are
+// ignored and resized input uses the recorded window.
+func TestPlayback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vu")
+	if err != nil {
+		t.Fatalf("Could not create temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+	played := filepath.Join(dir, "played.json")
+	recorded := filepath.Join(dir, "recorded.json")
+	session := `{"ut":101,"mx":10,"my":20,"focus":true}
+{"ut":102,"mx":11,"my":21,"down":{"65":0},"resized":true,"window":{"x":5,"y":6,"w":320,"h":240},"focus":true}
+{"ut":103,"mx":12,"my":22,"down":{"65":-999999999},"scroll":-1,"focus":true}
+`
+	if err := ioutil.WriteFile(played, []byte(session), 0644); err != nil {
+		t.Fatalf("Could not write session %s", err)
+	}
+	ra := &replayApp{played: played, recorded: recorded}
+	if err := RunHeadless(ra, Headless{Ticks: 4, W: 640, H: 480}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if len(ra.in) != 4 {
+		t.Fatalf("Expected 4 updates, got %d", len(ra.in))
+	}
+	if in := ra.in[0]; in.Mx != 10 || in.My != 20 || len(in.Down) != 0 {
+		t.Errorf("Expected first input 10 20 [], got %d %d %v", in.Mx, in.My, in.Down)
+	}
+	if in := ra.in[1]; in.Down[65] != 0 || len(in.Down) != 1 || !in.Resized || in.Ut != 2 {
+		t.Errorf("Expected key 65 down and resized at 2, got %v %t %d", in.Down, in.Resized, in.Ut)
+	}
+	if win := ra.win[1]; win != (window{X: 5, Y: 6, W: 320, H: 240}) {
+		t.Errorf("Expected recorded window, got %+v", win)
+	}
+	if in := ra.in[2]; in.Down[65] >= 0 || in.Scroll != -1 || in.Ut != 3 {
+		t.Errorf("Expected key 65 release and scroll at 3, got %v %d %d", in.Down, in.Scroll, in.Ut)
+	}
+	if in := ra.in[3]; in.Mx != 0 || len(in.Down) != 0 || in.Ut != 4 {
+		t.Errorf("Expected device input after playback, got %d %v %d", in.Mx, in.Down, in.Ut)
+	}
+
+	// The played back input is recorded along with the device input.
+	data, err := ioutil.ReadFile(recorded)
+	if err != nil {
+		t.Fatalf("Expected recording %s", err)
+	}
+	want := strings.NewReplacer(`"ut":10`, `"ut":`).Replace(session) +
+		`{"ut":4,"mx":0,"my":0,"focus":true}` + "\n"
+	if string(data) != want {
+		t.Errorf("Expected recording\n%s got\n%s", want, data)
+	}
+}
+
+// replayApp plays back and records its input.
+type replayApp struct {
+	played, recorded string   // Files.
+	in               []Input  // Input from each update.
+	win              []window // Screen from each update.
+}
+
+// Create starts the playback and recording.
+func (ra *replayApp) Create(eng Eng, s *State) {
+	eng.Set(Playback(ra.played), Record(ra.recorded))
+}
+
+// Update saves a copy of the input.
+func (ra *replayApp) Update(eng Eng, in *Input, s *State) {
+	cp := *in
+	cp.Down = map[int]int{}
+	for key, val := range in.Down {
+		cp.Down[key] = val
+	}
+	ra.in = append(ra.in, cp)
+	ra.win = append(ra.win, window{X: s.X, Y: s.Y, W: s.W, H: s.H, Full: s.Full})
+}
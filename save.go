@@ -0,0 +1,528 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// save.go writes scenes to, and reads scenes from, a versioned JSON format.
+// Only the data needed to recreate a scene is saved. Assets are saved by
+// name and are reloaded by the loader when the scene is read.
+// FUTURE: Application functions like culler and particle movers can't
+//         be saved and need to be set again after loading. Same for
+//         generated mesh and texture data.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// sceneVersion is incremented whenever the saved scene format changes.
+// Scenes saved with older versions are loaded using defaults for any
+// data added by later versions. Scenes saved with newer versions are
+// not loaded. The versions are:
+//    1: parts, scene flags, camera, sky, models, lights, and bodies.
+//    2: adds part tags.
+//    3: adds model texture filters.
+//    4: adds post processing passes.
+//    5: adds capsule, cylinder, and convex hull bodies.
+//    6: adds triangle mesh and heightfield bodies.
+//    7: adds compound bodies.
+const sceneVersion = 7
+
+// SaveScene writes the scene entity, its camera, and all of its child
+// parts to the given writer. Use LoadScene to recreate the scene.
+// Implements Eng interface.
+func (app *application) SaveScene(e *Ent, w io.Writer) error {
+	if e == nil || app.scenes.get(e.eid) == nil || !app.eids.valid(e.eid) {
+		return fmt.Errorf("SaveScene needs AddScene")
+	}
+	saved := &savedScene{Version: sceneVersion, Root: app.savePart(e.eid)}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(saved); err != nil {
+		log.Printf("SaveScene %d: %s", e.eid, err)
+		return err
+	}
+	return nil
+}
+
+// LoadScene creates a new scene from data previously written by
+// SaveScene. Nil is returned if the scene could not be read.
+// Implements Eng interface.
+func (app *application) LoadScene(r io.Reader) *Ent {
+	saved := &savedScene{}
+	if err := json.NewDecoder(r).Decode(saved); err != nil {
+		log.Printf("LoadScene: %s", err)
+		return nil
+	}
+	if saved.Version < 1 || saved.Version > sceneVersion {
+		log.Printf("LoadScene: unsupported version %d", saved.Version)
+		return nil
+	}
+	if saved.Root.Scene == nil {
+		log.Printf("LoadScene: missing scene")
+		return nil
+	}
+	if sb := badBody(&saved.Root); sb != nil {
+		log.Printf("LoadScene: invalid body shape %d %v", sb.Shape, sb.Dims)
+		return nil
+	}
+	scene := app.AddScene()
+	app.loadPart(scene, scene, &saved.Root)
+	return scene
+}
+
+// SaveScene, LoadScene
+// =============================================================================
+// saved scene format.
+
+// savedScene is the top level saved data.
+type savedScene struct {
+	Version int       `json:"version"` // Saved format version.
+	Root    savedPart `json:"root"`    // Scene entity.
+}
+
+// savedPart is a pov and its optional components.
+// Child parts are saved in creation order.
+type savedPart struct {
+	At    [3]float64 `json:"at"`             // Local location.
+	Rot   [4]float64 `json:"rot"`            // Local orientation X,Y,Z,W.
+	Scale [3]float64 `json:"scale"`          // Local per axis scale.
+	Cull  bool       `json:"cull,omitempty"` // Excluded from rendering.
+	Tags  []string   `json:"tags,omitempty"` // Ent.Tag names.
+
+	// Optional components.
+	Scene *savedScn   `json:"scene,omitempty"` // Only for the root part.
+	Model *savedModel `json:"model,omitempty"`
+	Light *savedLight `json:"light,omitempty"`
+	Body  *savedBody  `json:"body,omitempty"`
+	Kids  []savedPart `json:"kids,omitempty"`
+}
+
+// savedScn holds the scene flags and camera.
+type savedScn struct {
+	UI      bool        `json:"ui,omitempty"`      // SetUI.
+	Ortho   bool        `json:"ortho,omitempty"`   // SetOrtho.
+	Over    uint8       `json:"over,omitempty"`    // SetOver.
+	Scissor []int32     `json:"scissor,omitempty"` // SetScissor x,y,w,h.
+	Shadows bool        `json:"shadows,omitempty"` // SetShadows.
+	AsTex   bool        `json:"astex,omitempty"`   // AsTex.
+	Cam     savedCam    `json:"cam"`               // Scene camera.
+	Sky     *savedPart  `json:"sky,omitempty"`     // AddSky.
+	Posts   []savedPost `json:"posts,omitempty"`   // AddPost in order.
+}
+
+// savedPost holds one post processing pass.
+type savedPost struct {
+	Shader   string               `json:"shader"`             // Pass shader.
+	Assets   []string             `json:"assets,omitempty"`   // Extra assets.
+	Uniforms map[string][]float32 `json:"uniforms,omitempty"` // SetUniform.
+}
+
+// savedCam holds the camera settings.
+type savedCam struct {
+	At    [3]float64 `json:"at"`    // Location.
+	Rot   [4]float64 `json:"rot"`   // Lookat orientation.
+	Pitch float64    `json:"pitch"` // X-axis rotation in degrees.
+	Yaw   float64    `json:"yaw"`   // Y-axis rotation in degrees.
+	Fov   float64    `json:"fov"`   // Field of view in degrees.
+	Near  float64    `json:"near"`  // Near clip plane.
+	Far   float64    `json:"far"`   // Far clip plane.
+}
+
+// savedModel holds the model assets and shader uniforms.
+type savedModel struct {
+	Kind      string               `json:"kind"`                // model, instanced, label, actor, effect.
+	Assets    []string             `json:"assets"`              // Asset names, ie: "shd:name".
+	Uniforms  map[string][]float32 `json:"uniforms,omitempty"`  // Shader uniform data.
+	Mode      int                  `json:"mode"`                // Triangles, Lines, Points.
+	Clamps    []string             `json:"clamps,omitempty"`    // Clamped textures.
+	Filters   map[string]int       `json:"filters,omitempty"`   // Filtered textures.
+	Instances int                  `json:"instances,omitempty"` // DrawInstances.
+	Str       string               `json:"str,omitempty"`       // Label string.
+	Wrap      int                  `json:"wrap,omitempty"`      // Label wrap.
+}
+
+// savedLight holds the light settings. The cone angles are cosines.
+type savedLight struct {
+	Kind      int        `json:"kind"`      // DirectionalLight, PointLight, SpotLight.
+	Color     [3]float64 `json:"color"`     // r,g,b.
+	Intensity [3]float64 `json:"intensity"` // ambient, diffuse, specular.
+	Atten     [3]float64 `json:"atten"`     // constant, linear, quadratic.
+	Cone      [2]float64 `json:"cone"`      // inner, outer.
+}
+
+// savedBody holds the physics body shape and properties.
+type savedBody struct {
+	Shape  int        `json:"shape"`           // physics shape type.
+	Dims   []float64  `json:"dims"`            // See physics.Dims.
+	Solid  bool       `json:"solid,omitempty"` // SetSolid.
+	Mass   float64    `json:"mass,omitempty"`  // Solid body mass.
+	Bounce float64    `json:"bounce,omitempty"`
+	Speed  [3]float64 `json:"speed"` // Linear velocity.
+	Whirl  [3]float64 `json:"whirl"` // Angular velocity.
+}
+
+// saved scene format.
+// =============================================================================
+// save and load helpers.
+
+// savePart recursively saves the given entity and its children.
+func (app *application) savePart(id eid) savedPart {
+	sp := savedPart{}
+	if p := app.povs.get(id); p != nil {
+		sp.At = [3]float64{p.tn.Loc.X, p.tn.Loc.Y, p.tn.Loc.Z}
+		sp.Rot = [4]float64{p.tn.Rot.X, p.tn.Rot.Y, p.tn.Rot.Z, p.tn.Rot.W}
+		sp.Scale = [3]float64{p.sn.X, p.sn.Y, p.sn.Z}
+	}
+	sp.Tags = append(sp.Tags, app.tags.names[id]...)
+	if s := app.scenes.get(id); s != nil {
+		sp.Scene = app.saveScn(s)
+	}
+	if m := app.models.get(id); m != nil {
+		sp.Model = app.saveModel(id, m)
+	}
+	if l := app.lights.get(id); l != nil {
+		sp.Light = &savedLight{Kind: l.kind,
+			Color:     [3]float64{l.r, l.g, l.b},
+			Intensity: [3]float64{l.ka, l.kd, l.ks},
+			Atten:     [3]float64{l.kc, l.kl, l.kq},
+			Cone:      [2]float64{l.innerAngle, l.outerAngle},
+		}
+	}
+	if b := app.bodies.get(id); b != nil {
+		sb := &savedBody{Shape: b.Shape().Type(), Dims: physics.Dims(b.Shape())}
+		if _, ok := app.bodies.solids[id]; ok {
+			sb.Solid = true
+			sb.Mass, sb.Bounce = b.Props()
+		}
+		sb.Speed[0], sb.Speed[1], sb.Speed[2] = b.Speed()
+		sb.Whirl[0], sb.Whirl[1], sb.Whirl[2] = b.Whirl()
+		sp.Body = sb
+	}
+	if n := app.povs.getNode(id); n != nil {
+		sp.Cull = n.cull
+		for _, kid := range n.kids {
+			sp.Kids = append(sp.Kids, app.savePart(kid))
+		}
+	}
+	return sp
+}
+
+// saveScn saves the scene flags, camera, optional sky and post passes.
+func (app *application) saveScn(s *scene) *savedScn {
+	c := s.cam
+	ss := &savedScn{UI: s.isUI, Ortho: s.isOrtho, Over: s.overlay}
+	ss.Cam = savedCam{Pitch: c.Pitch, Yaw: c.Yaw, Fov: c.fov, Near: c.near, Far: c.far}
+	ss.Cam.At = [3]float64{c.at.Loc.X, c.at.Loc.Y, c.at.Loc.Z}
+	ss.Cam.Rot = [4]float64{c.at.Rot.X, c.at.Rot.Y, c.at.Rot.Z, c.at.Rot.W}
+	if s.scissor {
+		ss.Scissor = []int32{s.sx, s.sy, s.sw, s.sh}
+	}
+	_, ss.Shadows = app.scenes.shadows[s.eid]
+	_, ss.AsTex = app.scenes.targets[s.eid]
+	if sky, ok := app.scenes.skys[s.eid]; ok {
+		sp := app.savePart(sky.eid)
+		ss.Sky = &sp
+	}
+	if p, ok := app.scenes.posts[s.eid]; ok {
+		for _, id := range p.passes {
+			if m := app.models.get(id); m != nil {
+				ss.Posts = append(ss.Posts, savePost(m))
+			}
+		}
+	}
+	return ss
+}
+
+// savePost saves a post processing pass shader, assets and uniforms.
+func savePost(m *model) savedPost {
+	sp := savedPost{Uniforms: map[string][]float32{}}
+	for _, attr := range m.assets {
+		if name := strings.TrimPrefix(attr, "shd:"); name != attr {
+			sp.Shader = name
+			continue
+		}
+		sp.Assets = append(sp.Assets, attr)
+	}
+	for key, vals := range m.uniforms {
+		sp.Uniforms[key] = append([]float32{}, vals...)
+	}
+	return sp
+}
+
+// saveModel saves the model assets and settings.
+func (app *application) saveModel(id eid, m *model) *savedModel {
+	sm := &savedModel{Kind: "model", Mode: m.mode}
+	sm.Assets = append(sm.Assets, m.assets...)
+	sm.Clamps = append(sm.Clamps, app.models.clamps[id]...)
+	for name, filter := range app.models.filters[id] {
+		if sm.Filters == nil {
+			sm.Filters = map[string]int{}
+		}
+		sm.Filters[name] = filter
+	}
+	if len(m.uniforms) > 0 {
+		sm.Uniforms = map[string][]float32{}
+		for key, vals := range m.uniforms {
+			sm.Uniforms[key] = append([]float32{}, vals...)
+		}
+	}
+	switch {
+	case m.isInstanced:
+		sm.Kind = "instanced"
+		sm.Instances = m.drawInstances
+	case m.isEffect:
+		sm.Kind = "effect"
+	case app.models.getLabel(id) != nil:
+		l := app.models.getLabel(id)
+		sm.Kind, sm.Str, sm.Wrap = "label", l.str, l.wrap
+	case app.models.getActor(id) != nil:
+		sm.Kind = "actor"
+	}
+	return sm
+}
+
+// loadPart recursively recreates saved entities.
+// The entity e has already been created.
+func (app *application) loadPart(scene, e *Ent, sp *savedPart) {
+	if p := app.povs.get(e.eid); p != nil {
+		p.tn.Loc.SetS(sp.At[0], sp.At[1], sp.At[2])
+		p.tn.Rot.SetS(sp.Rot[0], sp.Rot[1], sp.Rot[2], sp.Rot[3])
+		p.sn.SetS(sp.Scale[0], sp.Scale[1], sp.Scale[2])
+		app.povs.updateWorld(p, e.eid)
+	}
+	for _, tag := range sp.Tags {
+		e.Tag(tag)
+	}
+	if sp.Scene != nil && app.scenes.get(e.eid) != nil {
+		app.loadScn(e, sp.Scene)
+	}
+	if sp.Model != nil {
+		app.loadModel(e, sp.Model)
+	}
+	if sl := sp.Light; sl != nil {
+		l := app.lights.create(scene, e.eid, sl.Kind)
+		l.r, l.g, l.b = sl.Color[0], sl.Color[1], sl.Color[2]
+		l.ka, l.kd, l.ks = sl.Intensity[0], sl.Intensity[1], sl.Intensity[2]
+		l.kc, l.kl, l.kq = sl.Atten[0], sl.Atten[1], sl.Atten[2]
+		l.innerAngle, l.outerAngle = sl.Cone[0], sl.Cone[1]
+	}
+	if sb := sp.Body; sb != nil {
+		if b := newSavedBody(sb); b != nil {
+			e.MakeBody(b)
+			if sb.Solid {
+				e.SetSolid(sb.Mass, sb.Bounce)
+			}
+			b.Push(sb.Speed[0], sb.Speed[1], sb.Speed[2])
+			b.Turn(sb.Whirl[0], sb.Whirl[1], sb.Whirl[2])
+		}
+	}
+	for cnt := range sp.Kids {
+		app.loadPart(scene, e.AddPart(), &sp.Kids[cnt])
+	}
+	if sp.Cull {
+		e.Cull(true)
+	}
+}
+
+// loadScn restores the scene flags, camera, optional sky and post passes.
+func (app *application) loadScn(e *Ent, ss *savedScn) {
+	if ss.UI {
+		e.SetUI()
+	}
+	if ss.Ortho {
+		e.SetOrtho()
+	}
+	e.SetOver(ss.Over)
+	if len(ss.Scissor) == 4 {
+		sc := ss.Scissor
+		e.SetScissor(int(sc[0]), int(sc[1]), int(sc[2]), int(sc[3]))
+	}
+	if ss.Shadows {
+		e.SetShadows()
+	}
+	if ss.AsTex {
+		e.AsTex(true)
+	}
+	sc := ss.Cam
+	c := e.Cam().SetClip(sc.Near, sc.Far).SetFov(sc.Fov)
+	c.SetAt(sc.At[0], sc.At[1], sc.At[2]).SetPitch(sc.Pitch).SetYaw(sc.Yaw)
+	c.at.Rot.SetS(sc.Rot[0], sc.Rot[1], sc.Rot[2], sc.Rot[3])
+	if ss.Sky != nil {
+		if sky := e.AddSky(); sky != nil {
+			app.loadPart(e, sky, ss.Sky)
+		}
+	}
+	for _, sp := range ss.Posts {
+		if pass := e.AddPost(sp.Shader, sp.Assets...); pass != nil {
+			m := app.models.get(pass.eid)
+			for key, vals := range sp.Uniforms {
+				m.uniforms[key] = append([]float32{}, vals...)
+			}
+		}
+	}
+}
+
+// loadModel recreates the model and requests its assets.
+func (app *application) loadModel(e *Ent, sm *savedModel) {
+	if len(sm.Clamps) > 0 {
+		e.app.models.clamps[e.eid] = append([]string{}, sm.Clamps...)
+	}
+	for name, filter := range sm.Filters {
+		e.Filter(name, filter)
+	}
+	var m *model
+	switch sm.Kind {
+	case "label":
+		m = app.models.createLabel(e, sm.Assets...)
+	case "actor":
+		m = app.models.createActor(e, sm.Assets...)
+	case "effect":
+		m = app.models.createEffect(e, sm.Assets...)
+	case "instanced":
+		if m = app.models.create(e); m != nil {
+			m.isInstanced = true
+			app.models.loadAssets(e, m, sm.Assets...)
+		}
+	default:
+		if m = app.models.create(e); m != nil {
+			app.models.loadAssets(e, m, sm.Assets...)
+		}
+	}
+	if m == nil {
+		log.Printf("LoadScene: model %d not created", e.eid)
+		return
+	}
+	m.mode = sm.Mode
+	m.drawInstances = sm.Instances
+	for key, vals := range sm.Uniforms {
+		m.uniforms[key] = append([]float32{}, vals...)
+	}
+	if sm.Kind == "label" {
+		e.SetWrap(sm.Wrap).SetStr(sm.Str)
+	}
+}
+
+// newSavedBody creates a physics body from saved shape data.
+// Returns nil if the shape data is not valid.
+func newSavedBody(sb *savedBody) Body {
+	if shape := newSavedShape(sb.Shape, sb.Dims); shape != nil {
+		return physics.NewBody(shape)
+	}
+	log.Printf("LoadScene: invalid body shape %d %v", sb.Shape, sb.Dims)
+	return nil
+}
+
+// savedCount returns the saved value as a count or an index.
+// The value must be a whole number from 0 to max inclusive.
+func savedCount(v float64, max int) (int, bool) {
+	if v < 0 || v > float64(max) || v != math.Trunc(v) {
+		return 0, false
+	}
+	return int(v), true
+}
+
+// badBody returns the first saved body in the part, its sky, or its
+// kids whose shape data is not valid. Returns nil if all are valid.
+func badBody(sp *savedPart) *savedBody {
+	if sb := sp.Body; sb != nil && newSavedShape(sb.Shape, sb.Dims) == nil {
+		return sb
+	}
+	if sp.Scene != nil && sp.Scene.Sky != nil {
+		if sb := badBody(sp.Scene.Sky); sb != nil {
+			return sb
+		}
+	}
+	for cnt := range sp.Kids {
+		if sb := badBody(&sp.Kids[cnt]); sb != nil {
+			return sb
+		}
+	}
+	return nil
+}
+
+// newSavedShape creates a physics shape from saved shape data.
+// See physics.Dims for the shape data layouts.
+// Returns nil if the shape data is not valid.
+func newSavedShape(kind int, d []float64) physics.Shape {
+	switch {
+	case kind == physics.BoxShape && len(d) == 3:
+		return physics.NewBox(d[0], d[1], d[2])
+	case kind == physics.SphereShape && len(d) == 1:
+		return physics.NewSphere(d[0])
+	case kind == physics.CapsuleShape && len(d) == 2:
+		return physics.NewCapsule(d[0], d[1])
+	case kind == physics.CylinderShape && len(d) == 2:
+		return physics.NewCylinder(d[0], d[1])
+	case kind == physics.HullShape && len(d) > 0 && len(d)%3 == 0:
+		points := make([]float32, len(d))
+		for cnt, v := range d {
+			points[cnt] = float32(v)
+		}
+		return physics.NewHull(points)
+	case kind == physics.TriMeshShape && len(d) > 0:
+		nv, ok := savedCount(d[0], (len(d)-1)/3)
+		if !ok {
+			return nil
+		}
+		vertices, faces := make([]float32, nv*3), []uint32{}
+		for cnt := range vertices {
+			vertices[cnt] = float32(d[1+cnt])
+		}
+		for _, f := range d[1+nv*3:] {
+			index, ok := savedCount(f, nv-1)
+			if !ok {
+				return nil
+			}
+			faces = append(faces, uint32(index))
+		}
+		return physics.NewTriMesh(vertices, faces)
+	case kind == physics.HeightfieldShape && len(d) > 2:
+		rows, ok := savedCount(d[1], len(d)-2)
+		if heights := d[2:]; ok && rows >= 1 && len(heights)%rows == 0 {
+			cols := len(heights) / rows
+			topo := make([][]float64, rows)
+			for x := range topo {
+				topo[x] = heights[x*cols : (x+1)*cols]
+			}
+			return physics.NewHeightfield(topo, d[0], 1)
+		}
+	case kind == physics.CompoundShape && len(d) > 0:
+		children, ok := savedCount(d[0], (len(d)-1)/9)
+		if !ok {
+			return nil
+		}
+		compound := physics.NewCompound()
+		for d = d[1:]; children > 0; children-- {
+			if len(d) < 9 {
+				return nil
+			}
+			childKind, kindOk := savedCount(d[0], physics.NumShapes)
+			dims, dimsOk := savedCount(d[8], len(d)-9)
+			if !kindOk || !dimsOk {
+				return nil
+			}
+			size := 9 + dims
+			shape := newSavedShape(childKind, d[9:size])
+			if shape == nil {
+				return nil
+			}
+			offset := lin.NewT().SetLoc(d[1], d[2], d[3]).SetRot(d[4], d[5], d[6], d[7])
+			compound.Add(shape, offset)
+			d = d[size:]
+		}
+		return compound
+	case kind == physics.PlaneShape && len(d) == 3:
+		return physics.NewPlane(d[0], d[1], d[2])
+	case kind == physics.RayShape && len(d) == 3:
+		return physics.NewRay(d[0], d[1], d[2])
+	}
+	return nil
+}
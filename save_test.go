@@ -0,0 +1,148 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gazed/vu/physics"
+)
+
+// Check that a loaded scene saves the same as the original scene.
+func TestSaveLoadScene(t *testing.T) {
+	sa := &saveApp{}
+	if err := RunHeadless(sa, Headless{Ticks: 2}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if sa.saved == "" || sa.saved != sa.loaded {
+		t.Errorf("Expected\n%s got\n%s", sa.saved, sa.loaded)
+	}
+	for _, want := range []string{`"version": 7`, `"ui": true`, `"shd:colored"`, `"kd"`, `"solid": true`, `"ball"`, `"vignette"`, `"strength"`} {
+		if !strings.Contains(sa.saved, want) {
+			t.Errorf("Expected %s in saved scene", want)
+		}
+	}
+	if sa.drawn != 4 {
+		t.Errorf("Expected both scenes and post passes drawn, got %d draws", sa.drawn)
+	}
+	if sa.bad != nil {
+		t.Errorf("Expected nil scene for unsupported version")
+	}
+	if sa.old == nil || len(sa.old.app.povs.getNode(sa.old.eid).kids) != 1 {
+		t.Errorf("Expected scene with one part from version 1")
+	}
+}
+
+// version1 is a scene saved using the first saved scene format.
+const version1 = `{"version": 1, "root": {"scene": {"cam": {"fov": 60, "near": 0.1, "far": 50}},
+ "kids": [{"at": [0, 0, -5], "rot": [0, 0, 0, 1], "scale": [1, 1, 1],
+ "model": {"kind": "model", "assets": ["shd:colored"], "mode": 0},
+ "body": {"shape": 0, "dims": [1], "speed": [0, 0, 0], "whirl": [0, 0, 0]}}]}}`
+
+// saveApp saves a scene, loads it, and saves the loaded scene.
+type saveApp struct {
+	saved, loaded string // Saved scenes.
+	drawn         int    // Draw calls from the last render.
+	bad           *Ent   // Expected nil load.
+	old           *Ent   // Scene from an older version.
+}
+
+// Create saves and loads a scene with one of each component.
+func (sa *saveApp) Create(eng Eng, s *State) {
+	scene := eng.AddScene().SetUI().SetScissor(1, 2, 300, 400)
+	scene.Cam().SetClip(0, 10).SetAt(1, 2, 3).SetYaw(45)
+	scene.MakeLight(PointLight).SetAt(0, 5, 0).SetLightColor(0.5, 0.5, 1)
+	part := scene.AddPart().SetAt(100, 100, 0).SetScale(50, 50, 1).SetSpin(0, 0, 30)
+	part.MakeModel("colored").SetColor(1, 0, 0).SetAlpha(0.5)
+	kid := part.AddPart().SetAt(0, 1, 0).MakeBody(Sphere(0.5)).Tag("ball")
+	kid.SetSolid(2, 0.3)
+	kid.Cull(true)
+	scene.AddPost("vignette").SetUniform("strength", 0.25)
+
+	buff := &bytes.Buffer{}
+	if err := eng.SaveScene(scene, buff); err != nil {
+		return
+	}
+	sa.saved = buff.String()
+	loaded := eng.LoadScene(strings.NewReader(sa.saved))
+	buff.Reset()
+	if err := eng.SaveScene(loaded, buff); err != nil {
+		return
+	}
+	sa.loaded = buff.String()
+	sa.bad = eng.LoadScene(strings.NewReader(`{"version": 99, "root": {"scene": {}}}`))
+	sa.old = eng.LoadScene(strings.NewReader(version1))
+
+	// Generated meshes are not saved.
+	genTriangle(part, "triangle")
+	for _, kid := range loaded.app.povs.getNode(loaded.eid).kids {
+		if e := (&Ent{app: loaded.app, eid: kid}); loaded.app.models.get(kid) != nil {
+			genTriangle(e, "triangle")
+		}
+	}
+}
+
+// Update counts the draw calls for both scenes.
+func (sa *saveApp) Update(eng Eng, in *Input, s *State) {
+	sa.drawn = len(eng.(*application).frame)
+}
+
+// Check that bodies with malformed shape data are rejected.
+func TestLoadSceneBadDims(t *testing.T) {
+	ba := &badDimsApp{dims: map[int][]string{
+		physics.TriMeshShape: {
+			`[-1]`,           // negative vertex count.
+			`[1.5, 0, 0, 0]`, // fractional vertex count.
+			`[2, 0, 0, 0]`,   // missing vertices.
+			`[3, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 1, 3]`,   // face index past vertices.
+			`[3, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 1, 0.5]`, // fractional face index.
+			`[3, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 1, -1]`,  // negative face index.
+		},
+		physics.HeightfieldShape: {
+			`[1, -2, 0, 0]`,      // negative rows.
+			`[1, 1.5, 0, 0]`,     // fractional rows.
+			`[1, 5, 0, 0]`,       // more rows than heights.
+			`[1, 3, 0, 0, 0, 0]`, // heights not a multiple of rows.
+		},
+		physics.CompoundShape: {
+			`[-1]`,                                // negative child count.
+			`[0.5]`,                               // fractional child count.
+			`[1, 0, 0, 0, 0, 0, 0, 0, 1, -1, 1]`,  // negative dims count.
+			`[1, 0, 0, 0, 0, 0, 0, 0, 1, 1.5, 1]`, // fractional dims count.
+			`[1, 0.5, 0, 0, 0, 0, 0, 0, 1, 1, 1]`, // fractional child shape.
+			`[1, 0, 0, 0, 0, 0, 0, 0, 1, 9, 1]`,   // missing child dims.
+		},
+	}}
+	if err := RunHeadless(ba, Headless{Ticks: 1}); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	for _, dims := range ba.loaded {
+		t.Errorf("Expected nil scene for dims %s", dims)
+	}
+}
+
+// badDimsApp loads scenes with malformed body shape data.
+type badDimsApp struct {
+	dims   map[int][]string // Malformed dims by shape type.
+	loaded []string         // Malformed dims that created a scene.
+}
+
+// Create loads a scene for each malformed body.
+func (ba *badDimsApp) Create(eng Eng, s *State) {
+	for shape, all := range ba.dims {
+		for _, dims := range all {
+			saved := fmt.Sprintf(`{"version": %d, "root": {"scene": {"cam": {}},
+ "kids": [{"body": {"shape": %d, "dims": %s}}]}}`, sceneVersion, shape, dims)
+			if eng.LoadScene(strings.NewReader(saved)) != nil {
+				ba.loaded = append(ba.loaded, dims)
+			}
+		}
+	}
+}
+
+// Update does nothing.
+func (ba *badDimsApp) Update(eng Eng, in *Input, s *State) {}
@@ -20,6 +20,7 @@ import (
 func (e *Ent) SetUI() *Ent {
 	if s := e.app.scenes.get(e.eid); s != nil {
 		s.overlay = 1    // Draw over 3D scenes.
+		s.isUI = true    // 2D view transforms.
 		s.isOrtho = true // orthographic projection
 		s.cam.vt = vo    // orthographic view transform.
 		s.cam.it = nv    // no inverse view transform needed.
@@ -127,6 +128,8 @@ type scene struct {
 	// Cam is this scenes camera data. Guaranteed to be non-nil.
 	cam     *Camera // Created automatically with a new scene.
 	isOrtho bool    // 2D or 3D orthographic projection
+	isUI    bool    // 2D orthographic view transforms. Set by SetUI.
+	post    bool    // True if drawn to the display through post passes.
 
 	// scissor the scene to be drawn within the following area.
 	scissor bool // Set true to scissor the scene.
@@ -158,6 +161,8 @@ func (s *scene) setProjection(ww, wh int) {
 	switch {
 	case s.is2D(), s.isOrtho:
 		c.setOrthographic(0, w, 0, h, c.near, c.far)
+	case s.post:
+		c.setPerspective(c.fov, w/h, c.near, c.far) // targets match display.
 	case s.fbo > 0:
 		c.setPerspective(c.fov, 1.0, c.near, c.far)
 	default:
@@ -192,6 +197,7 @@ type scenes struct {
 	shadows  map[eid]*shadows // Optional scene shadows.
 	targets  map[eid]*target  // Optional scene render targets.
 	skys     map[eid]*sky     // Optional sky dome.
+	posts    map[eid]*post    // Optional post processing passes.
 	rebinds  map[eid][]asset  // Scene assets needing rebinds.
 	released []asset          // Scene assets being disposed.
 
@@ -209,6 +215,7 @@ func newScenes() *scenes {
 	ss.shadows = map[eid]*shadows{}
 	ss.targets = map[eid]*target{}
 	ss.skys = map[eid]*sky{}
+	ss.posts = map[eid]*post{}
 	ss.rebinds = map[eid][]asset{}
 	ss.parts = []uint32{} // updated each frame
 	ss.v0 = &lin.V4{}     // scratch
@@ -260,9 +267,25 @@ func (ss *scenes) createShadows(s *scene, ld *loader) {
 	}
 }
 
+// createPost adds the render targets needed for post processing
+// a scene, queuing create requests for GPU assets.
+func (ss *scenes) createPost(s *scene, w, h int) *post {
+	if _, ok := ss.posts[s.eid]; !ok {
+		p := newPost(w, h)
+		ss.posts[s.eid] = p
+		ss.rebinds[s.eid] = append(ss.rebinds[s.eid], p.targets[0], p.targets[1])
+		s.post = true
+	}
+	return ss.posts[s.eid]
+}
+
 // setTarget enables or disables rendering to a texture target.
 // Requests to create or delete the necessary GPU resources are queued.
 func (ss *scenes) setTarget(s *scene, on bool) {
+	if _, ok := ss.posts[s.eid]; ok && on {
+		log.Printf("AsTex ignored for post processed scene %d", s.eid)
+		return
+	}
 	t, ok := ss.targets[s.eid]
 	if on && !ok {
 		t := newTarget()
@@ -288,6 +311,9 @@ func (ss *scenes) draw(app *application, fr frame) frame {
 			index := app.povs.index[sc.eid]
 			ss.parts = ss.filter(app, sc, index, ss.parts[:0])
 			fr = ss.drawScene(app, sc, ss.parts, fr)
+			if p, ok := ss.posts[sc.eid]; ok {
+				fr = p.draw(app, sc, fr) // optional post processing.
+			}
 		}
 	}
 	render.SortDraws(fr)
@@ -441,11 +467,17 @@ func (ss *scenes) setRenderTransforms(lerp float64, w, h int) {
 func (ss *scenes) rebind(eng *engine) {
 	for eid, assets := range ss.rebinds {
 		for _, a := range assets {
+			if t, ok := a.(*target); ok && t.bid != 0 {
+				continue // already bound, ie: queued twice by resize.
+			}
 			if err := eng.bind(a); err != nil {
 				log.Printf("Bind scene asset %s failed: %s", a.label(), err)
 			}
 			if t, ok := a.(*target); ok {
-				ss.all[eid].fbo = t.bid
+				// post processed scenes are drawn to the first target.
+				if p, ok := ss.posts[eid]; !ok || t == p.targets[0] {
+					ss.all[eid].fbo = t.bid
+				}
 			}
 		}
 		ss.rebinds[eid] = ss.rebinds[eid][:0] // reset preserving memory.
@@ -462,11 +494,18 @@ func (ss *scenes) release(eng *engine) {
 }
 
 // resize adjusts all the cameras to the latest application window size.
+// Post processing targets are recreated to match the new size.
 // Called by the engine each time the application window changes size.
 func (ss *scenes) resize(ww, wh int) {
 	for _, scene := range ss.all {
 		scene.setProjection(ww, wh)
 	}
+	for eid, p := range ss.posts {
+		if p.resize(ww, wh) {
+			ss.released = append(ss.released, p.targets[0], p.targets[1])
+			ss.rebinds[eid] = append(ss.rebinds[eid], p.targets[0], p.targets[1])
+		}
+	}
 }
 
 // dispose removes the scene data associated with the given entity.
@@ -487,5 +526,10 @@ func (ss *scenes) dispose(eid eid, dead []eid) []eid {
 		delete(ss.skys, eid)
 		dead = append(dead, sky.eid)
 	}
+	if p, ok := ss.posts[eid]; ok {
+		ss.released = append(ss.released, p.targets[0], p.targets[1])
+		delete(ss.posts, eid)
+		dead = append(dead, p.passes...)
+	}
 	return dead
 }
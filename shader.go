@@ -7,6 +7,7 @@ package vu
 // FUTURE: enhance design to incorporate/handle HLSL and Vulkan shaders.
 
 import (
+	"log"
 	"strings"
 )
 
@@ -20,6 +21,7 @@ type shader struct {
 	vsh     []string // Vertex shader source, empty if data not loaded.
 	fsh     []string // Fragment shader source, empty if data not loaded.
 	program uint32   // Compiled program reference. Zero if not compiled.
+	reload  *shader  // Changed source waiting to be compiled.
 
 	// Vertex layout data and uniform expectations are discovered from the
 	// shader source. This can be verified later against available data.
@@ -44,10 +46,31 @@ func (s *shader) label() string { return s.name } // asset name
 // The uniform references are set on binding and later used by Model
 // to set the uniform values during rendering.
 func (s *shader) setSource(vsh, fsh []string) {
-	s.vsh, s.fsh = vsh, fsh
+	s.vsh = append([]string(nil), vsh...) // copy: library sources are shared.
+	s.fsh = append([]string(nil), fsh...)
 	s.ensureNewLines()
 }
 
+// recompile replaces the shader program using the reloaded shader
+// source. The existing program is kept when the reloaded source fails
+// to compile. Expected to be called on the main thread.
+func (s *shader) recompile(eng *engine) {
+	fresh := s.reload
+	s.reload = nil
+	if err := eng.bind(fresh); err != nil {
+		log.Printf("Reload shader %s failed: %s", s.name, err)
+		if fresh.program != 0 {
+			eng.release(fresh)
+		}
+		return // dev error - keep using the previous program.
+	}
+	if s.program != 0 {
+		eng.release(s)
+	}
+	s.vsh, s.fsh, s.program = fresh.vsh, fresh.fsh, fresh.program
+	s.layouts, s.uniforms = fresh.layouts, fresh.uniforms
+}
+
 // stripID is a helper method used by SetSource to parse GLSL
 // shader code.
 func (s *shader) stripID(id string) string {
@@ -108,6 +131,13 @@ var shaderLibrary = map[string]func() (vsh, fsh []string){
 	"normalMapped":      func() (vsh, fsh []string) { return normalMappedShaderV, normalMappedShaderF },
 	"castShadow":        func() (vsh, fsh []string) { return castShadowShaderV, castShadowShaderF },
 	"showShadow":        func() (vsh, fsh []string) { return showShadowShaderV, showShadowShaderF },
+	"cubemapped":        func() (vsh, fsh []string) { return cubemappedShaderV, cubemappedShaderF },
+	"reflected":         func() (vsh, fsh []string) { return reflectedShaderV, reflectedShaderF },
+	"bloom":             func() (vsh, fsh []string) { return postShaderV, bloomShaderF },
+	"toneMap":           func() (vsh, fsh []string) { return postShaderV, toneMapShaderF },
+	"colorGrade":        func() (vsh, fsh []string) { return postShaderV, colorGradeShaderF },
+	"fxaa":              func() (vsh, fsh []string) { return postShaderV, fxaaShaderF },
+	"vignette":          func() (vsh, fsh []string) { return postShaderV, vignetteShaderF },
 }
 
 // FUTURE: Add edge-detect and emboss shaders, see:
@@ -588,3 +618,208 @@ var showShadowShaderF = []string{
 	"    f_color = visibility * diffuseColor * lightColor;",
 	"}",
 }
+
+// =============================================================================
+
+// cubemappedShader colors a model using a cube map texture sampled in
+// the direction of each vertex from the model center. Used for sky boxes
+// and sky domes, ie: AddSky().MakeModel("cubemapped", "msh:dome", "cub:sky"),
+// and for texturing spheres like procedurally generated planets.
+var cubemappedShaderV = []string{
+	"layout(location=0) in vec3 in_v;", // verticies
+	"",
+	"uniform mat4 pm;",  // projection matrix
+	"uniform mat4 vm;",  // view matrix
+	"uniform mat4 mm;",  // model matrix
+	"out     vec3 v_d;", // cube map direction.
+	"void main() {",
+	"   v_d = in_v;",
+	"   gl_Position = pm * vm * mm * vec4(in_v, 1.0);",
+	"}",
+}
+var cubemappedShaderF = []string{
+	"in      vec3        v_d;",     // interpolated cube map direction.
+	"uniform samplerCube uv;",      // cube map sampler.
+	"uniform float       alpha;",   // transparency
+	"out     vec4        f_color;", // final fragment color
+	"void main() {",
+	"   f_color = texture(uv, v_d);",
+	"   f_color.a *= alpha;",
+	"}",
+}
+
+// =============================================================================
+
+// reflectedShader colors a model with the environment reflected from a
+// cube map, ie: the cube map used for the sky. The reflection is tinted
+// by the material diffuse color.
+//     https://learnopengl.com/Advanced-OpenGL/Cubemaps
+var reflectedShaderV = []string{
+	"layout(location=0) in vec3 in_v;", // verticies
+	"layout(location=1) in vec3 in_n;", // vertex normals
+	"",
+	"uniform mat4 pm;",  // projection matrix
+	"uniform mat4 vm;",  // view matrix
+	"uniform mat4 mm;",  // model matrix
+	"out     vec3 v_p;", // vertex position in world space.
+	"out     vec3 v_n;", // vertex normal in world space.
+	"void main() {",
+	"   vec4 wpos = mm * vec4(in_v, 1.0);",
+	"   v_p = wpos.xyz;",
+	"   v_n = mat3(mm) * in_n;",
+	"   gl_Position = pm * vm * wpos;",
+	"}",
+}
+var reflectedShaderF = []string{
+	"in      vec3        v_p;",     // interpolated world position.
+	"in      vec3        v_n;",     // interpolated world normal.
+	"uniform vec3        viewPos;", // camera world position.
+	"uniform vec3        kd;",      // material diffuse color tints reflection.
+	"uniform float       alpha;",   // transparency
+	"uniform samplerCube uv;",      // environment cube map sampler.
+	"out     vec4        f_color;", // final fragment color
+	"void main() {",
+	"   vec3 r = reflect(normalize(v_p - viewPos), normalize(v_n));",
+	"   f_color = vec4(texture(uv, r).rgb * kd, alpha);",
+	"}",
+}
+
+// =============================================================================
+
+// postShader draws a full screen quad for the post processing passes
+// added with AddPost. The quad vertices are already in clip space.
+// Each post shader samples the previous pass result using uv.
+var postShaderV = []string{
+	"layout(location=0) in vec2 in_v;", // clip space verticies
+	"layout(location=2) in vec2 in_t;", // texture coordinates
+	"",
+	"out vec2 v_t;", // pass on vertex texture coordinates.
+	"void main() {",
+	"   v_t = in_t;",
+	"   gl_Position = vec4(in_v, 0.0, 1.0);",
+	"}",
+}
+
+// bloomShader adds a glow around bright areas. Colors brighter than the
+// threshold are averaged over a 5x5 grid of samples that are spread
+// texels apart and added back to the original color.
+var bloomShaderF = []string{
+	"in      vec2      v_t;",       // interpolated texture coordinates.
+	"uniform sampler2D uv;",        // previous pass result.
+	"uniform float     threshold;", // brightness that starts to glow.
+	"uniform float     intensity;", // glow brightness.
+	"uniform float     spread;",    // texels between glow samples.
+	"out     vec4      f_color;",   // final fragment color
+	"void main() {",
+	"   vec2 texel = spread / vec2(textureSize(uv, 0));",
+	"   vec4 color = texture(uv, v_t);",
+	"   vec3 glow = vec3(0.0);",
+	"   for (int x = -2; x <= 2; x++) {",
+	"      for (int y = -2; y <= 2; y++) {",
+	"         vec3 c = texture(uv, v_t + vec2(x, y) * texel).rgb;",
+	"         glow += max(c - vec3(threshold), vec3(0.0));",
+	"      }",
+	"   }",
+	"   f_color = vec4(color.rgb + glow * intensity / 25.0, color.a);",
+	"}",
+}
+
+// toneMapShader maps high dynamic range colors to the display range
+// using an approximation of the ACES filmic curve followed by gamma
+// correction. Exposure scales the colors before mapping.
+//     https://knarkowicz.wordpress.com/2016/01/06/aces-filmic-tone-mapping-curve/
+var toneMapShaderF = []string{
+	"in      vec2      v_t;",      // interpolated texture coordinates.
+	"uniform sampler2D uv;",       // previous pass result.
+	"uniform float     exposure;", // color scale before mapping.
+	"uniform float     gamma;",    // display gamma.
+	"out     vec4      f_color;",  // final fragment color
+	"void main() {",
+	"   vec4 color = texture(uv, v_t);",
+	"   vec3 c = color.rgb * exposure;",
+	"   c = clamp((c*(2.51*c+0.03))/(c*(2.43*c+0.59)+0.14), 0.0, 1.0);",
+	"   f_color = vec4(pow(c, vec3(1.0/gamma)), color.a);",
+	"}",
+}
+
+// colorGradeShader remaps colors using a lookup table texture. The table
+// is a 256x16 strip of 16 squares, one for each blue level, where red
+// increases left to right and green increases top to bottom.
+// Blend mixes between the original and graded colors.
+var colorGradeShaderF = []string{
+	"in      vec2      v_t;",     // interpolated texture coordinates.
+	"uniform sampler2D uv;",      // previous pass result.
+	"uniform sampler2D uv1;",     // color lookup table.
+	"uniform float     blend;",   // 1 graded, 0 original.
+	"out     vec4      f_color;", // final fragment color
+	"void main() {",
+	"   vec4 color = texture(uv, v_t);",
+	"   vec3 c = clamp(color.rgb, 0.0, 1.0);",
+	"   float b = c.b * 15.0;",
+	"   float b0 = floor(b);",
+	"   float b1 = min(b0 + 1.0, 15.0);",
+	"   vec2 rg = vec2((c.r*15.0+0.5)/256.0, (c.g*15.0+0.5)/16.0);",
+	"   vec3 g0 = texture(uv1, rg + vec2(b0/16.0, 0.0)).rgb;",
+	"   vec3 g1 = texture(uv1, rg + vec2(b1/16.0, 0.0)).rgb;",
+	"   vec3 graded = mix(g0, g1, b - b0);",
+	"   f_color = vec4(mix(color.rgb, graded, blend), color.a);",
+	"}",
+}
+
+// fxaaShader is a fast approximate anti-aliasing pass that blurs
+// along edges found by comparing the luminance of nearby texels.
+// Expected after tone mapping since it works on display colors.
+//     http://developer.download.nvidia.com/assets/gamedev/files/sdk/11/FXAA_WhitePaper.pdf
+var fxaaShaderF = []string{
+	"in      vec2      v_t;",     // interpolated texture coordinates.
+	"uniform sampler2D uv;",      // previous pass result.
+	"out     vec4      f_color;", // final fragment color
+	"void main() {",
+	"   vec2 texel = 1.0 / vec2(textureSize(uv, 0));",
+	"   vec3 luma = vec3(0.299, 0.587, 0.114);",
+	"   vec4 color = texture(uv, v_t);",
+	"   float lm  = dot(color.rgb, luma);",
+	"   float lnw = dot(texture(uv, v_t + vec2(-1.0, -1.0)*texel).rgb, luma);",
+	"   float lne = dot(texture(uv, v_t + vec2( 1.0, -1.0)*texel).rgb, luma);",
+	"   float lsw = dot(texture(uv, v_t + vec2(-1.0,  1.0)*texel).rgb, luma);",
+	"   float lse = dot(texture(uv, v_t + vec2( 1.0,  1.0)*texel).rgb, luma);",
+	"   float lmin = min(lm, min(min(lnw, lne), min(lsw, lse)));",
+	"   float lmax = max(lm, max(max(lnw, lne), max(lsw, lse)));",
+	"",
+	"", // blur direction is perpendicular to the luminance change.
+	"   vec2 dir = vec2(-((lnw + lne) - (lsw + lse)), (lnw + lsw) - (lne + lse));",
+	"   float reduce = max((lnw + lne + lsw + lse) * 0.25 * (1.0/8.0), 1.0/128.0);",
+	"   float scale = 1.0 / (min(abs(dir.x), abs(dir.y)) + reduce);",
+	"   dir = clamp(dir * scale, vec2(-8.0), vec2(8.0)) * texel;",
+	"",
+	"", // use the wider blur unless it samples past the edge.
+	"   vec3 a = 0.5 * (texture(uv, v_t + dir*(1.0/3.0-0.5)).rgb +",
+	"                   texture(uv, v_t + dir*(2.0/3.0-0.5)).rgb);",
+	"   vec3 b = a*0.5 + 0.25 * (texture(uv, v_t - dir*0.5).rgb +",
+	"                            texture(uv, v_t + dir*0.5).rgb);",
+	"   float lb = dot(b, luma);",
+	"   if (lb < lmin || lb > lmax) {",
+	"      f_color = vec4(a, color.a);",
+	"   } else {",
+	"      f_color = vec4(b, color.a);",
+	"   }",
+	"}",
+}
+
+// vignetteShader darkens the image towards the corners. Radius is the
+// distance from center, where 1 is a corner, that darkening starts
+// and softness is the distance over which it fades in.
+var vignetteShaderF = []string{
+	"in      vec2      v_t;",      // interpolated texture coordinates.
+	"uniform sampler2D uv;",       // previous pass result.
+	"uniform float     radius;",   // distance where darkening starts.
+	"uniform float     softness;", // distance to fully darken.
+	"uniform float     strength;", // 1 for black corners, 0 for none.
+	"out     vec4      f_color;",  // final fragment color
+	"void main() {",
+	"   vec4 color = texture(uv, v_t);",
+	"   float d = distance(v_t, vec2(0.5)) * 1.41421356;",
+	"   float v = 1.0 - smoothstep(radius, radius + softness, d);",
+	"   f_color = vec4(color.rgb * mix(1.0, v, strength), color.a);",
+	"}",
+}
@@ -19,6 +19,11 @@ import (
 // The returned entity expects to be populated with a sky dome model
 // and sky texture. Nil is returned if the entity is not a 3D scene or
 // if there is already a sky dome attached to the scene.
+// The sky can use a 2D texture or a cube map texture:
+//    sky.MakeModel("textured", "msh:dome", "tex:sky")
+//    sky.MakeModel("cubemapped", "msh:dome", "cub:sky")
+// A cube map sky can also be reflected by scene models using the
+// "reflected" shader and the same "cub:sky" cube map.
 func (e *Ent) AddSky() *Ent {
 	sky := e.app.scenes.createSky(e)
 	if sky == nil {
@@ -0,0 +1,167 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// tag.go groups entities using application supplied names.
+// DESIGN: tags are a component so that applications don't need to keep
+//         their own parallel maps of entities, ie: "all enemies".
+
+import (
+	"log"
+)
+
+// Component filters for Eng.Query. Combine filters to find entities
+// that have all of the requested components, ie: HasModel|HasBody.
+const (
+	HasPart  = 1 << iota // Ent.AddPart
+	HasModel             // Ent.MakeModel and all other models.
+	HasBody              // Ent.MakeBody
+	HasLight             // Ent.MakeLight
+	HasLabel             // Ent.MakeLabel
+	HasActor             // Ent.MakeActor
+)
+
+// Tag adds a name to the entity so that it can be found later using
+// Eng.Query. An entity can have many tags. Adding an existing tag
+// does nothing.
+func (e *Ent) Tag(name string) *Ent {
+	if !e.app.eids.valid(e.eid) {
+		log.Printf("Tag needs valid entity %d", e.eid)
+		return e
+	}
+	e.app.tags.add(e.eid, name)
+	return e
+}
+
+// Untag removes the tag name from the entity.
+// Does nothing if the entity does not have the tag.
+func (e *Ent) Untag(name string) *Ent {
+	e.app.tags.remove(e.eid, name)
+	return e
+}
+
+// Tags returns the tags for this entity in the order they were added.
+// The returned slice is a copy.
+func (e *Ent) Tags() []string {
+	return append([]string{}, e.app.tags.names[e.eid]...)
+}
+
+// Tagged returns true if this entity has the given tag.
+func (e *Ent) Tagged(name string) bool {
+	for _, tag := range e.app.tags.names[e.eid] {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the live entities that have the given tag and all the
+// components in the mask, ie: HasModel|HasBody. A zero mask matches
+// any components. An empty tag matches all scenes and parts.
+// Entities are returned in the order they were tagged or, for an
+// empty tag, in the order they were created.
+// Implements Eng interface.
+func (app *application) Query(tag string, mask uint32) []*Ent {
+	found := []*Ent{}
+	if tag != "" {
+		for _, id := range app.tags.ents[tag] {
+			if app.eids.valid(id) && app.hasComponents(id, mask) {
+				found = append(found, &Ent{app: app, eid: id})
+			}
+		}
+		return found
+	}
+	for index, edition := range app.eids.editions {
+		id := eid(uint32(index+1) | uint32(edition)<<idBits)
+		if !app.eids.valid(id) || !app.hasComponents(id, mask) {
+			continue
+		}
+		if mask == 0 && app.povs.get(id) == nil && app.scenes.get(id) == nil {
+			continue // ignore sounds and reserved entities.
+		}
+		found = append(found, &Ent{app: app, eid: id})
+	}
+	return found
+}
+
+// hasComponents returns true if the entity has all the
+// components in the given mask.
+func (app *application) hasComponents(id eid, mask uint32) bool {
+	switch {
+	case mask&HasPart != 0 && app.povs.get(id) == nil:
+		return false
+	case mask&HasModel != 0 && app.models.get(id) == nil:
+		return false
+	case mask&HasBody != 0 && app.bodies.get(id) == nil:
+		return false
+	case mask&HasLight != 0 && app.lights.get(id) == nil:
+		return false
+	case mask&HasLabel != 0 && app.models.getLabel(id) == nil:
+		return false
+	case mask&HasActor != 0 && app.models.getActor(id) == nil:
+		return false
+	}
+	return true
+}
+
+// tag entity methods.
+// =============================================================================
+// tags component manager.
+
+// tags tracks entity tags. Each tag lists its entities
+// and each entity lists its tags.
+type tags struct {
+	ents  map[string][]eid // Entities for each tag.
+	names map[eid][]string // Tags for each entity.
+}
+
+// newTags creates the tag component manager.
+// Expected to be called once on startup.
+func newTags() *tags {
+	return &tags{ents: map[string][]eid{}, names: map[eid][]string{}}
+}
+
+// add the tag to the entity, ignoring duplicates.
+func (ts *tags) add(id eid, name string) {
+	for _, tag := range ts.names[id] {
+		if tag == name {
+			return
+		}
+	}
+	ts.names[id] = append(ts.names[id], name)
+	ts.ents[name] = append(ts.ents[name], id)
+}
+
+// remove the tag from the entity.
+func (ts *tags) remove(id eid, name string) {
+	names := ts.names[id]
+	for cnt, tag := range names {
+		if tag == name {
+			ts.names[id] = append(names[:cnt], names[cnt+1:]...)
+			break
+		}
+	}
+	if len(ts.names[id]) == 0 {
+		delete(ts.names, id)
+	}
+	ents := ts.ents[name]
+	for cnt, ent := range ents {
+		if ent == id {
+			ts.ents[name] = append(ents[:cnt], ents[cnt+1:]...)
+			break
+		}
+	}
+	if len(ts.ents[name]) == 0 {
+		delete(ts.ents, name)
+	}
+}
+
+// dispose removes all tags from the entity.
+func (ts *tags) dispose(id eid) {
+	names := append([]string{}, ts.names[id]...)
+	for _, name := range names {
+		ts.remove(id, name)
+	}
+}
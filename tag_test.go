@@ -0,0 +1,48 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	app := newApplication(nil, nil)
+	defer app.shutdown()
+	scene := app.AddScene()
+	e0 := scene.AddPart().Tag("enemy").MakeBody(Sphere(1))
+	e1 := scene.AddPart().Tag("enemy").Tag("boss")
+	scene.AddPart().Tag("friend").MakeBody(Box(1, 1, 1))
+	if ents := app.Query("enemy", 0); len(ents) != 2 || ents[0].eid != e0.eid || ents[1].eid != e1.eid {
+		t.Errorf("Expected 2 enemies, got %d", len(ents))
+	}
+	if ents := app.Query("enemy", HasBody); len(ents) != 1 || ents[0].eid != e0.eid {
+		t.Errorf("Expected 1 enemy body, got %d", len(ents))
+	}
+	if ents := app.Query("", HasPart|HasBody); len(ents) != 2 {
+		t.Errorf("Expected 2 bodies, got %d", len(ents))
+	}
+	if ents := app.Query("", 0); len(ents) != 4 {
+		t.Errorf("Expected scene and 3 parts, got %d", len(ents))
+	}
+	if tags := e1.Tags(); len(tags) != 2 || !e1.Tagged("boss") {
+		t.Errorf("Expected enemy boss tags, got %v", tags)
+	}
+}
+
+func TestQueryDisposed(t *testing.T) {
+	app := newApplication(nil, nil)
+	defer app.shutdown()
+	scene := app.AddScene()
+	parent := scene.AddPart().Tag("enemy")
+	parent.AddPart().Tag("enemy")
+	scene.AddPart().Tag("enemy").Untag("enemy")
+	parent.Dispose()
+	if ents := app.Query("enemy", 0); len(ents) != 0 {
+		t.Errorf("Expected no enemies, got %d", len(ents))
+	}
+	if len(app.tags.ents) != 0 || len(app.tags.names) != 0 {
+		t.Errorf("Expected disposed tags to be removed")
+	}
+}
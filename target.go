@@ -40,6 +40,9 @@ type target struct {
 	// ImageBuffer depth reference. Needed for rendering texture image
 	// that uses depth to simulate rendering to a normal framebuffer.
 	db uint32 // Valid for ImageBuffer.
+
+	// Post processing targets match the display size.
+	w, h int // Zero for LayerSize targets.
 }
 
 // newTarget creates the framebuffer needed to render to a texture.
@@ -8,6 +8,7 @@ package vu
 
 import (
 	"image"
+	"log"
 )
 
 // Texture manages the link between loaded texture assets and textures
@@ -18,12 +19,16 @@ import (
 // Texture data is copied to the graphics card. One or more textures
 // can be associated with a model entity and consumed by a shader.
 type Texture struct {
-	name   string      // Unique name of the texture.
-	tag    aid         // Name and type as a number.
-	img    image.Image // Texture data.
-	tid    uint32      // Graphics card texture identifier.
-	rebind bool        // True if data needs to be sent to the GPU.
-	clamp  bool        // Set to True to trigger a one time clamp.
+	name   string        // Unique name of the texture.
+	tag    aid           // Name and type as a number.
+	img    image.Image   // Texture data.
+	mips   []image.Image // Optional precomputed mipmaps.
+	faces  []image.Image // Cube map faces. Only used by cube maps.
+	tid    uint32        // Graphics card texture identifier.
+	rebind bool          // True if data needs to be sent to the GPU.
+	clamp  bool          // True for a clamped instead of repeating texture.
+	filter int           // Texture filtering. render.Trilinear by default.
+	mode   bool          // Set to True to trigger a one time mode update.
 }
 
 // newTexture allocates space for a texture object.
@@ -31,17 +36,40 @@ func newTexture(name string) *Texture {
 	return &Texture{name: name, tag: assetID(tex, name)}
 }
 
+// newCubemap allocates space for a cube map texture. A cube map is
+// a texture with six faces instead of one image.
+func newCubemap(name string) *Texture {
+	return &Texture{name: name, tag: assetID(cub, name)}
+}
+
 // aid is used to uniquely identify assets.
 func (t *Texture) aid() aid      { return t.tag }  // hashed type and name.
 func (t *Texture) label() string { return t.name } // asset name
 
 // Set replaces the texture image - no questions asked.
 // Marks the texture as needing to be updated on the GPU.
+// Mipmaps are generated for the new image.
 func (t *Texture) Set(img image.Image) {
-	t.img = img
+	t.img, t.mips = img, nil
+	t.rebind = true
+}
+
+// SetFaces replaces the six cube map images ordered +X, -X, +Y, -Y, +Z, -Z.
+// This is the same order as the synth cube faces, ie: synth.XPos.
+// The faces must be square and the same size. Marks the cube map
+// as needing to be updated on the GPU. Ignored for non cube map textures.
+func (t *Texture) SetFaces(faces ...image.Image) {
+	if !t.isCube() || len(faces) != 6 {
+		log.Printf("SetFaces needs 6 faces for cube map %s", t.name)
+		return
+	}
+	t.faces = append(t.faces[:0], faces...)
 	t.rebind = true
 }
 
+// isCube returns true for cube map textures.
+func (t *Texture) isCube() bool { return t.tag.kind() == cub }
+
 // bind updates the texture on the GPU.
 func (t *Texture) bind(eng *engine) error {
 	t.rebind = false
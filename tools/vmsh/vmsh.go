@@ -0,0 +1,78 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// Package vmsh converts .obj and .iqm model files into the binary .vmsh
+// format that the vu engine loads in preference to the original files.
+// The .vmsh files avoid parsing text and large allocations at startup.
+//
+// Running "vmsh name.obj name.iqm ..." where:
+//     name.obj : is a static mesh.
+//     name.iqm : is an animated model.
+// will produce : name.vmsh beside each of the original files.
+//
+// Regenerate the .vmsh files whenever the original files change.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gazed/vu/load"
+)
+
+// vmsh converts each of the model files given on the command line.
+func main() {
+	failed := false
+	for _, name := range os.Args[1:] {
+		if err := convert(name); err != nil {
+			log.Printf("Could not convert %s: %s", name, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// convert reads the named model and writes it as a .vmsh file
+// with the same name and directory.
+func convert(name string) (err error) {
+	reader, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	ext := filepath.Ext(name)
+	mod := &load.ModData{}
+	switch strings.ToLower(ext) {
+	case ".obj":
+		err = load.Obj(reader, &mod.MshData)
+	case ".iqm":
+		err = load.Iqm(reader, mod)
+	default:
+		err = fmt.Errorf("expecting .obj or .iqm file")
+	}
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file so a failure leaves any old file intact.
+	out := strings.TrimSuffix(name, ext) + ".vmsh"
+	writer, err := os.Create(out + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err = load.WriteVmsh(writer, mod); err != nil {
+		writer.Close()
+		os.Remove(writer.Name())
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		os.Remove(writer.Name())
+		return err
+	}
+	return os.Rename(writer.Name(), out)
+}
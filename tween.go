@@ -0,0 +1,426 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// tween.go interpolates entity locations, orientations, scales, and
+// model uniforms over a number of update ticks. Tweens are stepped
+// each update, before App.Update, so the application always sees
+// the latest tweened values.
+// DESIGN: tweens are created by entity methods and are controlled
+//         using chained Tween methods, ie:
+//           e.TweenAt(0, 5, 0, 50).Ease(EaseOut).Yoyo().Loop(-1)
+//         Tweens are sequenced using Then to build timelines, ie:
+//           fade := e.TweenAlpha(0, 25)
+//           e.TweenScale(2, 2, 2, 25).Then(fade).OnDone(dispose)
+
+import (
+	"log"
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// TweenAt moves the entity from its current location to the given
+// location over the given number of update ticks.
+//
+// Depends on Ent.AddPart.
+func (e *Ent) TweenAt(x, y, z float64, ticks int) *Tween {
+	if e.app.povs.get(e.eid) == nil {
+		log.Printf("TweenAt needs AddPart %d", e.eid)
+	}
+	return e.app.tweens.create(e, tweenAt, "", ticks, x, y, z)
+}
+
+// TweenView rotates the entity from its current orientation to the
+// given orientation over the given number of update ticks.
+//
+// Depends on Ent.AddPart.
+func (e *Ent) TweenView(q *lin.Q, ticks int) *Tween {
+	if e.app.povs.get(e.eid) == nil {
+		log.Printf("TweenView needs AddPart %d", e.eid)
+	}
+	return e.app.tweens.create(e, tweenView, "", ticks, q.X, q.Y, q.Z, q.W)
+}
+
+// TweenScale changes the entity from its current scale to the given
+// per-axis scale over the given number of update ticks.
+//
+// Depends on Ent.AddPart.
+func (e *Ent) TweenScale(x, y, z float64, ticks int) *Tween {
+	if e.app.povs.get(e.eid) == nil {
+		log.Printf("TweenScale needs AddPart %d", e.eid)
+	}
+	return e.app.tweens.create(e, tweenScale, "", ticks, x, y, z)
+}
+
+// TweenAlpha fades the model from its current alpha to the given
+// alpha over the given number of update ticks.
+//
+// Depends on Ent.MakeModel.
+func (e *Ent) TweenAlpha(a float64, ticks int) *Tween {
+	if e.app.models.get(e.eid) == nil {
+		log.Printf("TweenAlpha needs MakeModel %d", e.eid)
+	}
+	return e.app.tweens.create(e, tweenUniform, "alpha", ticks, a)
+}
+
+// TweenColor changes the model from its current material color to the
+// given r,g,b color over the given number of update ticks.
+//
+// Depends on Ent.MakeModel.
+func (e *Ent) TweenColor(r, g, b float64, ticks int) *Tween {
+	if e.app.models.get(e.eid) == nil {
+		log.Printf("TweenColor needs MakeModel %d", e.eid)
+	}
+	return e.app.tweens.create(e, tweenUniform, "kd", ticks, r, g, b)
+}
+
+// TweenUniform changes the named shader uniform from its current values
+// to the given values over the given number of update ticks. Missing
+// current values start at 0.
+//
+// Depends on Ent.MakeModel.
+func (e *Ent) TweenUniform(id string, ticks int, values ...float64) *Tween {
+	if e.app.models.get(e.eid) == nil {
+		log.Printf("TweenUniform needs MakeModel %d", e.eid)
+	}
+	return e.app.tweens.create(e, tweenUniform, id, ticks, values...)
+}
+
+// tween entity methods.
+// =============================================================================
+// Tween
+
+// Tween changes one entity attribute over a number of update ticks.
+// Tweens start on the next update unless they are sequenced using Then.
+// Tweens are stopped if their entity is disposed.
+type Tween struct {
+	eid  eid          // Entity being tweened.
+	app  *application // Entity component managers.
+	kind int          // tweenAt, tweenView, tweenScale, tweenUniform.
+	id   string       // Uniform name.
+
+	// Interpolate from the values when the tween starts.
+	from, to []float64 // Start and end values.
+	vals     []float64 // Scratch for interpolated values.
+	ease     Ease      // Changes the rate of interpolation.
+
+	// Timing and repeats.
+	ticks   int  // Number of updates for one run.
+	tick    int  // Current update tick.
+	delay   int  // Updates to wait before starting.
+	loops   int  // Additional runs. Negative repeats forever.
+	yoyo    bool // Alternate runs go from end back to start.
+	reverse bool // True when running backwards.
+
+	// Sequencing.
+	next    []*Tween // Tweens started when this tween is done.
+	done    func()   // Called when the tween is done.
+	waiting bool     // True until a previous tween is done.
+	started bool     // True once the from values are captured.
+	stopped bool     // True once done or stopped.
+}
+
+// Ease sets the easing curve. The default is Linear.
+func (t *Tween) Ease(ease Ease) *Tween {
+	if ease != nil {
+		t.ease = ease
+	}
+	return t
+}
+
+// Delay waits the given number of update ticks before starting.
+func (t *Tween) Delay(ticks int) *Tween {
+	t.delay = ticks
+	return t
+}
+
+// Loop runs the tween again the given number of times once
+// it reaches the end. Negative values loop forever.
+func (t *Tween) Loop(times int) *Tween {
+	t.loops = times
+	return t
+}
+
+// Yoyo runs each loop in the opposite direction of the previous run.
+// Use with Loop to go back to the start values.
+func (t *Tween) Yoyo() *Tween {
+	t.yoyo = true
+	return t
+}
+
+// OnDone calls the given function once the tween, including
+// all of its loops, is done. It is called on the update goroutine.
+func (t *Tween) OnDone(done func()) *Tween {
+	t.done = done
+	return t
+}
+
+// Then starts the given tweens once this tween is done.
+// Multiple tweens are run at the same time. The last given
+// tween is returned so that sequences can be chained, ie:
+//    a.Then(b).Then(c) // runs a, then b, then c.
+func (t *Tween) Then(next ...*Tween) *Tween {
+	for _, n := range next {
+		n.waiting = true
+		t.next = append(t.next, n)
+	}
+	if len(next) > 0 {
+		return next[len(next)-1]
+	}
+	return t
+}
+
+// Stop ends the tween leaving the entity with its current values.
+// OnDone is not called and any following tweens are also stopped.
+func (t *Tween) Stop() {
+	t.stopped = true
+	for _, n := range t.next {
+		n.Stop()
+	}
+}
+
+// Done returns true if the tween has finished or was stopped.
+func (t *Tween) Done() bool { return t.stopped }
+
+// capture saves the starting values. Returns false if the
+// entity no longer has the component needed by the tween.
+func (t *Tween) capture() bool {
+	var from []float64
+	switch t.kind {
+	case tweenAt, tweenScale, tweenView:
+		p := t.app.povs.get(t.eid)
+		if p == nil {
+			return false
+		}
+		switch t.kind {
+		case tweenAt:
+			from = append(from, p.tn.Loc.X, p.tn.Loc.Y, p.tn.Loc.Z)
+		case tweenScale:
+			from = append(from, p.sn.X, p.sn.Y, p.sn.Z)
+		case tweenView:
+			r := p.tn.Rot
+			from = append(from, r.X, r.Y, r.Z, r.W)
+			if r.X*t.to[0]+r.Y*t.to[1]+r.Z*t.to[2]+r.W*t.to[3] < 0 {
+				for cnt := range t.to { // rotate the short way.
+					t.to[cnt] = -t.to[cnt]
+				}
+			}
+		}
+	case tweenUniform:
+		m := t.app.models.get(t.eid)
+		if m == nil {
+			return false
+		}
+		current := m.uniforms[t.id]
+		switch {
+		case t.id == "alpha" && len(current) == 0:
+			from = append(from, m.alpha())
+		case t.id == "kd" && len(current) == 0:
+			from = append(from, 1, 1, 1)
+			if m.mat != nil {
+				kd := m.mat.kd
+				from = append(from[:0], float64(kd.R), float64(kd.G), float64(kd.B))
+			}
+		default:
+			for cnt := range t.to {
+				if cnt < len(current) {
+					from = append(from, float64(current[cnt]))
+				} else {
+					from = append(from, 0)
+				}
+			}
+		}
+	}
+	t.from = from
+	t.vals = make([]float64, len(t.to))
+	return true
+}
+
+// apply sets the entity values for the given 0-1 run ratio.
+func (t *Tween) apply(ratio float64) {
+	if t.reverse {
+		ratio = 1 - ratio
+	}
+	ratio = t.ease(ratio)
+	for cnt := range t.vals {
+		t.vals[cnt] = lin.Lerp(t.from[cnt], t.to[cnt], ratio)
+	}
+	v := t.vals
+	switch t.kind {
+	case tweenAt, tweenScale, tweenView:
+		if p := t.app.povs.get(t.eid); p != nil {
+			switch t.kind {
+			case tweenAt:
+				p.tn.Loc.SetS(v[0], v[1], v[2])
+			case tweenScale:
+				p.sn.SetS(v[0], v[1], v[2])
+			case tweenView:
+				p.tn.Rot.SetS(v[0], v[1], v[2], v[3]).Unit()
+			}
+			t.app.povs.updateWorld(p, t.eid)
+		}
+	case tweenUniform:
+		if m := t.app.models.get(t.eid); m != nil {
+			values := m.uniforms[t.id][:0] // reset preserving memory.
+			for _, val := range v {
+				values = append(values, float32(val))
+			}
+			m.uniforms[t.id] = values
+		}
+	}
+}
+
+// Tween
+// =============================================================================
+// Ease
+
+// Ease changes the rate of a tween. It maps the tween run ratio
+// from 0 to 1 to an eased ratio that starts at 0 and ends at 1.
+// Eased ratios can go outside 0 to 1, ie: BackOut overshoots.
+type Ease func(ratio float64) float64
+
+// Linear is the default constant rate easing.
+func Linear(r float64) float64 { return r }
+
+// EaseIn starts slow and speeds up.
+func EaseIn(r float64) float64 { return r * r }
+
+// EaseOut starts fast and slows down.
+func EaseOut(r float64) float64 { return r * (2 - r) }
+
+// EaseInOut starts slow, speeds up, and slows down at the end.
+func EaseInOut(r float64) float64 {
+	if r < 0.5 {
+		return 2 * r * r
+	}
+	return -1 + (4-2*r)*r
+}
+
+// SineInOut is a gentler version of EaseInOut.
+func SineInOut(r float64) float64 { return 0.5 * (1 - math.Cos(math.Pi*r)) }
+
+// BackOut overshoots the end and then settles back.
+func BackOut(r float64) float64 {
+	const s = 1.70158 // standard 10% overshoot.
+	r = r - 1
+	return r*r*((s+1)*r+s) + 1
+}
+
+// ElasticOut overshoots the end and springs back and forth.
+func ElasticOut(r float64) float64 {
+	if r == 0 || r == 1 {
+		return r
+	}
+	return math.Pow(2, -10*r)*math.Sin((r-0.075)*(2*math.Pi)/0.3) + 1
+}
+
+// BounceOut bounces off the end like a dropped ball.
+func BounceOut(r float64) float64 {
+	switch {
+	case r < 1/2.75:
+		return 7.5625 * r * r
+	case r < 2/2.75:
+		r -= 1.5 / 2.75
+		return 7.5625*r*r + 0.75
+	case r < 2.5/2.75:
+		r -= 2.25 / 2.75
+		return 7.5625*r*r + 0.9375
+	}
+	r -= 2.625 / 2.75
+	return 7.5625*r*r + 0.984375
+}
+
+// Ease
+// =============================================================================
+// tweens component manager.
+
+// Kinds of tweens.
+const (
+	tweenAt      = iota // Ent.SetAt
+	tweenView           // Ent.SetView
+	tweenScale          // Ent.SetScale
+	tweenUniform        // Ent.SetUniform, SetAlpha, SetColor
+)
+
+// tweens runs all the active tweens. Tweens are kept in creation order.
+type tweens struct {
+	active []*Tween // Running and waiting tweens.
+}
+
+// newTweens creates the tween component manager.
+// Expected to be called once on startup.
+func newTweens() *tweens { return &tweens{active: []*Tween{}} }
+
+// create a tween that starts on the next update.
+func (ts *tweens) create(e *Ent, kind int, id string, ticks int, to ...float64) *Tween {
+	if ticks < 1 {
+		ticks = 1 // finish on the next update.
+	}
+	t := &Tween{eid: e.eid, app: e.app, kind: kind, id: id, ticks: ticks, ease: Linear}
+	t.to = append(t.to, to...)
+	ts.active = append(ts.active, t)
+	return t
+}
+
+// step advances all running tweens by one update tick.
+// Tweens created by OnDone callbacks start on the next update.
+func (ts *tweens) step(app *application) {
+	for _, t := range ts.active {
+		switch {
+		case t.stopped || t.waiting:
+			continue
+		case !app.eids.valid(t.eid):
+			t.Stop() // disposed entities end their tweens.
+			continue
+		case t.delay > 0:
+			t.delay--
+			continue
+		case !t.started:
+			t.started = true
+			if !t.capture() {
+				ts.finish(t)
+				continue
+			}
+		}
+		t.tick++
+		t.apply(float64(t.tick) / float64(t.ticks))
+		if t.tick >= t.ticks {
+			if t.loops == 0 {
+				ts.finish(t)
+				continue
+			}
+			if t.loops > 0 {
+				t.loops--
+			}
+			t.tick = 0
+			if t.yoyo {
+				t.reverse = !t.reverse
+			}
+		}
+	}
+
+	// remove finished tweens preserving order.
+	active := ts.active[:0]
+	for _, t := range ts.active {
+		if !t.stopped {
+			active = append(active, t)
+		}
+	}
+	for cnt := len(active); cnt < len(ts.active); cnt++ {
+		ts.active[cnt] = nil // release references.
+	}
+	ts.active = active
+}
+
+// finish completes a tween and starts any following tweens.
+func (ts *tweens) finish(t *Tween) {
+	t.stopped = true
+	for _, n := range t.next {
+		n.waiting = false
+	}
+	if t.done != nil {
+		t.done()
+	}
+}
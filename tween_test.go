@@ -0,0 +1,87 @@
+// Copyright © 2018 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+func TestTweenAt(t *testing.T) {
+	app := newApplication(nil, nil)
+	defer app.shutdown()
+	e := app.AddScene().AddPart().SetAt(0, 0, 0)
+	done := 0
+	tw := e.TweenAt(4, 8, 0, 4).OnDone(func() { done++ })
+	app.tweens.step(app)
+	if x, y, _ := e.At(); x != 1 || y != 2 {
+		t.Errorf("Expected 1 2 after one tick, got %f %f", x, y)
+	}
+	for cnt := 0; cnt < 5; cnt++ {
+		app.tweens.step(app)
+	}
+	if x, y, _ := e.At(); x != 4 || y != 8 || !tw.Done() || done != 1 {
+		t.Errorf("Expected done at 4 8, got %f %f %t %d", x, y, tw.Done(), done)
+	}
+	if len(app.tweens.active) != 0 {
+		t.Errorf("Expected finished tweens to be removed")
+	}
+}
+
+func TestTweenSequence(t *testing.T) {
+	app := newApplication(nil, nil)
+	defer app.shutdown()
+	e := app.AddScene().AddPart()
+	e.MakeModel("colored")
+	fade := e.TweenAlpha(0, 2)
+	e.TweenScale(3, 3, 3, 2).Then(fade)
+	app.tweens.step(app)
+	if sx, _, _ := e.Scale(); sx != 2 || e.Alpha() != 1 {
+		t.Errorf("Expected scale before fade, got %f %f", sx, e.Alpha())
+	}
+	app.tweens.step(app) // scale done, fade starts.
+	app.tweens.step(app)
+	if sx, _, _ := e.Scale(); sx != 3 || !lin.Aeq(e.Alpha(), 0.5) {
+		t.Errorf("Expected half faded, got %f %f", sx, e.Alpha())
+	}
+}
+
+func TestTweenYoyo(t *testing.T) {
+	app := newApplication(nil, nil)
+	defer app.shutdown()
+	e := app.AddScene().AddPart()
+	e.MakeModel("colored")
+	e.TweenUniform("time", 2, 10).Loop(1).Yoyo().Ease(EaseInOut)
+	want := []float32{5, 10, 5, 0}
+	for cnt, w := range want {
+		app.tweens.step(app)
+		if got := app.models.get(e.eid).uniforms["time"]; len(got) != 1 || got[0] != w {
+			t.Errorf("Tick %d expected %f, got %v", cnt, w, got)
+		}
+	}
+}
+
+func TestTweenDisposed(t *testing.T) {
+	app := newApplication(nil, nil)
+	defer app.shutdown()
+	e := app.AddScene().AddPart()
+	tw := e.TweenAt(1, 1, 1, 10).Loop(-1)
+	next := e.TweenScale(2, 2, 2, 10)
+	tw.Then(next)
+	app.tweens.step(app)
+	e.Dispose()
+	app.tweens.step(app)
+	if !tw.Done() || !next.Done() || len(app.tweens.active) != 0 {
+		t.Errorf("Expected tweens stopped for disposed entity")
+	}
+}
+
+func TestEases(t *testing.T) {
+	for cnt, ease := range []Ease{Linear, EaseIn, EaseOut, EaseInOut, SineInOut, BackOut, ElasticOut, BounceOut} {
+		if !lin.AeqZ(ease(0)) || !lin.Aeq(ease(1), 1) {
+			t.Errorf("Ease %d expected 0 to 1, got %f %f", cnt, ease(0), ease(1))
+		}
+	}
+}
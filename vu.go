@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/gazed/vu/device"
+	"github.com/gazed/vu/load"
 	"github.com/gazed/vu/physics"
 	"github.com/gazed/vu/render"
 )
@@ -38,11 +39,19 @@ import (
 // The render loop calls the application through the App interface.
 // Run is expected to be called once on application startup.
 //    app  : used by engine to communicate with App.
-func Run(app App) (err error) {
+func Run(app App) (err error) { return RunWith(app, nil) }
+
+// RunWith is Run using the given Locator to find assets instead of
+// the default load.NewLocator. For example load.NewFSLocator finds
+// assets embedded in the application and load.NewOverlay lets a mod
+// or patch directory override individual assets.
+//    app  : used by engine to communicate with App.
+//    loc  : finds assets. Uses the default Locator if nil.
+func RunWith(app App, loc load.Locator) (err error) {
 	if app == nil {
 		return fmt.Errorf("No application. Shutting down.")
 	}
-	eng, err := newEngine(app) // engine operator starts engine.
+	eng, err := newEngine(app, loc) // engine operator starts engine.
 	if err != nil {
 		return err
 	}
@@ -78,6 +87,54 @@ func Sphere(radius float64) Body {
 	return physics.NewBody(physics.NewSphere(radius))
 }
 
+// Capsule creates a pill shaped physics body located at the origin.
+// The capsule is aligned with the Y axis. It is a cylinder with
+// the given radius and half height capped by half spheres.
+func Capsule(radius, halfHeight float64) Body {
+	return physics.NewBody(physics.NewCapsule(radius, halfHeight))
+}
+
+// Cylinder creates a cylinder shaped physics body located at the origin.
+// The cylinder is aligned with the Y axis and has the given radius
+// and half height.
+func Cylinder(radius, halfHeight float64) Body {
+	return physics.NewBody(physics.NewCylinder(radius, halfHeight))
+}
+
+// Hull creates a convex physics body located at the origin that
+// surrounds the given x, y, z points, ie: load.MshData.V.
+func Hull(points []float32) Body {
+	return physics.NewBody(physics.NewHull(points))
+}
+
+// TriMesh creates a static physics body located at the origin from
+// triangle mesh vertices and faces, ie: load.MshData.V and load.MshData.F.
+// Triangles collide from the front only. Use zero mass for the body.
+func TriMesh(vertices []float32, faces []uint32) Body {
+	return physics.NewBody(physics.NewTriMesh(vertices, faces))
+}
+
+// Heightfield creates a static terrain physics body centered on the
+// origin from a grid of heights, ie: synth.Tile.Topo(). The grid points
+// are spacing apart and each height is multiplied by scale.
+// Use zero mass for the body.
+func Heightfield(topo [][]float64, spacing, scale float64) Body {
+	return physics.NewBody(physics.NewHeightfield(topo, spacing, scale))
+}
+
+// Compound creates a single physics body located at the origin from
+// the shapes of the given bodies. Each body's current location and
+// direction becomes the offset of its shape within the compound,
+// ie: arrange the parts of a vehicle around the origin and then join
+// them so they move as one.
+func Compound(parts ...Body) Body {
+	compound := physics.NewCompound()
+	for _, part := range parts {
+		compound.Add(part.Shape(), part.World())
+	}
+	return physics.NewBody(compound)
+}
+
 // Ray creates a ray located at the origin and pointing in the
 // direction dx, dy, dz.
 func Ray(dx, dy, dz float64) Body {